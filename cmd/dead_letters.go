@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"__MODULE__/internal/client/bus"
+	dto "__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/internal/repository"
+	"__MODULE__/internal/usecase"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	deadLettersTopic string
+	deadLettersLimit int
+)
+
+// deadLettersCmd groups the commands inspecting the messages the bus consumers
+// gave up on. Listing works on the database only; replaying needs the brokers.
+var deadLettersCmd = &cobra.Command{
+	Use:   "dead-letters",
+	Short: "inspect and replay the messages the bus consumers dead-lettered",
+}
+
+var deadLettersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the latest dead letters, newest first",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		c, err := newConsumers(nil)
+		if err != nil {
+			return err
+		}
+		items, err := c.ListDeadLetters(context.Background(), dto.ListDeadLettersRequestDTO{Topic: deadLettersTopic, Limit: deadLettersLimit})
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTOPIC\tOFFSET\tATTEMPTS\tCREATED\tREPLAYED\tREASON")
+		for _, d := range items {
+			replayed := "-"
+			if d.ReplayedAt != nil {
+				replayed = d.ReplayedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\t%s\t%s\n", d.ID, d.Topic, d.Offset, d.Attempts, d.CreatedAt.Format(time.RFC3339), replayed, d.Reason)
+		}
+		return w.Flush()
+	},
+}
+
+var deadLettersShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "print a dead letter with its headers and value",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		id, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			return fmt.Errorf("invalid dead letter id %q", args[0])
+		}
+		c, err := newConsumers(nil)
+		if err != nil {
+			return err
+		}
+		d, err := c.GetDeadLetter(context.Background(), uint(id))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("id:          %d\ntopic:       %s\nconsumer:    %s\ndead letter: %s\npartition:   %d\noffset:      %d\nkey:         %s\nattempts:    %d\nreason:      %s\ncreated:     %s\n",
+			d.ID, d.Topic, d.Consumer, d.DeadLetterTopic, d.Partition, d.Offset, d.Key, d.Attempts, d.Reason, d.CreatedAt.Format(time.RFC3339Nano))
+		if d.ReplayedAt != nil {
+			fmt.Printf("replayed:    %s\n", d.ReplayedAt.Format(time.RFC3339Nano))
+		}
+		names := make([]string, 0, len(d.Headers))
+		for name := range d.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s: %s\n", name, d.Headers[name])
+		}
+		fmt.Println(string(d.Value))
+		return nil
+	},
+}
+
+var deadLettersReplayCmd = &cobra.Command{
+	Use:   "replay <id>...",
+	Short: "publish dead letters to the topic they were received from again",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		ids := make([]uint, 0, len(args))
+		for _, arg := range args {
+			id, err := strconv.ParseUint(arg, 10, 0)
+			if err != nil {
+				return fmt.Errorf("invalid dead letter id %q", arg)
+			}
+			ids = append(ids, uint(id))
+		}
+		// the in-memory bus only delivers within this process
+		if len(conf.Brokers) == 0 {
+			return errors.New("replaying needs the kafka brokers, set KAFKA_BROKERS")
+		}
+		messageBus, err := bus.New(conf)
+		if err != nil {
+			return err
+		}
+		defer messageBus.Close()
+		c, err := newConsumers(messageBus)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			d, err := c.ReplayDeadLetter(context.Background(), id)
+			if err != nil {
+				return fmt.Errorf("dead letter %d: %w", id, err)
+			}
+			fmt.Printf("dead letter %d replayed to %s\n", d.ID, d.Topic)
+		}
+		return nil
+	},
+}
+
+// newConsumers connects to the database and returns the consumer usecase
+// publishing to b.
+func newConsumers(b interfaces.MessageBus) (interfaces.ConsumerUsecase, error) {
+	rp := repository.NewServiceRepository(conf)
+	if repository.DB() == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+	return usecase.NewConsumerUsecase(rp, b, conf.UserEventConsumerConfig), nil
+}
+
+func init() {
+	rootCmd.AddCommand(deadLettersCmd)
+	deadLettersCmd.AddCommand(deadLettersListCmd, deadLettersShowCmd, deadLettersReplayCmd)
+
+	deadLettersListCmd.Flags().StringVar(&deadLettersTopic, "topic", "", "only list the dead letters received from this topic")
+	deadLettersListCmd.Flags().IntVar(&deadLettersLimit, "limit", 20, "maximum number of dead letters to list")
+}
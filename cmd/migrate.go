@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+
+	"__MODULE__/internal/repository"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var migrateLegacyProvider string
+
+// migrateCmd creates or updates the database tables of the service.
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "create or update the database tables",
+	Run: func(_ *cobra.Command, _ []string) {
+		repository.NewServiceRepository(conf)
+		if err := repository.Migrate(migrateLegacyProvider); err != nil {
+			log.Error("failed to migrate the database: " + err.Error())
+			os.Exit(1)
+		}
+		log.Info("database migrated")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVar(&migrateLegacyProvider, "legacy-provider", "",
+		"provider instance that users stored without a provider and with a non-UUID id are attributed to")
+}
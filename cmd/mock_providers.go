@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"__MODULE__/internal/mockserver"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var mockProvidersOpts struct {
+	addr    string
+	fixture string
+	users   int
+	mockserver.Options
+}
+
+// mockProvidersCmd serves fake reqres and jsonplaceholder upstreams. Point
+// REQRES_BASE_URL and JSONPLACEHOLDER_BASE_URL at it to run serve offline.
+var mockProvidersCmd = &cobra.Command{
+	Use:   "mock-providers",
+	Short: "serve fake reqres and jsonplaceholder upstreams",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		o := mockProvidersOpts
+		users := mockserver.Generate(o.users, o.Seed)
+		if o.fixture != "" {
+			var err error
+			if users, err = mockserver.LoadFixture(o.fixture); err != nil {
+				return err
+			}
+		}
+
+		e := mockserver.New(users, o.Options).Handler()
+		errCh := make(chan error, 1)
+		go func() {
+			log.WithFields(log.Fields{"addr": o.addr, "users": len(users)}).Info("mock providers listening")
+			if err := e.Start(o.addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+		select {
+		case err := <-errCh:
+			return err
+		case <-stop:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return e.Shutdown(ctx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mockProvidersCmd)
+
+	f := mockProvidersCmd.Flags()
+	f.StringVar(&mockProvidersOpts.addr, "addr", ":8010", "listen address")
+	f.StringVar(&mockProvidersOpts.fixture, "fixture", "", "JSON file with the users to serve; generated users are served when empty")
+	f.IntVar(&mockProvidersOpts.users, "users", 12, "number of generated users")
+	f.Uint64Var(&mockProvidersOpts.Seed, "seed", 1, "seed of the generated users and injected faults")
+	f.IntVar(&mockProvidersOpts.PerPage, "per-page", 6, "reqres page size")
+	f.DurationVar(&mockProvidersOpts.Latency, "latency", 0, "delay added to every answer")
+	f.DurationVar(&mockProvidersOpts.Jitter, "jitter", 0, "random extra delay of up to this much")
+	f.Float64Var(&mockProvidersOpts.ErrorRate, "error-rate", 0, "share of requests answered with 500, 0-1")
+	f.Float64Var(&mockProvidersOpts.RateLimitRate, "rate-limit-rate", 0, "share of requests answered with 429, 0-1")
+	f.DurationVar(&mockProvidersOpts.RetryAfter, "retry-after", time.Second, "Retry-After sent with injected 429s")
+	f.StringVar(&mockProvidersOpts.APIKey, "api-key", "", "x-api-key required by the reqres endpoint")
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	dto "__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/internal/repository"
+	"__MODULE__/internal/usecase"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	outboxStatus string
+	outboxLimit  int
+	outboxFromID uint
+)
+
+// outboxCmd groups the commands inspecting the user event outbox. They work
+// on the database directly, so they also help while the server is down.
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "inspect and replay the user events relayed to the message bus",
+}
+
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the latest outbox events, newest first",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		req := dto.ListOutboxEventsRequestDTO{Limit: outboxLimit}
+		switch outboxStatus {
+		case "":
+		case "pending", "sent":
+			pending := outboxStatus == "pending"
+			req.Pending = &pending
+		default:
+			return fmt.Errorf("unknown status %q, want pending or sent", outboxStatus)
+		}
+		o, err := newOutbox()
+		if err != nil {
+			return err
+		}
+		events, err := o.ListEvents(context.Background(), req)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEVENT ID\tTYPE\tUSER\tCREATED\tSENT")
+		for _, e := range events {
+			sent := "pending"
+			if e.SentAt != nil {
+				sent = e.SentAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.EventID, e.EventType, e.UserID, e.CreatedAt.Format(time.RFC3339), sent)
+		}
+		return w.Flush()
+	},
+}
+
+var outboxShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "print an outbox event with its payload",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		id, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			return fmt.Errorf("invalid outbox event id %q", args[0])
+		}
+		o, err := newOutbox()
+		if err != nil {
+			return err
+		}
+		e, err := o.GetEvent(context.Background(), uint(id))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("id:       %d\nevent id: %s\ntype:     %s\nuser:     %s\ncreated:  %s\n",
+			e.ID, e.EventID, e.EventType, e.UserID, e.CreatedAt.Format(time.RFC3339Nano))
+		if e.SentAt != nil {
+			fmt.Printf("sent:     %s\n", e.SentAt.Format(time.RFC3339Nano))
+		} else {
+			fmt.Println("sent:     pending")
+		}
+		var payload bytes.Buffer
+		if json.Indent(&payload, e.Payload, "", "  ") != nil {
+			payload.Reset()
+			payload.Write(e.Payload)
+		}
+		fmt.Println(payload.String())
+		return nil
+	},
+}
+
+var outboxReplayCmd = &cobra.Command{
+	Use:   "replay [id...]",
+	Short: "publish sent outbox events again, by id or from --from on",
+	RunE: func(_ *cobra.Command, args []string) error {
+		req := dto.ReplayOutboxEventsRequestDTO{FromID: outboxFromID}
+		for _, arg := range args {
+			id, err := strconv.ParseUint(arg, 10, 0)
+			if err != nil {
+				return fmt.Errorf("invalid outbox event id %q", arg)
+			}
+			req.IDs = append(req.IDs, uint(id))
+		}
+		if len(req.IDs) == 0 && req.FromID == 0 {
+			return errors.New("select the events to replay by id or with --from")
+		}
+		o, err := newOutbox()
+		if err != nil {
+			return err
+		}
+		n, err := o.ReplayEvents(context.Background(), req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d events queued for replay\n", n)
+		return nil
+	},
+}
+
+// newOutbox connects to the database and returns the outbox usecase, without
+// a message bus: the running servers relay the events.
+func newOutbox() (interfaces.OutboxUsecase, error) {
+	rp := repository.NewServiceRepository(conf)
+	if repository.DB() == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+	return usecase.NewOutboxUsecase(rp, nil, conf.OutboxConfig), nil
+}
+
+func init() {
+	rootCmd.AddCommand(outboxCmd)
+	outboxCmd.AddCommand(outboxListCmd, outboxShowCmd, outboxReplayCmd)
+
+	outboxListCmd.Flags().StringVar(&outboxStatus, "status", "", "only list pending or sent events")
+	outboxListCmd.Flags().IntVar(&outboxLimit, "limit", 20, "maximum number of events to list")
+	outboxReplayCmd.Flags().UintVar(&outboxFromID, "from", 0, "replay every sent event from this id on")
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"__MODULE__/internal/client/integration"
+	adapter "__MODULE__/internal/dto/adapter/http"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	providersAddr   string
+	providersToken  string
+	providersConfig string
+	providersLimit  int
+	providersWait   time.Duration
+)
+
+// providersCmd groups the runtime provider administration commands. Apart from
+// "types", every subcommand talks to the admin API of a running serve instance.
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "administer user provider instances of a running server",
+}
+
+var providersTypesCmd = &cobra.Command{
+	Use:   "types",
+	Short: "list supported provider types",
+	Run: func(_ *cobra.Command, _ []string) {
+		for _, name := range integration.SupportedProviders() {
+			fmt.Println(name)
+		}
+	},
+}
+
+var providersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list registered provider instances and their status",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return adminRequest(http.MethodGet, "/admin/providers", nil)
+	},
+}
+
+var providersRegisterCmd = &cobra.Command{
+	Use:   "register <id> <type>",
+	Short: "register a new provider instance",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		req := adapter.RegisterProviderRequestDTO{Id: args[0], Type: args[1]}
+		if providersConfig != "" {
+			req.Config = json.RawMessage(providersConfig)
+		}
+		return adminRequest(http.MethodPost, "/admin/providers", req)
+	},
+}
+
+var providersReplaceCmd = &cobra.Command{
+	Use:   "replace <id> <type>",
+	Short: "replace a registered provider instance with a new one",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		req := adapter.ReplaceProviderRequestDTO{Type: args[1]}
+		if providersConfig != "" {
+			req.Config = json.RawMessage(providersConfig)
+		}
+		return adminRequest(http.MethodPut, "/admin/providers/"+args[0], req)
+	},
+}
+
+var providersStopCmd = &cobra.Command{
+	Use:   "stop <id>",
+	Short: "stop a registered provider instance",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return adminRequest(http.MethodDelete, "/admin/providers/"+args[0], nil)
+	},
+}
+
+var providersSyncCmd = &cobra.Command{
+	Use:   "sync [id]",
+	Short: "synchronise a provider instance into the database, or all of them in the background",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return adminRequest(http.MethodPost, "/admin/sync", nil)
+		}
+		return adminRequest(http.MethodPost, "/admin/providers/"+args[0]+"/sync", nil)
+	},
+}
+
+var providersSyncRunsCmd = &cobra.Command{
+	Use:   "sync-runs [id]",
+	Short: "list the latest synchronisation runs",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		q := url.Values{}
+		if len(args) == 1 {
+			q.Set("provider", args[0])
+		}
+		if providersLimit > 0 {
+			q.Set("limit", strconv.Itoa(providersLimit))
+		}
+		return adminRequest(http.MethodGet, "/admin/sync/runs?"+q.Encode(), nil)
+	},
+}
+
+// adminRequest sends body as JSON to the admin API, authenticated with the
+// admin token, and prints the response.
+func adminRequest(method, path string, body any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(providersAddr, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if providersToken != "" {
+		req.Header.Set("Authorization", "Bearer "+providersToken)
+	}
+
+	client := &http.Client{Timeout: providersWait}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if len(respBody) > 0 {
+		var out bytes.Buffer
+		if json.Indent(&out, respBody, "", "  ") == nil {
+			respBody = out.Bytes()
+		}
+		fmt.Fprintln(os.Stdout, string(respBody))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("admin api returned %s", resp.Status)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(providersCmd)
+	providersCmd.AddCommand(providersTypesCmd, providersListCmd, providersRegisterCmd, providersReplaceCmd, providersStopCmd,
+		providersSyncCmd, providersSyncRunsCmd)
+
+	providersCmd.PersistentFlags().StringVar(&providersAddr, "addr", "http://localhost:8009", "base URL of the running server")
+	providersCmd.PersistentFlags().StringVar(&providersToken, "token", os.Getenv("ADMIN_TOKEN"), "admin bearer token, defaults to $ADMIN_TOKEN")
+	providersCmd.PersistentFlags().DurationVar(&providersWait, "timeout", 5*time.Minute, "how long to wait for the server, a single provider sync can take a while")
+	providersRegisterCmd.Flags().StringVar(&providersConfig, "config", "", "provider config as a JSON object")
+	providersReplaceCmd.Flags().StringVar(&providersConfig, "config", "", "provider config as a JSON object")
+	providersSyncRunsCmd.Flags().IntVar(&providersLimit, "limit", 0, "maximum number of runs to list")
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	handler "__MODULE__/internal/adapter/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvidersCmd_SendsAdminToken(t *testing.T) {
+	e := echo.New()
+	e.GET("/admin/providers", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, []string{})
+	}, handler.AdminAuth("secret"))
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	run := func(args ...string) error {
+		t.Cleanup(func() { providersToken = "" })
+		rootCmd.SetArgs(append([]string{"providers", "list", "--addr", srv.URL}, args...))
+		return rootCmd.Execute()
+	}
+
+	require.NoError(t, run("--token", "secret"))
+	assert.ErrorContains(t, run("--token", "wrong"), "401")
+}
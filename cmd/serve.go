@@ -4,12 +4,16 @@ Package cmd provides the CLI interface for the open-banking service.
 package cmd
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"__MODULE__/internal/adapter/consumer"
 	"__MODULE__/internal/adapter/http"
+	"__MODULE__/internal/client/bus"
 	"__MODULE__/internal/client/integration"
+	"__MODULE__/internal/client/webhook"
 	"__MODULE__/internal/repository"
 	"__MODULE__/internal/usecase"
 	"__MODULE__/internal/worker"
@@ -42,26 +46,70 @@ var serveCmd = &cobra.Command{
 		// fmt.Println(settingService)
 		pr := integration.NewUserProviderService(conf)
 
-		err := pr.RegisterNewProvider(integration.JsonPlaceholderProvider, integration.JsonPlaceholderProvider, "")
+		err := pr.RegisterInstances(conf.Instances)
 		if err != nil {
-			log.Error("failed to get RegisterNewProvider: " + err.Error())
+			log.Error("failed to register provider instances: " + err.Error())
 			os.Exit(1)
 		}
-		userSvc, err := pr.GetUserService(integration.JsonPlaceholderProvider)
+		defaultProvider := conf.ProviderInstancesConfig.Default()
+		if _, err := pr.GetUserService(defaultProvider); err != nil {
+			log.Error("default provider is not registered: " + defaultProvider)
+			os.Exit(1)
+		}
+		// resolves the instance per request (?provider= / X-User-Provider) and
+		// on every call, so admin replacements take effect immediately
+		userSvc := pr.UserServiceRouter(defaultProvider)
+
+		sender, err := webhook.NewSender(conf)
+		if err != nil {
+			log.Error("failed to create webhook sender: " + err.Error())
+			os.Exit(1)
+		}
+		outboundWebhooks := usecase.NewOutboundWebhookUsecase(rp, sender, conf.OutboundWebhookConfig)
+		outboundWebhooks.Start(context.Background())
+
+		messageBus, err := bus.New(conf)
 		if err != nil {
-			log.Error("failed to get user provider instance: " + err.Error())
+			log.Error("failed to create message bus: " + err.Error())
 			os.Exit(1)
 		}
+		defer messageBus.Close()
 
-		userUsecase := usecase.NewUserUsecase(rp, userSvc, 50)
+		archive := usecase.WithRawArchive(conf.RawArchiveConfig)
+		userOpts := []usecase.Option{archive, usecase.WithUpsertPolicy(conf.UserUpsertConfig), usecase.WithUserEvents(outboundWebhooks)}
+		if conf.OutboxEnabled {
+			usecase.NewOutboxUsecase(rp, messageBus, conf.OutboxConfig).Start(context.Background())
+			userOpts = append(userOpts, usecase.WithOutbox())
+		}
+		userUsecase := usecase.NewUserUsecase(rp, userSvc, 50, userOpts...)
+
+		syncUsecase := usecase.NewProviderSyncUsecase(rp, pr, userOpts...)
+
+		worker.NewWorker(syncUsecase, conf.WorkerConfig).Start()
+
+		webhookUsecase := usecase.NewWebhookUsecase(rp, pr, conf.WebhookConfig, userOpts...)
+		webhookUsecase.Start(context.Background())
 
-		worker.NewWorker(userUsecase, conf.WorkerConfig).Start()
+		consumers := usecase.NewConsumerUsecase(rp, messageBus, conf.UserEventConsumerConfig)
+		consumers.Start(context.Background())
+		err = consumer.RegisterUserEventConsumer(context.Background(), messageBus, &userUsecase, consumers, conf.UserEventConsumerConfig)
+		if err != nil {
+			log.Error("failed to start user event consumer: " + err.Error())
+			os.Exit(1)
+		}
 
 		// echo server
 		e := echo.New()
+		e.Use(http.RequestID())
 		// setup validator and routes
 		http.SetupValidator(e)
 		http.RegisterUserRoutes(e, &userUsecase)
+		http.RegisterProviderRoutes(e, pr, conf.AdminConfig)
+		http.RegisterSyncRoutes(e, syncUsecase, conf.AdminConfig)
+		http.RegisterHealthRoutes(e, pr)
+		http.RegisterSCIMRoutes(e, &userUsecase, conf.SCIMServerConfig)
+		http.RegisterWebhookRoutes(e, webhookUsecase, conf.WebhookConfig)
+		http.RegisterWebhookSubscriptionRoutes(e, outboundWebhooks, conf.AdminConfig)
 
 		// run echo in a goroutine so we can block on signals
 		serverErrCh := make(chan error, 1)
@@ -0,0 +1,25 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	consumer "__MODULE__/internal/dto/adapter/consumer"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = validator.New()
+
+// decodeUserEvent parses a message value and validates it against the
+// UserEventMessage schema.
+func decodeUserEvent(value []byte) (consumer.UserEventMessage, error) {
+	var msg consumer.UserEventMessage
+	if err := json.Unmarshal(value, &msg); err != nil {
+		return msg, fmt.Errorf("invalid user event: %w", err)
+	}
+	if err := validate.Struct(msg); err != nil {
+		return msg, fmt.Errorf("invalid user event: %w", err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,30 @@
+/*
+Package consumer is the inbound adapter applying the messages other systems
+publish to the message bus, as package http does for requests: it decodes and
+validates a message, hands it to a usecase, and settles what to do when that
+fails.
+*/
+package consumer
+
+import (
+	"context"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/interfaces"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RegisterUserEventConsumer subscribes the user event handler to the
+// configured topic until ctx is done. It does nothing while no topic is set.
+func RegisterUserEventConsumer(ctx context.Context, b interfaces.MessageBus, uc interfaces.UserUsecase, cu interfaces.ConsumerUsecase, conf config.UserEventConsumerConfig) error {
+	if conf.ConsumerTopic == "" {
+		return nil
+	}
+	h := NewUserEventHandler(uc, cu, conf)
+	if err := b.Subscribe(ctx, conf.ConsumerTopic, conf.ConsumerGroup, h.Handle); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"topic": conf.ConsumerTopic, "group": conf.ConsumerGroup}).Info("consuming upstream user events")
+	return nil
+}
@@ -0,0 +1,127 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UserEventHandler applies the user events upstream systems publish.
+type UserEventHandler struct {
+	uc        interfaces.UserUsecase
+	consumers interfaces.ConsumerUsecase
+	conf      config.UserEventConsumerConfig
+	sleep     func(ctx context.Context, d time.Duration) bool
+}
+
+// NewUserEventHandler constructs a handler applying events through uc and
+// routing the ones it gives up on through consumers.
+func NewUserEventHandler(uc interfaces.UserUsecase, consumers interfaces.ConsumerUsecase, conf config.UserEventConsumerConfig) *UserEventHandler {
+	conf.ConsumerMaxAttempts = max(conf.ConsumerMaxAttempts, 1)
+	return &UserEventHandler{uc: uc, consumers: consumers, conf: conf, sleep: sleep}
+}
+
+// Handle applies a user event message. An invalid message is dead-lettered
+// right away; a failing one is retried with exponential backoff and
+// dead-lettered after ConsumerMaxAttempts. An error is only returned when the
+// message could not be dead-lettered, so the bus delivers it again.
+func (h *UserEventHandler) Handle(ctx context.Context, msg bus.Message) error {
+	event, err := decodeUserEvent(msg.Value)
+	if err != nil {
+		return h.deadLetter(ctx, msg, err, 0)
+	}
+	req := mapper.UserEventMessageToUsecase(event, h.conf.ConsumerGroup, h.conf.ConsumerProvider)
+	fields := log.Fields{"event": req.EventID, "type": req.Type, "topic": msg.Topic, "offset": msg.Offset}
+
+	for attempt := 1; ; attempt++ {
+		applied, err := h.uc.ApplyUserEvent(ctx, req)
+		if err == nil {
+			status := "applied"
+			if !applied {
+				status = "duplicate"
+			}
+			pkg.CounterAdd("user_events_consumed_total", 1, "type", string(req.Type), "status", status)
+			return nil
+		}
+		if permanent(err) || attempt >= h.conf.ConsumerMaxAttempts {
+			return h.deadLetter(ctx, msg, err, attempt)
+		}
+		log.WithError(err).WithFields(fields).WithField("attempt", attempt).Warn("user event failed, retrying")
+		if !h.sleep(ctx, h.backoff(attempt)) {
+			return ctx.Err()
+		}
+	}
+}
+
+// deadLetter routes msg to the dead-letter topic after attempts failed with
+// err; attempts is 0 for a message that was never applied.
+func (h *UserEventHandler) deadLetter(ctx context.Context, msg bus.Message, cause error, attempts int) error {
+	d, err := h.consumers.DeadLetter(ctx, usecase.DeadLetter{
+		Topic:           msg.Topic,
+		Consumer:        h.conf.ConsumerGroup,
+		DeadLetterTopic: h.conf.DeadLetterTopic(),
+		Key:             msg.Key,
+		Value:           msg.Value,
+		Headers:         msg.Headers,
+		Partition:       msg.Partition,
+		Offset:          msg.Offset,
+		Reason:          cause.Error(),
+		Attempts:        attempts,
+	})
+	if err != nil {
+		return err
+	}
+	log.WithError(cause).WithFields(log.Fields{
+		"topic": msg.Topic, "offset": msg.Offset, "attempts": attempts, "dead_letter": d.ID,
+	}).Error("user event dead-lettered")
+	return nil
+}
+
+// backoff returns the delay before the attempt following attempt.
+func (h *UserEventHandler) backoff(attempt int) time.Duration {
+	delay := time.Duration(h.conf.ConsumerRetryBase) * time.Millisecond
+	limit := time.Duration(h.conf.ConsumerRetryMax) * time.Millisecond
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if limit > 0 {
+		delay = min(delay, limit)
+	}
+	return delay
+}
+
+// permanent reports whether err rejects the event itself, so retrying it
+// cannot succeed.
+func permanent(err error) bool {
+	var appErr *pkg.AppError
+	if !errors.As(err, &appErr) {
+		return false
+	}
+	switch appErr.ExternalCode() {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// sleep waits for d, reporting false when ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,173 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	busclient "__MODULE__/internal/client/bus"
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockUserUsecase only implements ApplyUserEvent; the handler calls nothing else.
+type MockUserUsecase struct {
+	interfaces.UserUsecase
+	mock.Mock
+}
+
+func (m *MockUserUsecase) ApplyUserEvent(ctx context.Context, req usecase.ApplyUserEventRequestDTO) (bool, error) {
+	args := m.Called(ctx, req)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockConsumerUsecase struct {
+	interfaces.ConsumerUsecase
+	mock.Mock
+}
+
+func (m *MockConsumerUsecase) DeadLetter(ctx context.Context, d usecase.DeadLetter) (usecase.DeadLetter, error) {
+	args := m.Called(ctx, d)
+	d.ID = 7
+	return d, args.Error(0)
+}
+
+var testConf = config.UserEventConsumerConfig{
+	ConsumerTopic: "upstream-users", ConsumerGroup: "user-service", ConsumerProvider: "hr",
+	ConsumerMaxAttempts: 3, ConsumerRetryBase: 100, ConsumerRetryMax: 150,
+}
+
+const validEvent = `{"id":"evt-1","type":"user.updated","occurred_at":"2024-05-01T10:00:00Z",
+	"user":{"id":"42","name":"Ada","email":"ada@example.com","extra":{"city":"London","team":"core"}}}`
+
+func newTestHandler(uc *MockUserUsecase, cu *MockConsumerUsecase) (*UserEventHandler, *[]time.Duration) {
+	h := NewUserEventHandler(uc, cu, testConf)
+	var slept []time.Duration
+	h.sleep = func(_ context.Context, d time.Duration) bool {
+		slept = append(slept, d)
+		return true
+	}
+	return h, &slept
+}
+
+func TestHandle_AppliesValidEvent(t *testing.T) {
+	ctx := context.Background()
+	uc, cu := &MockUserUsecase{}, &MockConsumerUsecase{}
+	uc.On("ApplyUserEvent", ctx, mock.MatchedBy(func(req usecase.ApplyUserEventRequestDTO) bool {
+		return req.EventID == "evt-1" && req.Type == usecase.UserUpdated && req.Consumer == "user-service" &&
+			req.Provider == "hr" && *req.User.ExternalID == "42" && *req.User.FullName == "Ada" &&
+			*req.User.City == "London" && req.User.Extra["team"] == "core" &&
+			req.OccurredAt.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	})).Return(true, nil).Once()
+	uc.On("ApplyUserEvent", ctx, mock.Anything).Return(false, nil).Once()
+	h, _ := newTestHandler(uc, cu)
+
+	msg := bus.Message{Topic: "upstream-users", Value: []byte(validEvent)}
+	require.NoError(t, h.Handle(ctx, msg))
+	require.NoError(t, h.Handle(ctx, msg), "a redelivered event is acknowledged")
+	uc.AssertExpectations(t)
+	cu.AssertNotCalled(t, "DeadLetter", mock.Anything, mock.Anything)
+}
+
+func TestHandle_DeadLettersInvalidEvents(t *testing.T) {
+	for name, value := range map[string]string{
+		"not json":     `{"id":`,
+		"missing id":   `{"type":"user.created","occurred_at":"2024-05-01T10:00:00Z","user":{"id":"42"}}`,
+		"unknown type": `{"id":"e","type":"user.exploded","occurred_at":"2024-05-01T10:00:00Z","user":{"id":"42"}}`,
+		"no user id":   `{"id":"e","type":"user.created","occurred_at":"2024-05-01T10:00:00Z","user":{"name":"Ada"}}`,
+		"bad email":    `{"id":"e","type":"user.created","occurred_at":"2024-05-01T10:00:00Z","user":{"id":"42","email":"nope"}}`,
+		"no time":      `{"id":"e","type":"user.deleted","user":{"id":"42"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			uc, cu := &MockUserUsecase{}, &MockConsumerUsecase{}
+			cu.On("DeadLetter", ctx, mock.MatchedBy(func(d usecase.DeadLetter) bool {
+				return d.Topic == "upstream-users" && d.DeadLetterTopic == "upstream-users.dlq" && d.Attempts == 0 &&
+					d.Offset == 12 && string(d.Value) == value && d.Reason != ""
+			})).Return(nil).Once()
+			h, _ := newTestHandler(uc, cu)
+
+			require.NoError(t, h.Handle(ctx, bus.Message{Topic: "upstream-users", Value: []byte(value), Offset: 12}))
+			cu.AssertExpectations(t)
+			uc.AssertNotCalled(t, "ApplyUserEvent", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandle_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	uc, cu := &MockUserUsecase{}, &MockConsumerUsecase{}
+	uc.On("ApplyUserEvent", ctx, mock.Anything).Return(false, errors.New("database unavailable")).Times(3)
+	cu.On("DeadLetter", ctx, mock.MatchedBy(func(d usecase.DeadLetter) bool {
+		return d.Attempts == 3 && d.Reason == "database unavailable"
+	})).Return(nil).Once()
+	h, slept := newTestHandler(uc, cu)
+
+	require.NoError(t, h.Handle(ctx, bus.Message{Topic: "upstream-users", Value: []byte(validEvent)}))
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}, *slept)
+	uc.AssertExpectations(t)
+	cu.AssertExpectations(t)
+}
+
+func TestHandle_RecoversAfterTransientFailure(t *testing.T) {
+	ctx := context.Background()
+	uc, cu := &MockUserUsecase{}, &MockConsumerUsecase{}
+	uc.On("ApplyUserEvent", ctx, mock.Anything).Return(false, errors.New("database unavailable")).Once()
+	uc.On("ApplyUserEvent", ctx, mock.Anything).Return(true, nil).Once()
+	h, slept := newTestHandler(uc, cu)
+
+	require.NoError(t, h.Handle(ctx, bus.Message{Topic: "upstream-users", Value: []byte(validEvent)}))
+	assert.Len(t, *slept, 1)
+	cu.AssertNotCalled(t, "DeadLetter", mock.Anything, mock.Anything)
+}
+
+func TestHandle_RejectedEventsAreNotRetried(t *testing.T) {
+	ctx := context.Background()
+	uc, cu := &MockUserUsecase{}, &MockConsumerUsecase{}
+	uc.On("ApplyUserEvent", ctx, mock.Anything).Return(false, pkg.NewAppError(pkg.ErrBadRequest)).Once()
+	cu.On("DeadLetter", ctx, mock.MatchedBy(func(d usecase.DeadLetter) bool { return d.Attempts == 1 })).Return(nil).Once()
+	h, slept := newTestHandler(uc, cu)
+
+	require.NoError(t, h.Handle(ctx, bus.Message{Topic: "upstream-users", Value: []byte(validEvent)}))
+	assert.Empty(t, *slept)
+	cu.AssertExpectations(t)
+}
+
+func TestHandle_NacksWhenDeadLetteringFails(t *testing.T) {
+	ctx := context.Background()
+	uc, cu := &MockUserUsecase{}, &MockConsumerUsecase{}
+	cu.On("DeadLetter", ctx, mock.Anything).Return(errors.New("broker unavailable")).Once()
+	h, _ := newTestHandler(uc, cu)
+
+	assert.Error(t, h.Handle(ctx, bus.Message{Topic: "upstream-users", Value: []byte("garbage")}))
+}
+
+func TestRegisterUserEventConsumer_ConsumesTheTopic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := busclient.NewMemoryBus(time.Millisecond)
+	uc := &MockUserUsecase{}
+	applied := make(chan string, 1)
+	uc.On("ApplyUserEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		applied <- args.Get(1).(usecase.ApplyUserEventRequestDTO).EventID
+	}).Return(true, nil)
+
+	require.NoError(t, RegisterUserEventConsumer(ctx, b, uc, &MockConsumerUsecase{}, testConf))
+	require.NoError(t, b.Publish(ctx, bus.Message{Topic: "upstream-users", Key: []byte("42"), Value: []byte(validEvent)}))
+	select {
+	case id := <-applied:
+		assert.Equal(t, "evt-1", id)
+	case <-time.After(time.Second):
+		t.Fatal("event not applied")
+	}
+
+	require.NoError(t, RegisterUserEventConsumer(ctx, b, uc, nil, config.UserEventConsumerConfig{}), "disabled without a topic")
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HealthHandler serves liveness and metrics endpoints.
+type HealthHandler struct {
+	providers interfaces.ProviderService
+}
+
+// NewHealthHandler constructs a handler.
+func NewHealthHandler(ps interfaces.ProviderService) *HealthHandler {
+	return &HealthHandler{providers: ps}
+}
+
+// GetHealth handles GET /health. The service reports "degraded" while any
+// active provider instance has a breaker that is not closed.
+func (h *HealthHandler) GetHealth(c echo.Context) error {
+	resp := adapter.HealthResponse{Status: "ok", Providers: []adapter.ProviderResponse{}}
+	for _, p := range h.providers.ListProviders() {
+		if p.Resilience != nil && p.Resilience.Breaker != integration.BreakerClosed {
+			resp.Status = "degraded"
+		}
+		resp.Providers = append(resp.Providers, mapper.ProviderInstanceToResponse(p))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetMetrics handles GET /metrics in the Prometheus text format.
+func (h *HealthHandler) GetMetrics(c echo.Context) error {
+	var buf bytes.Buffer
+	if err := pkg.WriteMetrics(&buf); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4", buf.Bytes())
+}
@@ -23,6 +23,9 @@ func handleUsecaseError(c echo.Context, err error) error {
 		logData["metadata"] = appErr.Meta()
 		logData["internal_code"] = appErr.InternalCode()
 		logData["external_code"] = appErr.ExternalCode()
+		if id, ok := pkg.RequestIDFromContext(c.Request().Context()); ok {
+			logData["request_id"] = id
+		}
 
 		logrus.WithFields(logData).Log(appErr.Level(), appErr.Message())
 
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"__MODULE__/pkg"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ProviderHeader lets a request pick the user provider instance that backs it.
+const ProviderHeader = "X-User-Provider"
+
+// ProviderSelector stores the provider instance chosen by the caller, via the
+// ?provider= query parameter or the X-User-Provider header, in the request
+// context. The query parameter wins when both are set.
+func ProviderSelector() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := strings.TrimSpace(c.QueryParam("provider"))
+			if id == "" {
+				id = strings.TrimSpace(c.Request().Header.Get(ProviderHeader))
+			}
+			if id != "" {
+				req := c.Request()
+				c.SetRequest(req.WithContext(pkg.WithProvider(req.Context(), id)))
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequestID makes sure every request carries an id: the caller's X-Request-ID
+// when present, a new UUID otherwise. The id is echoed in the response and
+// stored in the request context so outgoing provider calls forward it.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := strings.TrimSpace(req.Header.Get(pkg.RequestIDHeader))
+			if id == "" {
+				id = uuid.New().String()
+			}
+			c.Response().Header().Set(pkg.RequestIDHeader, id)
+			c.SetRequest(req.WithContext(pkg.WithRequestID(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// AdminAuth rejects requests without the configured admin bearer token.
+func AdminAuth(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			got, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="admin"`)
+				return handleUsecaseError(c, pkg.NewAppError(pkg.ErrUnauthorized).AddDescription([]byte("invalid admin token")))
+			}
+			return next(c)
+		}
+	}
+}
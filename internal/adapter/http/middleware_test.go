@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"__MODULE__/internal/config"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuth(t *testing.T) {
+	e := echo.New()
+	e.GET("/admin", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }, AdminAuth("s3cret"))
+
+	for name, tc := range map[string]struct {
+		header string
+		want   int
+	}{
+		"no token":    {"", http.StatusUnauthorized},
+		"wrong token": {"Bearer nope", http.StatusUnauthorized},
+		"not bearer":  {"Basic s3cret", http.StatusUnauthorized},
+		"valid token": {"Bearer s3cret", http.StatusNoContent},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tc.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tc.want, rec.Code)
+		})
+	}
+}
+
+func TestAdminRoutes_RequireToken(t *testing.T) {
+	disabled := echo.New()
+	RegisterProviderRoutes(disabled, nil, config.AdminConfig{})
+	RegisterSyncRoutes(disabled, nil, config.AdminConfig{})
+	RegisterWebhookSubscriptionRoutes(disabled, nil, config.AdminConfig{})
+	enabled := echo.New()
+	RegisterProviderRoutes(enabled, nil, config.AdminConfig{AdminToken: "s3cret"})
+	RegisterSyncRoutes(enabled, nil, config.AdminConfig{AdminToken: "s3cret"})
+	RegisterWebhookSubscriptionRoutes(enabled, nil, config.AdminConfig{AdminToken: "s3cret"})
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/providers"},
+		{http.MethodPost, "/admin/providers"},
+		{http.MethodDelete, "/admin/providers/hr"},
+		{http.MethodPost, "/admin/providers/hr/sync"},
+		{http.MethodPost, "/admin/sync"},
+		{http.MethodGet, "/admin/sync/runs"},
+		{http.MethodGet, "/admin/webhooks"},
+		{http.MethodPost, "/admin/webhooks"},
+		{http.MethodDelete, "/admin/webhooks/w-1"},
+		{http.MethodPost, "/admin/webhooks/w-1/deliveries/d-1/redeliver"},
+	} {
+		rec := httptest.NewRecorder()
+		disabled.ServeHTTP(rec, httptest.NewRequest(route.method, route.path, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, "%s %s without ADMIN_TOKEN", route.method, route.path)
+
+		rec = httptest.NewRecorder()
+		enabled.ServeHTTP(rec, httptest.NewRequest(route.method, route.path, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "%s %s without credentials", route.method, route.path)
+	}
+}
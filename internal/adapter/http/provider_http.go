@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/interfaces"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProviderHandler handles the runtime provider administration endpoints.
+type ProviderHandler struct {
+	providers interfaces.ProviderService
+}
+
+// NewProviderHandler constructs a handler.
+func NewProviderHandler(ps interfaces.ProviderService) *ProviderHandler {
+	return &ProviderHandler{providers: ps}
+}
+
+// GetProviderTypes handles GET /admin/providers/types
+func (h *ProviderHandler) GetProviderTypes(c echo.Context) error {
+	return c.JSON(http.StatusOK, adapter.GetProviderTypesResponse{Types: h.providers.SupportedProviders()})
+}
+
+// GetProviders handles GET /admin/providers
+func (h *ProviderHandler) GetProviders(c echo.Context) error {
+	instances := h.providers.ListProviders()
+	resp := adapter.GetProvidersResponse{Providers: make([]adapter.ProviderResponse, 0, len(instances))}
+	for _, p := range instances {
+		resp.Providers = append(resp.Providers, mapper.ProviderInstanceToResponse(p))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RegisterProvider handles POST /admin/providers
+func (h *ProviderHandler) RegisterProvider(c echo.Context) error {
+	var req adapter.RegisterProviderRequestDTO
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.providers.RegisterNewProvider(req.Id, req.Type, mapper.ProviderConfigToString(req.Config)); err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return h.providerResponse(c, http.StatusCreated, req.Id)
+}
+
+// ReplaceProvider handles PUT /admin/providers/:id
+func (h *ProviderHandler) ReplaceProvider(c echo.Context) error {
+	var req adapter.ReplaceProviderRequestDTO
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	id := c.Param("id")
+	if err := h.providers.ReplaceProvider(id, req.Type, mapper.ProviderConfigToString(req.Config)); err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return h.providerResponse(c, http.StatusOK, id)
+}
+
+// StopProvider handles DELETE /admin/providers/:id
+func (h *ProviderHandler) StopProvider(c echo.Context) error {
+	if err := h.providers.StopUserService(c.Param("id")); err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *ProviderHandler) providerResponse(c echo.Context, status int, id string) error {
+	for _, p := range h.providers.ListProviders() {
+		if p.ID == id {
+			return c.JSON(status, mapper.ProviderInstanceToResponse(p))
+		}
+	}
+	return c.NoContent(status)
+}
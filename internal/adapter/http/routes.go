@@ -1,11 +1,13 @@
 package http
 
 import (
+	"__MODULE__/internal/config"
 	"__MODULE__/internal/interfaces"
 
 	client "github.com/seyedmo30/go-clean-template-client/api"
 
 	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
 )
 
 // RegisterUserRoutes registers user-related routes on the given Echo instance.
@@ -18,10 +20,94 @@ func RegisterUserRoutes(e *echo.Echo, uc interfaces.UserUsecase) {
 	SetupValidator(e) // ensure validator is set
 
 	h := NewUserHandler(uc)
-	g := e.Group("/users")
-	// GET /users?page=1
+	g := e.Group("/users", ProviderSelector())
+	// GET /users?page=1&provider=<instance id>
 	g.GET("", h.GetUsers)
 	g.POST("", h.CreateUsers)
 	// you can add other endpoints: POST /users, GET /users/:id, etc.
 
 }
+
+// RegisterProviderRoutes registers the provider administration routes on the
+// given Echo instance. Nothing is registered while no admin token is configured.
+func RegisterProviderRoutes(e *echo.Echo, ps interfaces.ProviderService, conf config.AdminConfig) {
+	if conf.AdminToken == "" {
+		log.Info("provider administration disabled: ADMIN_TOKEN is not set")
+		return
+	}
+	h := NewProviderHandler(ps)
+	g := e.Group("/admin/providers", AdminAuth(conf.AdminToken))
+	g.GET("", h.GetProviders)
+	g.GET("/types", h.GetProviderTypes)
+	g.POST("", h.RegisterProvider)
+	g.PUT("/:id", h.ReplaceProvider)
+	g.DELETE("/:id", h.StopProvider)
+}
+
+// RegisterHealthRoutes registers the health and metrics routes on the given Echo instance.
+func RegisterHealthRoutes(e *echo.Echo, ps interfaces.ProviderService) {
+	h := NewHealthHandler(ps)
+	e.GET("/health", h.GetHealth)
+	e.GET("/metrics", h.GetMetrics)
+}
+
+// RegisterSyncRoutes registers the provider synchronisation routes on the
+// given Echo instance. Nothing is registered while no admin token is configured.
+func RegisterSyncRoutes(e *echo.Echo, uc interfaces.ProviderSyncUsecase, conf config.AdminConfig) {
+	if conf.AdminToken == "" {
+		log.Info("sync administration disabled: ADMIN_TOKEN is not set")
+		return
+	}
+	h := NewSyncHandler(uc)
+	auth := AdminAuth(conf.AdminToken)
+	e.POST("/admin/providers/:id/sync", h.SyncProvider, auth)
+	e.POST("/admin/sync", h.SyncAll, auth)
+	e.GET("/admin/sync/runs", h.GetSyncRuns, auth)
+}
+
+// RegisterWebhookRoutes registers the inbound provider webhook route on the given Echo instance.
+func RegisterWebhookRoutes(e *echo.Echo, uc interfaces.WebhookUsecase, conf config.WebhookConfig) {
+	h := NewWebhookHandler(uc, conf.WebhookMaxBodyBytes)
+	e.POST("/webhooks/:provider", h.ReceiveWebhook)
+}
+
+// RegisterWebhookSubscriptionRoutes registers the outbound webhook administration
+// routes on the given Echo instance. Nothing is registered while no admin token
+// is configured.
+func RegisterWebhookSubscriptionRoutes(e *echo.Echo, uc interfaces.OutboundWebhookUsecase, conf config.AdminConfig) {
+	if conf.AdminToken == "" {
+		log.Info("webhook administration disabled: ADMIN_TOKEN is not set")
+		return
+	}
+	h := NewWebhookSubscriptionHandler(uc)
+	g := e.Group("/admin/webhooks", AdminAuth(conf.AdminToken))
+	g.GET("", h.GetSubscriptions)
+	g.POST("", h.CreateSubscription)
+	g.GET("/:id", h.GetSubscription)
+	g.PUT("/:id", h.ReplaceSubscription)
+	g.DELETE("/:id", h.DeleteSubscription)
+	g.GET("/:id/deliveries", h.GetDeliveries)
+	g.POST("/:id/deliveries/:delivery/redeliver", h.Redeliver)
+}
+
+// RegisterSCIMRoutes registers the SCIM 2.0 provisioning routes on the given
+// Echo instance. Nothing is registered while no token is configured.
+func RegisterSCIMRoutes(e *echo.Echo, uc interfaces.UserUsecase, conf config.SCIMServerConfig) {
+	if conf.Token == "" {
+		log.Info("scim server disabled: SCIM_SERVER_TOKEN is not set")
+		return
+	}
+	h := NewSCIMHandler(uc, conf)
+	g := e.Group("/scim/v2", scimBearerAuth(conf.Token))
+	g.GET("/Users", h.GetUsers)
+	g.POST("/Users", h.CreateUser)
+	g.GET("/Users/:id", h.GetUser)
+	g.PUT("/Users/:id", h.ReplaceUser)
+	g.PATCH("/Users/:id", h.PatchUser)
+	g.DELETE("/Users/:id", h.DeleteUser)
+	g.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+	g.GET("/ResourceTypes", h.GetResourceTypes)
+	g.GET("/ResourceTypes/:id", h.GetResourceType)
+	g.GET("/Schemas", h.GetSchemas)
+	g.GET("/Schemas/:id", h.GetSchema)
+}
@@ -0,0 +1,506 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/entity/user"
+)
+
+// opValuePath marks a `attr[filter]` expression: Operands[0] applies to the
+// elements of the multi-valued attribute Attr.
+const opValuePath user.Op = "[]"
+
+// scimFilter is a parsed SCIM filter (RFC 7644 3.4.2.2). Attr is lower case
+// with the core User schema URN stripped.
+type scimFilter struct {
+	Op       user.Op
+	Attr     string
+	Value    any // string, bool, json.Number or nil
+	Operands []scimFilter
+}
+
+// scimFilterError is a filter the server cannot parse or evaluate.
+type scimFilterError struct{ msg string }
+
+func (e *scimFilterError) Error() string { return e.msg }
+
+func filterErrorf(format string, args ...any) error {
+	return &scimFilterError{msg: fmt.Sprintf(format, args...)}
+}
+
+type scimToken struct {
+	text   string
+	quoted bool // a string literal, text holds the decoded value
+	end    int  // offset just past the token
+}
+
+// lexSCIMFilter splits a filter into words, string literals and brackets.
+func lexSCIMFilter(s string) ([]scimToken, error) {
+	var tokens []scimToken
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, scimToken{text: string(c), end: i + 1})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, filterErrorf("unterminated string at offset %d", i)
+			}
+			var v string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &v); err != nil {
+				return nil, filterErrorf("invalid string at offset %d", i)
+			}
+			tokens = append(tokens, scimToken{text: v, quoted: true, end: j + 1})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, scimToken{text: s[i:j], end: j})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type scimFilterParser struct {
+	tokens []scimToken
+	pos    int
+}
+
+// parseSCIMFilter parses a complete filter expression.
+func parseSCIMFilter(s string) (scimFilter, error) {
+	tokens, err := lexSCIMFilter(s)
+	if err != nil {
+		return scimFilter{}, err
+	}
+	p := &scimFilterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return scimFilter{}, err
+	}
+	if t, ok := p.peek(); ok {
+		return scimFilter{}, filterErrorf("unexpected %q", t.text)
+	}
+	return f, nil
+}
+
+func (p *scimFilterParser) peek() (scimToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return scimToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// keyword reports whether the next token is the unquoted word kw and consumes it.
+func (p *scimFilterParser) keyword(kw string) bool {
+	t, ok := p.peek()
+	if ok && !t.quoted && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *scimFilterParser) expect(kw string) error {
+	if !p.keyword(kw) {
+		if t, ok := p.peek(); ok {
+			return filterErrorf("expected %q, got %q", kw, t.text)
+		}
+		return filterErrorf("expected %q at end of filter", kw)
+	}
+	return nil
+}
+
+func (p *scimFilterParser) parseOr() (scimFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return left, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return right, err
+		}
+		left = scimFilter{Op: user.OpOr, Operands: []scimFilter{left, right}}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseAnd() (scimFilter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return left, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return right, err
+		}
+		left = scimFilter{Op: user.OpAnd, Operands: []scimFilter{left, right}}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseUnary() (scimFilter, error) {
+	if p.keyword("not") {
+		if err := p.expect("("); err != nil {
+			return scimFilter{}, err
+		}
+		f, err := p.parseOr()
+		if err != nil {
+			return f, err
+		}
+		if err := p.expect(")"); err != nil {
+			return f, err
+		}
+		return scimFilter{Op: user.OpNot, Operands: []scimFilter{f}}, nil
+	}
+	if p.keyword("(") {
+		f, err := p.parseOr()
+		if err != nil {
+			return f, err
+		}
+		return f, p.expect(")")
+	}
+	return p.parseAttrExp()
+}
+
+func (p *scimFilterParser) parseAttrExp() (scimFilter, error) {
+	t, ok := p.peek()
+	if !ok {
+		return scimFilter{}, filterErrorf("expected an attribute at end of filter")
+	}
+	if t.quoted || strings.ContainsAny(t.text, "()[]") {
+		return scimFilter{}, filterErrorf("expected an attribute, got %q", t.text)
+	}
+	p.pos++
+	attr := normalizeSCIMAttr(t.text)
+
+	if p.keyword("[") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return inner, err
+		}
+		if err := p.expect("]"); err != nil {
+			return inner, err
+		}
+		return scimFilter{Op: opValuePath, Attr: attr, Operands: []scimFilter{inner}}, nil
+	}
+
+	opTok, ok := p.peek()
+	if !ok || opTok.quoted {
+		return scimFilter{}, filterErrorf("expected an operator after %q", t.text)
+	}
+	p.pos++
+	op := user.Op(strings.ToLower(opTok.text))
+	switch op {
+	case user.OpPr:
+		return scimFilter{Op: op, Attr: attr}, nil
+	case user.OpEq, user.OpNe, user.OpCo, user.OpSw, user.OpEw, user.OpGt, user.OpGe, user.OpLt, user.OpLe:
+	default:
+		return scimFilter{}, filterErrorf("unknown operator %q", opTok.text)
+	}
+
+	v, ok := p.peek()
+	if !ok {
+		return scimFilter{}, filterErrorf("expected a value after %q", opTok.text)
+	}
+	p.pos++
+	f := scimFilter{Op: op, Attr: attr}
+	switch word := strings.ToLower(v.text); {
+	case v.quoted:
+		f.Value = v.text
+	case word == "true" || word == "false":
+		f.Value = word == "true"
+	case word == "null":
+		f.Value = nil
+	default:
+		if _, err := strconv.ParseFloat(v.text, 64); err != nil {
+			return scimFilter{}, filterErrorf("invalid value %q", v.text)
+		}
+		f.Value = json.Number(v.text)
+	}
+	return f, nil
+}
+
+// normalizeSCIMAttr lower-cases an attribute path and strips the core User
+// schema URN; extension attributes keep theirs.
+func normalizeSCIMAttr(attr string) string {
+	attr = strings.ToLower(attr)
+	return strings.TrimPrefix(attr, strings.ToLower(adapter.SCIMUserSchema)+":")
+}
+
+// scimFilterFields maps the filterable attributes onto user fields.
+var scimFilterFields = map[string]user.Field{
+	"id":                 user.FieldID,
+	"externalid":         user.FieldExternalID,
+	"username":           user.FieldUsername,
+	"displayname":        user.FieldFullName,
+	"name.formatted":     user.FieldFullName,
+	"emails":             user.FieldEmail,
+	"emails.value":       user.FieldEmail,
+	"phonenumbers":       user.FieldPhone,
+	"phonenumbers.value": user.FieldPhone,
+	"profileurl":         user.FieldWebsite,
+	"addresses.locality": user.FieldCity,
+	"active":             user.FieldActive,
+	"meta.created":       user.FieldCreatedAt,
+	"meta.lastmodified":  user.FieldUpdatedAt,
+	strings.ToLower(adapter.SCIMEnterpriseUserSchema) + ":organization": user.FieldCompany,
+}
+
+// userFilter translates f into a filter over stored users.
+func (f scimFilter) userFilter() (user.Filter, error) {
+	switch f.Op {
+	case user.OpAnd, user.OpOr, user.OpNot:
+		out := user.Filter{Op: f.Op, Operands: make([]user.Filter, 0, len(f.Operands))}
+		for _, op := range f.Operands {
+			uf, err := op.userFilter()
+			if err != nil {
+				return user.Filter{}, err
+			}
+			out.Operands = append(out.Operands, uf)
+		}
+		return out, nil
+	case opValuePath:
+		return f.Operands[0].prefixed(f.Attr + ".").userFilter()
+	}
+
+	field, ok := scimFilterFields[f.Attr]
+	if !ok {
+		return user.Filter{}, filterErrorf("attribute %q cannot be filtered", f.Attr)
+	}
+	if f.Op == user.OpPr {
+		return user.Filter{Op: f.Op, Field: field}, nil
+	}
+	// eq null tests for absence, ne null for presence
+	if f.Value == nil {
+		switch f.Op {
+		case user.OpEq:
+			return user.Filter{Op: user.OpNot, Operands: []user.Filter{{Op: user.OpPr, Field: field}}}, nil
+		case user.OpNe:
+			return user.Filter{Op: user.OpPr, Field: field}, nil
+		}
+		return user.Filter{}, filterErrorf("%s does not apply to null", f.Op)
+	}
+
+	out := user.Filter{Op: f.Op, Field: field}
+	switch field {
+	case user.FieldActive:
+		b, ok := f.Value.(bool)
+		if !ok {
+			return user.Filter{}, filterErrorf("%s takes a boolean", f.Attr)
+		}
+		out.Value = b
+	case user.FieldCreatedAt, user.FieldUpdatedAt:
+		s, _ := f.Value.(string)
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return user.Filter{}, filterErrorf("%s takes a dateTime", f.Attr)
+		}
+		out.Value = t
+	default:
+		switch v := f.Value.(type) {
+		case string:
+			out.Value = v
+		case json.Number:
+			out.Value = v.String()
+		default:
+			return user.Filter{}, filterErrorf("%s takes a string", f.Attr)
+		}
+	}
+	return out, nil
+}
+
+// prefixed returns f with every attribute prefixed, turning a value filter
+// into one over the enclosing resource.
+func (f scimFilter) prefixed(prefix string) scimFilter {
+	if f.Attr != "" {
+		f.Attr = prefix + f.Attr
+	}
+	ops := make([]scimFilter, len(f.Operands))
+	for i, op := range f.Operands {
+		ops[i] = op.prefixed(prefix)
+	}
+	f.Operands = ops
+	return f
+}
+
+// matches evaluates f against a decoded JSON object, such as an element of a
+// multi-valued attribute in a PATCH path. Strings compare ignoring case.
+func (f scimFilter) matches(obj map[string]any) bool {
+	switch f.Op {
+	case user.OpAnd:
+		return f.Operands[0].matches(obj) && f.Operands[1].matches(obj)
+	case user.OpOr:
+		return f.Operands[0].matches(obj) || f.Operands[1].matches(obj)
+	case user.OpNot:
+		return !f.Operands[0].matches(obj)
+	case opValuePath:
+		elems, _ := lookupPath(obj, f.Attr).([]any)
+		for _, e := range elems {
+			if m, ok := e.(map[string]any); ok && f.Operands[0].matches(m) {
+				return true
+			}
+		}
+		return false
+	}
+
+	actual := lookupPath(obj, f.Attr)
+	if values, ok := actual.([]any); ok {
+		for _, v := range values {
+			if m, ok := v.(map[string]any); ok {
+				v = m["value"]
+			}
+			if compareSCIMValue(f.Op, v, f.Value) {
+				return true
+			}
+		}
+		return false
+	}
+	return compareSCIMValue(f.Op, actual, f.Value)
+}
+
+// lookupPath resolves a dotted, case-insensitive attribute path in obj.
+func lookupPath(obj map[string]any, path string) any {
+	var cur any = obj
+	for _, name := range splitSCIMPath(path) {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		key, ok := lookupKey(m, name)
+		if !ok {
+			return nil
+		}
+		cur = m[key]
+	}
+	return cur
+}
+
+// splitSCIMPath splits an attribute path on dots, keeping an extension schema
+// URN, which contains dots of its own, as the first segment.
+func splitSCIMPath(path string) []string {
+	lower := strings.ToLower(path)
+	if urn := strings.ToLower(adapter.SCIMEnterpriseUserSchema); strings.HasPrefix(lower, urn) {
+		rest := strings.TrimPrefix(path[len(urn):], ":")
+		if rest == "" {
+			return []string{adapter.SCIMEnterpriseUserSchema}
+		}
+		return append([]string{adapter.SCIMEnterpriseUserSchema}, strings.Split(rest, ".")...)
+	}
+	if urn := strings.ToLower(adapter.SCIMUserSchema) + ":"; strings.HasPrefix(lower, urn) {
+		path = path[len(urn):]
+	}
+	return strings.Split(path, ".")
+}
+
+// lookupKey finds the key of m equal to name ignoring case.
+func lookupKey(m map[string]any, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func compareSCIMValue(op user.Op, actual, want any) bool {
+	if op == user.OpPr {
+		switch v := actual.(type) {
+		case nil:
+			return false
+		case string:
+			return v != ""
+		case []any:
+			return len(v) > 0
+		}
+		return true
+	}
+	if want == nil {
+		switch op {
+		case user.OpEq:
+			return !compareSCIMValue(user.OpPr, actual, nil)
+		case user.OpNe:
+			return compareSCIMValue(user.OpPr, actual, nil)
+		}
+		return false
+	}
+
+	switch w := want.(type) {
+	case bool:
+		a, ok := actual.(bool)
+		return ok && (op == user.OpEq && a == w || op == user.OpNe && a != w)
+	case json.Number:
+		a, ok := toFloat(actual)
+		b, _ := w.Float64()
+		if !ok {
+			return op == user.OpNe
+		}
+		return compareOrdered(op, a, b)
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return op == user.OpNe
+		}
+		a, w = strings.ToLower(a), strings.ToLower(w)
+		switch op {
+		case user.OpCo:
+			return strings.Contains(a, w)
+		case user.OpSw:
+			return strings.HasPrefix(a, w)
+		case user.OpEw:
+			return strings.HasSuffix(a, w)
+		}
+		return compareOrdered(op, a, w)
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compareOrdered[T float64 | string](op user.Op, a, b T) bool {
+	switch op {
+	case user.OpEq:
+		return a == b
+	case user.OpNe:
+		return a != b
+	case user.OpGt:
+		return a > b
+	case user.OpGe:
+		return a >= b
+	case user.OpLt:
+		return a < b
+	case user.OpLe:
+		return a <= b
+	}
+	return false
+}
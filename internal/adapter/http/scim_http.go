@@ -0,0 +1,396 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"__MODULE__/internal/config"
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// SCIMContentType is the media type of SCIM requests and responses.
+const SCIMContentType = "application/scim+json"
+
+// scimError is a failure reported with a SCIM Error message.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func newSCIMError(status int, scimType, detail string) *scimError {
+	return &scimError{status: status, scimType: scimType, detail: detail}
+}
+
+func (e *scimError) Error() string { return e.detail }
+
+// SCIMHandler serves the SCIM 2.0 User endpoints through which identity
+// providers provision users. It only sees the users recorded under the
+// configured provider.
+type SCIMHandler struct {
+	usecase interfaces.UserUsecase
+	conf    config.SCIMServerConfig
+}
+
+// NewSCIMHandler constructs a handler.
+func NewSCIMHandler(uc interfaces.UserUsecase, conf config.SCIMServerConfig) *SCIMHandler {
+	if conf.MaxResults <= 0 {
+		conf.MaxResults = 200
+	}
+	return &SCIMHandler{usecase: uc, conf: conf}
+}
+
+// scimBearerAuth rejects requests without the configured bearer token.
+func scimBearerAuth(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			got, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="scim"`)
+				return writeSCIMError(c, newSCIMError(http.StatusUnauthorized, "", "invalid bearer token"))
+			}
+			return next(c)
+		}
+	}
+}
+
+// GetUsers handles GET /scim/v2/Users?filter=&startIndex=&count=
+func (h *SCIMHandler) GetUsers(c echo.Context) error {
+	startIndex, err := scimIntParam(c, "startIndex", 1)
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	count, err := scimIntParam(c, "count", h.conf.MaxResults)
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	startIndex, count = max(startIndex, 1), min(max(count, 0), h.conf.MaxResults)
+
+	filter := h.scope()
+	if expr := c.QueryParam("filter"); expr != "" {
+		parsed, err := parseSCIMFilter(expr)
+		if err != nil {
+			return writeSCIMError(c, newSCIMError(http.StatusBadRequest, "invalidFilter", err.Error()))
+		}
+		uf, err := parsed.userFilter()
+		if err != nil {
+			return writeSCIMError(c, newSCIMError(http.StatusBadRequest, "invalidFilter", err.Error()))
+		}
+		filter = user.Filter{Op: user.OpAnd, Operands: []user.Filter{filter, uf}}
+	}
+
+	res, err := h.usecase.ListUsers(c.Request().Context(), usecase.ListUsersRequestDTO{
+		Filter: &filter,
+		Offset: startIndex - 1,
+		Limit:  count,
+	})
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+
+	resources := make([]any, 0, len(res.Users))
+	for _, u := range res.Users {
+		su, _ := h.resource(c, u)
+		resources = append(resources, su)
+	}
+	return writeSCIM(c, http.StatusOK, adapter.SCIMListResponse{
+		Schemas:      []string{adapter.SCIMListResponseSchema},
+		TotalResults: res.Total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// GetUser handles GET /scim/v2/Users/:id. A matching If-None-Match answers
+// 304 Not Modified.
+func (h *SCIMHandler) GetUser(c echo.Context) error {
+	u, err := h.find(c)
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	su, etag := h.resource(c, u)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		c.Response().Header().Set("ETag", etag)
+		return c.NoContent(http.StatusNotModified)
+	}
+	return writeSCIMResource(c, http.StatusOK, su, etag)
+}
+
+// CreateUser handles POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(c echo.Context) error {
+	var in adapter.SCIMUser
+	if err := decodeSCIMBody(c, &in); err != nil {
+		return writeSCIMError(c, err)
+	}
+	if err := h.checkUserName(c, in.UserName, ""); err != nil {
+		return writeSCIMError(c, err)
+	}
+
+	bu := mapper.SCIMToUserUsecase(in)
+	bu.Provider = &h.conf.Provider
+	created, err := h.usecase.CreateUser(c.Request().Context(), usecase.CreateUserRequestDTO{BaseUser: bu})
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	if len(created) == 0 || created[0].ID == nil {
+		return writeSCIMError(c, fmt.Errorf("created user has no id"))
+	}
+	// read back for the timestamps set by the database
+	u, err := h.usecase.GetUser(c.Request().Context(), string(*created[0].ID))
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+
+	su, etag := h.resource(c, u)
+	c.Response().Header().Set(echo.HeaderLocation, su.Meta.Location)
+	return writeSCIMResource(c, http.StatusCreated, su, etag)
+}
+
+// ReplaceUser handles PUT /scim/v2/Users/:id, honouring If-Match.
+func (h *SCIMHandler) ReplaceUser(c echo.Context) error {
+	current, err := h.findForUpdate(c)
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	var in adapter.SCIMUser
+	if err := decodeSCIMBody(c, &in); err != nil {
+		return writeSCIMError(c, err)
+	}
+	return h.replace(c, current, in)
+}
+
+// PatchUser handles PATCH /scim/v2/Users/:id, honouring If-Match. The
+// operations are applied to the current resource, which then replaces the user.
+func (h *SCIMHandler) PatchUser(c echo.Context) error {
+	current, err := h.findForUpdate(c)
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	var req adapter.SCIMPatchRequest
+	if err := decodeSCIMBody(c, &req); err != nil {
+		return writeSCIMError(c, err)
+	}
+	if len(req.Operations) == 0 {
+		return writeSCIMError(c, newSCIMError(http.StatusBadRequest, "invalidSyntax", "no Operations"))
+	}
+
+	su, _ := h.resource(c, current)
+	doc, err := toJSONObject(su)
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	if err := applySCIMPatch(doc, req.Operations); err != nil {
+		return writeSCIMError(c, err)
+	}
+	// Entra ID sends booleans as "True" and "False"
+	if s, ok := doc["active"].(string); ok {
+		if b, err := strconv.ParseBool(s); err == nil {
+			doc["active"] = b
+		}
+	}
+
+	var patched adapter.SCIMUser
+	raw, _ := json.Marshal(doc)
+	if err := json.Unmarshal(raw, &patched); err != nil {
+		return writeSCIMError(c, newSCIMError(http.StatusBadRequest, "invalidValue", err.Error()))
+	}
+	return h.replace(c, current, patched)
+}
+
+// DeleteUser handles DELETE /scim/v2/Users/:id, honouring If-Match.
+func (h *SCIMHandler) DeleteUser(c echo.Context) error {
+	current, err := h.findForUpdate(c)
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	if err := h.usecase.DeleteUser(c.Request().Context(), string(*current.ID)); err != nil {
+		return writeSCIMError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// replace stores in as the new state of current and answers with the result.
+func (h *SCIMHandler) replace(c echo.Context, current usecase.BaseUser, in adapter.SCIMUser) error {
+	if err := h.checkUserName(c, in.UserName, string(*current.ID)); err != nil {
+		return writeSCIMError(c, err)
+	}
+	bu := mapper.SCIMToUserUsecase(in)
+	bu.ID, bu.Provider = current.ID, current.Provider
+
+	u, err := h.usecase.ReplaceUser(c.Request().Context(), usecase.ReplaceUserRequestDTO{BaseUser: bu})
+	if err != nil {
+		return writeSCIMError(c, err)
+	}
+	su, etag := h.resource(c, u)
+	return writeSCIMResource(c, http.StatusOK, su, etag)
+}
+
+// scope restricts a query to the users provisioned through SCIM.
+func (h *SCIMHandler) scope() user.Filter {
+	return user.Filter{Op: user.OpEq, Field: user.FieldProvider, Value: h.conf.Provider}
+}
+
+// find loads the user named in the path; users of other providers are not found.
+func (h *SCIMHandler) find(c echo.Context) (usecase.BaseUser, error) {
+	u, err := h.usecase.GetUser(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return u, err
+	}
+	if u.ID == nil || u.Provider == nil || *u.Provider != h.conf.Provider {
+		return usecase.BaseUser{}, newSCIMError(http.StatusNotFound, "", "user "+c.Param("id")+" not found")
+	}
+	return u, nil
+}
+
+// findForUpdate loads the user named in the path and checks If-Match.
+func (h *SCIMHandler) findForUpdate(c echo.Context) (usecase.BaseUser, error) {
+	u, err := h.find(c)
+	if err != nil {
+		return u, err
+	}
+	if ifMatch := c.Request().Header.Get("If-Match"); ifMatch != "" {
+		if _, etag := h.resource(c, u); !etagMatches(ifMatch, etag) {
+			return u, newSCIMError(http.StatusPreconditionFailed, "", "the user was modified, its version is now "+etag)
+		}
+	}
+	return u, nil
+}
+
+// checkUserName requires a userName not taken by another provisioned user.
+func (h *SCIMHandler) checkUserName(c echo.Context, userName, id string) error {
+	if strings.TrimSpace(userName) == "" {
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "userName is required")
+	}
+	taken := user.Filter{Op: user.OpAnd, Operands: []user.Filter{
+		h.scope(),
+		{Op: user.OpEq, Field: user.FieldUsername, Value: userName},
+		{Op: user.OpNot, Operands: []user.Filter{{Op: user.OpEq, Field: user.FieldID, Value: id}}},
+	}}
+	res, err := h.usecase.ListUsers(c.Request().Context(), usecase.ListUsersRequestDTO{Filter: &taken, Limit: 0})
+	if err != nil {
+		return err
+	}
+	if res.Total > 0 {
+		return newSCIMError(http.StatusConflict, "uniqueness", "userName "+userName+" is already taken")
+	}
+	return nil
+}
+
+// resource maps u to its SCIM representation and versions it with a weak
+// ETag, a hash of the stored user. The representation itself is not hashed
+// since meta.location depends on the Host and scheme of the request.
+func (h *SCIMHandler) resource(c echo.Context, u usecase.BaseUser) (adapter.SCIMUser, string) {
+	var id string
+	if u.ID != nil {
+		id = string(*u.ID)
+	}
+	su := mapper.UserUsecaseToSCIM(u, scimBaseURL(c)+"/Users/"+id)
+	raw, _ := json.Marshal(u)
+	sum := sha256.Sum256(raw)
+	etag := fmt.Sprintf(`W/"%x"`, sum[:8])
+	su.Meta.Version = etag
+	return su, etag
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists etag,
+// comparing weakly.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func scimBaseURL(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host + "/scim/v2"
+}
+
+func scimIntParam(c echo.Context, name string, def int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, newSCIMError(http.StatusBadRequest, "invalidValue", "invalid "+name)
+	}
+	return n, nil
+}
+
+func decodeSCIMBody(c echo.Context, v any) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(v); err != nil {
+		return newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid request body: "+err.Error())
+	}
+	return nil
+}
+
+func toJSONObject(v any) (map[string]any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	return doc, json.Unmarshal(raw, &doc)
+}
+
+func writeSCIM(c echo.Context, status int, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Blob(status, SCIMContentType, raw)
+}
+
+func writeSCIMResource(c echo.Context, status int, su adapter.SCIMUser, etag string) error {
+	c.Response().Header().Set("ETag", etag)
+	return writeSCIM(c, status, su)
+}
+
+// writeSCIMError answers with a SCIM Error message. Usecase errors keep their
+// HTTP status; a conflict is reported as a uniqueness violation.
+func writeSCIMError(c echo.Context, err error) error {
+	se, ok := err.(*scimError)
+	if !ok {
+		se = newSCIMError(http.StatusInternalServerError, "", "internal server error")
+		var appErr *pkg.AppError
+		if errors.As(err, &appErr) {
+			se.status, se.detail = appErr.ExternalCode(), appErr.Message()
+			switch se.status {
+			case http.StatusConflict:
+				se.scimType = "uniqueness"
+			case http.StatusBadRequest:
+				se.scimType = "invalidValue"
+			}
+		}
+		if se.status >= http.StatusInternalServerError {
+			log.WithError(err).Error("scim request failed")
+		}
+	}
+	return writeSCIM(c, se.status, adapter.SCIMError{
+		Schemas:  []string{adapter.SCIMErrorSchema},
+		Status:   strconv.Itoa(se.status),
+		ScimType: se.scimType,
+		Detail:   se.detail,
+	})
+}
@@ -0,0 +1,245 @@
+package http
+
+import (
+	"maps"
+	"net/http"
+	"strings"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/entity/user"
+)
+
+// scimPatchPath is a parsed PATCH path: an attribute, optionally narrowed to
+// the elements matching Filter and to their sub-attribute Sub.
+type scimPatchPath struct {
+	Segments []string
+	Filter   *scimFilter
+	Sub      string
+}
+
+func parseSCIMPatchPath(path string) (scimPatchPath, error) {
+	i := strings.IndexByte(path, '[')
+	if i < 0 {
+		return scimPatchPath{Segments: splitSCIMPath(path)}, nil
+	}
+	out := scimPatchPath{Segments: splitSCIMPath(path[:i])}
+
+	tokens, err := lexSCIMFilter(path[i:])
+	if err != nil {
+		return out, err
+	}
+	p := &scimFilterParser{tokens: tokens}
+	if err := p.expect("["); err != nil {
+		return out, err
+	}
+	f, err := p.parseOr()
+	if err != nil {
+		return out, err
+	}
+	if err := p.expect("]"); err != nil {
+		return out, err
+	}
+	out.Filter = &f
+
+	rest := path[i+tokens[p.pos-1].end:]
+	switch {
+	case rest == "":
+	case strings.HasPrefix(rest, ".") && !strings.ContainsAny(rest[1:], ".[] "):
+		out.Sub = rest[1:]
+	default:
+		return out, filterErrorf("unexpected %q after value filter", rest)
+	}
+	return out, nil
+}
+
+// applySCIMPatch applies PATCH operations (RFC 7644 3.5.2) to the JSON form of
+// a resource. Operation names are case-insensitive, as Entra ID capitalises them.
+func applySCIMPatch(doc map[string]any, ops []adapter.SCIMPatchOperation) error {
+	for _, op := range ops {
+		if err := applySCIMPatchOp(doc, strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applySCIMPatchOp(doc map[string]any, op, path string, value any) error {
+	switch op {
+	case "add", "replace", "remove":
+	default:
+		return newSCIMError(http.StatusBadRequest, "invalidSyntax", "unknown operation "+op)
+	}
+
+	if path == "" {
+		if op == "remove" {
+			return newSCIMError(http.StatusBadRequest, "noTarget", "remove requires a path")
+		}
+		attrs, ok := value.(map[string]any)
+		if !ok {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", op+" without a path takes an object")
+		}
+		// keys may themselves be paths, e.g. "name.givenName"
+		for k, v := range attrs {
+			if err := applySCIMPatchOp(doc, op, k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if op != "remove" && value == nil {
+		return newSCIMError(http.StatusBadRequest, "invalidValue", op+" "+path+" without a value")
+	}
+
+	p, err := parseSCIMPatchPath(path)
+	if err != nil {
+		return newSCIMError(http.StatusBadRequest, "invalidPath", err.Error())
+	}
+	parent, key, ok := patchTarget(doc, p.Segments, op != "remove")
+	if !ok {
+		if op == "remove" {
+			return nil
+		}
+		return newSCIMError(http.StatusBadRequest, "invalidPath", "cannot reach "+path)
+	}
+	if p.Filter == nil {
+		patchAttribute(parent, key, op, value)
+		return nil
+	}
+	return patchElements(parent, key, op, p, value)
+}
+
+// patchTarget walks to the object holding the last segment, creating missing
+// objects on the way when create is set.
+func patchTarget(doc map[string]any, segments []string, create bool) (map[string]any, string, bool) {
+	m := doc
+	for _, s := range segments[:len(segments)-1] {
+		k, ok := lookupKey(m, s)
+		if !ok {
+			if !create {
+				return nil, "", false
+			}
+			k = s
+			m[k] = map[string]any{}
+		}
+		next, ok := m[k].(map[string]any)
+		if !ok {
+			return nil, "", false
+		}
+		m = next
+	}
+	last := segments[len(segments)-1]
+	if k, ok := lookupKey(m, last); ok {
+		last = k
+	}
+	return m, last, true
+}
+
+// patchAttribute adds, replaces or removes parent[key]. Adding to a
+// multi-valued attribute appends, adding or replacing a complex one merges
+// the given sub-attributes.
+func patchAttribute(parent map[string]any, key, op string, value any) {
+	if op == "remove" {
+		delete(parent, key)
+		return
+	}
+	switch cur := parent[key].(type) {
+	case []any:
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		if op == "add" {
+			values = append(cur, values...)
+		}
+		parent[key] = values
+	case map[string]any:
+		if sub, ok := value.(map[string]any); ok {
+			maps.Copy(cur, sub)
+			return
+		}
+		parent[key] = value
+	default:
+		parent[key] = value
+	}
+}
+
+// patchElements applies op to the elements of the multi-valued attribute
+// parent[key] matching p.Filter. An add matching nothing appends an element
+// built from the filter's eq comparisons, e.g. emails[type eq "work"].value;
+// a replace matching nothing fails with noTarget.
+func patchElements(parent map[string]any, key, op string, p scimPatchPath, value any) error {
+	elems, _ := parent[key].([]any)
+	kept := elems[:0:0]
+	matched := false
+	for _, e := range elems {
+		m, ok := e.(map[string]any)
+		if !ok || !p.Filter.matches(m) {
+			kept = append(kept, e)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && p.Sub == "":
+			continue
+		case op == "remove":
+			if k, ok := lookupKey(m, p.Sub); ok {
+				delete(m, k)
+			}
+		case p.Sub != "":
+			k, ok := lookupKey(m, p.Sub)
+			if !ok {
+				k = p.Sub
+			}
+			m[k] = value
+		default:
+			sub, ok := value.(map[string]any)
+			if !ok {
+				return newSCIMError(http.StatusBadRequest, "invalidValue", "a filtered "+op+" without a sub-attribute takes an object")
+			}
+			maps.Copy(m, sub)
+		}
+		kept = append(kept, m)
+	}
+
+	if !matched {
+		switch op {
+		case "remove":
+			return nil
+		case "replace":
+			return newSCIMError(http.StatusBadRequest, "noTarget", "no "+key+" value matches the filter")
+		}
+		elem := eqAttributes(*p.Filter)
+		if p.Sub != "" {
+			elem[p.Sub] = value
+		} else if sub, ok := value.(map[string]any); ok {
+			maps.Copy(elem, sub)
+		} else {
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "a filtered add without a sub-attribute takes an object")
+		}
+		kept = append(kept, elem)
+	}
+
+	if len(kept) == 0 {
+		delete(parent, key)
+		return nil
+	}
+	parent[key] = kept
+	return nil
+}
+
+// eqAttributes returns the attribute values a conjunction of eq comparisons
+// pins down.
+func eqAttributes(f scimFilter) map[string]any {
+	out := map[string]any{}
+	switch f.Op {
+	case user.OpEq:
+		if f.Value != nil {
+			out[f.Attr] = f.Value
+		}
+	case user.OpAnd:
+		for _, op := range f.Operands {
+			maps.Copy(out, eqAttributes(op))
+		}
+	}
+	return out
+}
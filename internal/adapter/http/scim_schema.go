@@ -0,0 +1,187 @@
+package http
+
+import (
+	"net/http"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetServiceProviderConfig handles GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) GetServiceProviderConfig(c echo.Context) error {
+	return writeSCIM(c, http.StatusOK, map[string]any{
+		"schemas":          []string{adapter.SCIMServiceProviderConfigSchema},
+		"documentationUri": "https://datatracker.ietf.org/doc/html/rfc7644",
+		"patch":            map[string]any{"supported": true},
+		"bulk":             map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           map[string]any{"supported": true, "maxResults": h.conf.MaxResults},
+		"changePassword":   map[string]any{"supported": false},
+		"sort":             map[string]any{"supported": false},
+		"etag":             map[string]any{"supported": true},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with the bearer token set in SCIM_SERVER_TOKEN",
+			"primary":     true,
+		}},
+		"meta": map[string]any{
+			"resourceType": "ServiceProviderConfig",
+			"location":     scimBaseURL(c) + "/ServiceProviderConfig",
+		},
+	})
+}
+
+// GetResourceTypes handles GET /scim/v2/ResourceTypes
+func (h *SCIMHandler) GetResourceTypes(c echo.Context) error {
+	return writeSCIM(c, http.StatusOK, scimList([]any{userResourceType(c)}))
+}
+
+// GetResourceType handles GET /scim/v2/ResourceTypes/:id
+func (h *SCIMHandler) GetResourceType(c echo.Context) error {
+	if c.Param("id") != "User" {
+		return writeSCIMError(c, newSCIMError(http.StatusNotFound, "", "resource type "+c.Param("id")+" not found"))
+	}
+	return writeSCIM(c, http.StatusOK, userResourceType(c))
+}
+
+// GetSchemas handles GET /scim/v2/Schemas
+func (h *SCIMHandler) GetSchemas(c echo.Context) error {
+	schemas := scimSchemas(c)
+	return writeSCIM(c, http.StatusOK, scimList([]any{schemas[adapter.SCIMUserSchema], schemas[adapter.SCIMEnterpriseUserSchema]}))
+}
+
+// GetSchema handles GET /scim/v2/Schemas/:id
+func (h *SCIMHandler) GetSchema(c echo.Context) error {
+	schema, ok := scimSchemas(c)[c.Param("id")]
+	if !ok {
+		return writeSCIMError(c, newSCIMError(http.StatusNotFound, "", "schema "+c.Param("id")+" not found"))
+	}
+	return writeSCIM(c, http.StatusOK, schema)
+}
+
+func scimList(resources []any) adapter.SCIMListResponse {
+	return adapter.SCIMListResponse{
+		Schemas:      []string{adapter.SCIMListResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func userResourceType(c echo.Context) map[string]any {
+	return map[string]any{
+		"schemas":          []string{adapter.SCIMResourceTypeSchema},
+		"id":               "User",
+		"name":             "User",
+		"endpoint":         "/Users",
+		"description":      "User Account",
+		"schema":           adapter.SCIMUserSchema,
+		"schemaExtensions": []map[string]any{{"schema": adapter.SCIMEnterpriseUserSchema, "required": false}},
+		"meta": map[string]any{
+			"resourceType": "ResourceType",
+			"location":     scimBaseURL(c) + "/ResourceTypes/User",
+		},
+	}
+}
+
+// scimAttribute describes an attribute in a Schema resource. opts are
+// "multi", "required" and "server" (unique).
+func scimAttribute(name, typ, description string, subs []map[string]any, opts ...string) map[string]any {
+	attr := map[string]any{
+		"name":        name,
+		"type":        typ,
+		"multiValued": false,
+		"description": description,
+		"required":    false,
+		"caseExact":   false,
+		"mutability":  "readWrite",
+		"returned":    "default",
+		"uniqueness":  "none",
+	}
+	for _, opt := range opts {
+		switch opt {
+		case "multi":
+			attr["multiValued"] = true
+		case "required":
+			attr["required"] = true
+		case "server":
+			attr["uniqueness"] = "server"
+		}
+	}
+	if subs != nil {
+		attr["subAttributes"] = subs
+	}
+	return attr
+}
+
+// multiValueAttributes are the sub-attributes of emails, phoneNumbers and photos.
+func multiValueAttributes(what string) []map[string]any {
+	return []map[string]any{
+		scimAttribute("value", "string", "The "+what+"; only the primary one is stored.", nil),
+		scimAttribute("type", "string", "A label such as work or home.", nil),
+		scimAttribute("primary", "boolean", "Marks the value that is stored.", nil),
+	}
+}
+
+// scimSchemas returns the supported schemas by id. Only the attributes
+// stored for a user are listed.
+func scimSchemas(c echo.Context) map[string]map[string]any {
+	schema := func(id, name, description string, attrs []map[string]any) map[string]any {
+		return map[string]any{
+			"schemas":     []string{adapter.SCIMSchemaSchema},
+			"id":          id,
+			"name":        name,
+			"description": description,
+			"attributes":  attrs,
+			"meta": map[string]any{
+				"resourceType": "Schema",
+				"location":     scimBaseURL(c) + "/Schemas/" + id,
+			},
+		}
+	}
+	str := func(name, description string) map[string]any {
+		return scimAttribute(name, "string", description, nil)
+	}
+
+	return map[string]map[string]any{
+		adapter.SCIMUserSchema: schema(adapter.SCIMUserSchema, "User", "User Account", []map[string]any{
+			scimAttribute("userName", "string", "Unique identifier of the user.", nil, "required", "server"),
+			scimAttribute("name", "complex", "The components of the user's name.", []map[string]any{
+				str("formatted", "The full name; used when displayName is absent."),
+				str("familyName", "The family name."),
+				str("givenName", "The given name."),
+			}),
+			str("displayName", "The name of the user, suitable for display."),
+			str("nickName", "The casual way to address the user."),
+			scimAttribute("profileUrl", "reference", "A URI to the user's online profile.", nil),
+			str("title", "The user's title."),
+			str("userType", "The relationship between the organization and the user."),
+			str("preferredLanguage", "The user's preferred written or spoken language."),
+			str("locale", "The user's default location."),
+			str("timezone", "The user's time zone."),
+			scimAttribute("active", "boolean", "The user's administrative status.", nil),
+			scimAttribute("emails", "complex", "Email addresses of the user.", multiValueAttributes("email address"), "multi"),
+			scimAttribute("phoneNumbers", "complex", "Phone numbers of the user.", multiValueAttributes("phone number"), "multi"),
+			scimAttribute("photos", "complex", "URLs of photos of the user.", multiValueAttributes("photo URL"), "multi"),
+			scimAttribute("addresses", "complex", "Physical addresses of the user; only the primary one is stored.", []map[string]any{
+				str("locality", "The city."),
+				str("country", "The country."),
+				str("type", "A label such as work or home."),
+				scimAttribute("primary", "boolean", "Marks the address that is stored.", nil),
+			}, "multi"),
+		}),
+		adapter.SCIMEnterpriseUserSchema: schema(adapter.SCIMEnterpriseUserSchema, "EnterpriseUser", "Enterprise User", []map[string]any{
+			str("employeeNumber", "Identifier assigned by the organization."),
+			str("costCenter", "The cost center."),
+			str("organization", "The organization."),
+			str("division", "The division."),
+			str("department", "The department."),
+			scimAttribute("manager", "complex", "The user's manager.", []map[string]any{
+				str("value", "The id of the manager."),
+				str("displayName", "The name of the manager."),
+			}),
+		}),
+	}
+}
@@ -0,0 +1,343 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/pkg"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUsers is an in-memory UserUsecase evaluating the eq, pr, and and not
+// filters the SCIM handler sends for its scoping and uniqueness checks.
+type memoryUsers struct {
+	users map[string]usecase.BaseUser
+	next  int
+}
+
+func (m *memoryUsers) GetUsers(context.Context, int) ([]usecase.BaseUser, error) { return nil, nil }
+
+func (m *memoryUsers) CreateUser(_ context.Context, req usecase.CreateUserRequestDTO) ([]usecase.BaseUser, error) {
+	m.next++
+	id, now := user.ID(fmt.Sprintf("u-%d", m.next)), time.Date(2024, 5, 1, 12, 0, m.next, 0, time.UTC)
+	req.ID, req.CreatedAt, req.UpdatedAt = &id, &now, &now
+	m.users[string(id)] = req.BaseUser
+	return []usecase.BaseUser{req.BaseUser}, nil
+}
+
+func (m *memoryUsers) GetUser(_ context.Context, id string) (usecase.BaseUser, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return u, pkg.NewAppError(pkg.ErrNotFound).AddDescription([]byte(id))
+	}
+	return u, nil
+}
+
+func (m *memoryUsers) ListUsers(_ context.Context, req usecase.ListUsersRequestDTO) (usecase.ListUsersResponseDTO, error) {
+	var matched []usecase.BaseUser
+	for _, u := range m.users {
+		if req.Filter == nil || matchUser(*req.Filter, u) {
+			matched = append(matched, u)
+		}
+	}
+	slices.SortFunc(matched, func(a, b usecase.BaseUser) int { return strings.Compare(string(*a.ID), string(*b.ID)) })
+	from := min(req.Offset, len(matched))
+	return usecase.ListUsersResponseDTO{Users: matched[from:min(from+req.Limit, len(matched))], Total: len(matched)}, nil
+}
+
+func (m *memoryUsers) ReplaceUser(_ context.Context, req usecase.ReplaceUserRequestDTO) (usecase.BaseUser, error) {
+	cur, ok := m.users[string(*req.ID)]
+	if !ok {
+		return cur, pkg.NewAppError(pkg.ErrNotFound)
+	}
+	now := cur.UpdatedAt.Add(time.Minute)
+	req.CreatedAt, req.UpdatedAt = cur.CreatedAt, &now
+	m.users[string(*req.ID)] = req.BaseUser
+	return req.BaseUser, nil
+}
+
+func (m *memoryUsers) DeleteUser(_ context.Context, id string) error {
+	delete(m.users, id)
+	return nil
+}
+
+func (m *memoryUsers) ApplyUserEvent(context.Context, usecase.ApplyUserEventRequestDTO) (bool, error) {
+	return false, nil
+}
+
+func matchUser(f user.Filter, u usecase.BaseUser) bool {
+	switch f.Op {
+	case user.OpAnd:
+		for _, op := range f.Operands {
+			if !matchUser(op, u) {
+				return false
+			}
+		}
+		return true
+	case user.OpNot:
+		return !matchUser(f.Operands[0], u)
+	}
+	var v string
+	switch f.Field {
+	case user.FieldID:
+		v = string(*u.ID)
+	case user.FieldUsername:
+		v = string(*u.Username)
+	case user.FieldProvider:
+		v = *u.Provider
+	case user.FieldEmail:
+		if u.Email != nil {
+			v = string(*u.Email)
+		}
+	case user.FieldActive:
+		return f.Op == user.OpEq && *u.Active == f.Value.(bool)
+	}
+	if f.Op == user.OpPr {
+		return v != ""
+	}
+	return f.Op == user.OpEq && strings.EqualFold(v, f.Value.(string))
+}
+
+func newSCIMServer(t *testing.T) (*echo.Echo, *memoryUsers) {
+	t.Helper()
+	users := &memoryUsers{users: map[string]usecase.BaseUser{}}
+	// a user pulled from another provider stays invisible
+	id, name, provider := user.ID("pulled"), user.Username("pulled"), "jsonplaceholder"
+	users.users["pulled"] = usecase.BaseUser{ID: &id, Username: &name, Provider: &provider}
+
+	e := echo.New()
+	RegisterSCIMRoutes(e, users, config.SCIMServerConfig{Token: "s3cret", Provider: "scim-inbound", MaxResults: 10})
+	return e, users
+}
+
+func scimDo(e *echo.Echo, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer s3cret")
+	req.Header.Set(echo.HeaderContentType, SCIMContentType)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeSCIM[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v), rec.Body.String())
+	return v
+}
+
+const bjensen = `{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+	"externalId": "701984",
+	"userName": "bjensen@example.com",
+	"name": {"givenName": "Barbara", "familyName": "Jensen"},
+	"displayName": "Babs Jensen",
+	"emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}],
+	"active": true,
+	"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"organization": "Universal Studios", "department": "Tour Operations"}
+}`
+
+func TestSCIM_UserLifecycle(t *testing.T) {
+	e, users := newSCIMServer(t)
+
+	rec := scimDo(e, http.MethodPost, "/scim/v2/Users", bjensen)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, SCIMContentType, rec.Header().Get(echo.HeaderContentType))
+	created := decodeSCIM[adapter.SCIMUser](t, rec)
+	assert.Equal(t, "http://example.com/scim/v2/Users/"+created.Id, rec.Header().Get(echo.HeaderLocation))
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, etag, created.Meta.Version)
+	assert.Equal(t, "Babs Jensen", created.DisplayName)
+	assert.Equal(t, "Universal Studios", created.Enterprise.Organization)
+	stored := users.users[created.Id]
+	assert.Equal(t, "scim-inbound", *stored.Provider)
+	assert.Equal(t, map[string]string{"given_name": "Barbara", "family_name": "Jensen", "department": "Tour Operations"}, stored.Extra)
+
+	rec = scimDo(e, http.MethodPost, "/scim/v2/Users", bjensen)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "uniqueness", decodeSCIM[adapter.SCIMError](t, rec).ScimType)
+
+	rec = scimDo(e, http.MethodGet, "/scim/v2/Users/"+created.Id, "", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = scimDo(e, http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName eq "BJENSEN@example.com" and active eq true`), "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	list := decodeSCIM[adapter.SCIMListResponse](t, rec)
+	assert.Equal(t, 1, list.TotalResults)
+
+	rec = scimDo(e, http.MethodPatch, "/scim/v2/Users/"+created.Id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "Add", "path": "phoneNumbers[type eq \"work\"].value", "value": "555-555-5555"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "babs@example.com"},
+			{"op": "replace", "value": {"name.givenName": "Babs", "title": "Tour Guide"}}
+		]}`, "If-Match", etag)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	patched := decodeSCIM[adapter.SCIMUser](t, rec)
+	assert.False(t, *patched.Active)
+	assert.Equal(t, "555-555-5555", patched.PhoneNumbers[0].Value)
+	assert.Equal(t, "babs@example.com", patched.Emails[0].Value)
+	assert.Equal(t, "Babs", patched.Name.GivenName)
+	assert.Equal(t, "Tour Guide", patched.Title)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	rec = scimDo(e, http.MethodDelete, "/scim/v2/Users/"+created.Id, "", "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = scimDo(e, http.MethodDelete, "/scim/v2/Users/"+created.Id, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = scimDo(e, http.MethodGet, "/scim/v2/Users/"+created.Id, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, []string{adapter.SCIMErrorSchema}, decodeSCIM[adapter.SCIMError](t, rec).Schemas)
+}
+
+func TestSCIM_ETagIgnoresHostAndScheme(t *testing.T) {
+	e, _ := newSCIMServer(t)
+	rec := scimDo(e, http.MethodPost, "/scim/v2/Users", bjensen)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	created := decodeSCIM[adapter.SCIMUser](t, rec)
+	etag := rec.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users/"+created.Id, nil)
+	req.Host = "scim.internal:8443"
+	req.Header.Set(echo.HeaderAuthorization, "Bearer s3cret")
+	req.Header.Set(echo.HeaderXForwardedProto, "https")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "https://scim.internal:8443/scim/v2/Users/"+created.Id, decodeSCIM[adapter.SCIMUser](t, rec).Meta.Location)
+	assert.Equal(t, etag, rec.Header().Get("ETag"), "the version of a user does not depend on how it is addressed")
+}
+
+func TestSCIM_ListIsScopedAndPaged(t *testing.T) {
+	e, _ := newSCIMServer(t)
+	for _, name := range []string{"a", "b", "c"} {
+		require.Equal(t, http.StatusCreated, scimDo(e, http.MethodPost, "/scim/v2/Users", `{"userName":"`+name+`"}`).Code)
+	}
+
+	list := decodeSCIM[adapter.SCIMListResponse](t, scimDo(e, http.MethodGet, "/scim/v2/Users?startIndex=2&count=1", ""))
+	assert.Equal(t, 3, list.TotalResults)
+	assert.Equal(t, 2, list.StartIndex)
+	require.Len(t, list.Resources, 1)
+	assert.Equal(t, "b", list.Resources[0].(map[string]any)["userName"])
+
+	assert.Equal(t, http.StatusNotFound, scimDo(e, http.MethodGet, "/scim/v2/Users/pulled", "").Code)
+
+	rec := scimDo(e, http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`password eq "x"`), "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalidFilter", decodeSCIM[adapter.SCIMError](t, rec).ScimType)
+}
+
+func TestSCIM_RequiresBearerToken(t *testing.T) {
+	e, _ := newSCIMServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer wrong")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "401", decodeSCIM[adapter.SCIMError](t, rec).Status)
+}
+
+func TestSCIM_DiscoveryEndpoints(t *testing.T) {
+	e, _ := newSCIMServer(t)
+
+	spc := decodeSCIM[map[string]any](t, scimDo(e, http.MethodGet, "/scim/v2/ServiceProviderConfig", ""))
+	assert.Equal(t, true, spc["patch"].(map[string]any)["supported"])
+	assert.Equal(t, float64(10), spc["filter"].(map[string]any)["maxResults"])
+
+	types := decodeSCIM[adapter.SCIMListResponse](t, scimDo(e, http.MethodGet, "/scim/v2/ResourceTypes", ""))
+	assert.Equal(t, 1, types.TotalResults)
+
+	schemas := decodeSCIM[adapter.SCIMListResponse](t, scimDo(e, http.MethodGet, "/scim/v2/Schemas", ""))
+	assert.Equal(t, 2, schemas.TotalResults)
+	rec := scimDo(e, http.MethodGet, "/scim/v2/Schemas/"+adapter.SCIMEnterpriseUserSchema, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusNotFound, scimDo(e, http.MethodGet, "/scim/v2/ResourceTypes/Group", "").Code)
+}
+
+func TestParseSCIMFilter_TranslatesToUserFilter(t *testing.T) {
+	f, err := parseSCIMFilter(`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "J" and not (emails[value co "@example.com"] or meta.lastModified gt "2011-05-13T04:42:34Z") or externalId eq null`)
+	require.NoError(t, err)
+	uf, err := f.userFilter()
+	require.NoError(t, err)
+
+	assert.Equal(t, user.Filter{Op: user.OpOr, Operands: []user.Filter{
+		{Op: user.OpAnd, Operands: []user.Filter{
+			{Op: user.OpSw, Field: user.FieldUsername, Value: "J"},
+			{Op: user.OpNot, Operands: []user.Filter{{Op: user.OpOr, Operands: []user.Filter{
+				{Op: user.OpCo, Field: user.FieldEmail, Value: "@example.com"},
+				{Op: user.OpGt, Field: user.FieldUpdatedAt, Value: time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+			}}}},
+		}},
+		{Op: user.OpNot, Operands: []user.Filter{{Op: user.OpPr, Field: user.FieldExternalID}}},
+	}}, uf)
+}
+
+func TestParseSCIMFilter_Errors(t *testing.T) {
+	for _, expr := range []string{
+		`userName eq`,
+		`userName regex "x"`,
+		`(userName eq "x"`,
+		`userName eq "x" extra`,
+		`userName eq "unterminated`,
+	} {
+		_, err := parseSCIMFilter(expr)
+		assert.Error(t, err, expr)
+	}
+	f, err := parseSCIMFilter(`active eq "yes"`)
+	require.NoError(t, err)
+	_, err = f.userFilter()
+	assert.Error(t, err)
+}
+
+func TestApplySCIMPatch(t *testing.T) {
+	doc := map[string]any{
+		"userName": "bjensen",
+		"emails": []any{
+			map[string]any{"value": "work@example.com", "type": "work", "primary": true},
+			map[string]any{"value": "home@example.com", "type": "home"},
+		},
+		"name": map[string]any{"givenName": "Barbara"},
+	}
+	err := applySCIMPatch(doc, []adapter.SCIMPatchOperation{
+		{Op: "remove", Path: `emails[type eq "home"]`},
+		{Op: "add", Path: "name", Value: map[string]any{"familyName": "Jensen"}},
+		{Op: "replace", Path: adapter.SCIMEnterpriseUserSchema + ":department", Value: "Tours"},
+		{Op: "remove", Path: "nickName"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"userName":                       "bjensen",
+		"emails":                         []any{map[string]any{"value": "work@example.com", "type": "work", "primary": true}},
+		"name":                           map[string]any{"givenName": "Barbara", "familyName": "Jensen"},
+		adapter.SCIMEnterpriseUserSchema: map[string]any{"department": "Tours"},
+	}, doc)
+
+	err = applySCIMPatch(doc, []adapter.SCIMPatchOperation{{Op: "replace", Path: `emails[type eq "other"].value`, Value: "x"}})
+	assert.Equal(t, "noTarget", err.(*scimError).scimType)
+	err = applySCIMPatch(doc, []adapter.SCIMPatchOperation{{Op: "remove"}})
+	assert.Equal(t, "noTarget", err.(*scimError).scimType)
+	err = applySCIMPatch(doc, []adapter.SCIMPatchOperation{{Op: "move", Path: "userName"}})
+	assert.Equal(t, "invalidSyntax", err.(*scimError).scimType)
+	err = applySCIMPatch(doc, []adapter.SCIMPatchOperation{{Op: "add", Path: `emails[type eq`, Value: "x"}})
+	assert.Equal(t, "invalidPath", err.(*scimError).scimType)
+}
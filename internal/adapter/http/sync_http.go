@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/interfaces"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// SyncHandler triggers provider synchronisation on demand and lists past runs.
+type SyncHandler struct {
+	sync interfaces.ProviderSyncUsecase
+}
+
+// NewSyncHandler constructs a handler.
+func NewSyncHandler(uc interfaces.ProviderSyncUsecase) *SyncHandler {
+	return &SyncHandler{sync: uc}
+}
+
+// SyncProvider handles POST /admin/providers/:id/sync. It waits for the run
+// and returns its summary, also when the run itself failed.
+func (h *SyncHandler) SyncProvider(c echo.Context) error {
+	res, err := h.sync.SyncProvider(c.Request().Context(), c.Param("id"))
+	if err != nil && res.StartedAt.IsZero() {
+		return handleUsecaseError(c, err)
+	}
+	return c.JSON(http.StatusOK, mapper.SyncRunToResponse(res))
+}
+
+// SyncAll handles POST /admin/sync. All active providers are synchronised in
+// the background; progress is visible through GET /admin/sync/runs.
+func (h *SyncHandler) SyncAll(c echo.Context) error {
+	ctx := context.WithoutCancel(c.Request().Context())
+	go func() {
+		if err := h.sync.SyncProviders(ctx); err != nil {
+			log.WithError(err).Warn("on-demand provider sync finished with errors")
+		}
+	}()
+	return c.NoContent(http.StatusAccepted)
+}
+
+// GetSyncRuns handles GET /admin/sync/runs?provider=<id>&limit=<n>
+func (h *SyncHandler) GetSyncRuns(c echo.Context) error {
+	limit := 0
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+		}
+		limit = n
+	}
+
+	runs, err := h.sync.GetSyncRuns(c.Request().Context(), c.QueryParam("provider"), limit)
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	resp := adapter.GetSyncRunsResponse{Runs: make([]adapter.SyncRunResponse, 0, len(runs))}
+	for _, r := range runs {
+		resp.Runs = append(resp.Runs, mapper.SyncRunToResponse(r))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -41,12 +41,12 @@ func (h *UserHandler) GetUsers(c echo.Context) error {
 		return handleUsecaseError(c, err)
 	}
 
-	usersResp := make([]adapter.UserResponse, 0, len(users))
+	usersResp := make([]adapter.UserWithIdentityResponse, 0, len(users))
 	for _, u := range users {
 		usersResp = append(usersResp, mapper.UserUsecaseToIntegration(u))
 	}
 
-	resp := adapter.GetUsersResponse{Users: &usersResp}
+	resp := adapter.GetUsersWithIdentityResponse{Users: &usersResp}
 	return c.JSON(http.StatusOK, resp)
 }
 
@@ -68,7 +68,7 @@ func (h *UserHandler) CreateUsers(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	createdUsersResp := make([]adapter.UserResponse, 0, len(createdUsers))
+	createdUsersResp := make([]adapter.UserWithIdentityResponse, 0, len(createdUsers))
 	for _, u := range createdUsers {
 		createdUsersResp = append(createdUsersResp, mapper.UserUsecaseToIntegration(u))
 	}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookHandler receives the change events pushed by provider instances.
+type WebhookHandler struct {
+	uc      interfaces.WebhookUsecase
+	maxBody int64
+}
+
+// NewWebhookHandler constructs a handler reading at most maxBody bytes of a delivery.
+func NewWebhookHandler(uc interfaces.WebhookUsecase, maxBody int64) *WebhookHandler {
+	return &WebhookHandler{uc: uc, maxBody: maxBody}
+}
+
+// ReceiveWebhook handles POST /webhooks/:provider. Events are applied in the
+// background; the response only tells how many were queued.
+func (h *WebhookHandler) ReceiveWebhook(c echo.Context) error {
+	req := c.Request()
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, h.maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "payload too large"})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+	}
+
+	n, err := h.uc.ReceiveWebhook(req.Context(), usecase.ReceiveWebhookRequestDTO{
+		Provider: c.Param("provider"),
+		Header:   req.Header,
+		Body:     body,
+	})
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, adapter.WebhookAcceptedResponse{Accepted: n})
+}
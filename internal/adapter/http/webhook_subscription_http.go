@@ -0,0 +1,132 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookSubscriptionHandler manages outbound webhook subscriptions and their deliveries.
+type WebhookSubscriptionHandler struct {
+	uc interfaces.OutboundWebhookUsecase
+}
+
+// NewWebhookSubscriptionHandler constructs a handler.
+func NewWebhookSubscriptionHandler(uc interfaces.OutboundWebhookUsecase) *WebhookSubscriptionHandler {
+	return &WebhookSubscriptionHandler{uc: uc}
+}
+
+// GetSubscriptions handles GET /admin/webhooks
+func (h *WebhookSubscriptionHandler) GetSubscriptions(c echo.Context) error {
+	subs, err := h.uc.ListSubscriptions(c.Request().Context())
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	resp := adapter.GetWebhookSubscriptionsResponse{Subscriptions: make([]adapter.WebhookSubscriptionResponse, 0, len(subs))}
+	for _, s := range subs {
+		resp.Subscriptions = append(resp.Subscriptions, mapper.WebhookSubscriptionToResponse(s, false))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CreateSubscription handles POST /admin/webhooks. The response is the only
+// one carrying the secret.
+func (h *WebhookSubscriptionHandler) CreateSubscription(c echo.Context) error {
+	req, err := bindSubscription(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	sub, err := h.uc.CreateSubscription(c.Request().Context(), mapper.WebhookSubscriptionRequestToUsecase("", req))
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return c.JSON(http.StatusCreated, mapper.WebhookSubscriptionToResponse(sub, true))
+}
+
+// GetSubscription handles GET /admin/webhooks/:id
+func (h *WebhookSubscriptionHandler) GetSubscription(c echo.Context) error {
+	sub, err := h.uc.GetSubscription(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return c.JSON(http.StatusOK, mapper.WebhookSubscriptionToResponse(sub, false))
+}
+
+// ReplaceSubscription handles PUT /admin/webhooks/:id. An omitted secret is kept.
+func (h *WebhookSubscriptionHandler) ReplaceSubscription(c echo.Context) error {
+	req, err := bindSubscription(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	sub, err := h.uc.ReplaceSubscription(c.Request().Context(), mapper.WebhookSubscriptionRequestToUsecase(c.Param("id"), req))
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return c.JSON(http.StatusOK, mapper.WebhookSubscriptionToResponse(sub, false))
+}
+
+// DeleteSubscription handles DELETE /admin/webhooks/:id
+func (h *WebhookSubscriptionHandler) DeleteSubscription(c echo.Context) error {
+	if err := h.uc.DeleteSubscription(c.Request().Context(), c.Param("id")); err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetDeliveries handles GET /admin/webhooks/:id/deliveries?status=<status>&limit=<n>
+func (h *WebhookSubscriptionHandler) GetDeliveries(c echo.Context) error {
+	limit := 0
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+		}
+		limit = n
+	}
+
+	deliveries, err := h.uc.ListDeliveries(c.Request().Context(), usecase.ListWebhookDeliveriesRequestDTO{
+		SubscriptionID: c.Param("id"),
+		Status:         usecase.WebhookDeliveryStatus(c.QueryParam("status")),
+		Limit:          limit,
+	})
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	resp := adapter.GetWebhookDeliveriesResponse{Deliveries: make([]adapter.WebhookDeliveryResponse, 0, len(deliveries))}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, mapper.WebhookDeliveryToResponse(d))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Redeliver handles POST /admin/webhooks/:id/deliveries/:delivery/redeliver
+// and returns the queued delivery.
+func (h *WebhookSubscriptionHandler) Redeliver(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("delivery"), 10, 0)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid delivery id"})
+	}
+	d, err := h.uc.Redeliver(c.Request().Context(), c.Param("id"), uint(id))
+	if err != nil {
+		return handleUsecaseError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, mapper.WebhookDeliveryToResponse(d))
+}
+
+func bindSubscription(c echo.Context) (adapter.WebhookSubscriptionRequestDTO, error) {
+	var req adapter.WebhookSubscriptionRequestDTO
+	if err := c.Bind(&req); err != nil {
+		return req, errors.New("invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return req, err
+	}
+	return req, nil
+}
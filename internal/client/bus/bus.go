@@ -0,0 +1,74 @@
+/*
+Package bus implements the message bus: on Kafka, or in memory when no broker
+is configured, which only delivers within the process and is meant for tests
+and local runs. Messages carry the request id they were published under in
+the X-Request-ID header.
+*/
+package bus
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// New returns the bus configured in cfg.
+func New(cfg config.App) (interfaces.MessageBus, error) {
+	if len(cfg.Brokers) == 0 {
+		log.Warn("no kafka brokers configured, messages stay in memory")
+		return NewMemoryBus(time.Duration(cfg.RetryBackoff) * time.Millisecond), nil
+	}
+	return NewKafkaBus(cfg.KafkaConfig)
+}
+
+func validateSubscription(topic, group string, handler interfaces.MessageHandler) error {
+	switch {
+	case topic == "":
+		return errors.New("bus: subscription needs a topic")
+	case group == "":
+		return errors.New("bus: subscription needs a group")
+	case handler == nil:
+		return errors.New("bus: subscription needs a handler")
+	}
+	return nil
+}
+
+// stamp returns msg with the request id of ctx unless it carries one already.
+func stamp(ctx context.Context, msg bus.Message) bus.Message {
+	id, ok := pkg.RequestIDFromContext(ctx)
+	if !ok || msg.Headers[pkg.RequestIDHeader] != "" {
+		return msg
+	}
+	msg.Headers = maps.Clone(msg.Headers)
+	if msg.Headers == nil {
+		msg.Headers = map[string]string{}
+	}
+	msg.Headers[pkg.RequestIDHeader] = id
+	return msg
+}
+
+// deliver hands msg to handler under the request id it carries and counts
+// the outcome.
+func deliver(ctx context.Context, group string, msg bus.Message, handler interfaces.MessageHandler) error {
+	if id := msg.Headers[pkg.RequestIDHeader]; id != "" {
+		ctx = pkg.WithRequestID(ctx, id)
+	}
+	err := handler(ctx, msg)
+	status := "acked"
+	if err != nil {
+		status = "nacked"
+		log.WithError(err).WithFields(log.Fields{
+			"topic": msg.Topic, "group": group, "partition": msg.Partition, "offset": msg.Offset,
+		}).Warn("bus: message not acknowledged, it will be delivered again")
+	}
+	pkg.CounterAdd("bus_messages_consumed_total", 1, "topic", msg.Topic, "group", group, "status", status)
+	return err
+}
@@ -0,0 +1,276 @@
+package bus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	log "github.com/sirupsen/logrus"
+)
+
+// produceBatchTimeout bounds how long Publish waits for more messages to
+// batch with before sending, as it returns only once they are written.
+const produceBatchTimeout = 10 * time.Millisecond
+
+// kafkaBus publishes to and consumes from Kafka topics. Each subscription has
+// a reader of its own, so its fetches do not hold up publishing.
+type kafkaBus struct {
+	cfg    config.KafkaConfig
+	dialer *kafka.Dialer
+	writer *kafka.Writer
+
+	mu      sync.Mutex
+	cancels []context.CancelFunc
+	closed  bool
+}
+
+var _ interfaces.MessageBus = (*kafkaBus)(nil)
+
+// NewKafkaBus returns a bus on the cluster of cfg. Connections are opened on
+// first use.
+func NewKafkaBus(cfg config.KafkaConfig) (*kafkaBus, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers configured")
+	}
+	if cfg.StartOffset != "earliest" && cfg.StartOffset != "latest" {
+		return nil, fmt.Errorf("kafka: start offset must be earliest or latest, got %q", cfg.StartOffset)
+	}
+	mechanism, err := newMechanism(cfg.SASLMechanism, cfg.SASLUsername, cfg.SASLPassword)
+	if err != nil {
+		return nil, err
+	}
+	var tlsConfig *tls.Config
+	if cfg.TLSEnabled {
+		if tlsConfig, err = newTLSConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := time.Duration(cfg.RequestTimeout) * time.Millisecond
+	writer := &kafka.Writer{
+		Addr: kafka.TCP(cfg.Brokers...),
+		// the partitioner of the Java client, so keys land where other
+		// producers put them
+		Balancer:     kafka.Murmur2Balancer{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: produceBatchTimeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		Transport: &kafka.Transport{
+			ClientID:    cfg.ClientID,
+			DialTimeout: time.Duration(cfg.DialTimeout) * time.Millisecond,
+			TLS:         tlsConfig,
+			SASL:        mechanism,
+		},
+		ErrorLogger: kafkaLogger(log.Fields{"client": "producer"}),
+	}
+	dialer := &kafka.Dialer{
+		ClientID:      cfg.ClientID,
+		Timeout:       time.Duration(cfg.DialTimeout) * time.Millisecond,
+		KeepAlive:     30 * time.Second,
+		DualStack:     true,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
+	}
+	return &kafkaBus{cfg: cfg, dialer: dialer, writer: writer}, nil
+}
+
+// Publish produces msgs and returns once the in-sync replicas have them. The
+// messages of a topic are appended in order when they share a key.
+func (k *kafkaBus) Publish(ctx context.Context, msgs ...bus.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	out := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Topic == "" {
+			return errors.New("bus: message needs a topic")
+		}
+		out = append(out, toKafkaMessage(stamp(ctx, msg)))
+	}
+	if err := k.writer.WriteMessages(ctx, out...); err != nil {
+		return fmt.Errorf("kafka: produce: %w", err)
+	}
+	for _, msg := range msgs {
+		pkg.CounterAdd("bus_messages_published_total", 1, "topic", msg.Topic)
+	}
+	return nil
+}
+
+// Subscribe runs a group member consuming topic. Messages of a partition are
+// handed to handler one at a time, in order, and committed once it returns
+// nil; a failing message is handed again after RetryBackoff. When the member
+// stops on an error it is started again after RetryBackoff.
+func (k *kafkaBus) Subscribe(ctx context.Context, topic, group string, handler interfaces.MessageHandler) error {
+	if err := validateSubscription(topic, group, handler); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return errors.New("bus: closed")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	k.cancels = append(k.cancels, cancel)
+	k.mu.Unlock()
+
+	go func() {
+		for {
+			err := k.consume(ctx, topic, group, handler)
+			if ctx.Err() != nil {
+				return
+			}
+			log.WithError(err).WithFields(log.Fields{"topic": topic, "group": group}).Error("bus: consumer stopped, restarting")
+			if !sleep(ctx, k.retryBackoff()) {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// consume reads topic as a member of group until ctx is done or the reader
+// fails.
+func (k *kafkaBus) consume(ctx context.Context, topic, group string, handler interfaces.MessageHandler) error {
+	r := kafka.NewReader(k.readerConfig(topic, group))
+	defer r.Close()
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		msg := fromKafkaMessage(m)
+		for deliver(ctx, group, msg, handler) != nil {
+			if !sleep(ctx, k.retryBackoff()) {
+				return ctx.Err()
+			}
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+}
+
+func (k *kafkaBus) readerConfig(topic, group string) kafka.ReaderConfig {
+	start := kafka.FirstOffset
+	if k.cfg.StartOffset == "latest" {
+		start = kafka.LastOffset
+	}
+	return kafka.ReaderConfig{
+		Brokers:           k.cfg.Brokers,
+		GroupID:           group,
+		Topic:             topic,
+		Dialer:            k.dialer,
+		StartOffset:       start,
+		MaxWait:           time.Duration(k.cfg.FetchMaxWait) * time.Millisecond,
+		MaxBytes:          k.cfg.FetchMaxBytes,
+		SessionTimeout:    time.Duration(k.cfg.SessionTimeout) * time.Millisecond,
+		HeartbeatInterval: time.Duration(k.cfg.HeartbeatInterval) * time.Millisecond,
+		JoinGroupBackoff:  k.retryBackoff(),
+		// commits are synchronous, so an acknowledged message is committed
+		// before the next one is handed out
+		CommitInterval: 0,
+		ErrorLogger:    kafkaLogger(log.Fields{"topic": topic, "group": group}),
+	}
+}
+
+func (k *kafkaBus) retryBackoff() time.Duration {
+	return time.Duration(k.cfg.RetryBackoff) * time.Millisecond
+}
+
+func (k *kafkaBus) Close() error {
+	k.mu.Lock()
+	k.closed = true
+	cancels := k.cancels
+	k.cancels = nil
+	k.mu.Unlock()
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return k.writer.Close()
+}
+
+// kafkaLogger logs what the kafka client reports as a warning.
+func kafkaLogger(fields log.Fields) kafka.Logger {
+	return kafka.LoggerFunc(func(msg string, args ...any) {
+		log.WithFields(fields).Warnf("kafka: "+msg, args...)
+	})
+}
+
+func newMechanism(name, username, password string) (sasl.Mechanism, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "PLAIN":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "SCRAM-SHA-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "SCRAM-SHA-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	}
+	return nil, fmt.Errorf("kafka: unsupported sasl mechanism %q", name)
+}
+
+func newTLSConfig(cfg config.KafkaConfig) (*tls.Config, error) {
+	t := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSCACertFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("read kafka ca cert file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCACertFile)
+		}
+		t.RootCAs = pool
+	}
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load kafka client certificate: %w", err)
+		}
+		t.Certificates = []tls.Certificate{cert}
+	}
+	return t, nil
+}
+
+func toKafkaMessage(msg bus.Message) kafka.Message {
+	m := kafka.Message{Topic: msg.Topic, Key: msg.Key, Value: msg.Value}
+	for _, key := range slices.Sorted(maps.Keys(msg.Headers)) {
+		m.Headers = append(m.Headers, kafka.Header{Key: key, Value: []byte(msg.Headers[key])})
+	}
+	return m
+}
+
+func fromKafkaMessage(m kafka.Message) bus.Message {
+	msg := bus.Message{
+		Topic: m.Topic, Key: m.Key, Value: m.Value,
+		Partition: m.Partition, Offset: m.Offset, Time: m.Time,
+	}
+	if len(m.Headers) > 0 {
+		msg.Headers = make(map[string]string, len(m.Headers))
+		for _, h := range m.Headers {
+			msg.Headers[h.Key] = string(h.Value)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,92 @@
+package bus
+
+import (
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKafkaConfig() config.KafkaConfig {
+	return config.KafkaConfig{
+		Brokers: []string{"localhost:9092"}, ClientID: "test", DialTimeout: 1000, RequestTimeout: 2000,
+		SessionTimeout: 6000, HeartbeatInterval: 20, RetryBackoff: 1, StartOffset: "earliest",
+		FetchMaxWait: 10, FetchMaxBytes: 1 << 20,
+	}
+}
+
+func TestKafkaMessageConversion(t *testing.T) {
+	m := toKafkaMessage(bus.Message{Topic: "users", Key: []byte("u-1"), Value: []byte("{}"), Headers: map[string]string{"b": "2", "a": "1"}})
+	assert.Equal(t, "users", m.Topic)
+	assert.Equal(t, []kafka.Header{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}}, m.Headers)
+
+	at := time.UnixMilli(1_700_000_000_000)
+	m.Partition, m.Offset, m.Time = 2, 7, at
+	assert.Equal(t, bus.Message{
+		Topic: "users", Key: []byte("u-1"), Value: []byte("{}"), Headers: map[string]string{"a": "1", "b": "2"},
+		Partition: 2, Offset: 7, Time: at,
+	}, fromKafkaMessage(m))
+}
+
+func TestNewKafkaBus_ValidatesConfig(t *testing.T) {
+	for name, tc := range map[string]struct {
+		change func(*config.KafkaConfig)
+		want   string
+	}{
+		"no brokers":        {func(c *config.KafkaConfig) { c.Brokers = nil }, "no brokers"},
+		"start offset":      {func(c *config.KafkaConfig) { c.StartOffset = "middle" }, "start offset"},
+		"sasl mechanism":    {func(c *config.KafkaConfig) { c.SASLMechanism = "GSSAPI" }, "unsupported sasl mechanism"},
+		"missing ca file":   {func(c *config.KafkaConfig) { c.TLSEnabled, c.TLSCACertFile = true, "/nonexistent/ca.pem" }, "read kafka ca cert file"},
+		"missing cert file": {func(c *config.KafkaConfig) { c.TLSEnabled, c.TLSCertFile = true, "/nonexistent/cert.pem" }, "load kafka client certificate"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := testKafkaConfig()
+			tc.change(&cfg)
+			_, err := NewKafkaBus(cfg)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+
+	for _, mechanism := range []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"} {
+		cfg := testKafkaConfig()
+		cfg.SASLMechanism, cfg.SASLUsername, cfg.SASLPassword = mechanism, "user", "pencil"
+		k, err := NewKafkaBus(cfg)
+		require.NoError(t, err, mechanism)
+		assert.Equal(t, mechanism, k.dialer.SASLMechanism.Name())
+	}
+}
+
+func TestKafkaBus_ReaderConfig(t *testing.T) {
+	cfg := testKafkaConfig()
+	cfg.StartOffset = "latest"
+	k, err := NewKafkaBus(cfg)
+	require.NoError(t, err)
+	defer k.Close()
+
+	rc := k.readerConfig("users", "user-service")
+	assert.Equal(t, "users", rc.Topic)
+	assert.Equal(t, "user-service", rc.GroupID)
+	assert.Equal(t, kafka.LastOffset, rc.StartOffset)
+	assert.Zero(t, rc.CommitInterval, "offsets are committed synchronously")
+	assert.Equal(t, 6*time.Second, rc.SessionTimeout)
+	assert.Equal(t, 10*time.Millisecond, rc.MaxWait)
+	assert.Same(t, k.dialer, rc.Dialer)
+	assert.Equal(t, kafka.RequireAll, k.writer.RequiredAcks)
+	require.NoError(t, rc.Validate())
+}
+
+func TestNew_FallsBackToMemory(t *testing.T) {
+	b, err := New(config.App{})
+	require.NoError(t, err)
+	assert.IsType(t, &memoryBus{}, b)
+
+	b, err = New(config.App{KafkaConfig: config.KafkaConfig{Brokers: []string{"localhost:9092"}, StartOffset: "earliest"}})
+	require.NoError(t, err)
+	assert.IsType(t, &kafkaBus{}, b)
+	require.NoError(t, b.Close())
+}
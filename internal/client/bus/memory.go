@@ -0,0 +1,170 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+)
+
+// memoryBus keeps every topic as a single in-memory log. Each group reads it
+// in order, one message at a time, from its first message; a group created
+// later still gets the messages published before.
+type memoryBus struct {
+	retryDelay time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*memoryTopic
+	// changed is closed, and replaced, whenever a log or a group moves
+	changed chan struct{}
+	closed  bool
+}
+
+type memoryTopic struct {
+	log    []bus.Message
+	groups map[string]*memoryGroup
+}
+
+type memoryGroup struct {
+	next int  // offset of the next message to hand out
+	busy bool // a subscriber is handling message next
+}
+
+var _ interfaces.MessageBus = (*memoryBus)(nil)
+
+// NewMemoryBus returns an empty in-memory bus handing a message that was not
+// acknowledged again after retryDelay.
+func NewMemoryBus(retryDelay time.Duration) *memoryBus {
+	return &memoryBus{retryDelay: retryDelay, topics: map[string]*memoryTopic{}, changed: make(chan struct{})}
+}
+
+func (m *memoryBus) Publish(ctx context.Context, msgs ...bus.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return errors.New("bus: closed")
+	}
+	for _, msg := range msgs {
+		if msg.Topic == "" {
+			return errors.New("bus: message needs a topic")
+		}
+	}
+	now := time.Now()
+	for _, msg := range msgs {
+		msg = stamp(ctx, msg)
+		t := m.topic(msg.Topic)
+		msg.Partition, msg.Offset, msg.Time = 0, int64(len(t.log)), now
+		t.log = append(t.log, msg)
+		pkg.CounterAdd("bus_messages_published_total", 1, "topic", msg.Topic)
+	}
+	m.broadcast()
+	return nil
+}
+
+func (m *memoryBus) Subscribe(ctx context.Context, topic, group string, handler interfaces.MessageHandler) error {
+	if err := validateSubscription(topic, group, handler); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return errors.New("bus: closed")
+	}
+	t := m.topic(topic)
+	if t.groups[group] == nil {
+		t.groups[group] = &memoryGroup{}
+	}
+	go m.consume(ctx, t, t.groups[group], group, handler)
+	return nil
+}
+
+func (m *memoryBus) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.broadcast()
+	return nil
+}
+
+// Messages returns the messages published to topic so far.
+func (m *memoryBus) Messages(topic string) []bus.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.topics[topic]; ok {
+		return slices.Clone(t.log)
+	}
+	return nil
+}
+
+// consume hands the messages of t to handler as long as ctx and the bus are
+// alive, taking turns with the other subscribers of the group.
+func (m *memoryBus) consume(ctx context.Context, t *memoryTopic, g *memoryGroup, group string, handler interfaces.MessageHandler) {
+	for {
+		m.mu.Lock()
+		if m.closed || ctx.Err() != nil {
+			m.mu.Unlock()
+			return
+		}
+		if g.busy || g.next >= len(t.log) {
+			changed := m.changed
+			m.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			}
+			continue
+		}
+		msg := t.log[g.next]
+		msg.Headers = maps.Clone(msg.Headers)
+		g.busy = true
+		m.mu.Unlock()
+
+		err := deliver(ctx, group, msg, handler)
+
+		m.mu.Lock()
+		g.busy = false
+		if err == nil {
+			g.next++
+		}
+		m.broadcast()
+		m.mu.Unlock()
+		if err != nil && !sleep(ctx, m.retryDelay) {
+			return
+		}
+	}
+}
+
+// topic returns the topic called name, creating it. m.mu must be held.
+func (m *memoryBus) topic(name string) *memoryTopic {
+	t, ok := m.topics[name]
+	if !ok {
+		t = &memoryTopic{groups: map[string]*memoryGroup{}}
+		m.topics[name] = t
+	}
+	return t
+}
+
+// broadcast wakes the waiting subscribers up. m.mu must be held.
+func (m *memoryBus) broadcast() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// sleep waits for d, reporting false when ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
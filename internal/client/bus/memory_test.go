@@ -0,0 +1,124 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector records the messages handed to a subscriber.
+type collector struct {
+	mu   sync.Mutex
+	msgs []bus.Message
+	ids  []string
+}
+
+func (c *collector) handle(ctx context.Context, msg bus.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, _ := pkg.RequestIDFromContext(ctx)
+	c.msgs = append(c.msgs, msg)
+	c.ids = append(c.ids, id)
+	return nil
+}
+
+func (c *collector) values() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []string
+	for _, m := range c.msgs {
+		out = append(out, string(m.Value))
+	}
+	return out
+}
+
+func TestMemoryBus_GroupsShareAndEachGetsEverything(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewMemoryBus(time.Millisecond)
+	audit1, audit2, mail := &collector{}, &collector{}, &collector{}
+	require.NoError(t, b.Subscribe(ctx, "users", "audit", audit1.handle))
+	require.NoError(t, b.Subscribe(ctx, "users", "audit", audit2.handle))
+	require.NoError(t, b.Subscribe(ctx, "users", "mail", mail.handle))
+
+	require.NoError(t, b.Publish(ctx, bus.Message{Topic: "users", Value: []byte("1")}, bus.Message{Topic: "users", Value: []byte("2")}))
+	require.NoError(t, b.Publish(ctx, bus.Message{Topic: "users", Value: []byte("3")}))
+
+	require.Eventually(t, func() bool { return len(mail.values()) == 3 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return len(audit1.values())+len(audit2.values()) == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"1", "2", "3"}, mail.values())
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, append(audit1.values(), audit2.values()...))
+	assert.Equal(t, int64(2), b.Messages("users")[2].Offset)
+}
+
+func TestMemoryBus_RedeliversUnacknowledged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewMemoryBus(time.Millisecond)
+	var mu sync.Mutex
+	var seen []string
+	attempts := 0
+	require.NoError(t, b.Subscribe(ctx, "users", "g", func(_ context.Context, msg bus.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, string(msg.Value))
+		if string(msg.Value) == "1" {
+			if attempts++; attempts < 3 {
+				return errors.New("database unavailable")
+			}
+		}
+		return nil
+	}))
+
+	require.NoError(t, b.Publish(ctx, bus.Message{Topic: "users", Value: []byte("1")}, bus.Message{Topic: "users", Value: []byte("2")}))
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"1", "1", "1", "2"}, seen, "a failing message holds back the ones after it")
+}
+
+func TestMemoryBus_PropagatesRequestID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewMemoryBus(time.Millisecond)
+	c := &collector{}
+	require.NoError(t, b.Subscribe(ctx, "users", "g", c.handle))
+
+	require.NoError(t, b.Publish(pkg.WithRequestID(ctx, "req-1"),
+		bus.Message{Topic: "users", Value: []byte("1")},
+		bus.Message{Topic: "users", Value: []byte("2"), Headers: map[string]string{pkg.RequestIDHeader: "req-0"}},
+	))
+	require.NoError(t, b.Publish(ctx, bus.Message{Topic: "users", Value: []byte("3")}))
+
+	require.Eventually(t, func() bool { return len(c.values()) == 3 }, time.Second, time.Millisecond)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Equal(t, []string{"req-1", "req-0", ""}, c.ids)
+	assert.Equal(t, "req-1", c.msgs[0].Headers[pkg.RequestIDHeader])
+}
+
+func TestMemoryBus_StopsWithContextAndClose(t *testing.T) {
+	b := NewMemoryBus(time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &collector{}
+	require.NoError(t, b.Subscribe(ctx, "users", "g", c.handle))
+	cancel()
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, b.Publish(context.Background(), bus.Message{Topic: "users", Value: []byte("1")}))
+	time.Sleep(5 * time.Millisecond)
+	assert.Empty(t, c.values())
+
+	assert.Error(t, b.Subscribe(context.Background(), "users", "", c.handle))
+	require.NoError(t, b.Close())
+	assert.Error(t, b.Publish(context.Background(), bus.Message{Topic: "users"}))
+}
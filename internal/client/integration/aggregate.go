@@ -0,0 +1,344 @@
+package integration
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const AggregateProvider = "aggregate"
+
+// ProvenancePrefix prefixes the Extra keys naming the member that supplied a
+// field of a merged user, e.g. "provenance.email" -> "tenant-a".
+const ProvenancePrefix = "provenance."
+
+// identity keys users can be matched on
+const (
+	identityEmail = "email"
+	identityPhone = "phone"
+)
+
+// providerLookup resolves registered instances by id.
+type providerLookup interface {
+	GetUserService(id string) (interfaces.UserService, error)
+}
+
+// providerBinder is implemented by composite services that call other
+// registered instances. They are bound once the factory has built them.
+type providerBinder interface {
+	bindProviders(id string, providers providerLookup) error
+}
+
+// compositeChainKey carries the ids of the composite instances a call passed
+// through, so composites nested into each other cannot recurse forever.
+type compositeChainKey struct{}
+
+// aggregateService fans a page out to several instances concurrently and
+// merges their users. Members are looked up on every call, so replacing or
+// stopping one takes effect immediately.
+type aggregateService struct {
+	id        string
+	cfg       config.AggregateConfig
+	providers providerLookup
+}
+
+func init() {
+	RegisterUserServiceFactory(AggregateProvider, func(_ config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		if inst.Aggregate == nil || len(inst.Aggregate.Members) == 0 {
+			return nil, fmt.Errorf("aggregate provider needs at least one member")
+		}
+		cfg := *inst.Aggregate
+		if len(cfg.IdentityKeys) == 0 {
+			cfg.IdentityKeys = []string{identityEmail, identityPhone}
+		}
+		for _, k := range cfg.IdentityKeys {
+			if k != identityEmail && k != identityPhone {
+				return nil, fmt.Errorf("unknown identity key %q, want email or phone", k)
+			}
+		}
+		if cfg.MinSuccess <= 0 {
+			cfg.MinSuccess = 1
+		}
+		if cfg.MinSuccess > len(cfg.Members) {
+			return nil, fmt.Errorf("min_success %d exceeds the %d members", cfg.MinSuccess, len(cfg.Members))
+		}
+		return &aggregateService{id: inst.ID, cfg: cfg}, nil
+	})
+}
+
+func (a *aggregateService) bindProviders(id string, providers providerLookup) error {
+	if slices.Contains(a.cfg.Members, id) {
+		return fmt.Errorf("aggregate %q cannot be its own member", id)
+	}
+	a.id, a.providers = id, providers
+	return nil
+}
+
+// memberResult is the answer of one member.
+type memberResult struct {
+	member string
+	res    integration.UserListResponseDTO
+	err    error
+}
+
+// GetUsers fetches page from every member and merges the answers. Failing
+// members are logged and skipped as long as MinSuccess members answered.
+func (a *aggregateService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	if a.providers == nil {
+		return integration.UserListResponseDTO{}, fmt.Errorf("aggregate %q is not bound to a provider registry", a.id)
+	}
+	chain, _ := ctx.Value(compositeChainKey{}).([]string)
+	if slices.Contains(chain, a.id) {
+		return integration.UserListResponseDTO{}, fmt.Errorf("provider cycle: %s -> %s", strings.Join(chain, " -> "), a.id)
+	}
+	ctx = context.WithValue(ctx, compositeChainKey{}, append(slices.Clone(chain), a.id))
+
+	results := make([]memberResult, len(a.cfg.Members))
+	var wg sync.WaitGroup
+	for i, member := range a.cfg.Members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = a.callMember(ctx, member, page)
+		}()
+	}
+	wg.Wait()
+
+	var ok []memberResult
+	var errs []error
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.member, r.err))
+			log.WithError(r.err).WithFields(log.Fields{"provider": a.id, "member": r.member}).Warn("aggregate member failed")
+			continue
+		}
+		ok = append(ok, r)
+	}
+	if len(ok) < a.cfg.MinSuccess {
+		desc := fmt.Sprintf("%s: %d of %d members answered: %v", a.id, len(ok), len(a.cfg.Members), errors.Join(errs...))
+		return integration.UserListResponseDTO{}, pkg.NewAppError(pkg.ErrAggregateUnavailable).AddDescription([]byte(desc)).AppendStackLog()
+	}
+
+	res := integration.UserListResponseDTO{
+		Provider: AggregateProvider,
+		Users:    a.merge(ok),
+	}
+	// the aggregate has as many pages as its longest member; Total is an
+	// upper bound as duplicates across members are only merged per page
+	for _, r := range ok {
+		res.Meta.TotalPages = max(res.Meta.TotalPages, r.res.Meta.TotalPages)
+		res.Meta.Total += cmp.Or(r.res.Meta.Total, len(r.res.Users))
+	}
+	// without a page count the aggregate is a single page: members that do not
+	// page answer every page with the same users, so a walk would never end
+	if res.Meta.TotalPages > 0 {
+		res.Meta.Page = page
+	}
+	res.Meta.PerPage = len(res.Users)
+	return res, nil
+}
+
+func (a *aggregateService) callMember(ctx context.Context, member string, page int) memberResult {
+	r := memberResult{member: member}
+	svc, err := a.providers.GetUserService(member)
+	if err != nil {
+		r.err = err
+	} else {
+		if a.cfg.MemberTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(a.cfg.MemberTimeout)*time.Second)
+			defer cancel()
+		}
+		r.res, r.err = svc.GetUsers(ctx, page)
+	}
+
+	result := "ok"
+	if r.err != nil {
+		result = "error"
+	}
+	pkg.CounterAdd("provider_aggregate_member_calls_total", 1, "provider", a.id, "member", member, "result", result)
+	return r
+}
+
+// contribution is one member's copy of a merged user.
+type contribution struct {
+	member string
+	user   integration.UserDTO
+}
+
+// merge groups the users of all members by identity key and resolves each
+// group into one user. Groups keep the order in which they were first seen.
+func (a *aggregateService) merge(results []memberResult) []integration.UserDTO {
+	var groups [][]contribution
+	index := map[string]int{}
+
+	for _, r := range results {
+		for _, u := range r.res.Users {
+			keys := a.identityKeys(u)
+			g := -1
+			for _, k := range keys {
+				if i, ok := index[k]; ok {
+					g = i
+					break
+				}
+			}
+			if g < 0 {
+				g = len(groups)
+				groups = append(groups, nil)
+			}
+			groups[g] = append(groups[g], contribution{member: r.member, user: u})
+			for _, k := range keys {
+				if _, ok := index[k]; !ok {
+					index[k] = g
+				}
+			}
+		}
+	}
+
+	out := make([]integration.UserDTO, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, a.resolve(g))
+	}
+	return out
+}
+
+// identityKeys returns the identity values of u in configured order.
+func (a *aggregateService) identityKeys(u integration.UserDTO) []string {
+	var keys []string
+	for _, k := range a.cfg.IdentityKeys {
+		var v string
+		switch k {
+		case identityEmail:
+			v = normaliseEmail(string(u.Email))
+		case identityPhone:
+			v = normalisePhone(string(u.Phone))
+		}
+		if v != "" {
+			keys = append(keys, k+":"+v)
+		}
+	}
+	return keys
+}
+
+// resolve builds the merged user of a group, taking every field from the
+// member with the highest precedence that has a value for it.
+func (a *aggregateService) resolve(group []contribution) integration.UserDTO {
+	out := integration.UserDTO{Extra: map[string]string{}}
+
+	pick := func(field string, value func(integration.UserDTO) string) (string, string) {
+		for _, member := range a.precedence(field) {
+			for _, c := range group {
+				if c.member != member {
+					continue
+				}
+				if v := value(c.user); v != "" {
+					return v, member
+				}
+			}
+		}
+		return "", ""
+	}
+	set := func(field string, value func(integration.UserDTO) string, assign func(string)) {
+		if v, member := pick(field, value); v != "" {
+			assign(v)
+			out.Extra[ProvenancePrefix+field] = member
+		}
+	}
+
+	set("name", func(u integration.UserDTO) string { return string(u.Name) }, func(v string) { out.Name = user.FullName(v) })
+	set("username", func(u integration.UserDTO) string { return string(u.Username) }, func(v string) { out.Username = user.Username(v) })
+	set("email", func(u integration.UserDTO) string { return string(u.Email) }, func(v string) { out.Email = user.Email(v) })
+	set("phone", func(u integration.UserDTO) string { return string(u.Phone) }, func(v string) { out.Phone = user.Phone(v) })
+	set("website", func(u integration.UserDTO) string { return string(u.Website) }, func(v string) { out.Website = user.Website(v) })
+
+	var extraKeys []string
+	for _, c := range group {
+		for k := range c.user.Extra {
+			if !slices.Contains(extraKeys, k) && !strings.HasPrefix(k, ProvenancePrefix) {
+				extraKeys = append(extraKeys, k)
+			}
+		}
+	}
+	for _, k := range extraKeys {
+		set(k, func(u integration.UserDTO) string { return u.Extra[k] }, func(v string) { out.Extra[k] = v })
+	}
+
+	out.ID = a.mergedID(group)
+	return out
+}
+
+// precedence returns the member order used for field.
+func (a *aggregateService) precedence(field string) []string {
+	if order, ok := a.cfg.Precedence[field]; ok {
+		return appendMissing(order, a.cfg.Members)
+	}
+	if order, ok := a.cfg.Precedence["*"]; ok {
+		return appendMissing(order, a.cfg.Members)
+	}
+	return a.cfg.Members
+}
+
+// mergedID derives a stable id from the group's first identity key, or from
+// the member id of an unmatched user, so the same person keeps the same
+// external id across calls and member outages.
+func (a *aggregateService) mergedID(group []contribution) user.ID {
+	for _, c := range group {
+		if keys := a.identityKeys(c.user); len(keys) > 0 {
+			sum := sha256.Sum256([]byte(keys[0]))
+			return user.ID(hex.EncodeToString(sum[:8]))
+		}
+	}
+	return user.ID(group[0].member + ":" + string(group[0].user.ID))
+}
+
+// appendMissing returns order followed by the members it does not list.
+func appendMissing(order, members []string) []string {
+	out := slices.Clone(order)
+	for _, m := range members {
+		if !slices.Contains(out, m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func normaliseEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// normalisePhone keeps the digits of s, dropping formatting and any extension
+// ("1-770-736-8031 x56442" -> "17707368031").
+func normalisePhone(s string) string {
+	s = strings.ToLower(s)
+	for _, sep := range []string{" x", "ext", "#"} {
+		if i := strings.Index(s, sep); i >= 0 {
+			s = s[:i]
+		}
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,233 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/client/pagination"
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticLookup resolves members from a fixed map.
+type staticLookup map[string]interfaces.UserService
+
+func (l staticLookup) GetUserService(id string) (interfaces.UserService, error) {
+	svc, ok := l[id]
+	if !ok {
+		return nil, pkg.NewAppError(pkg.ErrProviderNotFound).AddDescription([]byte(id)).AppendStackLog()
+	}
+	return svc, nil
+}
+
+func answer(users ...integration.UserDTO) *stubUserService {
+	return &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+		return integration.UserListResponseDTO{Users: users, Meta: integration.MetaInfoDTO{TotalPages: 1}}, nil
+	}}
+}
+
+func failing() *stubUserService {
+	return &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+		return integration.UserListResponseDTO{}, errors.New("down")
+	}}
+}
+
+func newTestAggregate(t *testing.T, cfg config.AggregateConfig, members staticLookup) *aggregateService {
+	t.Helper()
+	raw := config.ProviderInstance{ID: "agg", Type: AggregateProvider, Aggregate: &cfg}.String()
+	svc, err := lookupFactoryOrFail(t)(config.App{}, raw)
+	require.NoError(t, err)
+	a := svc.(*aggregateService)
+	require.NoError(t, a.bindProviders("agg", members))
+	return a
+}
+
+func lookupFactoryOrFail(t *testing.T) UserServiceFactory {
+	t.Helper()
+	f, ok := lookupFactory(AggregateProvider)
+	require.True(t, ok)
+	return f
+}
+
+func TestAggregate_MergesByEmailWithPrecedence(t *testing.T) {
+	a := newTestAggregate(t, config.AggregateConfig{
+		Members:    []string{"a", "b"},
+		Precedence: map[string][]string{"phone": {"b"}},
+	}, staticLookup{
+		"a": answer(integration.UserDTO{ID: "1", Name: "Leanne", Email: "Leanne@Example.com", Phone: "111", Extra: map[string]string{"city": "Gwenborough"}}),
+		"b": answer(
+			integration.UserDTO{ID: "9", Name: "Leanne Graham", Email: "leanne@example.com ", Phone: "222", Website: "hildegard.org"},
+			integration.UserDTO{ID: "10", Name: "Ervin"},
+		),
+	})
+
+	res, err := a.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, res.Users, 2)
+
+	merged := res.Users[0]
+	assert.Equal(t, "Leanne", string(merged.Name))
+	assert.Equal(t, "222", string(merged.Phone))
+	assert.Equal(t, "hildegard.org", string(merged.Website))
+	assert.Equal(t, "Gwenborough", merged.Extra["city"])
+	assert.Equal(t, "a", merged.Extra[ProvenancePrefix+"name"])
+	assert.Equal(t, "b", merged.Extra[ProvenancePrefix+"phone"])
+	assert.Equal(t, "b", merged.Extra[ProvenancePrefix+"website"])
+	assert.Equal(t, "a", merged.Extra[ProvenancePrefix+"city"])
+
+	// users without an identity key are kept apart and scoped to their member
+	assert.Equal(t, "b:10", string(res.Users[1].ID))
+	assert.Equal(t, AggregateProvider, res.Provider)
+	assert.Equal(t, 1, res.Meta.TotalPages)
+}
+
+func TestAggregate_MergedIDIsStable(t *testing.T) {
+	members := staticLookup{
+		"a": answer(integration.UserDTO{ID: "1", Email: "x@example.com"}),
+		"b": answer(integration.UserDTO{ID: "2", Email: "X@example.com"}),
+	}
+	a := newTestAggregate(t, config.AggregateConfig{Members: []string{"a", "b"}}, members)
+	first, err := a.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+
+	members["a"] = failing()
+	second, err := a.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, first.Users, 1)
+	require.Len(t, second.Users, 1)
+	assert.Equal(t, first.Users[0].ID, second.Users[0].ID)
+}
+
+func TestAggregate_MatchesNormalisedPhone(t *testing.T) {
+	a := newTestAggregate(t, config.AggregateConfig{Members: []string{"a", "b"}, IdentityKeys: []string{"phone"}}, staticLookup{
+		"a": answer(integration.UserDTO{ID: "1", Phone: "1-770-736-8031 x56442"}),
+		"b": answer(integration.UserDTO{ID: "2", Phone: "(177) 073-68031"}),
+	})
+
+	res, err := a.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Len(t, res.Users, 1)
+}
+
+func TestAggregate_PagesLikeItsLongestMember(t *testing.T) {
+	unpaged := &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+		return integration.UserListResponseDTO{Users: []integration.UserDTO{{ID: "u", Email: "u@example.com"}}}, nil
+	}}
+	paged := &stubUserService{fn: func(_ context.Context, page int) (integration.UserListResponseDTO, error) {
+		id := strconv.Itoa(page)
+		return integration.UserListResponseDTO{
+			Users: []integration.UserDTO{{ID: user.ID(id), Email: user.Email(id + "@example.com")}},
+			Meta:  integration.MetaInfoDTO{Page: page, TotalPages: 3},
+		}, nil
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a := newTestAggregate(t, config.AggregateConfig{Members: []string{"a", "b"}}, staticLookup{"a": unpaged, "b": unpaged})
+	users, err := pagination.Collect(ctx, a, pagination.Options{})
+	require.NoError(t, err, "an aggregate of unpaged members is a single page")
+	assert.Len(t, users, 1)
+
+	a = newTestAggregate(t, config.AggregateConfig{Members: []string{"a", "b"}}, staticLookup{"a": unpaged, "b": paged})
+	res, err := a.GetUsers(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, integration.MetaInfoDTO{Page: 1, TotalPages: 3, Total: 2, PerPage: 2}, res.Meta)
+	pages := 0
+	for _, err := range pagination.Pages(ctx, a, pagination.Options{}) {
+		require.NoError(t, err)
+		pages++
+	}
+	assert.Equal(t, 3, pages)
+}
+
+func TestAggregate_PartialFailure(t *testing.T) {
+	members := staticLookup{
+		"a": answer(integration.UserDTO{ID: "1", Email: "x@example.com"}),
+		"b": failing(),
+	}
+
+	a := newTestAggregate(t, config.AggregateConfig{Members: []string{"a", "b", "missing"}}, members)
+	res, err := a.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Len(t, res.Users, 1)
+
+	strict := newTestAggregate(t, config.AggregateConfig{Members: []string{"a", "b"}, MinSuccess: 2}, members)
+	_, err = strict.GetUsers(context.Background(), 1)
+	var appErr *pkg.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 502, appErr.ExternalCode())
+}
+
+func TestAggregate_MemberTimeout(t *testing.T) {
+	slow := &stubUserService{fn: func(ctx context.Context, _ int) (integration.UserListResponseDTO, error) {
+		select {
+		case <-ctx.Done():
+			return integration.UserListResponseDTO{}, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return integration.UserListResponseDTO{}, nil
+		}
+	}}
+	a := newTestAggregate(t, config.AggregateConfig{Members: []string{"fast", "slow"}, MemberTimeout: 1}, staticLookup{
+		"fast": answer(integration.UserDTO{ID: "1", Email: "x@example.com"}),
+		"slow": slow,
+	})
+
+	start := time.Now()
+	res, err := a.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Len(t, res.Users, 1)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestAggregate_Registry(t *testing.T) {
+	svc := NewUserProviderService(config.App{})
+	svc.UserServiceMap["m1"] = answer(integration.UserDTO{ID: "1", Email: "x@example.com"})
+
+	self := config.ProviderInstance{ID: "agg", Type: AggregateProvider, Aggregate: &config.AggregateConfig{Members: []string{"agg"}}}
+	assert.Error(t, svc.RegisterNewProvider("agg", AggregateProvider, self.String()))
+
+	inst := config.ProviderInstance{ID: "agg", Type: AggregateProvider, Aggregate: &config.AggregateConfig{Members: []string{"m1"}}}
+	require.NoError(t, svc.RegisterNewProvider("agg", AggregateProvider, inst.String()))
+
+	p, err := svc.GetUserService("agg")
+	require.NoError(t, err)
+	res, err := p.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "agg", res.Instance)
+	assert.Len(t, res.Users, 1)
+
+	// nested aggregates referring to each other are cut off
+	loop := config.ProviderInstance{ID: "loop", Type: AggregateProvider, Aggregate: &config.AggregateConfig{Members: []string{"agg"}}}
+	require.NoError(t, svc.RegisterNewProvider("loop", AggregateProvider, loop.String()))
+	back := config.ProviderInstance{ID: "agg", Type: AggregateProvider, Aggregate: &config.AggregateConfig{Members: []string{"loop"}}}
+	require.NoError(t, svc.ReplaceProvider("agg", AggregateProvider, back.String()))
+	_, err = p.GetUsers(context.Background(), 1)
+	assert.NoError(t, err, "the replaced instance keeps serving")
+	agg, _ := svc.GetUserService("agg")
+	_, err = agg.GetUsers(context.Background(), 1)
+	assert.Error(t, err)
+}
+
+func TestAggregate_InvalidConfig(t *testing.T) {
+	f := lookupFactoryOrFail(t)
+	for _, cfg := range []config.AggregateConfig{
+		{},
+		{Members: []string{"a"}, IdentityKeys: []string{"ssn"}},
+		{Members: []string{"a"}, MinSuccess: 2},
+	} {
+		raw := config.ProviderInstance{ID: "agg", Type: AggregateProvider, Aggregate: &cfg}.String()
+		_, err := f(config.App{}, raw)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,62 @@
+package integration
+
+import (
+	"net/http"
+	"time"
+
+	"__MODULE__/internal/client/internal/oauth"
+	"__MODULE__/internal/config"
+)
+
+// authSettings is the resolved OAuth2 configuration of a provider instance.
+// Authentication is enabled once a token URL is known.
+type authSettings struct {
+	tokenURL        string
+	grantType       string
+	clientID        string
+	clientSecret    string
+	scopes          []string
+	refreshInterval time.Duration
+}
+
+func (a authSettings) withInstance(inst config.ProviderInstance) authSettings {
+	if inst.Credentials.ClientID != "" {
+		a.clientID = inst.Credentials.ClientID
+	}
+	if inst.Credentials.ClientSecret != "" {
+		a.clientSecret = inst.Credentials.ClientSecret
+	}
+	if inst.Auth == nil {
+		return a
+	}
+	if inst.Auth.TokenURL != "" {
+		a.tokenURL = inst.Auth.TokenURL
+	}
+	if inst.Auth.GrantType != "" {
+		a.grantType = inst.Auth.GrantType
+	}
+	if len(inst.Auth.Scopes) > 0 {
+		a.scopes = inst.Auth.Scopes
+	}
+	if inst.Auth.RefreshInterval > 0 {
+		a.refreshInterval = time.Duration(inst.Auth.RefreshInterval) * time.Second
+	}
+	return a
+}
+
+func (a authSettings) enabled() bool {
+	return a.tokenURL != ""
+}
+
+func (a authSettings) tokenSource(provider string, client *http.Client) *oauth.TokenSource {
+	return oauth.NewTokenSource(oauth.Config{
+		Provider:        provider,
+		TokenURL:        a.tokenURL,
+		ClientID:        a.clientID,
+		ClientSecret:    a.clientSecret,
+		GrantType:       a.grantType,
+		Scopes:          a.scopes,
+		RefreshInterval: a.refreshInterval,
+		HTTPClient:      client,
+	})
+}
@@ -0,0 +1,224 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const FailoverProvider = "failover"
+
+// maxShadowInFlight bounds the background shadow calls of one instance;
+// calls beyond it are skipped rather than queued.
+const maxShadowInFlight = 4
+
+// failoverService serves a page from the first of its members that answers and
+// optionally mirrors the call to a shadow candidate for comparison.
+type failoverService struct {
+	id        string
+	cfg       config.FailoverConfig
+	providers providerLookup
+
+	shadowSlots chan struct{}
+	shadows     sync.WaitGroup // running shadow comparisons, waited on in tests
+}
+
+func init() {
+	RegisterUserServiceFactory(FailoverProvider, func(_ config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		if inst.Failover == nil || inst.Failover.Primary == "" {
+			return nil, fmt.Errorf("failover provider needs a primary")
+		}
+		cfg := *inst.Failover
+		if cfg.ShadowTimeout <= 0 {
+			cfg.ShadowTimeout = 30
+		}
+		return &failoverService{id: inst.ID, cfg: cfg, shadowSlots: make(chan struct{}, maxShadowInFlight)}, nil
+	})
+}
+
+func (f *failoverService) members() []string {
+	return append([]string{f.cfg.Primary}, f.cfg.Secondaries...)
+}
+
+func (f *failoverService) bindProviders(id string, providers providerLookup) error {
+	if slices.Contains(f.members(), id) || f.cfg.Shadow == id {
+		return fmt.Errorf("failover %q cannot be its own member", id)
+	}
+	f.id, f.providers = id, providers
+	return nil
+}
+
+// GetUsers tries the primary and then every secondary in order. The caller's
+// own cancellation is never failed over.
+func (f *failoverService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	if f.providers == nil {
+		return integration.UserListResponseDTO{}, fmt.Errorf("failover %q is not bound to a provider registry", f.id)
+	}
+	chain, _ := ctx.Value(compositeChainKey{}).([]string)
+	if slices.Contains(chain, f.id) {
+		return integration.UserListResponseDTO{}, fmt.Errorf("provider cycle: %s -> %s", strings.Join(chain, " -> "), f.id)
+	}
+	ctx = context.WithValue(ctx, compositeChainKey{}, append(slices.Clone(chain), f.id))
+
+	var errs []error
+	for _, member := range f.members() {
+		res, err := f.call(ctx, member, page, f.cfg.AttemptTimeout)
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		pkg.CounterAdd("provider_failover_calls_total", 1, "provider", f.id, "member", member, "result", result)
+		if err == nil {
+			if member != f.cfg.Primary {
+				log.WithFields(log.Fields{"provider": f.id, "member": member}).Warn("served by failover secondary")
+			}
+			// users are cached under the failover instance, whichever member served them
+			res.Instance = ""
+			f.startShadow(ctx, page, member, res)
+			return res, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", member, err))
+		if ctx.Err() != nil {
+			break
+		}
+		log.WithError(err).WithFields(log.Fields{"provider": f.id, "member": member}).Warn("failover member failed")
+	}
+	return integration.UserListResponseDTO{}, errors.Join(errs...)
+}
+
+func (f *failoverService) call(ctx context.Context, member string, page int, timeout int) (integration.UserListResponseDTO, error) {
+	svc, err := f.providers.GetUserService(member)
+	if err == nil {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+			defer cancel()
+		}
+		return svc.GetUsers(ctx, page)
+	}
+	return integration.UserListResponseDTO{}, err
+}
+
+// startShadow calls the shadow candidate in the background, detached from the
+// caller's cancellation, and compares its answer with served.
+func (f *failoverService) startShadow(ctx context.Context, page int, servedBy string, served integration.UserListResponseDTO) {
+	if f.cfg.Shadow == "" {
+		return
+	}
+	select {
+	case f.shadowSlots <- struct{}{}:
+	default:
+		pkg.CounterAdd("provider_shadow_calls_total", 1, "provider", f.id, "shadow", f.cfg.Shadow, "result", "skipped")
+		return
+	}
+
+	f.shadows.Add(1)
+	go func() {
+		defer f.shadows.Done()
+		defer func() { <-f.shadowSlots }()
+
+		candidate, err := f.call(context.WithoutCancel(ctx), f.cfg.Shadow, page, f.cfg.ShadowTimeout)
+		if err != nil {
+			pkg.CounterAdd("provider_shadow_calls_total", 1, "provider", f.id, "shadow", f.cfg.Shadow, "result", "error")
+			log.WithError(err).WithFields(log.Fields{"provider": f.id, "shadow": f.cfg.Shadow}).Warn("shadow provider failed")
+			return
+		}
+
+		diffs := diffUsers(served.Users, candidate.Users)
+		result := "match"
+		if len(diffs) > 0 {
+			result = "diff"
+		}
+		pkg.CounterAdd("provider_shadow_calls_total", 1, "provider", f.id, "shadow", f.cfg.Shadow, "result", result)
+		for _, d := range diffs {
+			pkg.CounterAdd("provider_shadow_diffs_total", 1, "provider", f.id, "shadow", f.cfg.Shadow, "field", d.field)
+			log.WithFields(log.Fields{
+				"provider":  f.id,
+				"served_by": servedBy,
+				"shadow":    f.cfg.Shadow,
+				"page":      page,
+				"user":      d.user,
+				"field":     d.field,
+				"served":    d.served,
+				"candidate": d.candidate,
+			}).Info("shadow provider differs")
+		}
+	}()
+}
+
+// userDiff is one field of one user on which the shadow candidate disagrees.
+// field is "missing" or "unexpected" when the user is only on one side.
+type userDiff struct {
+	user      string
+	field     string
+	served    string
+	candidate string
+}
+
+// diffUsers compares two pages of users, matched on id and then on email,
+// after normalising the fields so formatting differences do not count.
+func diffUsers(served, candidate []integration.UserDTO) []userDiff {
+	byID := make(map[string]int, len(candidate))
+	byEmail := make(map[string]int, len(candidate))
+	for i, u := range candidate {
+		byID[string(u.ID)] = i
+		if e := normaliseEmail(string(u.Email)); e != "" {
+			byEmail[e] = i
+		}
+	}
+
+	var diffs []userDiff
+	matched := make([]bool, len(candidate))
+	for _, s := range served {
+		i, ok := byID[string(s.ID)]
+		if !ok || matched[i] {
+			i, ok = byEmail[normaliseEmail(string(s.Email))]
+		}
+		if !ok || matched[i] {
+			diffs = append(diffs, userDiff{user: string(s.ID), field: "missing"})
+			continue
+		}
+		matched[i] = true
+
+		a, b := normalisedFields(s), normalisedFields(candidate[i])
+		for _, field := range []string{"name", "username", "email", "phone", "website"} {
+			if a[field] != b[field] {
+				diffs = append(diffs, userDiff{user: string(s.ID), field: field, served: a[field], candidate: b[field]})
+			}
+		}
+	}
+	for i, u := range candidate {
+		if !matched[i] {
+			diffs = append(diffs, userDiff{user: string(u.ID), field: "unexpected"})
+		}
+	}
+	return diffs
+}
+
+// normalisedFields returns the comparable form of each field of u.
+func normalisedFields(u integration.UserDTO) map[string]string {
+	website := strings.ToLower(strings.TrimSpace(string(u.Website)))
+	website = strings.TrimPrefix(strings.TrimPrefix(website, "https://"), "http://")
+	return map[string]string{
+		"name":     strings.Join(strings.Fields(string(u.Name)), " "),
+		"username": strings.ToLower(strings.TrimSpace(string(u.Username))),
+		"email":    normaliseEmail(string(u.Email)),
+		"phone":    normalisePhone(string(u.Phone)),
+		"website":  strings.TrimSuffix(website, "/"),
+	}
+}
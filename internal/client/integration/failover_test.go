@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFailover(t *testing.T, id string, cfg config.FailoverConfig, members staticLookup) *failoverService {
+	t.Helper()
+	f, ok := lookupFactory(FailoverProvider)
+	require.True(t, ok)
+	svc, err := f(config.App{}, config.ProviderInstance{ID: id, Type: FailoverProvider, Failover: &cfg}.String())
+	require.NoError(t, err)
+	fo := svc.(*failoverService)
+	require.NoError(t, fo.bindProviders(id, members))
+	return fo
+}
+
+// counter snapshots a metric and returns how much it grew since, so the
+// assertions hold whatever ran before the test.
+func counter(name string, labels ...string) func() float64 {
+	before := pkg.MetricValue(name, labels...)
+	return func() float64 { return pkg.MetricValue(name, labels...) - before }
+}
+
+func TestFailover_ServesFromPrimary(t *testing.T) {
+	var secondaryCalls int
+	secondary := &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+		secondaryCalls++
+		return integration.UserListResponseDTO{}, nil
+	}}
+	fo := newTestFailover(t, "fo-primary", config.FailoverConfig{Primary: "a", Secondaries: []string{"b"}}, staticLookup{
+		"a": answer(integration.UserDTO{ID: "1"}),
+		"b": secondary,
+	})
+
+	res, err := fo.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(res.Users[0].ID))
+	assert.Zero(t, secondaryCalls)
+}
+
+func TestFailover_FallsBackOnErrorAndTimeout(t *testing.T) {
+	hang := &stubUserService{fn: func(ctx context.Context, _ int) (integration.UserListResponseDTO, error) {
+		<-ctx.Done()
+		return integration.UserListResponseDTO{}, ctx.Err()
+	}}
+	fo := newTestFailover(t, "fo-fallback", config.FailoverConfig{Primary: "a", Secondaries: []string{"b", "c"}, AttemptTimeout: 1}, staticLookup{
+		"a": failing(),
+		"b": hang,
+		"c": answer(integration.UserDTO{ID: "3"}),
+	})
+
+	bFailed := counter("provider_failover_calls_total", "provider", "fo-fallback", "member", "b", "result", "error")
+	cServed := counter("provider_failover_calls_total", "provider", "fo-fallback", "member", "c", "result", "ok")
+
+	res, err := fo.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(res.Users[0].ID))
+	assert.Equal(t, 1.0, bFailed())
+	assert.Equal(t, 1.0, cServed())
+}
+
+func TestFailover_AllFail(t *testing.T) {
+	fo := newTestFailover(t, "fo-down", config.FailoverConfig{Primary: "a", Secondaries: []string{"missing"}}, staticLookup{"a": failing()})
+
+	_, err := fo.GetUsers(context.Background(), 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a: down")
+	assert.Contains(t, err.Error(), "missing")
+}
+
+func TestFailover_DoesNotFailOverCallerCancellation(t *testing.T) {
+	var secondaryCalls int
+	fo := newTestFailover(t, "fo-cancel", config.FailoverConfig{Primary: "a", Secondaries: []string{"b"}}, staticLookup{
+		"a": &stubUserService{fn: func(ctx context.Context, _ int) (integration.UserListResponseDTO, error) {
+			<-ctx.Done()
+			return integration.UserListResponseDTO{}, ctx.Err()
+		}},
+		"b": &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+			secondaryCalls++
+			return integration.UserListResponseDTO{}, nil
+		}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := fo.GetUsers(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, secondaryCalls)
+}
+
+func TestFailover_ShadowDiffs(t *testing.T) {
+	fo := newTestFailover(t, "fo-shadow", config.FailoverConfig{Primary: "a", Shadow: "candidate"}, staticLookup{
+		"a": answer(
+			integration.UserDTO{ID: "1", Name: "Leanne  Graham", Email: "Sincere@april.biz", Phone: "1-770-736-8031", Website: "hildegard.org"},
+			integration.UserDTO{ID: "2", Name: "Ervin"},
+		),
+		"candidate": answer(
+			integration.UserDTO{ID: "1", Name: "Leanne Graham", Email: "sincere@april.biz", Phone: "(177) 073-68031", Website: "https://hildegard.org/", Username: "Bret"},
+			integration.UserDTO{ID: "3", Name: "Clementine"},
+		),
+	})
+
+	diffs := func(field string) func() float64 {
+		return counter("provider_shadow_diffs_total", "provider", "fo-shadow", "shadow", "candidate", "field", field)
+	}
+	calls := counter("provider_shadow_calls_total", "provider", "fo-shadow", "shadow", "candidate", "result", "diff")
+	username, missing, unexpected, phone, website := diffs("username"), diffs("missing"), diffs("unexpected"), diffs("phone"), diffs("website")
+
+	res, err := fo.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Len(t, res.Users, 2, "the shadow answer is never served")
+	fo.shadows.Wait()
+
+	assert.Equal(t, 1.0, calls())
+	assert.Equal(t, 1.0, username())
+	assert.Equal(t, 1.0, missing())
+	assert.Equal(t, 1.0, unexpected())
+	assert.Zero(t, phone())
+	assert.Zero(t, website())
+}
+
+func TestFailover_ShadowOutlivesCaller(t *testing.T) {
+	fo := newTestFailover(t, "fo-shadow-ctx", config.FailoverConfig{Primary: "a", Shadow: "candidate"}, staticLookup{
+		"a": answer(integration.UserDTO{ID: "1"}),
+		"candidate": &stubUserService{fn: func(ctx context.Context, _ int) (integration.UserListResponseDTO, error) {
+			time.Sleep(20 * time.Millisecond)
+			if err := ctx.Err(); err != nil {
+				return integration.UserListResponseDTO{}, err
+			}
+			return integration.UserListResponseDTO{Users: []integration.UserDTO{{ID: "1"}}}, nil
+		}},
+	})
+
+	matches := counter("provider_shadow_calls_total", "provider", "fo-shadow-ctx", "shadow", "candidate", "result", "match")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := fo.GetUsers(ctx, 1)
+	require.NoError(t, err)
+	cancel()
+	fo.shadows.Wait()
+
+	assert.Equal(t, 1.0, matches())
+}
+
+func TestFailover_InvalidConfig(t *testing.T) {
+	svc := NewUserProviderService(config.App{})
+	noPrimary := config.ProviderInstance{ID: "fo", Type: FailoverProvider, Failover: &config.FailoverConfig{}}
+	assert.Error(t, svc.RegisterNewProvider("fo", FailoverProvider, noPrimary.String()))
+
+	self := config.ProviderInstance{ID: "fo", Type: FailoverProvider, Failover: &config.FailoverConfig{Primary: "a", Shadow: "fo"}}
+	assert.Error(t, svc.RegisterNewProvider("fo", FailoverProvider, self.String()))
+}
@@ -0,0 +1,303 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const FileProvider = "file"
+
+// file formats of the file provider
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// fileService serves users read from local files, reloading them when the
+// files change.
+type fileService struct {
+	id     string
+	cfg    config.FileConfig
+	fields userMapping
+	users  jsonPath
+
+	mu       sync.RWMutex
+	snapshot []integration.UserDTO
+	stamp    string // names, sizes and modification times of the loaded files
+
+	stop      chan struct{}
+	watchDone chan struct{}
+	closeOnce sync.Once
+}
+
+func init() {
+	RegisterUserServiceFactory(FileProvider, func(_ config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		f, err := newFileService(inst)
+		if err != nil {
+			return nil, fmt.Errorf("file provider %q: %w", inst.ID, err)
+		}
+		if _, err := f.reload(); err != nil {
+			return nil, fmt.Errorf("file provider %q: %w", inst.ID, err)
+		}
+		go f.watch()
+		return f, nil
+	})
+}
+
+func newFileService(inst config.ProviderInstance) (*fileService, error) {
+	if inst.File == nil || inst.File.Path == "" {
+		return nil, fmt.Errorf("missing file path")
+	}
+	cfg := *inst.File
+	if _, err := filepath.Match(cfg.Path, ""); err != nil {
+		return nil, fmt.Errorf("path %q: %w", cfg.Path, err)
+	}
+	if cfg.Format != "" && !slices.Contains([]string{formatCSV, formatJSON, formatNDJSON}, cfg.Format) {
+		return nil, fmt.Errorf("unknown format %q", cfg.Format)
+	}
+	if cfg.Delimiter == "" {
+		cfg.Delimiter = ","
+	}
+	if utf8.RuneCountInString(cfg.Delimiter) != 1 {
+		return nil, fmt.Errorf("delimiter must be a single character")
+	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = 50
+	}
+	if cfg.WatchInterval == 0 {
+		cfg.WatchInterval = 5
+	}
+	if len(cfg.Fields) == 0 {
+		cfg.Fields = make(map[string]string, len(userFields))
+		for _, name := range userFields {
+			cfg.Fields[name] = "$['" + name + "']"
+		}
+	}
+
+	f := &fileService{id: inst.ID, cfg: cfg, stop: make(chan struct{}), watchDone: make(chan struct{})}
+	var err error
+	if f.fields, err = compileUserMapping(cfg.Fields); err != nil {
+		return nil, err
+	}
+	if f.users, err = compileJSONPath(cfg.Users); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *fileService) GetUsers(_ context.Context, page int) (integration.UserListResponseDTO, error) {
+	page = max(page, 1)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	total := len(f.snapshot)
+	res := integration.UserListResponseDTO{
+		Provider: FileProvider,
+		Users:    []integration.UserDTO{},
+		Meta: integration.MetaInfoDTO{
+			Page:       page,
+			PerPage:    f.cfg.PageSize,
+			Total:      total,
+			TotalPages: (total + f.cfg.PageSize - 1) / f.cfg.PageSize,
+		},
+	}
+	if start := (page - 1) * f.cfg.PageSize; start < total {
+		res.Users = slices.Clone(f.snapshot[start:min(start+f.cfg.PageSize, total)])
+	}
+	return res, nil
+}
+
+// Close stops watching the files.
+func (f *fileService) Close() error {
+	f.closeOnce.Do(func() { close(f.stop) })
+	<-f.watchDone
+	return nil
+}
+
+func (f *fileService) watch() {
+	defer close(f.watchDone)
+	if f.cfg.WatchInterval < 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(f.cfg.WatchInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			if _, err := f.reload(); err != nil {
+				log.WithError(err).WithField("provider", f.id).Warn("reload user files, keeping the previous users")
+			}
+		}
+	}
+}
+
+// reload reads the files again if any of them was added, removed or changed
+// since the last load. A file that fails to parse leaves the previous users
+// in place.
+func (f *fileService) reload() (bool, error) {
+	paths, err := filepath.Glob(f.cfg.Path)
+	if err != nil {
+		return false, err
+	}
+	slices.Sort(paths)
+
+	var stamp strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(&stamp, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	f.mu.RLock()
+	unchanged := f.stamp == stamp.String() && f.snapshot != nil
+	f.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	users := []integration.UserDTO{}
+	for _, path := range paths {
+		records, err := f.readFile(path)
+		if err != nil {
+			pkg.CounterAdd("provider_file_reloads_total", 1, "provider", f.id, "result", "error")
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, r := range records {
+			users = append(users, f.fields.user(r))
+		}
+	}
+	if len(paths) == 0 {
+		log.WithFields(log.Fields{"provider": f.id, "path": f.cfg.Path}).Warn("no user files match")
+	}
+
+	f.mu.Lock()
+	f.snapshot, f.stamp = users, stamp.String()
+	f.mu.Unlock()
+	pkg.CounterAdd("provider_file_reloads_total", 1, "provider", f.id, "result", "ok")
+	pkg.GaugeSet("provider_file_users", float64(len(users)), "provider", f.id)
+	log.WithFields(log.Fields{"provider": f.id, "files": len(paths), "users": len(users)}).Info("loaded user files")
+	return true, nil
+}
+
+func (f *fileService) readFile(path string) ([]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	format := f.cfg.Format
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = formatCSV
+		case ".json":
+			format = formatJSON
+		case ".ndjson", ".jsonl":
+			format = formatNDJSON
+		default:
+			return nil, fmt.Errorf("cannot tell the format from the extension, set format")
+		}
+	}
+
+	switch format {
+	case formatCSV:
+		return f.readCSV(data)
+	case formatNDJSON:
+		return readNDJSON(data)
+	default:
+		doc, err := decodeJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		return f.users.records(doc), nil
+	}
+}
+
+// readCSV returns every row as an object keyed by the header row.
+func (f *fileService) readCSV(data []byte) ([]any, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma, _ = utf8.DecodeRuneInString(f.cfg.Delimiter)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rows []any
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(header))
+		for i, col := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(col)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func readNDJSON(data []byte) ([]any, error) {
+	var records []any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		v, err := decodeJSON(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, v)
+	}
+	return records, scanner.Err()
+}
+
+// decodeJSON keeps numbers as json.Number so ids are not rendered as floats.
+func decodeJSON(data []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/client/pagination"
+	"__MODULE__/internal/config"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func registerFile(t *testing.T, id string, cfg config.FileConfig) *fileService {
+	t.Helper()
+	svc := NewUserProviderService(config.App{})
+	inst := config.ProviderInstance{ID: id, Type: FileProvider, File: &cfg}
+	require.NoError(t, svc.RegisterInstances(config.ProviderInstances{inst}))
+	t.Cleanup(func() { _ = svc.StopUserService(id) })
+	return svc.UserServiceMap[id].(*resilientService).next.(*fileService)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestFile_CSVWithMappingAndPages(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "users.csv"), "\ufeffuser_id;first;last;mail;team\n1;Leanne;Graham;Sincere@april.biz;core\n2;Ervin;Howell;Shanna@melissa.tv;\n3;Clementine;Bauch;Nathan@yesenia.net;ops\n")
+
+	f := registerFile(t, "file-csv", config.FileConfig{
+		Path:          filepath.Join(dir, "users.csv"),
+		Delimiter:     ";",
+		PageSize:      2,
+		WatchInterval: -1,
+		Fields:        map[string]string{"id": "user_id", "name": "{first} {last}", "email": "mail", "team": "team"},
+	})
+
+	res, err := f.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Meta.TotalPages)
+	assert.Equal(t, 3, res.Meta.Total)
+	require.Len(t, res.Users, 2)
+	assert.Equal(t, "1", string(res.Users[0].ID))
+	assert.Equal(t, "Leanne Graham", string(res.Users[0].Name))
+	assert.Equal(t, "core", res.Users[0].Extra["team"])
+	assert.Nil(t, res.Users[1].Extra)
+
+	users, err := pagination.Collect(context.Background(), f, pagination.Options{})
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+
+	res, err = f.GetUsers(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Empty(t, res.Users)
+}
+
+func TestFile_GlobAcrossFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"users":[{"id":1,"name":"Leanne Graham","email":"Sincere@april.biz"}]}`)
+	writeFile(t, filepath.Join(dir, "b.ndjson"), "{\"id\":2,\"name\":\"Ervin Howell\"}\n\n{\"id\":3,\"name\":\"Clementine Bauch\"}\n")
+	writeFile(t, filepath.Join(dir, "c.csv"), "id,name,username\n4,Patricia Lebsack,Karianne\n")
+
+	// the json file nests its users, which the other formats ignore
+	f := registerFile(t, "file-glob", config.FileConfig{Path: filepath.Join(dir, "*"), Users: "$.users", WatchInterval: -1})
+
+	users, err := pagination.Collect(context.Background(), f, pagination.Options{})
+	require.NoError(t, err)
+	require.Len(t, users, 4)
+	assert.Equal(t, "1", string(users[0].ID))
+	assert.Equal(t, "Clementine Bauch", string(users[2].Name))
+	assert.Equal(t, "Karianne", string(users[3].Username))
+}
+
+func TestFile_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.json")
+	writeFile(t, path, `[{"id":1}]`)
+	f := registerFile(t, "file-reload", config.FileConfig{Path: filepath.Join(dir, "*.json"), WatchInterval: -1})
+
+	changed, err := f.reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	writeFile(t, path, `[{"id":1},{"id":2}]`)
+	changed, err = f.reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	res, _ := f.GetUsers(context.Background(), 1)
+	assert.Len(t, res.Users, 2)
+
+	// a broken edit keeps the last good users
+	writeFile(t, path, `[{"id":1},`)
+	_, err = f.reload()
+	assert.Error(t, err)
+	res, _ = f.GetUsers(context.Background(), 1)
+	assert.Len(t, res.Users, 2)
+
+	// new files matching the glob are picked up
+	writeFile(t, path, `[{"id":1}]`)
+	writeFile(t, filepath.Join(dir, "more.json"), `[{"id":3}]`)
+	_, err = f.reload()
+	require.NoError(t, err)
+	res, _ = f.GetUsers(context.Background(), 1)
+	assert.Len(t, res.Users, 2)
+}
+
+func TestFile_WatchPicksUpEdits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.ndjson")
+	writeFile(t, path, "{\"id\":1}\n")
+	f := registerFile(t, "file-watch", config.FileConfig{Path: path, WatchInterval: 1})
+
+	writeFile(t, path, "{\"id\":1}\n{\"id\":2}\n")
+	assert.Eventually(t, func() bool {
+		res, _ := f.GetUsers(context.Background(), 1)
+		return len(res.Users) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestFile_InvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "users.txt"), "id\n1\n")
+	writeFile(t, filepath.Join(dir, "broken.json"), "[")
+
+	svc := NewUserProviderService(config.App{})
+	for _, cfg := range []*config.FileConfig{
+		nil,
+		{Path: "[", WatchInterval: -1},
+		{Path: filepath.Join(dir, "users.txt"), WatchInterval: -1},
+		{Path: filepath.Join(dir, "users.txt"), Format: "xml", WatchInterval: -1},
+		{Path: filepath.Join(dir, "users.txt"), Format: "csv", Delimiter: "||", WatchInterval: -1},
+		{Path: filepath.Join(dir, "users.txt"), Format: "csv", Fields: map[string]string{"name": "name"}, WatchInterval: -1},
+		{Path: filepath.Join(dir, "broken.json"), WatchInterval: -1},
+	} {
+		inst := config.ProviderInstance{ID: "x", Type: FileProvider, File: cfg}
+		assert.Error(t, svc.RegisterInstances(config.ProviderInstances{inst}))
+	}
+}
+
+func TestFile_RejectedAtRuntime(t *testing.T) {
+	svc := NewUserProviderService(config.App{})
+	raw := config.ProviderInstance{ID: "file-runtime", Type: FileProvider, File: &config.FileConfig{Path: "/etc/passwd"}}.String()
+
+	err := svc.RegisterNewProvider("file-runtime", FileProvider, raw)
+	assertAppError(t, pkg.ErrBadRequest, err)
+	require.NoError(t, svc.RegisterNewProvider("file-runtime", JsonPlaceholderProvider, ""))
+	t.Cleanup(func() { _ = svc.StopUserService("file-runtime") })
+	assertAppError(t, pkg.ErrBadRequest, svc.ReplaceProvider("file-runtime", FileProvider, raw))
+}
@@ -0,0 +1,297 @@
+package integration
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/interfaces"
+)
+
+const HTTPJSONProvider = "http-json"
+
+// pagination styles of the http-json provider
+const (
+	pagingNone   = "none"
+	pagingPage   = "page"
+	pagingOffset = "offset"
+	pagingCursor = "cursor"
+)
+
+// httpJSONService is a provider defined purely by configuration, see
+// config.HTTPJSONConfig.
+type httpJSONService struct {
+	settings   httpSettings
+	httpClient *http.Client
+	cfg        config.HTTPJSONConfig
+	apiKey     string
+	token      string
+
+	users      jsonPath
+	fields     userMapping
+	nextCursor jsonPath
+	totalPages jsonPath
+	total      jsonPath
+	firstPage  int
+
+	mu      sync.Mutex
+	cursors map[int]string // cursor requesting page n, learnt while paging
+}
+
+func init() {
+	RegisterUserServiceFactory(HTTPJSONProvider, func(cfg config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		svc, err := newHTTPJSONService(inst)
+		if err != nil {
+			return nil, fmt.Errorf("http-json provider %q: %w", inst.ID, err)
+		}
+		svc.settings = httpJSONSettings(cfg).withInstance(inst)
+		if inst.ID != "" {
+			svc.settings.provider = HTTPJSONProvider + ":" + inst.ID
+		}
+		if svc.httpClient, err = svc.settings.httpClient(); err != nil {
+			return nil, err
+		}
+		return svc, nil
+	})
+	RegisterWebhookParser(HTTPJSONProvider, parseHTTPJSONWebhook)
+}
+
+// parseHTTPJSONWebhook maps the users of a delivery with the instance's
+// field mapping, so webhooks and paging agree on what a user looks like.
+func parseHTTPJSONWebhook(inst config.ProviderInstance, body []byte) ([]integration.WebhookEventDTO, error) {
+	if inst.HTTPJSON == nil {
+		return nil, fmt.Errorf("missing http_json config")
+	}
+	mapping, err := compileUserMapping(inst.HTTPJSON.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return parseWebhookEvents(body, func(raw json.RawMessage) (integration.UserDTO, error) {
+		doc, err := decodeJSON(raw)
+		if err != nil {
+			return integration.UserDTO{}, err
+		}
+		return mapping.user(doc), nil
+	})
+}
+
+// newHTTPJSONService validates and compiles the declarative config.
+func newHTTPJSONService(inst config.ProviderInstance) (*httpJSONService, error) {
+	if inst.HTTPJSON == nil {
+		return nil, fmt.Errorf("missing http_json config")
+	}
+	if inst.BaseURL == "" {
+		return nil, fmt.Errorf("base_url is required")
+	}
+	cfg := *inst.HTTPJSON
+
+	p := &cfg.Pagination
+	if p.Style == "" {
+		p.Style = pagingNone
+	}
+	if !slices.Contains([]string{pagingNone, pagingPage, pagingOffset, pagingCursor}, p.Style) {
+		return nil, fmt.Errorf("unknown pagination style %q", p.Style)
+	}
+	if p.Style == pagingCursor && p.NextCursor == "" {
+		return nil, fmt.Errorf("cursor pagination needs next_cursor")
+	}
+	if p.Style == pagingOffset && p.Size <= 0 {
+		p.Size = 50
+	}
+	if cfg.APIKeyHeader == "" && cfg.APIKeyQuery == "" {
+		cfg.APIKeyHeader = "X-Api-Key"
+	}
+
+	s := &httpJSONService{
+		cfg:     cfg,
+		apiKey:  inst.Credentials.APIKey,
+		token:   inst.Credentials.Token,
+		cursors: map[int]string{},
+	}
+	if p.FirstPage != nil {
+		s.firstPage = *p.FirstPage
+	} else {
+		s.firstPage = 1
+	}
+
+	var err error
+	if s.fields, err = compileUserMapping(cfg.Fields); err != nil {
+		return nil, err
+	}
+	if s.users, err = compileJSONPath(cfg.Users); err != nil {
+		return nil, err
+	}
+	for _, c := range []struct {
+		expr string
+		path *jsonPath
+	}{{p.NextCursor, &s.nextCursor}, {p.TotalPages, &s.totalPages}, {p.Total, &s.total}} {
+		if c.expr == "" {
+			continue
+		}
+		if *c.path, err = compileJSONPath(c.expr); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (h *httpJSONService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	page = max(page, 1)
+	p := h.cfg.Pagination
+	query := url.Values{}
+
+	switch p.Style {
+	case pagingNone:
+		if page > 1 {
+			return integration.UserListResponseDTO{Provider: HTTPJSONProvider, Meta: integration.MetaInfoDTO{Page: page}}, nil
+		}
+	case pagingPage:
+		query.Set(cmp.Or(p.PageParam, "page"), strconv.Itoa(h.firstPage+page-1))
+		if p.Size > 0 {
+			query.Set(cmp.Or(p.SizeParam, "per_page"), strconv.Itoa(p.Size))
+		}
+	case pagingOffset:
+		query.Set(cmp.Or(p.OffsetParam, "offset"), strconv.Itoa((page-1)*p.Size))
+		query.Set(cmp.Or(p.LimitParam, "limit"), strconv.Itoa(p.Size))
+	case pagingCursor:
+		cursor, ok, err := h.cursorFor(ctx, page)
+		if err != nil {
+			return integration.UserListResponseDTO{}, err
+		}
+		if !ok {
+			// the previous page was the last one
+			return integration.UserListResponseDTO{Provider: HTTPJSONProvider, Meta: integration.MetaInfoDTO{Page: page}}, nil
+		}
+		if cursor != "" {
+			query.Set(cmp.Or(p.CursorParam, "cursor"), cursor)
+		}
+		if p.Size > 0 {
+			query.Set(cmp.Or(p.SizeParam, "limit"), strconv.Itoa(p.Size))
+		}
+	}
+
+	doc, body, err := h.fetch(ctx, query)
+	if err != nil {
+		return integration.UserListResponseDTO{Provider: HTTPJSONProvider, Raw: body}, err
+	}
+
+	res := integration.UserListResponseDTO{Provider: HTTPJSONProvider, Raw: body}
+	for _, raw := range h.users.records(doc) {
+		res.Users = append(res.Users, h.fields.user(raw))
+	}
+	if p.Style == pagingNone {
+		return res, nil
+	}
+
+	res.Meta = integration.MetaInfoDTO{Page: page, PerPage: len(res.Users)}
+	if h.total != nil {
+		v, _ := h.total.first(doc)
+		res.Meta.Total, _ = strconv.Atoi(stringify(v))
+	}
+	if h.totalPages != nil {
+		v, _ := h.totalPages.first(doc)
+		res.Meta.TotalPages, _ = strconv.Atoi(stringify(v))
+	} else if res.Meta.Total > 0 && p.Size > 0 {
+		res.Meta.TotalPages = (res.Meta.Total + p.Size - 1) / p.Size
+	}
+	if p.Style == pagingCursor {
+		next, _ := h.nextCursor.first(doc)
+		h.mu.Lock()
+		if page == 1 {
+			// a new walk, cursors of the last one may have expired
+			clear(h.cursors)
+		}
+		h.cursors[page+1] = stringify(next)
+		h.mu.Unlock()
+	}
+	return res, nil
+}
+
+// cursorFor returns the cursor requesting page, walking forward from the last
+// page whose cursor is known. ok is false past the last page.
+func (h *httpJSONService) cursorFor(ctx context.Context, page int) (string, bool, error) {
+	if page == 1 {
+		return "", true, nil
+	}
+	for {
+		h.mu.Lock()
+		cursor, known := h.cursors[page]
+		from := page - 1
+		for from > 1 {
+			if _, ok := h.cursors[from]; ok {
+				break
+			}
+			from--
+		}
+		h.mu.Unlock()
+
+		if known {
+			return cursor, cursor != "", nil
+		}
+		// fetching the closest reachable page records the cursor of the next one
+		res, err := h.GetUsers(ctx, from)
+		if err != nil {
+			return "", false, err
+		}
+		if len(res.Users) == 0 {
+			return "", false, nil
+		}
+	}
+}
+
+func (h *httpJSONService) fetch(ctx context.Context, query url.Values) (any, []byte, error) {
+	for k, v := range h.cfg.Query {
+		query.Set(k, v)
+	}
+	if h.apiKey != "" && h.cfg.APIKeyQuery != "" {
+		query.Set(h.cfg.APIKeyQuery, h.apiKey)
+	}
+	reqURL := strings.TrimSuffix(h.settings.baseURL, "/") + h.cfg.Path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	resp, body, err := h.settings.do(ctx, h.httpClient, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		for _, k := range slices.Sorted(maps.Keys(h.cfg.Headers)) {
+			req.Header.Set(k, h.cfg.Headers[k])
+		}
+		if h.apiKey != "" && h.cfg.APIKeyHeader != "" {
+			req.Header.Set(h.cfg.APIKeyHeader, h.apiKey)
+		}
+		if h.token != "" {
+			req.Header.Set("Authorization", "Bearer "+h.token)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, body, fmt.Errorf("%s: status %d", h.settings.provider, resp.StatusCode)
+	}
+
+	doc, err := decodeJSON(body)
+	if err != nil {
+		return nil, body, fmt.Errorf("%s: decode response: %w", h.settings.provider, err)
+	}
+	return doc, body, nil
+}
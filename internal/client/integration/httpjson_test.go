@@ -0,0 +1,242 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"__MODULE__/internal/client/pagination"
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/interfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(`{"data":[{"id":1,"tags":["a","b"]},{"id":2,"tags":["c"]}],"first name":"Ada","meta":{"next":null}}`), &doc))
+
+	cases := map[string][]any{
+		"$.data[*].id":     {1.0, 2.0},
+		"data[1].id":       {2.0},
+		"$.data[-1].id":    {2.0},
+		"$['first name']":  {"Ada"},
+		"$.data[0].tags.*": {"a", "b"},
+		"$.data[5].id":     nil,
+		"$.meta.next":      {nil},
+		"$.missing.deeper": nil,
+	}
+	for expr, want := range cases {
+		p, err := compileJSONPath(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, p.eval(doc), expr)
+	}
+
+	for _, bad := range []string{"$.data[", "$.data[x]", "$..id"} {
+		_, err := compileJSONPath(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFieldExprTemplate(t *testing.T) {
+	f, err := compileFieldExpr("{$.first_name} {$.last_name}")
+	require.NoError(t, err)
+	assert.Equal(t, "George Bluth", f.eval(map[string]any{"first_name": "George", "last_name": "Bluth"}))
+	assert.Equal(t, "George", f.eval(map[string]any{"first_name": "George"}))
+
+	_, err = compileFieldExpr("{$.first_name")
+	assert.Error(t, err)
+}
+
+func newHTTPJSON(t *testing.T, id, baseURL string, cfg config.HTTPJSONConfig, creds ...config.ProviderCredentials) interfaces.UserService {
+	t.Helper()
+	inst := config.ProviderInstance{ID: id, Type: HTTPJSONProvider, BaseURL: baseURL, HTTPJSON: &cfg}
+	if len(creds) > 0 {
+		inst.Credentials = creds[0]
+	}
+	svc := NewUserProviderService(config.App{})
+	require.NoError(t, svc.RegisterNewProvider(id, HTTPJSONProvider, inst.String()))
+	p, err := svc.GetUserService(id)
+	require.NoError(t, err)
+	return p
+}
+
+func TestHTTPJSON_PagePaginationReqresShape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/users", r.URL.Path)
+		assert.Equal(t, "k", r.Header.Get("x-api-key"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `{"page":%d,"per_page":1,"total":2,"total_pages":2,"data":[{"id":%d,"email":"u%d@reqres.in","first_name":"U","last_name":"%d","avatar":"a%d.jpg"}]}`, page, page, page, page, page)
+	}))
+	defer srv.Close()
+
+	p := newHTTPJSON(t, "hj-page", srv.URL, config.HTTPJSONConfig{
+		Path:       "/api/users",
+		Users:      "$.data",
+		Pagination: config.HTTPJSONPagination{Style: "page", TotalPages: "$.total_pages", Total: "$.total"},
+		Fields: map[string]string{
+			"id":     "$.id",
+			"name":   "{$.first_name} {$.last_name}",
+			"email":  "$.email",
+			"avatar": "$.avatar",
+		},
+	}, config.ProviderCredentials{APIKey: "k"})
+
+	users, err := pagination.Collect(context.Background(), p, pagination.Options{})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "2", string(users[1].ID))
+	assert.Equal(t, "U 2", string(users[1].Name))
+	assert.Equal(t, "a2.jpg", users[1].Extra["avatar"])
+}
+
+func TestHTTPJSON_OffsetPagination(t *testing.T) {
+	all := []string{"a", "b", "c", "d", "e"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("take"))
+		items := []map[string]string{}
+		for _, id := range all[min(offset, len(all)):min(offset+limit, len(all))] {
+			items = append(items, map[string]string{"key": id, "contact": id + "@example.com"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": items, "count": len(all)})
+	}))
+	defer srv.Close()
+
+	p := newHTTPJSON(t, "hj-offset", srv.URL, config.HTTPJSONConfig{
+		Users:      "$.items[*]",
+		Pagination: config.HTTPJSONPagination{Style: "offset", OffsetParam: "skip", LimitParam: "take", Size: 2, Total: "$.count"},
+		Fields:     map[string]string{"id": "key", "email": "contact"},
+	})
+
+	res, err := p.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Meta.TotalPages)
+
+	users, err := pagination.Collect(context.Background(), p, pagination.Options{})
+	require.NoError(t, err)
+	require.Len(t, users, 5)
+	assert.Equal(t, "e@example.com", string(users[4].Email))
+}
+
+func TestHTTPJSON_CursorPagination(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"results":[{"uid":"1"},{"uid":"2"}],"next":"c2"}`,
+		"c2": `{"results":[{"uid":"3"}],"next":"c3"}`,
+		"c3": `{"results":[{"uid":"4"}],"next":null}`,
+	}
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, pages[r.URL.Query().Get("after")])
+	}))
+	defer srv.Close()
+
+	p := newHTTPJSON(t, "hj-cursor", srv.URL, config.HTTPJSONConfig{
+		Users:      "$.results",
+		Pagination: config.HTTPJSONPagination{Style: "cursor", CursorParam: "after", NextCursor: "$.next"},
+		Fields:     map[string]string{"id": "$.uid"},
+	})
+
+	// jumping to page 3 walks the cursors of the pages before it
+	res, err := p.GetUsers(context.Background(), 3)
+	require.NoError(t, err)
+	require.Len(t, res.Users, 1)
+	assert.Equal(t, "4", string(res.Users[0].ID))
+	assert.Equal(t, 3, calls)
+
+	res, err = p.GetUsers(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Empty(t, res.Users)
+
+	users, err := pagination.Collect(context.Background(), p, pagination.Options{})
+	require.NoError(t, err)
+	assert.Len(t, users, 4)
+}
+
+func TestHTTPJSON_CursorsAreForgottenOnANewWalk(t *testing.T) {
+	// the upstream hands out cursors valid for one walk only
+	walk := "a"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprintf(w, `{"results":[{"uid":"1"}],"next":"%s2"}`, walk)
+		case walk + "2":
+			fmt.Fprintf(w, `{"results":[{"uid":"2"}],"next":"%s3"}`, walk)
+		case walk + "3":
+			fmt.Fprint(w, `{"results":[{"uid":"3"}],"next":null}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	p := newHTTPJSON(t, "hj-cursor-walk", srv.URL, config.HTTPJSONConfig{
+		Users:      "$.results",
+		Pagination: config.HTTPJSONPagination{Style: "cursor", CursorParam: "after", NextCursor: "$.next"},
+		Fields:     map[string]string{"id": "$.uid"},
+	})
+	users, err := pagination.Collect(context.Background(), p, pagination.Options{})
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	// a walk from page 1 does not reuse the cursors of the previous one
+	walk = "b"
+	_, err = p.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	res, err := p.GetUsers(context.Background(), 3)
+	require.NoError(t, err)
+	require.Len(t, res.Users, 1)
+	assert.Equal(t, "3", string(res.Users[0].ID))
+}
+
+func TestHTTPJSON_NoPaginationTopLevelArray(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer t", r.Header.Get("Authorization"))
+		assert.Equal(t, "k", r.URL.Query().Get("apikey"))
+		fmt.Fprint(w, `[{"id":1,"name":"Leanne Graham","address":{"city":"Gwenborough"}},{"id":2,"name":"Ervin Howell"}]`)
+	}))
+	defer srv.Close()
+
+	p := newHTTPJSON(t, "hj-none", srv.URL, config.HTTPJSONConfig{
+		APIKeyQuery: "apikey",
+		Fields:      map[string]string{"id": "$.id", "name": "$.name", "city": "$.address.city"},
+	}, config.ProviderCredentials{APIKey: "k", Token: "t"})
+
+	users, err := pagination.Collect(context.Background(), p, pagination.Options{})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "Gwenborough", users[0].Extra["city"])
+	assert.Nil(t, users[1].Extra)
+}
+
+func TestHTTPJSON_UpstreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	p := newHTTPJSON(t, "hj-error", srv.URL, config.HTTPJSONConfig{Fields: map[string]string{"id": "$.id"}})
+	_, err := p.GetUsers(context.Background(), 1)
+	assert.ErrorContains(t, err, "status 403")
+}
+
+func TestHTTPJSON_InvalidConfig(t *testing.T) {
+	svc := NewUserProviderService(config.App{})
+	for _, inst := range []config.ProviderInstance{
+		{ID: "x"},
+		{ID: "x", HTTPJSON: &config.HTTPJSONConfig{Fields: map[string]string{"id": "$.id"}}},
+		{ID: "x", BaseURL: "http://upstream", HTTPJSON: &config.HTTPJSONConfig{Fields: map[string]string{"name": "$.name"}}},
+		{ID: "x", BaseURL: "http://upstream", HTTPJSON: &config.HTTPJSONConfig{Fields: map[string]string{"id": "$.id"}, Pagination: config.HTTPJSONPagination{Style: "link"}}},
+		{ID: "x", BaseURL: "http://upstream", HTTPJSON: &config.HTTPJSONConfig{Fields: map[string]string{"id": "$.id"}, Pagination: config.HTTPJSONPagination{Style: "cursor"}}},
+		{ID: "x", BaseURL: "http://upstream", HTTPJSON: &config.HTTPJSONConfig{Fields: map[string]string{"id": "$.id["}}},
+	} {
+		inst.Type = HTTPJSONProvider
+		assert.Error(t, svc.RegisterNewProvider("x", HTTPJSONProvider, inst.String()))
+	}
+}
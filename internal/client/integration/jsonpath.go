@@ -0,0 +1,255 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/entity/user"
+)
+
+// jsonPath is a compiled expression of the JSONPath subset understood by the
+// http-json provider: the root $, members as .name or ['name'], array indices
+// [n] (negative counts from the end) and the wildcard [*] or .*. A leading $
+// is optional, so "address.city" and "$.address.city" are the same.
+type jsonPath []pathStep
+
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func compileJSONPath(expr string) (jsonPath, error) {
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+	var path jsonPath
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".*"):
+			path = append(path, pathStep{wildcard: true})
+			s = s[2:]
+		case s[0] == '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("jsonpath %q: empty member name", expr)
+			}
+			path = append(path, pathStep{key: s[:end]})
+			s = s[end:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath %q: unclosed bracket", expr)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				path = append(path, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				path = append(path, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("jsonpath %q: invalid index %q", expr, inner)
+				}
+				path = append(path, pathStep{index: i, isIndex: true})
+			}
+		case len(path) == 0:
+			// relative expression without the leading $.
+			s = "." + s
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", expr, s)
+		}
+	}
+	return path, nil
+}
+
+// eval returns every value the path selects in v.
+func (p jsonPath) eval(v any) []any {
+	current := []any{v}
+	for _, step := range p {
+		var next []any
+		for _, c := range current {
+			switch c := c.(type) {
+			case map[string]any:
+				if step.wildcard {
+					for _, val := range c {
+						next = append(next, val)
+					}
+				} else if val, ok := c[step.key]; ok && !step.isIndex {
+					next = append(next, val)
+				}
+			case []any:
+				switch {
+				case step.wildcard:
+					next = append(next, c...)
+				case step.isIndex:
+					i := step.index
+					if i < 0 {
+						i += len(c)
+					}
+					if i >= 0 && i < len(c) {
+						next = append(next, c[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// first returns the first value selected, if any.
+func (p jsonPath) first(v any) (any, bool) {
+	res := p.eval(v)
+	if len(res) == 0 {
+		return nil, false
+	}
+	return res[0], true
+}
+
+// records returns the records the path selects in v. A selection that is a
+// single array is expanded, so "$.data" and "$.data[*]" are the same.
+func (p jsonPath) records(v any) []any {
+	values := p.eval(v)
+	if len(values) == 1 {
+		if arr, ok := values[0].([]any); ok {
+			return arr
+		}
+	}
+	return values
+}
+
+// fieldExpr is a field mapping: a single path or a template interpolating
+// paths in braces, e.g. "{$.first_name} {$.last_name}".
+type fieldExpr struct {
+	literals []string // len(paths)+1 for templates
+	paths    []jsonPath
+}
+
+func compileFieldExpr(expr string) (fieldExpr, error) {
+	if !strings.Contains(expr, "{") {
+		p, err := compileJSONPath(expr)
+		if err != nil {
+			return fieldExpr{}, err
+		}
+		return fieldExpr{paths: []jsonPath{p}}, nil
+	}
+
+	var f fieldExpr
+	rest := expr
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			f.literals = append(f.literals, rest)
+			return f, nil
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return fieldExpr{}, fmt.Errorf("template %q: unclosed brace", expr)
+		}
+		p, err := compileJSONPath(rest[open+1 : open+end])
+		if err != nil {
+			return fieldExpr{}, err
+		}
+		f.literals = append(f.literals, rest[:open])
+		f.paths = append(f.paths, p)
+		rest = rest[open+end+1:]
+	}
+}
+
+// eval renders the field for a user; missing values render empty.
+func (f fieldExpr) eval(v any) string {
+	if f.literals == nil {
+		val, _ := f.paths[0].first(v)
+		return stringify(val)
+	}
+	var b strings.Builder
+	for i, lit := range f.literals {
+		b.WriteString(lit)
+		if i < len(f.paths) {
+			val, _ := f.paths[i].first(v)
+			b.WriteString(stringify(val))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// userFields are the UserDTO fields a mapping can target; any other mapping
+// key goes into Extra.
+var userFields = []string{"id", "name", "username", "email", "phone", "website"}
+
+// userMapping maps decoded records to users, field name -> expression.
+type userMapping map[string]fieldExpr
+
+func compileUserMapping(fields map[string]string) (userMapping, error) {
+	if _, ok := fields["id"]; !ok {
+		return nil, fmt.Errorf("fields must map id")
+	}
+	m := make(userMapping, len(fields))
+	for name, expr := range fields {
+		f, err := compileFieldExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		m[name] = f
+	}
+	return m, nil
+}
+
+func (m userMapping) user(raw any) integration.UserDTO {
+	u := integration.UserDTO{
+		ID:       user.ID(m.field("id", raw)),
+		Name:     user.FullName(m.field("name", raw)),
+		Username: user.Username(m.field("username", raw)),
+		Email:    user.Email(m.field("email", raw)),
+		Phone:    user.Phone(m.field("phone", raw)),
+		Website:  user.Website(m.field("website", raw)),
+	}
+	for name, expr := range m {
+		if slices.Contains(userFields, name) {
+			continue
+		}
+		if v := expr.eval(raw); v != "" {
+			if u.Extra == nil {
+				u.Extra = map[string]string{}
+			}
+			u.Extra[name] = v
+		}
+	}
+	return u
+}
+
+func (m userMapping) field(name string, raw any) string {
+	expr, ok := m[name]
+	if !ok {
+		return ""
+	}
+	return expr.eval(raw)
+}
+
+// stringify renders a decoded JSON value as a user field.
+func stringify(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
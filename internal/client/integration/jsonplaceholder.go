@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 
@@ -18,36 +17,46 @@ import (
 const JsonPlaceholderProvider = "jsonplaceholder"
 
 type jsonPlaceholderService struct {
-	baseURL    string
+	settings   httpSettings
 	httpClient *http.Client
 	provider   string
 }
 
 func init() {
-	RegisterUserServiceFactory(JsonPlaceholderProvider, func(cfg config.App, _ string) (interfaces.UserService, error) {
+	RegisterUserServiceFactory(JsonPlaceholderProvider, func(cfg config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		settings := jsonPlaceholderSettings(cfg).withInstance(inst)
+		if inst.ID != "" {
+			settings.provider = JsonPlaceholderProvider + ":" + inst.ID
+		}
+		client, err := settings.httpClient()
+		if err != nil {
+			return nil, err
+		}
 		return &jsonPlaceholderService{
-			baseURL:    "https://jsonplaceholder.typicode.com",
-			httpClient: &http.Client{},
+			settings:   settings,
+			httpClient: client,
 			provider:   JsonPlaceholderProvider,
 		}, nil
 	})
 }
 
 func (j *jsonPlaceholderService) GetUsers(ctx context.Context, page int) (res integration.UserListResponseDTO, err error) {
-	u := fmt.Sprintf("%s/users", j.baseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	if err != nil {
-		return integration.UserListResponseDTO{}, err
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := j.httpClient.Do(req)
+	u := fmt.Sprintf("%s/users", j.settings.baseURL)
+	resp, body, err := j.settings.do(ctx, j.httpClient, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return integration.UserListResponseDTO{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
 		str := "status code response is not 200 ."
 		str = str + " resp.StatusCode : " + strconv.Itoa(resp.StatusCode)
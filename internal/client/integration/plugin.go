@@ -0,0 +1,493 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+	"__MODULE__/pkg/plugin"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const PluginProvider = "plugin"
+
+// pluginEnv are the variables of the service environment a plugin inherits;
+// everything else, secrets included, has to be passed explicitly in Env.
+var pluginEnv = []string{"PATH", "HOME", "TMPDIR"}
+
+// pluginService is a provider implemented by an external executable speaking
+// the protocol of pkg/plugin. The process is started when the instance is
+// registered, restarted when it crashes or stops answering health checks and
+// killed when the instance is stopped or replaced.
+type pluginService struct {
+	id          string
+	cfg         config.PluginConfig
+	credentials map[string]string
+
+	mu       sync.Mutex
+	proc     *pluginProcess
+	restarts []time.Time // within the restart window
+	closed   bool
+
+	stop       chan struct{}
+	healthDone chan struct{}
+}
+
+func init() {
+	RegisterUserServiceFactory(PluginProvider, func(app config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		if inst.Plugin == nil || inst.Plugin.Command == "" {
+			return nil, fmt.Errorf("plugin provider %q needs a command", inst.ID)
+		}
+		cfg := *inst.Plugin
+		if cfg.Command, err = allowedPluginCommand(app.PluginDirs, cfg.Command); err != nil {
+			return nil, fmt.Errorf("plugin provider %q: %w", inst.ID, err)
+		}
+		if cfg.CallTimeout <= 0 {
+			cfg.CallTimeout = 30
+		}
+		if cfg.StartTimeout <= 0 {
+			cfg.StartTimeout = 10
+		}
+		if cfg.HealthInterval == 0 {
+			cfg.HealthInterval = 30
+		}
+		if cfg.MaxRestarts <= 0 {
+			cfg.MaxRestarts = 5
+		}
+		if cfg.RestartWindow <= 0 {
+			cfg.RestartWindow = 60
+		}
+		if cfg.MaxMessageBytes <= 0 {
+			cfg.MaxMessageBytes = 16 << 20
+		}
+
+		var credentials map[string]string
+		b, _ := json.Marshal(inst.Credentials)
+		_ = json.Unmarshal(b, &credentials)
+
+		p := &pluginService{id: inst.ID, cfg: cfg, credentials: credentials, stop: make(chan struct{}), healthDone: make(chan struct{})}
+		if _, err := p.process(); err != nil {
+			return nil, err
+		}
+		go p.healthLoop()
+		return p, nil
+	})
+}
+
+func (p *pluginService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	proc, err := p.process()
+	if err != nil {
+		return integration.UserListResponseDTO{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(p.cfg.CallTimeout)*time.Second)
+	defer cancel()
+
+	var out plugin.GetUsersResult
+	raw, err := proc.call(ctx, plugin.MethodGetUsers, plugin.GetUsersParams{Page: page}, &out)
+	if err != nil {
+		return integration.UserListResponseDTO{Provider: PluginProvider, Raw: raw}, fmt.Errorf("plugin %s: %w", p.id, err)
+	}
+
+	res := integration.UserListResponseDTO{
+		Provider: PluginProvider,
+		Raw:      raw,
+		Users:    make([]integration.UserDTO, 0, len(out.Users)),
+		Meta: integration.MetaInfoDTO{
+			Page:       out.Meta.Page,
+			PerPage:    out.Meta.PerPage,
+			Total:      out.Meta.Total,
+			TotalPages: out.Meta.TotalPages,
+		},
+	}
+	for _, u := range out.Users {
+		res.Users = append(res.Users, integration.UserDTO{
+			ID:       user.ID(u.ID),
+			Name:     user.FullName(u.Name),
+			Username: user.Username(u.Username),
+			Email:    user.Email(u.Email),
+			Phone:    user.Phone(u.Phone),
+			Website:  user.Website(u.Website),
+			Extra:    u.Extra,
+		})
+	}
+	return res, nil
+}
+
+// Close kills the plugin and stops its health checks.
+func (p *pluginService) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	proc := p.proc
+	p.mu.Unlock()
+
+	close(p.stop)
+	<-p.healthDone
+	if proc != nil {
+		proc.kill()
+	}
+	pkg.GaugeSet("provider_plugin_healthy", 0, "provider", p.id)
+	return nil
+}
+
+// process returns the running plugin, starting it first if it has never run or
+// has exited. Restarts are limited to MaxRestarts within RestartWindow.
+func (p *pluginService) process() (*pluginProcess, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("plugin %s is stopped", p.id)
+	}
+	if p.proc != nil && p.proc.alive() {
+		return p.proc, nil
+	}
+
+	if p.proc != nil {
+		now := time.Now()
+		window := time.Duration(p.cfg.RestartWindow) * time.Second
+		recent := p.restarts[:0]
+		for _, t := range p.restarts {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+		p.restarts = recent
+		if len(p.restarts) >= p.cfg.MaxRestarts {
+			return nil, fmt.Errorf("plugin %s exited (%v) and was restarted %d times within %s", p.id, p.proc.exitErr(), len(p.restarts), window)
+		}
+		p.restarts = append(p.restarts, now)
+		pkg.CounterAdd("provider_plugin_restarts_total", 1, "provider", p.id)
+		log.WithError(p.proc.exitErr()).WithField("provider", p.id).Warn("restarting plugin")
+	}
+
+	proc, err := p.start()
+	if err != nil {
+		pkg.GaugeSet("provider_plugin_healthy", 0, "provider", p.id)
+		return nil, fmt.Errorf("plugin %s: %w", p.id, err)
+	}
+	p.proc = proc
+	pkg.GaugeSet("provider_plugin_healthy", 1, "provider", p.id)
+	return proc, nil
+}
+
+// start launches the executable and performs the handshake; must be called
+// with mu held.
+func (p *pluginService) start() (*pluginProcess, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.cfg.StartTimeout)*time.Second)
+	defer cancel()
+
+	proc, err := startPluginProcess(p.id, p.cfg)
+	if err != nil {
+		return nil, err
+	}
+	var info plugin.HandshakeResult
+	_, err = proc.call(ctx, plugin.MethodHandshake, plugin.HandshakeParams{
+		ProtocolVersion: plugin.ProtocolVersion,
+		Instance:        p.id,
+		Credentials:     p.credentials,
+		Config:          p.cfg.Config,
+	}, &info)
+	if err == nil && info.ProtocolVersion != plugin.ProtocolVersion {
+		err = fmt.Errorf("plugin speaks protocol version %d, want %d", info.ProtocolVersion, plugin.ProtocolVersion)
+	}
+	if err != nil {
+		proc.kill()
+		return nil, fmt.Errorf("handshake: %w", err)
+	}
+	log.WithFields(log.Fields{"provider": p.id, "plugin": info.Name, "version": info.Version}).Info("plugin started")
+	return proc, nil
+}
+
+// healthLoop restarts crashed plugins and kills plugins that stop answering,
+// so the next call gets a fresh one.
+func (p *pluginService) healthLoop() {
+	defer close(p.healthDone)
+	if p.cfg.HealthInterval < 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(p.cfg.HealthInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+func (p *pluginService) checkHealth() {
+	proc, err := p.process()
+	if err != nil {
+		log.WithError(err).WithField("provider", p.id).Warn("plugin unavailable")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.cfg.CallTimeout)*time.Second)
+	defer cancel()
+	var health plugin.HealthResult
+	if _, err := proc.call(ctx, plugin.MethodHealth, nil, &health); err != nil {
+		log.WithError(err).WithField("provider", p.id).Warn("plugin failed its health check, killing it")
+		pkg.GaugeSet("provider_plugin_healthy", 0, "provider", p.id)
+		proc.kill()
+		return
+	}
+	pkg.GaugeSet("provider_plugin_healthy", 1, "provider", p.id)
+}
+
+// pluginProcess is one run of a plugin executable.
+type pluginProcess struct {
+	provider string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	writeMu  sync.Mutex
+	nextID   atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[uint64]chan plugin.Response
+
+	exited chan struct{}
+	err    error // why the process exited, set before exited is closed
+}
+
+func startPluginProcess(id string, cfg config.PluginConfig) (*pluginProcess, error) {
+	cmd := pluginCommand(cfg)
+	cmd.Dir = cfg.Dir
+	for _, k := range pluginEnv {
+		if v, ok := os.LookupEnv(k); ok {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = &pluginLogWriter{provider: id}
+	cmd.WaitDelay = time.Second
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", cfg.Command, err)
+	}
+
+	proc := &pluginProcess{provider: id, cmd: cmd, stdin: stdin, pending: map[uint64]chan plugin.Response{}, exited: make(chan struct{})}
+	go proc.readLoop(stdout, cfg.MaxMessageBytes)
+	return proc, nil
+}
+
+// allowedPluginCommand resolves command, which must be an absolute path, and
+// checks that it lies within one of dirs. Symlinks are resolved on both sides
+// so a link cannot point outside the allowed directories.
+func allowedPluginCommand(dirs []string, command string) (string, error) {
+	if len(dirs) == 0 {
+		return "", errors.New("plugins are disabled: PLUGIN_DIRS is not set")
+	}
+	if !filepath.IsAbs(command) {
+		return "", fmt.Errorf("command %q is not an absolute path", command)
+	}
+	resolved, err := filepath.EvalSymlinks(command)
+	if err != nil {
+		return "", fmt.Errorf("command %q: %w", command, err)
+	}
+	for _, dir := range dirs {
+		dir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, resolved); err == nil && rel != "." && filepath.IsLocal(rel) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("command %q is outside PLUGIN_DIRS", command)
+}
+
+// pluginCommand wraps the plugin in a shell applying the resource limits, which
+// then execs the plugin itself.
+func pluginCommand(cfg config.PluginConfig) *exec.Cmd {
+	var limits []string
+	if cfg.MaxMemoryMB > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -v %d", cfg.MaxMemoryMB*1024))
+	}
+	if cfg.MaxCPUSeconds > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -t %d", cfg.MaxCPUSeconds))
+	}
+	if cfg.MaxOpenFiles > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -n %d", cfg.MaxOpenFiles))
+	}
+	if len(limits) == 0 {
+		return exec.Command(cfg.Command, cfg.Args...)
+	}
+	script := strings.Join(limits, " && ") + ` && exec "$0" "$@"`
+	return exec.Command("/bin/sh", append([]string{"-c", script, cfg.Command}, cfg.Args...)...)
+}
+
+// readLoop dispatches responses to their callers until stdout is closed, then
+// reaps the process and fails the calls still waiting.
+func (p *pluginProcess) readLoop(stdout io.Reader, maxMessageBytes int) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
+	for scanner.Scan() {
+		var resp plugin.Response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			log.WithError(err).WithField("provider", p.provider).Warn("plugin wrote an invalid response")
+			continue
+		}
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// e.g. a response over MaxMessageBytes; the stream cannot be resynced
+		_ = p.cmd.Process.Kill()
+	}
+
+	err := p.cmd.Wait()
+	if scanErr != nil {
+		err = scanErr
+	} else if err == nil {
+		err = errors.New("plugin exited")
+	}
+	p.err = err
+	close(p.exited)
+}
+
+func (p *pluginProcess) alive() bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *pluginProcess) exitErr() error {
+	if p.alive() {
+		return nil
+	}
+	return p.err
+}
+
+// call sends a request and decodes the result into out. The raw result is
+// returned for logging.
+func (p *pluginProcess) call(ctx context.Context, method string, params any, out any) ([]byte, error) {
+	req := plugin.Request{JSONRPC: "2.0", ID: p.nextID.Add(1), Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		req.Params = b
+	}
+	line, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan plugin.Response, 1)
+	p.mu.Lock()
+	p.pending[req.ID] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, req.ID)
+		p.mu.Unlock()
+	}()
+
+	p.writeMu.Lock()
+	_, err = p.stdin.Write(append(line, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		if !p.alive() {
+			return nil, p.err
+		}
+		return nil, fmt.Errorf("write %s request: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Result, fmt.Errorf("%s: %w", method, resp.Error)
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return resp.Result, fmt.Errorf("decode %s result: %w", method, err)
+		}
+		return resp.Result, nil
+	case <-p.exited:
+		return nil, p.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+// kill stops the process and waits until it has been reaped.
+func (p *pluginProcess) kill() {
+	_ = p.stdin.Close()
+	_ = p.cmd.Process.Kill()
+	<-p.exited
+}
+
+// pluginLogWriter logs what a plugin writes to stderr, one entry per line.
+type pluginLogWriter struct {
+	provider string
+	buf      []byte
+}
+
+func (w *pluginLogWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	// keep a runaway line from growing without bound
+	if len(w.buf) > 4096 {
+		w.log(w.buf)
+		w.buf = nil
+	}
+	return len(b), nil
+}
+
+func (w *pluginLogWriter) log(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	log.WithField("provider", w.provider).Info("plugin: " + string(line))
+}
@@ -0,0 +1,332 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/pkg"
+	"__MODULE__/pkg/plugin"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The plugin tests run this test binary as the plugin: with
+// GO_WANT_PLUGIN_HELPER set, TestPluginHelperProcess serves the protocol on
+// stdin/stdout instead of testing anything.
+
+type helperProvider struct {
+	params plugin.HandshakeParams
+}
+
+func (h *helperProvider) Configure(params plugin.HandshakeParams) error {
+	h.params = params
+	return nil
+}
+
+func (h *helperProvider) GetUsers(ctx context.Context, page int) (plugin.GetUsersResult, error) {
+	switch page {
+	case 13:
+		os.Exit(3)
+	case 99:
+		<-ctx.Done()
+	case 500:
+		return plugin.GetUsersResult{}, errors.New("upstream unavailable")
+	}
+	extra := map[string]string{"api_key": h.params.Credentials["api_key"], "config": string(h.params.Config), "secret": os.Getenv("PLUGIN_TEST_SECRET")}
+	if limits, err := os.ReadFile("/proc/self/limits"); err == nil {
+		for _, line := range strings.Split(string(limits), "\n") {
+			if strings.HasPrefix(line, "Max open files") {
+				extra["open_files"] = strings.Fields(line)[3]
+			}
+		}
+	}
+	return plugin.GetUsersResult{
+		Users: []plugin.User{{ID: fmt.Sprint(page), Name: "Leanne Graham", Email: "Sincere@april.biz", Extra: extra}},
+		Meta:  plugin.Meta{Page: page, TotalPages: 2},
+	}, nil
+}
+
+func (h *helperProvider) Health(ctx context.Context) error {
+	if os.Getenv("PLUGIN_HELPER_MODE") == "hung" {
+		<-ctx.Done()
+	}
+	return nil
+}
+
+func TestPluginHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_PLUGIN_HELPER") != "1" {
+		return
+	}
+	if os.Getenv("PLUGIN_HELPER_MODE") == "future" {
+		// answers every handshake with a protocol version from the future
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			var req plugin.Request
+			_ = json.Unmarshal(scanner.Bytes(), &req)
+			_ = json.NewEncoder(os.Stdout).Encode(plugin.Response{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`{"protocol_version":2,"name":"future"}`)})
+		}
+		os.Exit(0)
+	}
+	if dir := os.Getenv("PLUGIN_HELPER_PIDS"); dir != "" {
+		pid := strconv.Itoa(os.Getpid())
+		_ = os.WriteFile(filepath.Join(dir, pid), []byte(pid), 0o600)
+	}
+	fmt.Fprintln(os.Stderr, "helper plugin starting")
+	_ = plugin.Serve(context.Background(), os.Stdin, os.Stdout, plugin.HandshakeResult{Name: "helper", Version: "1.0.0"}, &helperProvider{})
+	os.Exit(0)
+}
+
+func helperPlugin(mode string) config.PluginConfig {
+	return config.PluginConfig{
+		Command:        os.Args[0],
+		Args:           []string{"-test.run=^TestPluginHelperProcess$"},
+		Env:            map[string]string{"GO_WANT_PLUGIN_HELPER": "1", "PLUGIN_HELPER_MODE": mode},
+		HealthInterval: -1,
+	}
+}
+
+// newPluginHost returns a service whose PLUGIN_DIRS allow the test binary.
+func newPluginHost() *userProviderService {
+	app := config.App{}
+	app.PluginDirs = []string{filepath.Dir(os.Args[0])}
+	return NewUserProviderService(app)
+}
+
+func pluginInstance(id string, cfg config.PluginConfig) config.ProviderInstance {
+	return config.ProviderInstance{ID: id, Type: PluginProvider, Credentials: config.ProviderCredentials{APIKey: "k"}, Plugin: &cfg}
+}
+
+func registerPlugin(t *testing.T, svc *userProviderService, id string, cfg config.PluginConfig) *pluginService {
+	t.Helper()
+	require.NoError(t, svc.RegisterInstances(config.ProviderInstances{pluginInstance(id, cfg)}))
+	t.Cleanup(func() { _ = svc.StopUserService(id) })
+	return svc.UserServiceMap[id].(*resilientService).next.(*pluginService)
+}
+
+func TestPlugin_HandshakeAndGetUsers(t *testing.T) {
+	t.Setenv("PLUGIN_TEST_SECRET", "leaked")
+	svc := newPluginHost()
+	cfg := helperPlugin("")
+	cfg.Config = json.RawMessage(`{"tenant":"acme"}`)
+	registerPlugin(t, svc, "plugin-ok", cfg)
+
+	p, err := svc.GetUserService("plugin-ok")
+	require.NoError(t, err)
+	res, err := p.GetUsers(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, PluginProvider, res.Provider)
+	assert.Equal(t, "plugin-ok", res.Instance)
+	assert.Equal(t, 2, res.Meta.TotalPages)
+	require.Len(t, res.Users, 1)
+	assert.Equal(t, "2", string(res.Users[0].ID))
+	assert.Equal(t, "k", res.Users[0].Extra["api_key"])
+	assert.Equal(t, `{"tenant":"acme"}`, res.Users[0].Extra["config"])
+	assert.Empty(t, res.Users[0].Extra["secret"], "the service environment must not leak into plugins")
+
+	_, err = p.GetUsers(context.Background(), 500)
+	assert.ErrorContains(t, err, "upstream unavailable")
+}
+
+func TestPlugin_RestartsAfterCrash(t *testing.T) {
+	svc := newPluginHost()
+	cfg := helperPlugin("")
+	cfg.MaxRestarts = 1
+	p := registerPlugin(t, svc, "plugin-crash", cfg)
+	restarts := counter("provider_plugin_restarts_total", "provider", "plugin-crash")
+
+	_, err := p.GetUsers(context.Background(), 13)
+	require.Error(t, err)
+
+	res, err := p.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Len(t, res.Users, 1)
+	assert.Equal(t, 1.0, restarts())
+
+	// the restart budget is spent
+	_, err = p.GetUsers(context.Background(), 13)
+	require.Error(t, err)
+	_, err = p.GetUsers(context.Background(), 1)
+	assert.ErrorContains(t, err, "restarted 1 times")
+}
+
+func TestPlugin_CallTimeout(t *testing.T) {
+	svc := newPluginHost()
+	cfg := helperPlugin("")
+	cfg.CallTimeout = 1
+	p := registerPlugin(t, svc, "plugin-slow", cfg)
+
+	start := time.Now()
+	_, err := p.GetUsers(context.Background(), 99)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// the plugin is still usable
+	_, err = p.GetUsers(context.Background(), 1)
+	assert.NoError(t, err)
+}
+
+func TestPlugin_HealthCheckKillsHungPlugin(t *testing.T) {
+	svc := newPluginHost()
+	cfg := helperPlugin("hung")
+	cfg.CallTimeout = 1
+	p := registerPlugin(t, svc, "plugin-hung", cfg)
+	restarts := counter("provider_plugin_restarts_total", "provider", "plugin-hung")
+	assert.Equal(t, 1.0, pkg.MetricValue("provider_plugin_healthy", "provider", "plugin-hung"))
+
+	p.mu.Lock()
+	proc := p.proc
+	p.mu.Unlock()
+	p.checkHealth()
+	assert.False(t, proc.alive())
+	assert.Equal(t, 0.0, pkg.MetricValue("provider_plugin_healthy", "provider", "plugin-hung"))
+
+	// the next call starts a fresh plugin
+	_, err := p.GetUsers(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, restarts())
+}
+
+func TestPlugin_ResourceLimits(t *testing.T) {
+	if _, err := os.Stat("/proc/self/limits"); err != nil {
+		t.Skip("resource limits are only observable on linux")
+	}
+	svc := newPluginHost()
+	cfg := helperPlugin("")
+	cfg.MaxOpenFiles = 64
+	cfg.MaxCPUSeconds = 60
+	p := registerPlugin(t, svc, "plugin-limits", cfg)
+
+	res, err := p.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "64", res.Users[0].Extra["open_files"])
+}
+
+func TestPlugin_RejectsProtocolVersionMismatch(t *testing.T) {
+	svc := newPluginHost()
+	err := svc.RegisterInstances(config.ProviderInstances{pluginInstance("plugin-future", helperPlugin("future"))})
+	assert.ErrorContains(t, err, "protocol version 2")
+}
+
+func TestPlugin_StopKillsProcess(t *testing.T) {
+	svc := newPluginHost()
+	p := registerPlugin(t, svc, "plugin-stop", helperPlugin(""))
+	p.mu.Lock()
+	proc := p.proc
+	p.mu.Unlock()
+
+	require.NoError(t, svc.StopUserService("plugin-stop"))
+	assert.False(t, proc.alive())
+	_, err := p.GetUsers(context.Background(), 1)
+	assert.ErrorContains(t, err, "stopped")
+}
+
+func TestPlugin_InvalidConfig(t *testing.T) {
+	svc := newPluginHost()
+	for _, inst := range []config.ProviderInstance{
+		{ID: "x", Type: PluginProvider},
+		{ID: "x", Type: PluginProvider, Plugin: &config.PluginConfig{Command: "/nonexistent/plugin"}},
+	} {
+		assert.Error(t, svc.RegisterInstances(config.ProviderInstances{inst}))
+	}
+}
+
+func TestPlugin_CommandMustBeInPluginDirs(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	plug := filepath.Join(dir, "partner")
+	require.NoError(t, os.WriteFile(plug, []byte("#!/bin/sh\n"), 0o755))
+	escape := filepath.Join(dir, "escape")
+	require.NoError(t, os.Symlink(filepath.Join(outside, "tool"), escape))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "tool"), []byte("#!/bin/sh\n"), 0o755))
+
+	got, err := allowedPluginCommand([]string{dir}, plug)
+	require.NoError(t, err)
+	assert.Equal(t, plug, filepath.Clean(got))
+
+	for name, tc := range map[string]struct {
+		dirs    []string
+		command string
+		want    string
+	}{
+		"no plugin dirs":   {nil, plug, "PLUGIN_DIRS is not set"},
+		"relative path":    {[]string{dir}, "partner", "not an absolute path"},
+		"outside the dirs": {[]string{dir}, filepath.Join(outside, "tool"), "outside PLUGIN_DIRS"},
+		"symlink escape":   {[]string{dir}, escape, "outside PLUGIN_DIRS"},
+		"parent traversal": {[]string{dir}, filepath.Join(dir, "..", filepath.Base(outside), "tool"), "outside PLUGIN_DIRS"},
+		"the dir itself":   {[]string{dir}, dir, "outside PLUGIN_DIRS"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := allowedPluginCommand(tc.dirs, tc.command)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+
+	// the factory applies the allowlist of the running service
+	err = NewUserProviderService(config.App{}).RegisterInstances(config.ProviderInstances{pluginInstance("plugin-off", helperPlugin(""))})
+	assert.ErrorContains(t, err, "PLUGIN_DIRS is not set")
+}
+
+func TestPlugin_RejectedAtRuntime(t *testing.T) {
+	svc := newPluginHost()
+	raw := pluginInstance("plugin-runtime", helperPlugin("")).String()
+
+	err := svc.RegisterNewProvider("plugin-runtime", PluginProvider, raw)
+	assertAppError(t, pkg.ErrBadRequest, err)
+	require.NoError(t, svc.RegisterNewProvider("plugin-runtime", JsonPlaceholderProvider, ""))
+	t.Cleanup(func() { _ = svc.StopUserService("plugin-runtime") })
+	assertAppError(t, pkg.ErrBadRequest, svc.ReplaceProvider("plugin-runtime", PluginProvider, raw))
+}
+
+func TestPlugin_DuplicateRegistrationStartsNoExtraProcess(t *testing.T) {
+	pids := t.TempDir()
+	cfg := helperPlugin("")
+	cfg.Env["PLUGIN_HELPER_PIDS"] = pids
+	svc := newPluginHost()
+	t.Cleanup(func() { _ = svc.StopUserService("plugin-dup") })
+
+	// concurrent registrations may all pass the early duplicate check and
+	// build, the losers must stop what they started
+	errs := make([]error, 5)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = svc.RegisterInstances(config.ProviderInstances{pluginInstance("plugin-dup", cfg)})
+		}()
+	}
+	wg.Wait()
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorContains(t, err, "already exists")
+			failed++
+		}
+	}
+	assert.Equal(t, len(errs)-1, failed)
+	assert.ErrorContains(t, svc.RegisterInstances(config.ProviderInstances{pluginInstance("plugin-dup", cfg)}), "already exists")
+
+	entries, err := os.ReadDir(pids)
+	require.NoError(t, err)
+	alive := 0
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		require.NoError(t, err)
+		if syscall.Kill(pid, 0) == nil {
+			alive++
+		}
+	}
+	assert.Equal(t, 1, alive, "only the registered plugin may be running")
+}
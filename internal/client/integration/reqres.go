@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -18,24 +17,44 @@ import (
 const ReqresProvider = "reqres"
 
 type reqresService struct {
-	baseURL    string
+	settings   httpSettings
+	apiKey     string
 	httpClient *http.Client
 	provider   string
 }
 
 func init() {
-	RegisterUserServiceFactory(ReqresProvider, func(cfg config.App, _ string) (interfaces.UserService, error) {
+	RegisterUserServiceFactory(ReqresProvider, func(cfg config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		settings := reqresSettings(cfg).withInstance(inst)
+		if inst.ID != "" {
+			settings.provider = ReqresProvider + ":" + inst.ID
+		}
+		client, err := settings.httpClient()
+		if err != nil {
+			return nil, err
+		}
 		svc := &reqresService{
-			baseURL:    "https://reqres.in",
-			httpClient: &http.Client{},
+			settings:   settings,
+			httpClient: client,
 			provider:   ReqresProvider,
 		}
+		// the public reqres.in demo key is only a fallback for unauthenticated instances
+		if !settings.auth.enabled() {
+			svc.apiKey = "reqres-free-v1"
+		}
+		if inst.Credentials.APIKey != "" {
+			svc.apiKey = inst.Credentials.APIKey
+		}
 		return svc, nil
 	})
 }
 
 func (r *reqresService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
-	u := fmt.Sprintf("%s/api/users", r.baseURL)
+	u := fmt.Sprintf("%s/api/users", r.settings.baseURL)
 	values := url.Values{}
 	if page <= 0 {
 		page = 1
@@ -43,21 +62,20 @@ func (r *reqresService) GetUsers(ctx context.Context, page int) (integration.Use
 	values.Set("page", strconv.Itoa(page))
 	reqURL := u + "?" + values.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
-	if err != nil {
-		return integration.UserListResponseDTO{}, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	// Example of custom header (as in your curl)
-	req.Header.Set("x-api-key", "reqres-free-v1")
-
-	resp, err := r.httpClient.Do(req)
+	resp, body, err := r.settings.do(ctx, r.httpClient, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if r.apiKey != "" {
+			req.Header.Set("x-api-key", r.apiKey)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return integration.UserListResponseDTO{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
 		return integration.UserListResponseDTO{Provider: r.provider, Raw: body}, fmt.Errorf("reqres: status %d", resp.StatusCode)
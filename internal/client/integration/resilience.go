@@ -0,0 +1,242 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// resilientService wraps a provider instance with a circuit breaker and a
+// concurrency bulkhead. Every registered instance gets its own wrapper, so one
+// failing upstream cannot slow down or exhaust calls to another.
+type resilientService struct {
+	id    string
+	next  interfaces.UserService
+	cfg   config.ResilienceConfig
+	now   func() time.Time
+	slots chan struct{}
+
+	mu            sync.Mutex
+	state         integration.BreakerState
+	failures      int
+	openedAt      time.Time
+	halfOpenCalls int
+	halfOpenGen   int // bumped on every transition to half-open, probes belong to one
+}
+
+// resilienceReporter is implemented by services that can report breaker state.
+type resilienceReporter interface {
+	resilienceState() integration.ResilienceStateDTO
+}
+
+func newResilientService(id string, next interfaces.UserService, cfg config.ResilienceConfig) *resilientService {
+	if cfg.BreakerFailureThreshold <= 0 {
+		cfg.BreakerFailureThreshold = 5
+	}
+	if cfg.BreakerHalfOpenMaxCalls <= 0 {
+		cfg.BreakerHalfOpenMaxCalls = 1
+	}
+	if cfg.BulkheadMaxConcurrent <= 0 {
+		cfg.BulkheadMaxConcurrent = 10
+	}
+	r := &resilientService{
+		id:    id,
+		next:  next,
+		cfg:   cfg,
+		now:   time.Now,
+		slots: make(chan struct{}, cfg.BulkheadMaxConcurrent),
+		state: integration.BreakerClosed,
+	}
+	r.publishState()
+	return r
+}
+
+func (r *resilientService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	probe, err := r.allow()
+	if err != nil {
+		pkg.CounterAdd("provider_calls_total", 1, "provider", r.id, "result", "rejected_open")
+		return integration.UserListResponseDTO{}, err
+	}
+	if err := r.acquire(ctx); err != nil {
+		r.abort(probe)
+		pkg.CounterAdd("provider_calls_total", 1, "provider", r.id, "result", "rejected_bulkhead")
+		return integration.UserListResponseDTO{}, err
+	}
+	defer r.release()
+
+	res, err := r.next.GetUsers(ctx, page)
+	r.record(ctx, probe, err)
+	if res.Instance == "" {
+		res.Instance = r.id
+	}
+	return res, err
+}
+
+// allow decides whether a call may go through the breaker. probe is the
+// half-open generation of a call that is one of the limited trial calls, or 0.
+func (r *resilientService) allow() (probe int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state == integration.BreakerOpen {
+		if r.now().Sub(r.openedAt) < time.Duration(r.cfg.BreakerOpenTimeout)*time.Second {
+			return 0, pkg.NewAppError(pkg.ErrProviderCircuitOpen).AddDescription([]byte(r.id)).AppendStackLog()
+		}
+		r.transition(integration.BreakerHalfOpen)
+	}
+	if r.state == integration.BreakerHalfOpen {
+		if r.halfOpenCalls >= r.cfg.BreakerHalfOpenMaxCalls {
+			return 0, pkg.NewAppError(pkg.ErrProviderCircuitOpen).AddDescription([]byte(r.id)).AppendStackLog()
+		}
+		r.halfOpenCalls++
+		return r.halfOpenGen, nil
+	}
+	return 0, nil
+}
+
+// abort gives back a half-open probe slot that was never used.
+func (r *resilientService) abort(probe int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endProbe(probe)
+}
+
+// endProbe gives back the slot of a finished probe. A probe that outlived its
+// half-open period has no slot left, the count started over without it. It
+// must be called with mu held.
+func (r *resilientService) endProbe(probe int) {
+	if probe != 0 && probe == r.halfOpenGen {
+		r.halfOpenCalls--
+	}
+}
+
+func (r *resilientService) acquire(ctx context.Context) error {
+	select {
+	case r.slots <- struct{}{}:
+		r.publishInFlight()
+		return nil
+	default:
+	}
+
+	full := func() error {
+		return pkg.NewAppError(pkg.ErrProviderBulkheadFull).AddDescription([]byte(r.id)).AppendStackLog()
+	}
+	if r.cfg.BulkheadMaxWait <= 0 {
+		return full()
+	}
+	timer := time.NewTimer(time.Duration(r.cfg.BulkheadMaxWait) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case r.slots <- struct{}{}:
+		r.publishInFlight()
+		return nil
+	case <-timer.C:
+		return full()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *resilientService) release() {
+	<-r.slots
+	r.publishInFlight()
+}
+
+// record feeds the outcome of a call into the breaker. Calls cancelled by the
+// caller say nothing about the provider and are ignored.
+func (r *resilientService) record(ctx context.Context, probe int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.endProbe(probe)
+
+	if err != nil && errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		pkg.CounterAdd("provider_calls_total", 1, "provider", r.id, "result", "success")
+		r.failures = 0
+		if r.state == integration.BreakerHalfOpen {
+			r.transition(integration.BreakerClosed)
+		}
+		return
+	}
+
+	pkg.CounterAdd("provider_calls_total", 1, "provider", r.id, "result", "failure")
+	r.failures++
+	if r.state == integration.BreakerHalfOpen || r.failures >= r.cfg.BreakerFailureThreshold {
+		r.openedAt = r.now()
+		r.transition(integration.BreakerOpen)
+	}
+}
+
+// transition must be called with mu held.
+func (r *resilientService) transition(to integration.BreakerState) {
+	if r.state == to {
+		return
+	}
+	log.WithFields(log.Fields{
+		"provider": r.id,
+		"from":     r.state,
+		"to":       to,
+		"failures": r.failures,
+	}).Warn("provider circuit breaker state changed")
+	r.state = to
+	if to == integration.BreakerHalfOpen {
+		r.halfOpenCalls = 0
+		r.halfOpenGen++
+	}
+	r.publishState()
+}
+
+// publishState must be called with mu held (or before the service is shared).
+func (r *resilientService) publishState() {
+	value := 0.0
+	switch r.state {
+	case integration.BreakerHalfOpen:
+		value = 1
+	case integration.BreakerOpen:
+		value = 2
+	}
+	pkg.GaugeSet("provider_breaker_state", value, "provider", r.id)
+}
+
+func (r *resilientService) publishInFlight() {
+	pkg.GaugeSet("provider_bulkhead_in_flight", float64(len(r.slots)), "provider", r.id)
+}
+
+func (r *resilientService) resilienceState() integration.ResilienceStateDTO {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := integration.ResilienceStateDTO{
+		Breaker:             r.state,
+		ConsecutiveFailures: r.failures,
+		InFlight:            len(r.slots),
+		MaxConcurrent:       cap(r.slots),
+	}
+	if r.state == integration.BreakerOpen {
+		openedAt := r.openedAt
+		state.OpenedAt = &openedAt
+	}
+	return state
+}
+
+// Close releases the wrapped instance if it holds resources, such as a plugin
+// process.
+func (r *resilientService) Close() error {
+	if c, ok := r.next.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubUserService answers GetUsers with fn.
+type stubUserService struct {
+	fn func(ctx context.Context, page int) (integration.UserListResponseDTO, error)
+}
+
+func (s *stubUserService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	return s.fn(ctx, page)
+}
+
+func TestResilience_OpensAfterThresholdAndFailsFast(t *testing.T) {
+	var calls int
+	fail := true
+	next := &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+		calls++
+		if fail {
+			return integration.UserListResponseDTO{}, errors.New("down")
+		}
+		return integration.UserListResponseDTO{Provider: "p"}, nil
+	}}
+
+	now := time.Now()
+	r := newResilientService("breaker-test", next, config.ResilienceConfig{BreakerFailureThreshold: 2, BreakerOpenTimeout: 10})
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := r.GetUsers(context.Background(), 1)
+		require.Error(t, err)
+	}
+	assert.Equal(t, integration.BreakerOpen, r.resilienceState().Breaker)
+
+	_, err := r.GetUsers(context.Background(), 1)
+	var appErr *pkg.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 503, appErr.ExternalCode())
+	assert.Equal(t, 2, calls, "open circuit must not reach the provider")
+	assert.Equal(t, 2.0, pkg.MetricValue("provider_breaker_state", "provider", "breaker-test"))
+
+	// after the open timeout a probe goes through and closes the circuit
+	now = now.Add(11 * time.Second)
+	fail = false
+	_, err = r.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, integration.BreakerClosed, r.resilienceState().Breaker)
+}
+
+func TestResilience_FailedProbeReopens(t *testing.T) {
+	next := &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+		return integration.UserListResponseDTO{}, errors.New("down")
+	}}
+	now := time.Now()
+	r := newResilientService("probe-test", next, config.ResilienceConfig{BreakerFailureThreshold: 1, BreakerOpenTimeout: 1})
+	r.now = func() time.Time { return now }
+
+	_, _ = r.GetUsers(context.Background(), 1)
+	assert.Equal(t, integration.BreakerOpen, r.resilienceState().Breaker)
+
+	now = now.Add(2 * time.Second)
+	_, _ = r.GetUsers(context.Background(), 1)
+	state := r.resilienceState()
+	assert.Equal(t, integration.BreakerOpen, state.Breaker)
+	require.NotNil(t, state.OpenedAt)
+	assert.Equal(t, now, *state.OpenedAt)
+}
+
+func TestResilience_ProbeOfAnEarlierHalfOpenFreesNoSlot(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	next := &stubUserService{fn: func(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+		switch page {
+		case 2: // a probe that only ends when its caller gives up
+			started <- struct{}{}
+			<-ctx.Done()
+			return integration.UserListResponseDTO{}, ctx.Err()
+		case 3: // a probe that hangs until the end of the test
+			started <- struct{}{}
+			<-release
+			return integration.UserListResponseDTO{}, nil
+		}
+		return integration.UserListResponseDTO{}, errors.New("down")
+	}}
+	now := time.Now()
+	r := newResilientService("probe-gen-test", next, config.ResilienceConfig{BreakerFailureThreshold: 1, BreakerOpenTimeout: 1, BreakerHalfOpenMaxCalls: 2})
+	r.now = func() time.Time { return now }
+	var wg sync.WaitGroup
+	call := func(ctx context.Context, page int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = r.GetUsers(ctx, page)
+		}()
+		<-started
+	}
+	defer func() {
+		close(release)
+		wg.Wait()
+	}()
+
+	_, _ = r.GetUsers(context.Background(), 1)
+	require.Equal(t, integration.BreakerOpen, r.resilienceState().Breaker)
+
+	// a slow probe outlives its half-open period, which a failed probe ends
+	now = now.Add(2 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	call(ctx, 2)
+	_, _ = r.GetUsers(context.Background(), 1)
+	require.Equal(t, integration.BreakerOpen, r.resilienceState().Breaker)
+
+	// the next half-open period has its own two slots
+	now = now.Add(2 * time.Second)
+	call(context.Background(), 3)
+	cancel()
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.halfOpenCalls == 1
+	}, time.Second, time.Millisecond, "the earlier probe must not free a slot")
+	call(context.Background(), 3)
+
+	_, err := r.GetUsers(context.Background(), 1)
+	assertAppError(t, pkg.ErrProviderCircuitOpen, err)
+}
+
+func TestResilience_CallerCancellationIsNotAFailure(t *testing.T) {
+	next := &stubUserService{fn: func(ctx context.Context, _ int) (integration.UserListResponseDTO, error) {
+		return integration.UserListResponseDTO{}, ctx.Err()
+	}}
+	r := newResilientService("cancel-test", next, config.ResilienceConfig{BreakerFailureThreshold: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := r.GetUsers(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, integration.BreakerClosed, r.resilienceState().Breaker)
+}
+
+func TestResilience_BulkheadRejectsWhenSaturated(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	next := &stubUserService{fn: func(context.Context, int) (integration.UserListResponseDTO, error) {
+		started <- struct{}{}
+		<-release
+		return integration.UserListResponseDTO{}, nil
+	}}
+	r := newResilientService("bulkhead-test", next, config.ResilienceConfig{BulkheadMaxConcurrent: 1})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = r.GetUsers(context.Background(), 1)
+	}()
+	<-started
+
+	_, err := r.GetUsers(context.Background(), 1)
+	var appErr *pkg.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Message(), "saturated")
+	assert.Equal(t, 1, r.resilienceState().InFlight)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, 0, r.resilienceState().InFlight)
+}
@@ -0,0 +1,256 @@
+package integration
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	clienthttp "__MODULE__/internal/client/internal/http"
+	"__MODULE__/internal/client/internal/oauth"
+	"__MODULE__/internal/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// httpSettings is the resolved HTTP behaviour of a provider instance: the
+// provider type's env config overridden by the instance config.
+type httpSettings struct {
+	provider       string
+	baseURL        string
+	timeout        time.Duration // overall deadline of one GetUsers call
+	attemptTimeout time.Duration // deadline of a single attempt
+	retryCount     int
+	baseDelay      time.Duration
+	maxDelay       time.Duration
+	auth           authSettings
+	client         config.HTTPClientConfig
+}
+
+func (s httpSettings) withInstance(inst config.ProviderInstance) httpSettings {
+	if inst.BaseURL != "" {
+		s.baseURL = inst.BaseURL
+	}
+	if inst.Timeout > 0 {
+		s.timeout = time.Duration(inst.Timeout) * time.Second
+	}
+	if inst.AttemptTimeout > 0 {
+		s.attemptTimeout = time.Duration(inst.AttemptTimeout) * time.Second
+	}
+	if inst.RetryCount > 0 {
+		s.retryCount = inst.RetryCount
+	}
+	if inst.ProxyURL != "" {
+		s.client.ProxyURL = inst.ProxyURL
+	}
+	if inst.CACertFile != "" {
+		s.client.CACertFile = inst.CACertFile
+	}
+	s.auth = s.auth.withInstance(inst)
+	return s
+}
+
+// httpClient returns a client from the shared client stack whose timeout backs
+// up the per-attempt deadline. Instances with OAuth2 configured get a client
+// that attaches bearer tokens.
+func (s httpSettings) httpClient() (*http.Client, error) {
+	opts := clienthttp.Options{Name: s.provider, Timeout: s.attemptTimeout, HTTPClientConfig: s.client}
+	if s.auth.enabled() {
+		tokenClient, err := clienthttp.NewClient(clienthttp.Options{Name: s.provider + "-oauth", Timeout: s.attemptTimeout, HTTPClientConfig: s.client})
+		if err != nil {
+			return nil, err
+		}
+		source := s.auth.tokenSource(s.provider, tokenClient)
+		opts.Wrap = append(opts.Wrap, func(next http.RoundTripper) http.RoundTripper {
+			return &oauth.Transport{Source: source, Base: next}
+		})
+	}
+	return clienthttp.NewClient(opts)
+}
+
+// do sends the request built by newReq, retrying retryable failures with
+// exponential backoff and full jitter. The response body is read before the
+// attempt deadline is released, so callers get the bytes rather than a stream.
+func (s httpSettings) do(ctx context.Context, client *http.Client, newReq func(ctx context.Context) (*http.Request, error)) (*http.Response, []byte, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := s.attempt(ctx, client, newReq)
+
+		fields := log.Fields{"provider": s.provider, "attempt": attempt + 1}
+		if resp != nil {
+			fields["status"] = resp.StatusCode
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+
+		if !s.retryable(ctx, resp, err) || attempt >= s.retryCount {
+			log.WithFields(fields).Debug("provider request finished")
+			return resp, body, err
+		}
+
+		delay := s.backoff(attempt, resp)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// waiting would only run into the overall deadline
+			log.WithFields(fields).Warn("provider request failed, no time left to retry")
+			return resp, body, err
+		}
+		fields["retry_in"] = delay.String()
+		log.WithFields(fields).Warn("provider request failed, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, body, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s httpSettings) attempt(ctx context.Context, client *http.Client, newReq func(ctx context.Context) (*http.Request, error)) (*http.Response, []byte, error) {
+	if s.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.attemptTimeout)
+		defer cancel()
+	}
+	req, err := newReq(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// retryable reports whether the outcome of an attempt is worth another try.
+// Once the overall deadline is gone nothing is retried.
+func (s httpSettings) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		var netErr net.Error
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, context.DeadlineExceeded) ||
+			(errors.As(err, &netErr) && netErr.Timeout())
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the delay before the next attempt. A Retry-After header wins
+// over the computed delay, up to maxDelay so a provider cannot stall the call.
+func (s httpSettings) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, s.maxDelay)
+		}
+	}
+	ceiling := s.baseDelay << attempt
+	if ceiling <= 0 || ceiling > s.maxDelay {
+		ceiling = s.maxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// retryAfter parses a Retry-After value given either in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func reqresSettings(cfg config.App) httpSettings {
+	c := cfg.ReqresConfig
+	return httpSettings{
+		provider:       ReqresProvider,
+		baseURL:        cmp.Or(c.BaseUrl, "https://reqres.in"),
+		timeout:        time.Duration(c.Timeout) * time.Second,
+		attemptTimeout: time.Duration(c.AttemptTimeout) * time.Second,
+		retryCount:     c.RetryCount,
+		baseDelay:      time.Duration(c.RetryBaseDelay) * time.Millisecond,
+		maxDelay:       time.Duration(c.RetryMaxDelay) * time.Millisecond,
+		auth: authSettings{
+			tokenURL:        c.AuthenticationUrl,
+			grantType:       c.GrantType,
+			clientID:        c.ClientID,
+			clientSecret:    c.ClientSecret,
+			refreshInterval: time.Duration(c.RefreshTokenInterval) * time.Second,
+		},
+		client: cfg.HTTPClientConfig,
+	}
+}
+
+func jsonPlaceholderSettings(cfg config.App) httpSettings {
+	c := cfg.JsonplaceholderConfig
+	return httpSettings{
+		provider:       JsonPlaceholderProvider,
+		baseURL:        cmp.Or(c.BaseUrl, "https://jsonplaceholder.typicode.com"),
+		timeout:        time.Duration(c.Timeout) * time.Second,
+		attemptTimeout: time.Duration(c.AttemptTimeout) * time.Second,
+		retryCount:     c.RetryCount,
+		baseDelay:      time.Duration(c.RetryBaseDelay) * time.Millisecond,
+		maxDelay:       time.Duration(c.RetryMaxDelay) * time.Millisecond,
+		auth: authSettings{
+			tokenURL:        c.AuthenticationUrl,
+			grantType:       c.GrantType,
+			clientID:        c.ClientID,
+			clientSecret:    c.ClientSecret,
+			refreshInterval: time.Duration(c.RefreshTokenInterval) * time.Second,
+		},
+		client: cfg.HTTPClientConfig,
+	}
+}
+
+// httpJSONSettings are the defaults of http-json instances, which have no env
+// config of their own; everything else comes from the instance config.
+func httpJSONSettings(cfg config.App) httpSettings {
+	return httpSettings{
+		provider:       HTTPJSONProvider,
+		timeout:        30 * time.Second,
+		attemptTimeout: 10 * time.Second,
+		baseDelay:      200 * time.Millisecond,
+		maxDelay:       5 * time.Second,
+		client:         cfg.HTTPClientConfig,
+	}
+}
+
+// scimSettings are the defaults of scim instances, which like http-json ones
+// are configured per instance.
+func scimSettings(cfg config.App) httpSettings {
+	s := httpJSONSettings(cfg)
+	s.provider = SCIMProvider
+	return s
+}
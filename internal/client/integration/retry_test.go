@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSettings(retries int) httpSettings {
+	return httpSettings{
+		provider:       "test",
+		timeout:        5 * time.Second,
+		attemptTimeout: time.Second,
+		retryCount:     retries,
+		baseDelay:      time.Millisecond,
+		maxDelay:       5 * time.Millisecond,
+	}
+}
+
+func testClient(t *testing.T, s httpSettings) *http.Client {
+	client, err := s.httpClient()
+	require.NoError(t, err)
+	return client
+}
+
+func getRequest(url string) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+}
+
+func TestRetry_RetriesServerErrorsUntilSuccess(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	s := testSettings(3)
+	resp, body, err := s.do(context.Background(), testClient(t, s), getRequest(srv.URL))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, int32(3), hits.Load())
+}
+
+func TestRetry_HonoursRetryAfter(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := testSettings(1)
+	s.maxDelay = 2 * time.Second
+	start := time.Now()
+	resp, _, err := s.do(context.Background(), testClient(t, s), getRequest(srv.URL))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestRetry_CapsRetryAfterAtMaxDelay(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := testSettings(1)
+	s.timeout = 0
+	start := time.Now()
+	resp, _, err := s.do(context.Background(), testClient(t, s), getRequest(srv.URL))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetry_DoesNotRetryClientErrors(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s := testSettings(3)
+	resp, _, err := s.do(context.Background(), testClient(t, s), getRequest(srv.URL))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, int32(1), hits.Load())
+}
+
+func TestRetry_GivesUpAfterRetryCount(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	s := testSettings(2)
+	resp, _, err := s.do(context.Background(), testClient(t, s), getRequest(srv.URL))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(3), hits.Load())
+}
+
+func TestRetry_AttemptTimeoutIsRetried(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := testSettings(1)
+	s.attemptTimeout = 50 * time.Millisecond
+	resp, _, err := s.do(context.Background(), &http.Client{}, getRequest(srv.URL))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestReqresFactory_UsesConfiguredBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/users", r.URL.Path)
+		assert.Equal(t, "k", r.Header.Get("x-api-key"))
+		_, _ = w.Write([]byte(`{"page":1,"per_page":1,"total":1,"total_pages":1,"data":[{"id":7,"email":"a@b.c","first_name":"A","last_name":"B"}]}`))
+	}))
+	defer srv.Close()
+
+	inst := config.ProviderInstance{ID: "t", Type: ReqresProvider, BaseURL: srv.URL, Credentials: config.ProviderCredentials{APIKey: "k"}}
+	svc, err := userRegistry[ReqresProvider](config.App{}, inst.String())
+	require.NoError(t, err)
+
+	resp, err := svc.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, resp.Users, 1)
+	assert.Equal(t, "7", string(resp.Users[0].ID))
+	assert.Equal(t, 1, resp.Meta.TotalPages)
+}
@@ -0,0 +1,281 @@
+package integration
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+)
+
+const SCIMProvider = "scim"
+
+// scimService pages the /Users endpoint of a SCIM 2.0 directory. Servers may
+// return fewer users than asked for, so each page starts where the previous
+// one ended rather than at a multiple of the configured count.
+type scimService struct {
+	settings   httpSettings
+	httpClient *http.Client
+	cfg        config.SCIMConfig
+	creds      config.ProviderCredentials
+
+	mu     sync.Mutex
+	starts map[int]int // startIndex requesting page n, learnt while paging; 0 past the last page
+}
+
+func init() {
+	RegisterUserServiceFactory(SCIMProvider, func(cfg config.App, providerConfig string) (interfaces.UserService, error) {
+		inst, err := config.ParseProviderInstance(providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		if inst.BaseURL == "" {
+			return nil, fmt.Errorf("scim provider %q: base_url is required", inst.ID)
+		}
+		svc := &scimService{settings: scimSettings(cfg).withInstance(inst), creds: inst.Credentials, starts: map[int]int{}}
+		if inst.SCIM != nil {
+			svc.cfg = *inst.SCIM
+		}
+		if svc.cfg.Count <= 0 {
+			svc.cfg.Count = 100
+		}
+		if inst.ID != "" {
+			svc.settings.provider = SCIMProvider + ":" + inst.ID
+		}
+		if svc.httpClient, err = svc.settings.httpClient(); err != nil {
+			return nil, err
+		}
+		return svc, nil
+	})
+	RegisterWebhookParser(SCIMProvider, parseSCIMWebhook)
+}
+
+// parseSCIMWebhook reads deliveries whose users are SCIM User resources. An
+// update that sets active to false deactivates the user.
+func parseSCIMWebhook(_ config.ProviderInstance, body []byte) ([]integration.WebhookEventDTO, error) {
+	inactive := map[string]bool{}
+	events, err := parseWebhookEvents(body, func(raw json.RawMessage) (integration.UserDTO, error) {
+		var u scimUser
+		if err := json.Unmarshal(raw, &u); err != nil {
+			return integration.UserDTO{}, err
+		}
+		if u.Active != nil && !*u.Active {
+			inactive[u.ID] = true
+		}
+		return u.toDTO(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range events {
+		if e.Type == integration.WebhookUserUpdated && inactive[string(e.User.ID)] {
+			events[i].Type = integration.WebhookUserDeactivated
+		}
+	}
+	return events, nil
+}
+
+func (s *scimService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	page = max(page, 1)
+	start, ok, err := s.startFor(ctx, page)
+	if err != nil {
+		return integration.UserListResponseDTO{}, err
+	}
+	if !ok {
+		// the previous page was the last one
+		return integration.UserListResponseDTO{Provider: SCIMProvider, Meta: integration.MetaInfoDTO{Page: page}}, nil
+	}
+	query := url.Values{}
+	query.Set("startIndex", strconv.Itoa(start))
+	query.Set("count", strconv.Itoa(s.cfg.Count))
+	if s.cfg.Filter != "" {
+		query.Set("filter", s.cfg.Filter)
+	}
+	if len(s.cfg.Attributes) > 0 {
+		query.Set("attributes", strings.Join(s.cfg.Attributes, ","))
+	}
+	reqURL := strings.TrimSuffix(s.settings.baseURL, "/") + "/Users?" + query.Encode()
+
+	resp, body, err := s.settings.do(ctx, s.httpClient, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/scim+json, application/json")
+		switch {
+		case s.settings.auth.enabled():
+			// the OAuth2 transport attaches the token
+		case s.creds.Token != "":
+			req.Header.Set("Authorization", "Bearer "+s.creds.Token)
+		case s.creds.Username != "":
+			req.SetBasicAuth(s.creds.Username, s.creds.Password)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return integration.UserListResponseDTO{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var scimErr scimError
+		if json.Unmarshal(body, &scimErr) == nil && scimErr.Detail != "" {
+			return integration.UserListResponseDTO{Provider: SCIMProvider, Raw: body}, fmt.Errorf("%s: status %d: %s", s.settings.provider, resp.StatusCode, scimErr.Detail)
+		}
+		return integration.UserListResponseDTO{Provider: SCIMProvider, Raw: body}, fmt.Errorf("%s: status %d", s.settings.provider, resp.StatusCode)
+	}
+
+	var parsed scimListResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return integration.UserListResponseDTO{Provider: SCIMProvider, Raw: body}, fmt.Errorf("%s: decode response: %w", s.settings.provider, err)
+	}
+
+	users := make([]integration.UserDTO, 0, len(parsed.Resources))
+	for _, u := range parsed.Resources {
+		users = append(users, u.toDTO())
+	}
+
+	// the server may cap the page size below the configured count
+	served := cmp.Or(parsed.ItemsPerPage, len(parsed.Resources))
+	next := cmp.Or(parsed.StartIndex, start) + served
+	if served == 0 || next > parsed.TotalResults {
+		next = 0
+	}
+	s.mu.Lock()
+	if page == 1 {
+		// a new walk, the directory may have changed since the last one
+		clear(s.starts)
+	}
+	s.starts[page+1] = next
+	s.mu.Unlock()
+
+	meta := integration.MetaInfoDTO{Page: page, PerPage: served, Total: parsed.TotalResults}
+	if served > 0 {
+		meta.TotalPages = (parsed.TotalResults + served - 1) / served
+	}
+	return integration.UserListResponseDTO{Provider: SCIMProvider, Users: users, Meta: meta, Raw: body}, nil
+}
+
+// startFor returns the startIndex requesting page, walking forward from the
+// last page whose start is known. ok is false past the last page.
+func (s *scimService) startFor(ctx context.Context, page int) (int, bool, error) {
+	if page == 1 {
+		return 1, true, nil
+	}
+	for {
+		s.mu.Lock()
+		start, known := s.starts[page]
+		from := page - 1
+		for from > 1 {
+			if _, ok := s.starts[from]; ok {
+				break
+			}
+			from--
+		}
+		s.mu.Unlock()
+
+		if known {
+			return start, start > 0, nil
+		}
+		// fetching the closest reachable page records the start of the next one
+		res, err := s.GetUsers(ctx, from)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(res.Users) == 0 {
+			return 0, false, nil
+		}
+	}
+}
+
+// toDTO maps the core attributes onto the user and everything else worth
+// keeping, enterprise extension included, into Extra. A user with active set
+// to false is inactive.
+func (u scimUser) toDTO() integration.UserDTO {
+	name := u.DisplayName
+	if name == "" {
+		name = u.Name.Formatted
+	}
+	if name == "" {
+		name = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+	}
+
+	extra := map[string]string{}
+	set := func(k, v string) {
+		if v != "" {
+			extra[k] = v
+		}
+	}
+	set("external_id", u.ExternalID)
+	set("given_name", u.Name.GivenName)
+	set("family_name", u.Name.FamilyName)
+	set("nick_name", u.NickName)
+	set("title", u.Title)
+	set("user_type", u.UserType)
+	set("preferred_language", u.PreferredLanguage)
+	set("locale", u.Locale)
+	set("timezone", u.Timezone)
+	if u.Active != nil {
+		set("active", strconv.FormatBool(*u.Active))
+	}
+	set("avatar", primaryValue(u.Photos))
+	if addr, ok := primaryAddress(u.Addresses); ok {
+		set("city", addr.Locality)
+		set("country", addr.Country)
+	}
+	if e := u.Enterprise; e != nil {
+		set("employee_number", e.EmployeeNumber)
+		set("cost_center", e.CostCenter)
+		set("company", e.Organization)
+		set("division", e.Division)
+		set("department", e.Department)
+		set("manager_id", e.Manager.Value)
+		set("manager_name", e.Manager.DisplayName)
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+
+	return integration.UserDTO{
+		ID:       user.ID(u.ID),
+		Name:     user.FullName(name),
+		Username: user.Username(u.UserName),
+		Email:    user.Email(primaryValue(u.Emails)),
+		Phone:    user.Phone(primaryValue(u.PhoneNumbers)),
+		Website:  user.Website(u.ProfileURL),
+		Extra:    extra,
+		Active:   u.Active,
+	}
+}
+
+// primaryValue returns the value marked primary, else the first one.
+func primaryValue(values []scimMultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func primaryAddress(addrs []scimAddress) (scimAddress, bool) {
+	for _, a := range addrs {
+		if a.Primary {
+			return a, true
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs[0], true
+	}
+	return scimAddress{}, false
+}
@@ -0,0 +1,82 @@
+package integration
+
+// SCIM 2.0 user structure (RFC 7643), core schema plus the enterprise extension
+const (
+	scimListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimEnterpriseSchema   = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+)
+
+type scimListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int        `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []scimUser `json:"Resources"`
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted"`
+	FamilyName string `json:"familyName"`
+	GivenName  string `json:"givenName"`
+	MiddleName string `json:"middleName"`
+}
+
+// scimMultiValue is an entry of emails, phoneNumbers and the like.
+type scimMultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+	Primary bool   `json:"primary"`
+}
+
+type scimAddress struct {
+	Formatted     string `json:"formatted"`
+	StreetAddress string `json:"streetAddress"`
+	Locality      string `json:"locality"`
+	Region        string `json:"region"`
+	PostalCode    string `json:"postalCode"`
+	Country       string `json:"country"`
+	Type          string `json:"type"`
+	Primary       bool   `json:"primary"`
+}
+
+type scimManager struct {
+	Value       string `json:"value"`
+	DisplayName string `json:"displayName"`
+}
+
+type scimEnterpriseUser struct {
+	EmployeeNumber string      `json:"employeeNumber"`
+	CostCenter     string      `json:"costCenter"`
+	Organization   string      `json:"organization"`
+	Division       string      `json:"division"`
+	Department     string      `json:"department"`
+	Manager        scimManager `json:"manager"`
+}
+
+type scimUser struct {
+	ID                string              `json:"id"`
+	ExternalID        string              `json:"externalId"`
+	UserName          string              `json:"userName"`
+	Name              scimName            `json:"name"`
+	DisplayName       string              `json:"displayName"`
+	NickName          string              `json:"nickName"`
+	ProfileURL        string              `json:"profileUrl"`
+	Title             string              `json:"title"`
+	UserType          string              `json:"userType"`
+	PreferredLanguage string              `json:"preferredLanguage"`
+	Locale            string              `json:"locale"`
+	Timezone          string              `json:"timezone"`
+	Active            *bool               `json:"active"`
+	Emails            []scimMultiValue    `json:"emails"`
+	PhoneNumbers      []scimMultiValue    `json:"phoneNumbers"`
+	Photos            []scimMultiValue    `json:"photos"`
+	Addresses         []scimAddress       `json:"addresses"`
+	Enterprise        *scimEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+// scimError is the error body of a SCIM endpoint.
+type scimError struct {
+	Status   string `json:"status"`
+	ScimType string `json:"scimType"`
+	Detail   string `json:"detail"`
+}
@@ -0,0 +1,206 @@
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"__MODULE__/internal/client/pagination"
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scimStandIn is a minimal SCIM directory: /Users with startIndex/count
+// paging, `attr eq value` filters joined by "and", and a fixed authorization.
+type scimStandIn struct {
+	users         []map[string]any
+	authorization string
+	maxCount      int // caps the page size when set, as servers may
+	queries       []string
+}
+
+func (d *scimStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	fail := func(status int, scimType, detail string) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
+			"status":  strconv.Itoa(status), "scimType": scimType, "detail": detail,
+		})
+	}
+	if r.Header.Get("Authorization") != d.authorization {
+		fail(http.StatusUnauthorized, "", "invalid credentials")
+		return
+	}
+	if r.URL.Path != "/scim/v2/Users" {
+		fail(http.StatusNotFound, "", "no such endpoint")
+		return
+	}
+	q := r.URL.Query()
+	d.queries = append(d.queries, q.Encode())
+
+	matched := d.users
+	if filter := q.Get("filter"); filter != "" {
+		matched = nil
+		for _, u := range d.users {
+			ok := true
+			for _, clause := range strings.Split(filter, " and ") {
+				parts := strings.SplitN(clause, " ", 3)
+				if len(parts) != 3 || parts[1] != "eq" {
+					fail(http.StatusBadRequest, "invalidFilter", "unsupported filter "+clause)
+					return
+				}
+				ok = ok && fmt.Sprint(u[parts[0]]) == strings.Trim(parts[2], `"`)
+			}
+			if ok {
+				matched = append(matched, u)
+			}
+		}
+	}
+	start, _ := strconv.Atoi(q.Get("startIndex"))
+	count, _ := strconv.Atoi(q.Get("count"))
+	if d.maxCount > 0 {
+		count = min(count, d.maxCount)
+	}
+	from := min(max(start, 1)-1, len(matched))
+	page := matched[from:min(from+count, len(matched))]
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"schemas":      []string{scimListResponseSchema},
+		"totalResults": len(matched),
+		"startIndex":   start,
+		"itemsPerPage": len(page),
+		"Resources":    page,
+	})
+}
+
+func scimUsers() []map[string]any {
+	return []map[string]any{
+		{
+			"id": "2819c223", "externalId": "701984", "userName": "bjensen@example.com", "active": true,
+			"name":        map[string]any{"formatted": "Ms. Barbara J Jensen, III", "givenName": "Barbara", "familyName": "Jensen"},
+			"displayName": "Babs Jensen", "title": "Tour Guide", "profileUrl": "https://login.example.com/bjensen",
+			"emails":       []map[string]any{{"value": "bjensen@example.com", "type": "work"}, {"value": "babs@jensen.org", "type": "home", "primary": true}},
+			"phoneNumbers": []map[string]any{{"value": "555-555-5555", "type": "work"}},
+			"addresses":    []map[string]any{{"locality": "Hollywood", "country": "USA", "primary": true}},
+			scimEnterpriseSchema: map[string]any{
+				"employeeNumber": "701984", "organization": "Universal Studios", "department": "Tour Operations",
+				"manager": map[string]any{"value": "26118915", "displayName": "John Smith"},
+			},
+		},
+		{"id": "3", "userName": "mpepper", "active": false, "name": map[string]any{"givenName": "Mark", "familyName": "Pepper"}},
+		{"id": "4", "userName": "jdoe", "active": true},
+	}
+}
+
+func newSCIM(t *testing.T, id, baseURL string, cfg *config.SCIMConfig, creds config.ProviderCredentials) interfaces.UserService {
+	t.Helper()
+	inst := config.ProviderInstance{ID: id, Type: SCIMProvider, BaseURL: baseURL, Credentials: creds, SCIM: cfg}
+	svc := NewUserProviderService(config.App{})
+	require.NoError(t, svc.RegisterNewProvider(id, SCIMProvider, inst.String()))
+	p, err := svc.GetUserService(id)
+	require.NoError(t, err)
+	return p
+}
+
+func TestSCIM_PagesUsersWithBearerAuth(t *testing.T) {
+	dir := &scimStandIn{users: scimUsers(), authorization: "Bearer t0k3n"}
+	srv := httptest.NewServer(dir)
+	defer srv.Close()
+
+	p := newSCIM(t, "scim-bearer", srv.URL+"/scim/v2/", &config.SCIMConfig{Count: 2}, config.ProviderCredentials{Token: "t0k3n"})
+
+	res, err := p.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Meta.TotalPages)
+	assert.Equal(t, 3, res.Meta.Total)
+	require.Len(t, res.Users, 2)
+
+	u := res.Users[0]
+	assert.Equal(t, "2819c223", string(u.ID))
+	assert.Equal(t, "Babs Jensen", string(u.Name))
+	assert.Equal(t, "bjensen@example.com", string(u.Username))
+	assert.Equal(t, "babs@jensen.org", string(u.Email), "the primary email wins")
+	assert.Equal(t, "555-555-5555", string(u.Phone))
+	assert.Equal(t, "https://login.example.com/bjensen", string(u.Website))
+	assert.Equal(t, map[string]string{
+		"external_id": "701984", "given_name": "Barbara", "family_name": "Jensen", "title": "Tour Guide", "active": "true",
+		"city": "Hollywood", "country": "USA",
+		"employee_number": "701984", "company": "Universal Studios", "department": "Tour Operations",
+		"manager_id": "26118915", "manager_name": "John Smith",
+	}, u.Extra)
+	assert.Equal(t, "Mark Pepper", string(res.Users[1].Name))
+	assert.Equal(t, pkg.PtrBool(true), u.Active)
+	assert.Equal(t, pkg.PtrBool(false), res.Users[1].Active)
+
+	users, err := pagination.Collect(context.Background(), p, pagination.Options{})
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+	assert.Contains(t, dir.queries, "count=2&startIndex=3")
+}
+
+func TestSCIM_PagesByServedPageSize(t *testing.T) {
+	users := make([]map[string]any, 5)
+	for i := range users {
+		users[i] = map[string]any{"id": strconv.Itoa(i + 1), "userName": "u" + strconv.Itoa(i+1)}
+	}
+	dir := &scimStandIn{users: users, authorization: "Bearer t", maxCount: 2}
+	srv := httptest.NewServer(dir)
+	defer srv.Close()
+
+	p := newSCIM(t, "scim-capped", srv.URL+"/scim/v2", &config.SCIMConfig{Count: 100}, config.ProviderCredentials{Token: "t"})
+	got, err := pagination.Collect(context.Background(), p, pagination.Options{Prefetch: 3})
+	require.NoError(t, err)
+	ids := make([]string, 0, len(got))
+	for _, u := range got {
+		ids = append(ids, string(u.ID))
+	}
+	assert.ElementsMatch(t, []string{"1", "2", "3", "4", "5"}, ids)
+
+	// past the last page
+	res, err := p.GetUsers(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Empty(t, res.Users)
+}
+
+func TestSCIM_FilterAndBasicAuth(t *testing.T) {
+	dir := &scimStandIn{users: scimUsers(), authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte("svc:s3cret"))}
+	srv := httptest.NewServer(dir)
+	defer srv.Close()
+
+	p := newSCIM(t, "scim-basic", srv.URL+"/scim/v2", &config.SCIMConfig{Filter: `active eq true and userName eq "jdoe"`, Attributes: []string{"userName", "emails"}},
+		config.ProviderCredentials{Username: "svc", Password: "s3cret"})
+
+	res, err := p.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, res.Users, 1)
+	assert.Equal(t, "jdoe", string(res.Users[0].Username))
+	assert.Contains(t, dir.queries[0], "attributes=userName%2Cemails")
+}
+
+func TestSCIM_ErrorResponses(t *testing.T) {
+	dir := &scimStandIn{users: scimUsers(), authorization: "Bearer right"}
+	srv := httptest.NewServer(dir)
+	defer srv.Close()
+
+	_, err := newSCIM(t, "scim-denied", srv.URL+"/scim/v2", nil, config.ProviderCredentials{Token: "wrong"}).GetUsers(context.Background(), 1)
+	assert.ErrorContains(t, err, "status 401: invalid credentials")
+
+	_, err = newSCIM(t, "scim-bad-filter", srv.URL+"/scim/v2", &config.SCIMConfig{Filter: `userName co "j"`}, config.ProviderCredentials{Token: "right"}).GetUsers(context.Background(), 1)
+	assert.ErrorContains(t, err, "unsupported filter")
+}
+
+func TestSCIM_RequiresBaseURL(t *testing.T) {
+	svc := NewUserProviderService(config.App{})
+	inst := config.ProviderInstance{ID: "x", Type: SCIMProvider}
+	assert.Error(t, svc.RegisterNewProvider("x", SCIMProvider, inst.String()))
+}
@@ -1,31 +1,72 @@
 package integration
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
 	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
 	"__MODULE__/internal/interfaces"
 	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type UserServiceFactory func(config.App, string) (interfaces.UserService, error)
 
-var userRegistry = make(map[string]UserServiceFactory)
+var (
+	userRegistry   = make(map[string]UserServiceFactory)
+	userRegistryMu sync.RWMutex
+)
 
 func RegisterUserServiceFactory(providerName string, factory UserServiceFactory) {
+	userRegistryMu.Lock()
+	defer userRegistryMu.Unlock()
 	userRegistry[providerName] = factory
 }
 
+// SupportedProviders returns the sorted names of every registered provider type.
+func SupportedProviders() []string {
+	userRegistryMu.RLock()
+	defer userRegistryMu.RUnlock()
+	names := make([]string, 0, len(userRegistry))
+	for name := range userRegistry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func lookupFactory(providerName string) (UserServiceFactory, bool) {
+	userRegistryMu.RLock()
+	defer userRegistryMu.RUnlock()
+	factory, ok := userRegistry[providerName]
+	return factory, ok
+}
+
 // Provider descriptor (same shape as your other provider types)
 type Provider struct {
-	Name string
+	ID           string
+	Name         string
+	Config       string
+	Status       integration.ProviderStatus
+	RegisteredAt time.Time
 }
 
 type userProviderService struct {
-	config         config.App
+	config config.App
+	// mu guards UserServiceMap and Providers; use the methods below rather than
+	// touching the fields directly.
+	mu             sync.RWMutex
 	UserServiceMap map[string]interfaces.UserService
 	Providers      []Provider
 }
 
-var _ interfaces.ProviderService = (*userProviderService)(nil) // optional assert if your ProviderService interface aligns
+var _ interfaces.ProviderService = (*userProviderService)(nil)
 
 // NewUserProviderService returns a manager for user providers.
 func NewUserProviderService(cfg config.App) *userProviderService {
@@ -36,32 +77,248 @@ func NewUserProviderService(cfg config.App) *userProviderService {
 	}
 }
 
-// RegisterNewProvider creates & registers a new user provider instance
+// SupportedProviders returns the provider types known to the registry.
+func (u *userProviderService) SupportedProviders() []string {
+	return SupportedProviders()
+}
+
+// ListProviders returns a snapshot of every registered instance.
+func (u *userProviderService) ListProviders() []integration.ProviderInstanceDTO {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	out := make([]integration.ProviderInstanceDTO, 0, len(u.Providers))
+	for _, p := range u.Providers {
+		dto := integration.ProviderInstanceDTO{
+			ID:           p.ID,
+			Type:         p.Name,
+			Config:       redactProviderConfig(p.Config),
+			Status:       p.Status,
+			RegisteredAt: p.RegisteredAt,
+		}
+		if r, ok := u.UserServiceMap[p.ID].(resilienceReporter); ok && p.Status == integration.ProviderStatusActive {
+			state := r.resilienceState()
+			dto.Resilience = &state
+		}
+		out = append(out, dto)
+	}
+	return out
+}
+
+// RegisterNewProvider creates & registers a new user provider instance at runtime
 // id - unique id in your system, providerName - "reqres" or "jsonplaceholder", providerConfig optional.
+// Plugin instances run local executables and can only be declared in
+// configuration, see RegisterInstances.
 func (u *userProviderService) RegisterNewProvider(id string, providerName string, providerConfig string) error {
-	factory, ok := userRegistry[providerName]
-	if !ok {
-		// return pkg.ErrProviderRegistration.AddDescription(fmt.Sprintf("unrecognized user provider: %s", providerName))
-		return pkg.NewAppError(pkg.ErrBadRequest)
+	if err := runtimeProvider(providerName); err != nil {
+		return err
 	}
-	svc, err := factory(u.config, providerConfig)
+	return u.register(id, providerName, providerConfig)
+}
+
+func (u *userProviderService) register(id string, providerName string, providerConfig string) error {
+	if _, ok := lookupFactory(providerName); !ok {
+		return pkg.NewAppError(pkg.ErrUnknownProvider).AddDescription([]byte(providerName)).AppendStackLog()
+	}
+	// checked before building so a duplicate never starts a plugin process
+	u.mu.RLock()
+	_, exists := u.UserServiceMap[id]
+	u.mu.RUnlock()
+	if exists {
+		return pkg.NewAppError(pkg.ErrProviderAlreadyExists).AddDescription([]byte(id)).AppendStackLog()
+	}
+
+	svc, err := u.build(id, providerName, providerConfig)
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	if _, ok := u.UserServiceMap[id]; ok {
+		// a concurrent registration of id won
+		u.mu.Unlock()
+		closeService(id, svc)
+		return pkg.NewAppError(pkg.ErrProviderAlreadyExists).AddDescription([]byte(id)).AppendStackLog()
+	}
+	u.UserServiceMap[id] = svc
+	u.setProvider(Provider{ID: id, Name: providerName, Config: providerConfig, Status: integration.ProviderStatusActive, RegisteredAt: time.Now()})
+	u.mu.Unlock()
+	return nil
+}
+
+// ReplaceProvider builds a new instance and swaps it in under id. The old instance
+// keeps serving until the new one has been built successfully. Like
+// RegisterNewProvider, it cannot create plugin instances.
+func (u *userProviderService) ReplaceProvider(id string, providerName string, providerConfig string) error {
+	if err := runtimeProvider(providerName); err != nil {
+		return err
+	}
+	u.mu.RLock()
+	_, known := u.indexOf(id)
+	u.mu.RUnlock()
+	if !known {
+		return pkg.NewAppError(pkg.ErrProviderNotFound).AddDescription([]byte(id)).AppendStackLog()
+	}
+
+	svc, err := u.build(id, providerName, providerConfig)
 	if err != nil {
 		return err
 	}
+
+	u.mu.Lock()
+	old := u.UserServiceMap[id]
 	u.UserServiceMap[id] = svc
-	u.Providers = append(u.Providers, Provider{Name: providerName})
+	u.setProvider(Provider{ID: id, Name: providerName, Config: providerConfig, Status: integration.ProviderStatusActive, RegisteredAt: time.Now()})
+	u.mu.Unlock()
+	closeService(id, old)
 	return nil
 }
 
 func (u *userProviderService) GetUserService(id string) (interfaces.UserService, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
 	svc, ok := u.UserServiceMap[id]
 	if !ok {
-		// return nil, pkg.ErrBankServiceNotActive.AddStack() // reuse existing error or define a new one
-		return nil, pkg.NewAppError(pkg.ErrBadRequest)
+		return nil, pkg.NewAppError(pkg.ErrProviderNotFound).AddDescription([]byte(id)).AppendStackLog()
 	}
 	return svc, nil
 }
 
-func (u *userProviderService) StopUserService(id string) {
+// StopUserService removes the running instance. The instance stays listed with a
+// stopped status so operators can see what was taken down.
+func (u *userProviderService) StopUserService(id string) error {
+	u.mu.Lock()
+	svc, ok := u.UserServiceMap[id]
+	if !ok {
+		u.mu.Unlock()
+		return pkg.NewAppError(pkg.ErrProviderNotFound).AddDescription([]byte(id)).AppendStackLog()
+	}
 	delete(u.UserServiceMap, id)
+	if i, ok := u.indexOf(id); ok {
+		u.Providers[i].Status = integration.ProviderStatusStopped
+	}
+	u.mu.Unlock()
+	closeService(id, svc)
+	return nil
+}
+
+// RegisterInstances registers every instance declared in configuration,
+// plugin instances included.
+func (u *userProviderService) RegisterInstances(instances config.ProviderInstances) error {
+	for _, inst := range instances {
+		if err := u.register(inst.ID, inst.Type, inst.String()); err != nil {
+			return fmt.Errorf("register provider %q: %w", inst.ID, err)
+		}
+	}
+	return nil
+}
+
+// UserServiceRouter returns a UserService that looks up the instance on every
+// call: the one selected in the request context (see pkg.WithProvider) or
+// defaultID otherwise. Replacing an instance therefore takes effect immediately.
+func (u *userProviderService) UserServiceRouter(defaultID string) interfaces.UserService {
+	return &userServiceRouter{providers: u, defaultID: defaultID}
+}
+
+// build creates an instance of providerName wrapped in its own circuit breaker
+// and bulkhead. Composite instances are bound to the registry so they can
+// reach their members.
+func (u *userProviderService) build(id string, providerName string, providerConfig string) (interfaces.UserService, error) {
+	factory, ok := lookupFactory(providerName)
+	if !ok {
+		return nil, pkg.NewAppError(pkg.ErrUnknownProvider).AddDescription([]byte(providerName)).AppendStackLog()
+	}
+	inst, err := config.ParseProviderInstance(providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	// factories name their clients, metrics and cassettes after the instance,
+	// so they always see the id it is registered under
+	if inst.ID == "" {
+		inst.ID, inst.Type = id, providerName
+		providerConfig = inst.String()
+	}
+	svc, err := factory(u.config, providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := svc.(providerBinder); ok {
+		if err := b.bindProviders(id, u); err != nil {
+			return nil, err
+		}
+	}
+	return newResilientService(id, svc, inst.Resilience.Apply(u.config.ResilienceConfig)), nil
+}
+
+// configOnlyProviders are the provider types that may only be declared in
+// configuration: plugins run commands and file instances read local paths, so
+// the admin API must not be able to point them anywhere.
+var configOnlyProviders = map[string]bool{PluginProvider: true, FileProvider: true}
+
+// runtimeProvider rejects the provider types that may only be declared in
+// configuration.
+func runtimeProvider(providerName string) error {
+	if configOnlyProviders[providerName] {
+		return pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte(providerName + " providers can only be declared in PROVIDER_INSTANCES")).AppendStackLog()
+	}
+	return nil
+}
+
+// closeService releases an instance taken out of service. It is called
+// without mu held since closing may wait for a process to exit.
+func closeService(id string, svc interfaces.UserService) {
+	c, ok := svc.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := c.Close(); err != nil {
+		log.WithError(err).WithField("provider", id).Warn("close provider instance")
+	}
+}
+
+// redactProviderConfig masks credentials before a config leaves the service.
+func redactProviderConfig(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	inst, err := config.ParseProviderInstance(raw)
+	if err != nil {
+		return ""
+	}
+	return inst.Redacted().String()
+}
+
+// indexOf must be called with mu held.
+func (u *userProviderService) indexOf(id string) (int, bool) {
+	for i, p := range u.Providers {
+		if p.ID == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// setProvider must be called with mu held.
+func (u *userProviderService) setProvider(p Provider) {
+	if i, ok := u.indexOf(p.ID); ok {
+		u.Providers[i] = p
+		return
+	}
+	u.Providers = append(u.Providers, p)
+}
+
+type userServiceRouter struct {
+	providers *userProviderService
+	defaultID string
+}
+
+func (r *userServiceRouter) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	id := r.defaultID
+	if selected, ok := pkg.ProviderFromContext(ctx); ok {
+		id = selected
+	}
+	svc, err := r.providers.GetUserService(id)
+	if err != nil {
+		return integration.UserListResponseDTO{}, err
+	}
+	return svc.GetUsers(ctx, page)
 }
@@ -2,18 +2,25 @@ package integration
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
+	clienthttp "__MODULE__/internal/client/internal/http"
 	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/pkg"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
-// Run real external integration tests only when SKIP_REAL_EXTERNAL_TESTS != "1".
-// This is useful to avoid flaky CI when external services are unreachable.
+// The "real" provider tests replay the exchanges recorded in testdata/cassettes
+// and need no network. HTTP_CLIENT_CASSETTE_MODE=record re-records them against
+// the live providers, HTTP_CLIENT_CASSETTE_MODE=live calls them without a
+// cassette. Live traffic is skipped when SKIP_REAL_EXTERNAL_TESTS == "1".
 
 type UserProviderRealSuite struct {
 	suite.Suite
@@ -23,11 +30,19 @@ type UserProviderRealSuite struct {
 
 func (s *UserProviderRealSuite) SetupSuite() {
 	s.cfg = config.App{}
+	switch mode := os.Getenv("HTTP_CLIENT_CASSETTE_MODE"); mode {
+	case "live":
+	case "":
+		s.cfg.CassetteMode = clienthttp.CassetteReplay
+	default:
+		s.cfg.CassetteMode = mode
+	}
+	s.cfg.CassetteDir = "testdata/cassettes"
 	s.svc = NewUserProviderService(s.cfg)
 }
 
 func (s *UserProviderRealSuite) shouldSkip() bool {
-	return os.Getenv("SKIP_REAL_EXTERNAL_TESTS") == "1"
+	return s.cfg.CassetteMode != clienthttp.CassetteReplay && os.Getenv("SKIP_REAL_EXTERNAL_TESTS") == "1"
 }
 
 func (s *UserProviderRealSuite) TestRegisterUnknownProvider() {
@@ -48,13 +63,142 @@ func (s *UserProviderRealSuite) TestRegisterReqresProviderExists() {
 func (s *UserProviderRealSuite) TestStopUserService() {
 	_ = s.svc.RegisterNewProvider("stop-test", ReqresProvider, "")
 
-	s.svc.StopUserService("stop-test")
+	assert.NoError(s.T(), s.svc.StopUserService("stop-test"))
 
 	_, err := s.svc.GetUserService("stop-test")
 	assert.Error(s.T(), err)
 }
 
-// ----- Real external calls -----
+func (s *UserProviderRealSuite) TestRegisterDuplicateProvider() {
+	assert.NoError(s.T(), s.svc.RegisterNewProvider("dup", ReqresProvider, ""))
+	err := s.svc.RegisterNewProvider("dup", JsonPlaceholderProvider, "")
+	assert.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "already exists")
+}
+
+func (s *UserProviderRealSuite) TestReplaceProvider() {
+	assert.NoError(s.T(), s.svc.RegisterNewProvider("swap", ReqresProvider, ""))
+	ref := s.svc.UserServiceRouter("swap")
+
+	assert.NoError(s.T(), s.svc.ReplaceProvider("swap", JsonPlaceholderProvider, ""))
+
+	p, err := s.svc.GetUserService("swap")
+	assert.NoError(s.T(), err)
+	assert.IsType(s.T(), &jsonPlaceholderService{}, p.(*resilientService).next)
+	assert.NotNil(s.T(), ref)
+
+	err = s.svc.ReplaceProvider("missing", ReqresProvider, "")
+	assert.Error(s.T(), err)
+}
+
+func (s *UserProviderRealSuite) TestListProvidersReportsStatus() {
+	assert.NoError(s.T(), s.svc.RegisterNewProvider("listed", ReqresProvider, ""))
+	assert.NoError(s.T(), s.svc.StopUserService("listed"))
+
+	var found bool
+	for _, p := range s.svc.ListProviders() {
+		if p.ID == "listed" {
+			found = true
+			assert.Equal(s.T(), ReqresProvider, p.Type)
+			assert.Equal(s.T(), integration.ProviderStatusStopped, p.Status)
+		}
+	}
+	assert.True(s.T(), found)
+	assert.Contains(s.T(), s.svc.SupportedProviders(), ReqresProvider)
+	assert.Contains(s.T(), s.svc.SupportedProviders(), JsonPlaceholderProvider)
+}
+
+func (s *UserProviderRealSuite) TestConcurrentAccess() {
+	const workers = 20
+	errs := make([][]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("concurrent-%d", i)
+			errs[i] = append(errs[i], s.svc.RegisterNewProvider(id, ReqresProvider, ""))
+			_, err := s.svc.GetUserService(id)
+			errs[i] = append(errs[i], err)
+			_ = s.svc.ListProviders()
+			errs[i] = append(errs[i], s.svc.ReplaceProvider(id, JsonPlaceholderProvider, ""))
+			errs[i] = append(errs[i], s.svc.StopUserService(id))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, errs := range errs {
+		for _, err := range errs {
+			assert.NoError(s.T(), err, "worker %d", i)
+		}
+	}
+	stopped := map[string]integration.ProviderInstanceDTO{}
+	for _, p := range s.svc.ListProviders() {
+		stopped[p.ID] = p
+	}
+	for i := 0; i < workers; i++ {
+		id := fmt.Sprintf("concurrent-%d", i)
+		p, ok := stopped[id]
+		if assert.True(s.T(), ok, id) {
+			assert.Equal(s.T(), JsonPlaceholderProvider, p.Type, id)
+			assert.Equal(s.T(), integration.ProviderStatusStopped, p.Status, id)
+		}
+		_, err := s.svc.GetUserService(id)
+		assert.Error(s.T(), err, id)
+	}
+}
+
+func (s *UserProviderRealSuite) TestConcurrentRegisterOfOneID() {
+	const workers = 20
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.svc.RegisterNewProvider("contended", ReqresProvider, "")
+		}(i)
+	}
+	wg.Wait()
+
+	registered := 0
+	for _, err := range errs {
+		if err == nil {
+			registered++
+			continue
+		}
+		assert.Contains(s.T(), err.Error(), "already exists")
+	}
+	assert.Equal(s.T(), 1, registered, "exactly one registration wins")
+	_, err := s.svc.GetUserService("contended")
+	assert.NoError(s.T(), err)
+}
+
+func (s *UserProviderRealSuite) TestRouterHonoursContextSelection() {
+	assert.NoError(s.T(), s.svc.RegisterNewProvider("route-default", ReqresProvider, ""))
+	router := s.svc.UserServiceRouter("route-default")
+
+	ctx := pkg.WithProvider(context.Background(), "route-missing")
+	_, err := router.GetUsers(ctx, 1)
+	assert.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "not found")
+}
+
+func (s *UserProviderRealSuite) TestRegisterInstancesRedactsCredentials() {
+	err := s.svc.RegisterInstances(config.ProviderInstances{
+		{ID: "cfg-reqres", Type: ReqresProvider, Credentials: config.ProviderCredentials{APIKey: "secret-key"}},
+		{ID: "cfg-jp", Type: JsonPlaceholderProvider},
+	})
+	assert.NoError(s.T(), err)
+
+	for _, p := range s.svc.ListProviders() {
+		assert.NotContains(s.T(), p.Config, "secret-key")
+	}
+	_, err = s.svc.GetUserService("cfg-jp")
+	assert.NoError(s.T(), err)
+}
+
+// ----- Real external calls, replayed from cassettes by default -----
 
 func (s *UserProviderRealSuite) TestReqresProvider_GetUsers_Real() {
 	if s.shouldSkip() {
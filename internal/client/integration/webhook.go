@@ -0,0 +1,231 @@
+package integration
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+)
+
+// WebhookParser decodes the events of a verified delivery to an instance.
+type WebhookParser func(inst config.ProviderInstance, body []byte) ([]integration.WebhookEventDTO, error)
+
+var (
+	webhookParsers   = make(map[string]WebhookParser)
+	webhookParsersMu sync.RWMutex
+)
+
+// RegisterWebhookParser sets the parser of the deliveries to instances of
+// providerName. Types without one use parseWebhookEvents with the user fields
+// taken as is.
+func RegisterWebhookParser(providerName string, parser WebhookParser) {
+	webhookParsersMu.Lock()
+	defer webhookParsersMu.Unlock()
+	webhookParsers[providerName] = parser
+}
+
+func lookupWebhookParser(providerName string) WebhookParser {
+	webhookParsersMu.RLock()
+	defer webhookParsersMu.RUnlock()
+	if p, ok := webhookParsers[providerName]; ok {
+		return p
+	}
+	return parseGenericWebhook
+}
+
+// GetWebhookReceiver returns the receiver of an active instance with a
+// webhook secret.
+func (u *userProviderService) GetWebhookReceiver(id string) (interfaces.WebhookReceiver, error) {
+	u.mu.RLock()
+	i, ok := u.indexOf(id)
+	var p Provider
+	if ok {
+		p = u.Providers[i]
+	}
+	u.mu.RUnlock()
+	if !ok || p.Status != integration.ProviderStatusActive {
+		return nil, pkg.NewAppError(pkg.ErrProviderNotFound).AddDescription([]byte(id)).AppendStackLog()
+	}
+
+	inst, err := config.ParseProviderInstance(p.Config)
+	if err != nil {
+		return nil, err
+	}
+	if inst.Webhook == nil || inst.Webhook.Secret == "" {
+		return nil, pkg.NewAppError(pkg.ErrWebhookNotEnabled).AddDescription([]byte(id)).AppendStackLog()
+	}
+	inst.ID, inst.Type = id, p.Name
+	return newWebhookReceiver(inst), nil
+}
+
+// webhookReceiver verifies deliveries signed as described on
+// config.ProviderWebhookConfig.
+type webhookReceiver struct {
+	inst   config.ProviderInstance
+	conf   config.ProviderWebhookConfig
+	parser WebhookParser
+}
+
+func newWebhookReceiver(inst config.ProviderInstance) *webhookReceiver {
+	conf := *inst.Webhook
+	if conf.Tolerance <= 0 {
+		conf.Tolerance = 300
+	}
+	if conf.SignatureHeader == "" {
+		conf.SignatureHeader = "X-Webhook-Signature"
+	}
+	if conf.TimestampHeader == "" {
+		conf.TimestampHeader = "X-Webhook-Timestamp"
+	}
+	if conf.IDHeader == "" {
+		conf.IDHeader = "X-Webhook-Id"
+	}
+	return &webhookReceiver{inst: inst, conf: conf, parser: lookupWebhookParser(inst.Type)}
+}
+
+// Verify accepts a delivery whose timestamp is within the tolerance and whose
+// signature header lists a valid signature; several may be given separated by
+// commas, e.g. during a secret rotation on the provider side. The id is part of
+// the signature, so a delivery cannot be replayed under a fresh id.
+func (r *webhookReceiver) Verify(header http.Header, body []byte, now time.Time) (string, time.Time, error) {
+	unauthorized := func(reason string) (string, time.Time, error) {
+		return "", time.Time{}, pkg.NewAppError(pkg.ErrWebhookUnauthorized).AddDescription([]byte(reason)).AppendStackLog()
+	}
+
+	id := strings.TrimSpace(header.Get(r.conf.IDHeader))
+	if id == "" {
+		return unauthorized("missing " + r.conf.IDHeader)
+	}
+	rawTS := strings.TrimSpace(header.Get(r.conf.TimestampHeader))
+	sec, err := strconv.ParseInt(rawTS, 10, 64)
+	if err != nil {
+		return unauthorized("invalid " + r.conf.TimestampHeader)
+	}
+	ts := time.Unix(sec, 0)
+	tolerance := time.Duration(r.conf.Tolerance) * time.Second
+	if d := now.Sub(ts); d > tolerance || d < -tolerance {
+		return unauthorized("timestamp outside the tolerance")
+	}
+
+	for _, candidate := range strings.Split(header.Get(r.conf.SignatureHeader), ",") {
+		got, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(candidate), "sha256="))
+		if err != nil || len(got) != sha256.Size {
+			continue
+		}
+		for _, secret := range []string{r.conf.Secret, r.conf.PreviousSecret} {
+			if secret != "" && hmac.Equal(got, pkg.SignWebhook(secret, id, rawTS, body)) {
+				return id, ts.Add(tolerance), nil
+			}
+		}
+	}
+	return unauthorized("signature mismatch")
+}
+
+func (r *webhookReceiver) Parse(body []byte) ([]integration.WebhookEventDTO, error) {
+	events, err := r.parser(r.inst, body)
+	if err != nil {
+		return nil, pkg.NewAppError(pkg.ErrWebhookInvalidPayload).AddDescription([]byte(err.Error())).AppendStackLog()
+	}
+	return events, nil
+}
+
+// webhookEvent is an event of the common delivery format: a single event, or
+// {"events": [...]}, with the user in the provider's own representation.
+type webhookEvent struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	User       json.RawMessage `json:"user"`
+}
+
+// webhookEventTypes maps the accepted event types; others are ignored.
+var webhookEventTypes = map[string]integration.WebhookEventType{
+	"user.created":     integration.WebhookUserCreated,
+	"user.updated":     integration.WebhookUserUpdated,
+	"user.deactivated": integration.WebhookUserDeactivated,
+	"user.deleted":     integration.WebhookUserDeactivated,
+}
+
+// parseWebhookEvents decodes a delivery in the common format, mapping each
+// user with decodeUser. Every event needs an id and a user id.
+func parseWebhookEvents(body []byte, decodeUser func(json.RawMessage) (integration.UserDTO, error)) ([]integration.WebhookEventDTO, error) {
+	var envelope struct {
+		Events []webhookEvent `json:"events"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("decode delivery: %w", err)
+	}
+	if envelope.Events == nil {
+		var single webhookEvent
+		if err := json.Unmarshal(body, &single); err != nil {
+			return nil, fmt.Errorf("decode delivery: %w", err)
+		}
+		envelope.Events = []webhookEvent{single}
+	}
+
+	out := make([]integration.WebhookEventDTO, 0, len(envelope.Events))
+	for i, e := range envelope.Events {
+		typ, ok := webhookEventTypes[e.Type]
+		if !ok {
+			continue
+		}
+		if e.ID == "" {
+			return nil, fmt.Errorf("event %d: missing id", i)
+		}
+		if len(e.User) == 0 {
+			return nil, fmt.Errorf("event %s: missing user", e.ID)
+		}
+		u, err := decodeUser(e.User)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %w", e.ID, err)
+		}
+		if u.ID == "" {
+			return nil, fmt.Errorf("event %s: missing user id", e.ID)
+		}
+		out = append(out, integration.WebhookEventDTO{ID: e.ID, Type: typ, OccurredAt: e.OccurredAt, User: u})
+	}
+	return out, nil
+}
+
+// parseGenericWebhook reads users with the id, name, username, email, phone
+// and website keys and an optional "extra" object of strings.
+func parseGenericWebhook(_ config.ProviderInstance, body []byte) ([]integration.WebhookEventDTO, error) {
+	fields := make(map[string]string, len(userFields))
+	for _, name := range userFields {
+		fields[name] = "$['" + name + "']"
+	}
+	mapping, err := compileUserMapping(fields)
+	if err != nil {
+		return nil, err
+	}
+	return parseWebhookEvents(body, func(raw json.RawMessage) (integration.UserDTO, error) {
+		doc, err := decodeJSON(raw)
+		if err != nil {
+			return integration.UserDTO{}, err
+		}
+		u := mapping.user(doc)
+		if obj, ok := doc.(map[string]any); ok {
+			extra, _ := obj["extra"].(map[string]any)
+			for k, v := range extra {
+				if s := fmt.Sprint(v); v != nil && s != "" {
+					if u.Extra == nil {
+						u.Extra = map[string]string{}
+					}
+					u.Extra[k] = s
+				}
+			}
+		}
+		return u, nil
+	})
+}
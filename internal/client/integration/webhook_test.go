@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedHeader(secret string, ts time.Time, id string, body []byte) http.Header {
+	rawTS := strconv.FormatInt(ts.Unix(), 10)
+	h := http.Header{}
+	h.Set("X-Webhook-Id", id)
+	h.Set("X-Webhook-Timestamp", rawTS)
+	h.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(pkg.SignWebhook(secret, id, rawTS, body)))
+	return h
+}
+
+func assertAppError(t *testing.T, code pkg.ErrorCode, err error) {
+	t.Helper()
+	var appErr *pkg.AppError
+	require.True(t, errors.As(err, &appErr), "expected an AppError, got %v", err)
+	assert.Equal(t, pkg.NewAppError(code).InternalCode(), appErr.InternalCode())
+}
+
+func TestWebhookReceiver_Verify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"id":"evt-1","type":"user.created","user":{"id":"42"}}`)
+	r := newWebhookReceiver(config.ProviderInstance{ID: "hr", Webhook: &config.ProviderWebhookConfig{Secret: "new", PreviousSecret: "old", Tolerance: 60}})
+
+	id, expiresAt, err := r.Verify(signedHeader("new", now.Add(-30*time.Second), "d-1", body), body, now)
+	require.NoError(t, err)
+	assert.Equal(t, "d-1", id)
+	assert.Equal(t, now.Add(30*time.Second), expiresAt)
+
+	// the previous secret is still accepted during a rotation, and so is one
+	// valid signature among several
+	_, _, err = r.Verify(signedHeader("old", now, "d-2", body), body, now)
+	require.NoError(t, err)
+	h := signedHeader("new", now, "d-3", body)
+	h.Set("X-Webhook-Signature", "sha256=00ff,"+h.Get("X-Webhook-Signature"))
+	_, _, err = r.Verify(h, body, now)
+	require.NoError(t, err)
+
+	for name, h := range map[string]http.Header{
+		"wrong secret":    signedHeader("other", now, "d-5", body),
+		"stale timestamp": signedHeader("new", now.Add(-2*time.Minute), "d-4", body),
+		"missing id":      signedHeader("new", now, "", body),
+	} {
+		_, _, err := r.Verify(h, body, now)
+		require.Error(t, err, name)
+		assertAppError(t, pkg.ErrWebhookUnauthorized, err)
+	}
+
+	_, _, err = r.Verify(signedHeader("new", now, "d-6", body), []byte(`{"tampered":true}`), now)
+	assertAppError(t, pkg.ErrWebhookUnauthorized, err)
+}
+
+func TestWebhookReceiver_RejectsReplayUnderNewID(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"id":"evt-1","type":"user.created","user":{"id":"42"}}`)
+	r := newWebhookReceiver(config.ProviderInstance{ID: "hr", Webhook: &config.ProviderWebhookConfig{Secret: "s"}})
+
+	captured := signedHeader("s", now, "d-1", body)
+	_, _, err := r.Verify(captured, body, now)
+	require.NoError(t, err)
+
+	// the captured delivery resent with a fresh id would pass the nonce check
+	replay := captured.Clone()
+	replay.Set("X-Webhook-Id", "d-2")
+	_, _, err = r.Verify(replay, body, now)
+	assertAppError(t, pkg.ErrWebhookUnauthorized, err)
+}
+
+func TestWebhookReceiver_CustomHeaders(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{}`)
+	r := newWebhookReceiver(config.ProviderInstance{Webhook: &config.ProviderWebhookConfig{
+		Secret: "s", SignatureHeader: "X-Sig", TimestampHeader: "X-Ts", IDHeader: "X-Delivery",
+	}})
+	rawTS := strconv.FormatInt(now.Unix(), 10)
+	h := http.Header{}
+	h.Set("X-Delivery", "d-1")
+	h.Set("X-Ts", rawTS)
+	h.Set("X-Sig", hex.EncodeToString(pkg.SignWebhook("s", "d-1", rawTS, body)))
+
+	id, _, err := r.Verify(h, body, now)
+	require.NoError(t, err)
+	assert.Equal(t, "d-1", id)
+}
+
+func TestParseGenericWebhook(t *testing.T) {
+	events, err := parseGenericWebhook(config.ProviderInstance{}, []byte(`{"events":[
+		{"id":"e1","type":"user.created","occurred_at":"2024-05-01T10:00:00Z","user":{"id":42,"name":"Ada","email":"ada@example.com","extra":{"company":"ACME"}}},
+		{"id":"e2","type":"user.deleted","user":{"id":"7"}},
+		{"id":"e3","type":"group.created","user":{"id":"g"}}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, integration.WebhookUserCreated, events[0].Type)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), events[0].OccurredAt)
+	assert.Equal(t, "42", string(events[0].User.ID))
+	assert.Equal(t, "Ada", string(events[0].User.Name))
+	assert.Equal(t, "ACME", events[0].User.Extra["company"])
+	assert.Equal(t, integration.WebhookUserDeactivated, events[1].Type)
+
+	single, err := parseGenericWebhook(config.ProviderInstance{}, []byte(`{"id":"e4","type":"user.updated","user":{"id":"1"}}`))
+	require.NoError(t, err)
+	require.Len(t, single, 1)
+	assert.Equal(t, integration.WebhookUserUpdated, single[0].Type)
+
+	for _, bad := range []string{`not json`, `{"type":"user.created","user":{"id":"1"}}`, `{"id":"e","type":"user.created","user":{"name":"x"}}`} {
+		_, err := parseGenericWebhook(config.ProviderInstance{}, []byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseSCIMWebhook_InactiveUpdateDeactivates(t *testing.T) {
+	events, err := parseSCIMWebhook(config.ProviderInstance{}, []byte(`{"events":[
+		{"id":"e1","type":"user.updated","user":{"id":"2819c223","userName":"bjensen","name":{"givenName":"Barbara","familyName":"Jensen"},"active":false}},
+		{"id":"e2","type":"user.updated","user":{"id":"9","userName":"ada","active":true}}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, integration.WebhookUserDeactivated, events[0].Type)
+	assert.Equal(t, "Barbara Jensen", string(events[0].User.Name))
+	assert.Equal(t, integration.WebhookUserUpdated, events[1].Type)
+}
+
+func TestGetWebhookReceiver(t *testing.T) {
+	svc := NewUserProviderService(config.App{})
+	fields := map[string]string{"id": "$.uid", "name": "{$.first} {$.last}"}
+	withHook := config.ProviderInstance{BaseURL: "http://hr.invalid", HTTPJSON: &config.HTTPJSONConfig{Path: "/users", Fields: fields},
+		Webhook: &config.ProviderWebhookConfig{Secret: "s"}}
+	withoutHook := config.ProviderInstance{BaseURL: "http://crm.invalid", HTTPJSON: &config.HTTPJSONConfig{Path: "/users", Fields: fields}}
+	require.NoError(t, svc.RegisterNewProvider("hr", HTTPJSONProvider, withHook.String()))
+	require.NoError(t, svc.RegisterNewProvider("crm", HTTPJSONProvider, withoutHook.String()))
+
+	_, err := svc.GetWebhookReceiver("crm")
+	assertAppError(t, pkg.ErrWebhookNotEnabled, err)
+	_, err = svc.GetWebhookReceiver("missing")
+	assertAppError(t, pkg.ErrProviderNotFound, err)
+
+	r, err := svc.GetWebhookReceiver("hr")
+	require.NoError(t, err)
+	// deliveries of an http-json instance are mapped with its fields
+	events, err := r.Parse([]byte(`{"id":"e1","type":"user.created","user":{"uid":"u-1","first":"Ada","last":"Lovelace"}}`))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "u-1", string(events[0].User.ID))
+	assert.Equal(t, "Ada Lovelace", string(events[0].User.Name))
+
+	_, err = r.Parse([]byte(`[`))
+	assertAppError(t, pkg.ErrWebhookInvalidPayload, err)
+
+	require.NoError(t, svc.StopUserService("hr"))
+	_, err = svc.GetWebhookReceiver("hr")
+	assertAppError(t, pkg.ErrProviderNotFound, err)
+}
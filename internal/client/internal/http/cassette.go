@@ -0,0 +1,344 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"__MODULE__/pkg"
+)
+
+// Cassette modes, see config.HTTPClientConfig.CassetteMode.
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// redacted replaces secrets in cassettes and logs.
+const redacted = "<redacted>"
+
+// sensitiveParams are query parameters and JSON or form body fields whose
+// values are redacted before an exchange is written to a cassette.
+var sensitiveParams = []string{
+	"api_key", "apikey", "token", "access_token", "refresh_token", "id_token",
+	"client_id", "client_secret", "secret", "password", "assertion",
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type CassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type CassetteResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// cassette is the file of one client. It is shared by every client with the
+// same file, so instances built twice append to one recording.
+type cassette struct {
+	path string
+	mode string
+
+	mu           sync.Mutex
+	loaded       bool
+	interactions []Interaction
+	used         []bool
+}
+
+var (
+	cassettesMu sync.Mutex
+	cassettes   = map[string]*cassette{}
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// CassettePath returns the file a client named name records to in dir.
+func CassettePath(dir, name string) string {
+	return filepath.Join(dir, unsafeFileChars.ReplaceAllString(name, "_")+".json")
+}
+
+func openCassette(path, mode string) *cassette {
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	c, ok := cassettes[path]
+	if !ok || c.mode != mode {
+		c = &cassette{path: path, mode: mode}
+		cassettes[path] = c
+	}
+	return c
+}
+
+// load reads the cassette on first use. Recording starts from an empty
+// cassette so stale exchanges do not survive a re-recording.
+// Must be called with mu held.
+func (c *cassette) load() error {
+	if c.loaded {
+		return nil
+	}
+	if c.mode == CassetteReplay {
+		b, err := os.ReadFile(c.path)
+		if err != nil {
+			return fmt.Errorf("cassette: %w", err)
+		}
+		if err := json.Unmarshal(b, &c.interactions); err != nil {
+			return fmt.Errorf("cassette %s: %w", c.path, err)
+		}
+		c.used = make([]bool, len(c.interactions))
+	}
+	c.loaded = true
+	return nil
+}
+
+// save writes the cassette through a temporary file so a crash never leaves a
+// half-written one behind. Must be called with mu held.
+func (c *cassette) save() error {
+	b, err := marshal(c.interactions, "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// cassetteTransport records exchanges passing through next, or answers from
+// the recording without touching the network.
+type cassetteTransport struct {
+	next     http.RoundTripper
+	cassette *cassette
+}
+
+func newCassetteTransport(mode, dir, name string, next http.RoundTripper) (http.RoundTripper, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("unknown cassette mode %q, want %s or %s", mode, CassetteRecord, CassetteReplay)
+	}
+	return &cassetteTransport{next: next, cassette: openCassette(CassettePath(dir, name), mode)}, nil
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := CassetteRequest{
+		Method:  req.Method,
+		URL:     redactURL(req.URL),
+		Headers: redactHeaderValues(req.Header),
+		Body:    redactBody(body, req.Header.Get("Content-Type")),
+	}
+
+	if t.cassette.mode == CassetteReplay {
+		return t.replay(req, recorded)
+	}
+	return t.record(req, recorded)
+}
+
+// replay answers with the first unused matching interaction, or the last
+// matching one once all have been used, so retried and repeated calls work.
+func (t *cassetteTransport) replay(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	c := t.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	match := -1
+	for i, in := range c.interactions {
+		if in.Request.Method != recorded.Method || in.Request.URL != recorded.URL || in.Request.Body != recorded.Body {
+			continue
+		}
+		match = i
+		if !c.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("cassette %s: no interaction recorded for %s %s", c.path, recorded.Method, recorded.URL)
+	}
+	c.used[match] = true
+
+	in := c.interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(in.Body)),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *cassetteTransport) record(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c := t.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	headers := redactHeaderValues(resp.Header)
+	headers.Del("Content-Length") // redaction may change the length
+	c.interactions = append(c.interactions, Interaction{
+		Request: recorded,
+		Response: CassetteResponse{
+			Status:  resp.StatusCode,
+			Headers: headers,
+			Body:    redactBody(body, resp.Header.Get("Content-Type")),
+		},
+	})
+	if err := c.save(); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", c.path, err)
+	}
+	return resp, nil
+}
+
+// requestBody returns the request body without consuming it.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		r, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+func isSensitive(name string) bool {
+	return slices.Contains(sensitiveParams, strings.ToLower(name))
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	q := c.Query()
+	for k, v := range q {
+		if isSensitive(k) {
+			for i := range v {
+				v[i] = redacted
+			}
+		}
+	}
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+func redactHeaderValues(h http.Header) http.Header {
+	out := h.Clone()
+	for k := range out {
+		for _, s := range sensitiveHeaders {
+			if strings.EqualFold(k, s) {
+				out[k] = []string{redacted}
+				break
+			}
+		}
+	}
+	return out
+}
+
+// redactBody redacts sensitive fields of JSON and form bodies; other bodies
+// go through the log masking rules.
+func redactBody(b []byte, contentType string) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(b)); err == nil {
+			for k, v := range form {
+				if isSensitive(k) {
+					for i := range v {
+						v[i] = redacted
+					}
+				}
+			}
+			return form.Encode()
+		}
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err == nil {
+		if out, err := marshal(redactJSON(v), ""); err == nil {
+			return string(bytes.TrimSuffix(out, []byte("\n")))
+		}
+	}
+	return pkg.MaskString(string(b))
+}
+
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if isSensitive(k) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactJSON(val)
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = redactJSON(val)
+		}
+	}
+	return v
+}
+
+// marshal encodes v without escaping HTML, so redaction markers stay legible.
+func marshal(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"__MODULE__/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, client *http.Client, url string, header ...string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestCassette_RecordsRedactedAndReplaysOffline(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = w.Write([]byte(`{"page":` + r.URL.Query().Get("page") + `,"access_token":"tok-123","data":[{"id":1}]}`))
+	}))
+
+	record, err := NewClient(Options{Name: "provider:tenant", HTTPClientConfig: config.HTTPClientConfig{CassetteMode: CassetteRecord, CassetteDir: dir}})
+	require.NoError(t, err)
+	code, body := get(t, record, srv.URL+"/users?page=1&api_key=k-secret", "X-Api-Key", "k-secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "tok-123", "the live caller sees the real response")
+	get(t, record, srv.URL+"/users?page=2&api_key=k-secret")
+	srv.Close()
+
+	raw, err := os.ReadFile(CassettePath(dir, "provider:tenant"))
+	require.NoError(t, err)
+	for _, secret := range []string{"k-secret", "tok-123", "session=abc"} {
+		assert.NotContains(t, string(raw), secret)
+	}
+
+	// the server is gone, so every answer comes from the cassette
+	replay, err := NewClient(Options{Name: "provider:tenant", HTTPClientConfig: config.HTTPClientConfig{CassetteMode: CassetteReplay, CassetteDir: dir}})
+	require.NoError(t, err)
+	for range 2 {
+		code, body = get(t, replay, srv.URL+"/users?page=2&api_key=other")
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, strings.Contains(body, `"page":2`), body)
+	}
+
+	_, err = replay.Get(srv.URL + "/users?page=3")
+	assert.ErrorContains(t, err, "no interaction recorded")
+}
+
+func TestCassette_ReplayWithoutFile(t *testing.T) {
+	client, err := NewClient(Options{Name: "nothing", HTTPClientConfig: config.HTTPClientConfig{CassetteMode: CassetteReplay, CassetteDir: t.TempDir()}})
+	require.NoError(t, err)
+	_, err = client.Get("http://127.0.0.1:1/")
+	assert.Error(t, err)
+}
+
+func TestCassette_UnknownMode(t *testing.T) {
+	_, err := NewClient(Options{Name: "x", HTTPClientConfig: config.HTTPClientConfig{CassetteMode: "rewind"}})
+	assert.Error(t, err)
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, `{"client_secret":"<redacted>","n":12345678901234567890}`,
+		redactBody([]byte(`{"client_secret":"s","n":12345678901234567890}`), "application/json"))
+	assert.Equal(t, "client_secret=%3Credacted%3E&grant_type=client_credentials",
+		redactBody([]byte("grant_type=client_credentials&client_secret=s"), "application/x-www-form-urlencoded"))
+}
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// sensitiveHeaders are never logged in clear text.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "X-Api-Key", "Cookie", "Set-Cookie"}
+
+// requestIDTransport forwards the request id found in the request context.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, ok := pkg.RequestIDFromContext(req.Context())
+	if !ok || req.Header.Get(pkg.RequestIDHeader) != "" {
+		return t.next.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set(pkg.RequestIDHeader, id)
+	return t.next.RoundTrip(r)
+}
+
+// metricsTransport counts requests and records their latency.
+type metricsTransport struct {
+	name string
+	next http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	pkg.CounterAdd("http_client_requests_total", 1, "client", t.name, "method", req.Method, "code", code)
+	pkg.Observe("http_client_request_duration_seconds", time.Since(start).Seconds(), "client", t.name)
+	return resp, err
+}
+
+// loggingTransport logs every exchange. Bodies are only logged when enabled,
+// truncated to limit bytes and passed through pkg.MaskString.
+type loggingTransport struct {
+	name      string
+	next      http.RoundTripper
+	logBodies bool
+	limit     int
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := log.Fields{
+		"client":  t.name,
+		"method":  req.Method,
+		"url":     pkg.MaskString(req.URL.Redacted()),
+		"headers": redactHeaders(req.Header),
+	}
+	if id, ok := pkg.RequestIDFromContext(req.Context()); ok {
+		fields["request_id"] = id
+	}
+
+	if t.logBodies && req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			fields["request_body"] = t.snippet(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields["duration"] = time.Since(start).String()
+	if err != nil {
+		log.WithFields(fields).WithError(err).Warn("http client request failed")
+		return resp, err
+	}
+
+	fields["status"] = resp.StatusCode
+	if !t.logBodies {
+		log.WithFields(fields).Debug("http client request")
+		return resp, nil
+	}
+
+	// log once the caller has consumed the body, so streaming is not affected
+	resp.Body = &capturingBody{ReadCloser: resp.Body, limit: t.limit, onClose: func(b []byte, truncated bool) {
+		fields["response_body"] = pkg.MaskString(string(b))
+		fields["response_truncated"] = truncated
+		log.WithFields(fields).Debug("http client request")
+	}}
+	return resp, nil
+}
+
+func (t *loggingTransport) snippet(r io.Reader) string {
+	b, _ := io.ReadAll(io.LimitReader(r, int64(t.limit)))
+	return pkg.MaskString(string(b))
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		value := strings.Join(v, ",")
+		for _, s := range sensitiveHeaders {
+			if strings.EqualFold(k, s) {
+				value = "<redacted>"
+				break
+			}
+		}
+		out[k] = value
+	}
+	return out
+}
+
+// capturingBody keeps the first limit bytes read through it and reports them on Close.
+type capturingBody struct {
+	io.ReadCloser
+	limit     int
+	buf       bytes.Buffer
+	truncated bool
+	onClose   func([]byte, bool)
+	closed    bool
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+		if n > room {
+			b.truncated = true
+		}
+	} else if n > 0 {
+		b.truncated = true
+	}
+	return n, err
+}
+
+func (b *capturingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.closed {
+		b.closed = true
+		b.onClose(b.buf.Bytes(), b.truncated)
+	}
+	return err
+}
@@ -1 +1,172 @@
+/*
+Package http builds the instrumented HTTP clients shared by the integrations:
+pooled transports with proxy and custom CA support, request-ID propagation,
+metrics, request/response logging with redaction and cassettes that record
+and replay exchanges for offline tests.
+*/
 package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"__MODULE__/internal/config"
+)
+
+// Options describes a client. Name labels logs and metrics, typically the
+// provider instance id.
+type Options struct {
+	Name    string
+	Timeout time.Duration
+	config.HTTPClientConfig
+	// Wrap adds transports between the instrumentation and the pooled base
+	// transport, e.g. OAuth2. They are applied in order, the first one outermost.
+	Wrap []func(http.RoundTripper) http.RoundTripper
+	// PublicOnly refuses connections to loopback, private, link-local and
+	// other non-public addresses, e.g. for URLs supplied by users. The address
+	// is checked when dialing, after DNS resolution; proxies are not used since
+	// the target they connect to could not be checked.
+	PublicOnly bool
+}
+
+// transportKey identifies pooled base transports that can be shared.
+type transportKey struct {
+	proxyURL            string
+	caCertFile          string
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     int
+	publicOnly          bool
+}
+
+var (
+	transportsMu sync.Mutex
+	transports   = map[transportKey]*http.Transport{}
+)
+
+// NewClient returns a client with the full middleware stack:
+// request id -> logging -> metrics -> Wrap... -> [cassette ->] pooled transport.
+// With a cassette mode set, exchanges are recorded to or replayed from the
+// cassette file named after the client.
+func NewClient(opts Options) (*http.Client, error) {
+	base, err := baseTransport(opts.HTTPClientConfig, opts.PublicOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt http.RoundTripper = base
+	if opts.CassetteMode != "" {
+		rt, err = newCassetteTransport(opts.CassetteMode, opts.CassetteDir, opts.Name, rt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := len(opts.Wrap) - 1; i >= 0; i-- {
+		rt = opts.Wrap[i](rt)
+	}
+	rt = &metricsTransport{name: opts.Name, next: rt}
+	rt = &loggingTransport{name: opts.Name, next: rt, logBodies: opts.LogBodies, limit: opts.LogBodyLimit}
+	rt = &requestIDTransport{next: rt}
+
+	return &http.Client{Timeout: opts.Timeout, Transport: rt}, nil
+}
+
+// baseTransport returns the pooled transport for cfg, creating it on first use
+// so clients with the same network settings share their connections.
+func baseTransport(cfg config.HTTPClientConfig, publicOnly bool) (*http.Transport, error) {
+	key := transportKey{
+		proxyURL:            cfg.ProxyURL,
+		caCertFile:          cfg.CACertFile,
+		maxIdleConns:        cfg.MaxIdleConns,
+		maxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		maxConnsPerHost:     cfg.MaxConnsPerHost,
+		idleConnTimeout:     cfg.IdleConnTimeout,
+		publicOnly:          publicOnly,
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if t, ok := transports[key]; ok {
+		return t, nil
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if publicOnly {
+		dialer.Control = dialPublicOnly
+	}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       time.Duration(cfg.IdleConnTimeout) * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	if publicOnly {
+		t.Proxy = nil
+	} else if cfg.ProxyURL != "" {
+		proxy, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca cert file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACertFile)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	transports[key] = t
+	return t, nil
+}
+
+// cgnat is the shared address space of carrier-grade NAT (RFC 6598).
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// dialPublicOnly is a net.Dialer Control refusing connections to addresses
+// that are not publicly routable. It sees the resolved address, so a host name
+// pointing at an internal address is refused as well.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("dial %s %s: %w", network, address, err)
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("dial %s %s: address is not public", network, address)
+	}
+	return nil
+}
+
+// isPublicAddr reports whether addr is a publicly routable unicast address.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnat.Contains(addr)
+}
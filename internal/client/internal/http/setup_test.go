@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient_PropagatesRequestIDAndCountsRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "req-1", r.Header.Get(pkg.RequestIDHeader))
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Options{Name: "setup-test", HTTPClientConfig: config.HTTPClientConfig{LogBodies: true, LogBodyLimit: 2}})
+	require.NoError(t, err)
+
+	requests := pkg.MetricValue("http_client_requests_total", "client", "setup-test", "method", "GET", "code", "200")
+	durations := pkg.MetricValue("http_client_request_duration_seconds_count", "client", "setup-test")
+	req, err := http.NewRequestWithContext(pkg.WithRequestID(context.Background(), "req-1"), http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, "hello", string(body), "body logging must not alter the response")
+	assert.Equal(t, requests+1, pkg.MetricValue("http_client_requests_total", "client", "setup-test", "method", "GET", "code", "200"))
+	assert.Equal(t, durations+1, pkg.MetricValue("http_client_request_duration_seconds_count", "client", "setup-test"))
+}
+
+func TestNewClient_SharesPooledTransport(t *testing.T) {
+	cfg := config.HTTPClientConfig{MaxIdleConns: 7}
+	a, err := baseTransport(cfg, false)
+	require.NoError(t, err)
+	b, err := baseTransport(cfg, false)
+	require.NoError(t, err)
+	assert.Same(t, a, b)
+	assert.Equal(t, 7, a.MaxIdleConns)
+
+	public, err := baseTransport(cfg, true)
+	require.NoError(t, err)
+	assert.NotSame(t, a, public, "public-only clients dial differently")
+}
+
+func TestNewClient_RejectsBadCAFile(t *testing.T) {
+	_, err := NewClient(Options{HTTPClientConfig: config.HTTPClientConfig{CACertFile: "/does/not/exist.pem"}})
+	assert.Error(t, err)
+}
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("x-api-key", "k")
+	h.Set("Accept", "application/json")
+
+	out := redactHeaders(h)
+	assert.Equal(t, "<redacted>", out["Authorization"])
+	assert.Equal(t, "<redacted>", out["X-Api-Key"])
+	assert.Equal(t, "application/json", out["Accept"])
+}
+
+func TestCapturingBody_Truncates(t *testing.T) {
+	var got string
+	var truncated bool
+	b := &capturingBody{ReadCloser: io.NopCloser(strings.NewReader("abcdef")), limit: 3, onClose: func(p []byte, tr bool) {
+		got, truncated = string(p), tr
+	}}
+	all, _ := io.ReadAll(b)
+	require.NoError(t, b.Close())
+	assert.Equal(t, "abcdef", string(all))
+	assert.Equal(t, "abc", got)
+	assert.True(t, truncated)
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::248": true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"0.0.0.0":              false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"100.64.0.1":           false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"224.0.0.1":            false,
+		"255.255.255.255":      false,
+	} {
+		assert.Equal(t, want, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Package oauth implements an OAuth2 client-credentials token source shared by
+the user provider integrations.
+*/
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Config describes how to obtain tokens from an authorization server.
+type Config struct {
+	// Provider is only used to label logs.
+	Provider     string
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// GrantType defaults to client_credentials.
+	GrantType string
+	Scopes    []string
+	// RefreshInterval forces a refresh once a token is this old, even if the
+	// server granted a longer lifetime. Zero relies on expires_in only.
+	RefreshInterval time.Duration
+	// ExpirySkew starts refreshing tokens in the background this long before
+	// they expire. Defaults to 30s.
+	ExpirySkew time.Duration
+	// HTTPClient is used for token requests. Defaults to a client with a 30s timeout.
+	HTTPClient *http.Client
+}
+
+type token struct {
+	value     string
+	issuedAt  time.Time
+	expiresAt time.Time // zero when the server sent no expires_in
+}
+
+// TokenSource fetches, caches and refreshes access tokens. It is safe for
+// concurrent use; concurrent refreshes are serialised so only one request
+// reaches the authorization server. A token inside the ExpirySkew window is
+// still handed out while the first Token call to see it refreshes it in the
+// background, so callers only wait for the first token, for an expired one and
+// for one past RefreshInterval.
+type TokenSource struct {
+	cfg Config
+	now func() time.Time
+
+	mu    sync.Mutex // guards tok
+	tok   token
+	fetch chan struct{} // holds a value while a refresh is in flight
+}
+
+// backgroundRefreshTimeout bounds a refresh no caller waits for.
+const backgroundRefreshTimeout = 30 * time.Second
+
+// NewTokenSource returns a TokenSource for cfg.
+func NewTokenSource(cfg Config) *TokenSource {
+	if cfg.GrantType == "" {
+		cfg.GrantType = "client_credentials"
+	}
+	if cfg.ExpirySkew <= 0 {
+		cfg.ExpirySkew = 30 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &TokenSource{cfg: cfg, now: time.Now, fetch: make(chan struct{}, 1)}
+}
+
+// Token returns a valid access token, refreshing it first when it is missing,
+// expired or older than RefreshInterval, and in the background when it is
+// about to expire. Callers waiting for another caller's refresh give up when
+// ctx is done.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	if v, due, ok := s.cached(); ok {
+		if due {
+			s.refreshInBackground()
+		}
+		return v, nil
+	}
+
+	select {
+	case s.fetch <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-s.fetch }()
+
+	// another caller may have refreshed while we waited
+	if v, _, ok := s.cached(); ok {
+		return v, nil
+	}
+	return s.refresh(ctx)
+}
+
+// refreshInBackground refreshes the token unless a refresh is in flight.
+func (s *TokenSource) refreshInBackground() {
+	select {
+	case s.fetch <- struct{}{}:
+	default:
+		return
+	}
+	go func() {
+		defer func() { <-s.fetch }()
+		// another caller may have refreshed since
+		if _, due, ok := s.cached(); ok && !due {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundRefreshTimeout)
+		defer cancel()
+		if _, err := s.refresh(ctx); err != nil {
+			log.WithError(err).WithField("provider", s.cfg.Provider).Warn("oauth: background token refresh failed")
+		}
+	}()
+}
+
+// Invalidate drops the cached token if it still equals stale, e.g. after the
+// provider answered 401. Passing the stale value avoids throwing away a token
+// another goroutine has just refreshed.
+func (s *TokenSource) Invalidate(stale string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tok.value == stale {
+		s.tok = token{}
+	}
+}
+
+// cached returns the cached token and whether it is inside the ExpirySkew
+// window. ok is false when there is no usable token: none, an expired one or
+// one past RefreshInterval.
+func (s *TokenSource) cached() (value string, due, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tok.value == "" {
+		return "", false, false
+	}
+	now := s.now()
+	if !s.tok.expiresAt.IsZero() && !now.Before(s.tok.expiresAt) {
+		return "", false, false
+	}
+	if s.cfg.RefreshInterval > 0 && now.Sub(s.tok.issuedAt) >= s.cfg.RefreshInterval {
+		return "", false, false
+	}
+	due = !s.tok.expiresAt.IsZero() && now.Add(s.cfg.ExpirySkew).After(s.tok.expiresAt)
+	return s.tok.value, due, true
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// refresh must be called with fetch held.
+func (s *TokenSource) refresh(ctx context.Context) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", s.cfg.GrantType)
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth: token request for %s: %w", s.cfg.Provider, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oauth: token request for %s: status %d", s.cfg.Provider, resp.StatusCode)
+	}
+
+	var parsed tokenResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("oauth: decode token response for %s: %w", s.cfg.Provider, err)
+	}
+	if parsed.AccessToken == "" {
+		return "", fmt.Errorf("oauth: empty access token for %s", s.cfg.Provider)
+	}
+
+	now := s.now()
+	tok := token{value: parsed.AccessToken, issuedAt: now}
+	if parsed.ExpiresIn > 0 {
+		tok.expiresAt = now.Add(time.Duration(parsed.ExpiresIn) * time.Second)
+	}
+
+	s.mu.Lock()
+	s.tok = tok
+	s.mu.Unlock()
+
+	log.WithFields(log.Fields{"provider": s.cfg.Provider, "expires_in": parsed.ExpiresIn}).Info("oauth token refreshed")
+	return tok.value, nil
+}
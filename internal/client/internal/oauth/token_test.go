@@ -0,0 +1,149 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer issues tok-1, tok-2, ... with the given lifetime.
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	var issued atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "id", id)
+		assert.Equal(t, "secret", secret)
+
+		n := issued.Add(1)
+		time.Sleep(10 * time.Millisecond) // widen the race window for concurrent callers
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"tok-%d","token_type":"bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+func TestTokenSource_CachesAndSerialisesRefresh(t *testing.T) {
+	srv, issued := tokenServer(t, 3600)
+	src := NewTokenSource(Config{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := src.Token(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "tok-1", tok)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), issued.Load())
+}
+
+func TestTokenSource_WaitersHonourTheirContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release // a hung authorization server
+	}))
+	defer srv.Close()
+	defer close(release)
+	src := NewTokenSource(Config{TokenURL: srv.URL})
+
+	go func() { _, _ = src.Token(context.Background()) }()
+	require.Eventually(t, func() bool { return len(src.fetch) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := src.Token(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	srv, issued := tokenServer(t, 60)
+	src := NewTokenSource(Config{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret", ExpirySkew: 10 * time.Second})
+	now := time.Now()
+	src.now = func() time.Time { return now }
+
+	tok, err := src.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "tok-1", tok)
+
+	now = now.Add(45 * time.Second)
+	tok, _ = src.Token(context.Background())
+	assert.Equal(t, "tok-1", tok)
+
+	// inside the skew window the current token is still handed out while a
+	// single refresh runs in the background
+	now = now.Add(10 * time.Second)
+	for range 5 {
+		tok, _ = src.Token(context.Background())
+		assert.Equal(t, "tok-1", tok)
+	}
+	require.Eventually(t, func() bool { tok, _, _ := src.cached(); return tok == "tok-2" }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return len(src.fetch) == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), issued.Load())
+
+	// an expired token is refreshed before it is handed out
+	now = now.Add(2 * time.Minute)
+	tok, _ = src.Token(context.Background())
+	assert.Equal(t, "tok-3", tok)
+}
+
+func TestTokenSource_RefreshInterval(t *testing.T) {
+	srv, _ := tokenServer(t, 3600)
+	src := NewTokenSource(Config{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret", RefreshInterval: time.Minute})
+	now := time.Now()
+	src.now = func() time.Time { return now }
+
+	tok, _ := src.Token(context.Background())
+	assert.Equal(t, "tok-1", tok)
+	now = now.Add(time.Minute)
+	tok, _ = src.Token(context.Background())
+	assert.Equal(t, "tok-2", tok)
+}
+
+func TestTransport_RefreshesOnUnauthorized(t *testing.T) {
+	tokens, _ := tokenServer(t, 3600)
+	var calls atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "Bearer tok-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	client := &http.Client{Transport: &Transport{Source: NewTokenSource(Config{TokenURL: tokens.URL, ClientID: "id", ClientSecret: "secret"})}}
+	resp, err := client.Get(api.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestTokenSource_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := NewTokenSource(Config{Provider: "p", TokenURL: srv.URL}).Token(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+}
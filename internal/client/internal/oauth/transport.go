@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"net/http"
+)
+
+// Transport authenticates requests with a bearer token from Source. When the
+// server answers 401 the token is invalidated and the request is retried once
+// with a fresh token, provided the request body can be replayed.
+type Transport struct {
+	Source *TokenSource
+	// Base defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := t.Source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base().RoundTrip(authorize(req, tok))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	t.Source.Invalidate(tok)
+	fresh, err := t.Source.Token(req.Context())
+	if err != nil {
+		return resp, nil
+	}
+	retry := authorize(req, fresh)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+	return t.base().RoundTrip(retry)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// authorize returns a clone of req carrying the bearer token; RoundTrippers
+// must not modify the caller's request.
+func authorize(req *http.Request, tok string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+tok)
+	return r
+}
@@ -0,0 +1,154 @@
+/*
+Package pagination walks every page of an interfaces.UserService.
+*/
+package pagination
+
+import (
+	"context"
+	"iter"
+
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/interfaces"
+)
+
+// Options tunes how pages are fetched.
+type Options struct {
+	// StartPage defaults to 1.
+	StartPage int
+	// Prefetch is the number of pages fetched concurrently ahead of the
+	// consumer once the total page count is known. 0 or 1 fetches sequentially.
+	Prefetch int
+	// MaxPages stops the walk after that many pages; 0 means no limit.
+	MaxPages int
+}
+
+// Pages yields every page of svc in order until the page reported in
+// Meta.TotalPages or the first empty page. Providers that return no paging
+// metadata at all (Meta.Page and Meta.TotalPages both zero) are treated as a
+// single page. The walk stops after the first error, which is yielded, and
+// when ctx is cancelled or the consumer stops ranging.
+func Pages(ctx context.Context, svc interfaces.UserService, opts Options) iter.Seq2[integration.UserListResponseDTO, error] {
+	return func(yield func(integration.UserListResponseDTO, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		page := max(opts.StartPage, 1)
+		first, err := svc.GetUsers(ctx, page)
+		if err != nil {
+			yield(first, err)
+			return
+		}
+		if len(first.Users) == 0 {
+			return
+		}
+		if !yield(first, nil) || unpaged(first.Meta) || opts.MaxPages == 1 {
+			return
+		}
+
+		last := first.Meta.TotalPages
+		if opts.MaxPages > 0 && (last == 0 || last > page+opts.MaxPages-1) {
+			last = page + opts.MaxPages - 1
+		}
+
+		if first.Meta.TotalPages > 0 && opts.Prefetch > 1 {
+			prefetch(ctx, svc, page+1, last, opts.Prefetch, yield)
+			return
+		}
+
+		for p := page + 1; last == 0 || p <= last; p++ {
+			if ctx.Err() != nil {
+				yield(integration.UserListResponseDTO{}, ctx.Err())
+				return
+			}
+			res, err := svc.GetUsers(ctx, p)
+			if err != nil {
+				yield(res, err)
+				return
+			}
+			if len(res.Users) == 0 || !yield(res, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Users yields every user of every page; see Pages.
+func Users(ctx context.Context, svc interfaces.UserService, opts Options) iter.Seq2[integration.UserDTO, error] {
+	return func(yield func(integration.UserDTO, error) bool) {
+		for page, err := range Pages(ctx, svc, opts) {
+			if err != nil {
+				yield(integration.UserDTO{}, err)
+				return
+			}
+			for _, u := range page.Users {
+				if !yield(u, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Collect fetches every user of svc in one call.
+func Collect(ctx context.Context, svc interfaces.UserService, opts Options) ([]integration.UserDTO, error) {
+	var out []integration.UserDTO
+	for u, err := range Users(ctx, svc, opts) {
+		if err != nil {
+			return out, err
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func unpaged(m integration.MetaInfoDTO) bool {
+	return m.Page == 0 && m.TotalPages == 0
+}
+
+type pageResult struct {
+	res integration.UserListResponseDTO
+	err error
+}
+
+// prefetch fetches pages from..to with at most window requests in flight and
+// yields them in order. Returning cancels the outstanding requests through the
+// caller's context.
+func prefetch(ctx context.Context, svc interfaces.UserService, from, to, window int, yield func(integration.UserListResponseDTO, error) bool) {
+	pending := make([]chan pageResult, 0, window)
+	next := from
+
+	launch := func() {
+		ch := make(chan pageResult, 1)
+		go func(p int) {
+			res, err := svc.GetUsers(ctx, p)
+			ch <- pageResult{res: res, err: err}
+		}(next)
+		pending = append(pending, ch)
+		next++
+	}
+
+	for next <= to && len(pending) < window {
+		launch()
+	}
+	for len(pending) > 0 {
+		var r pageResult
+		select {
+		case r = <-pending[0]:
+		case <-ctx.Done():
+			yield(integration.UserListResponseDTO{}, ctx.Err())
+			return
+		}
+		pending = pending[1:]
+
+		if r.err != nil {
+			yield(r.res, r.err)
+			return
+		}
+		if len(r.res.Users) == 0 || !yield(r.res, nil) {
+			return
+		}
+		if next <= to {
+			launch()
+		}
+	}
+}
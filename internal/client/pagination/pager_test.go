@@ -0,0 +1,137 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/entity/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedService serves totalPages pages of perPage users; meta controls whether
+// paging metadata is reported.
+type pagedService struct {
+	totalPages int
+	perPage    int
+	meta       bool
+	failOn     int
+	delay      time.Duration
+
+	mu       sync.Mutex
+	fetched  []int
+	inFlight atomic.Int32
+	maxInFly atomic.Int32
+}
+
+func (s *pagedService) GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		m := s.maxInFly.Load()
+		if n <= m || s.maxInFly.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.fetched = append(s.fetched, page)
+	s.mu.Unlock()
+
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return integration.UserListResponseDTO{}, ctx.Err()
+		}
+	}
+	if page == s.failOn {
+		return integration.UserListResponseDTO{}, errors.New("boom")
+	}
+
+	res := integration.UserListResponseDTO{Provider: "paged"}
+	if s.meta {
+		res.Meta = integration.MetaInfoDTO{Page: page, PerPage: s.perPage, TotalPages: s.totalPages}
+	}
+	if page <= s.totalPages {
+		for i := 0; i < s.perPage; i++ {
+			res.Users = append(res.Users, integration.UserDTO{ID: user.ID(strconv.Itoa(page*100 + i))})
+		}
+	}
+	return res, nil
+}
+
+func ids(users []integration.UserDTO) []string {
+	out := make([]string, 0, len(users))
+	for _, u := range users {
+		out = append(out, string(u.ID))
+	}
+	return out
+}
+
+func TestCollect_WalksUntilTotalPages(t *testing.T) {
+	svc := &pagedService{totalPages: 3, perPage: 2, meta: true}
+	users, err := Collect(context.Background(), svc, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100", "101", "200", "201", "300", "301"}, ids(users))
+	assert.Equal(t, []int{1, 2, 3}, svc.fetched)
+}
+
+func TestCollect_UnpagedProviderIsOnePage(t *testing.T) {
+	svc := &pagedService{totalPages: 5, perPage: 2}
+	users, err := Collect(context.Background(), svc, Options{})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, []int{1}, svc.fetched)
+}
+
+func TestCollect_PrefetchKeepsOrderAndBound(t *testing.T) {
+	svc := &pagedService{totalPages: 8, perPage: 1, meta: true, delay: 5 * time.Millisecond}
+	users, err := Collect(context.Background(), svc, Options{Prefetch: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100", "200", "300", "400", "500", "600", "700", "800"}, ids(users))
+	assert.LessOrEqual(t, svc.maxInFly.Load(), int32(3))
+	assert.Greater(t, svc.maxInFly.Load(), int32(1))
+}
+
+func TestPages_StopsOnError(t *testing.T) {
+	svc := &pagedService{totalPages: 5, perPage: 1, meta: true, failOn: 3}
+	users, err := Collect(context.Background(), svc, Options{Prefetch: 2})
+	require.Error(t, err)
+	assert.Equal(t, []string{"100", "200"}, ids(users))
+}
+
+func TestPages_StopsOnCancellation(t *testing.T) {
+	svc := &pagedService{totalPages: 100, perPage: 1, meta: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var seen int
+	var lastErr error
+	for _, err := range Pages(ctx, svc, Options{}) {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		seen++
+		if seen == 2 {
+			cancel()
+		}
+	}
+	assert.Equal(t, 2, seen)
+	assert.ErrorIs(t, lastErr, context.Canceled)
+}
+
+func TestPages_MaxPages(t *testing.T) {
+	svc := &pagedService{totalPages: 10, perPage: 1, meta: true}
+	users, err := Collect(context.Background(), svc, Options{MaxPages: 4, StartPage: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"200", "300", "400", "500"}, ids(users))
+}
@@ -0,0 +1,84 @@
+/*
+Package webhook posts user events to the endpoints of webhook subscriptions.
+*/
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	clienthttp "__MODULE__/internal/client/internal/http"
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+)
+
+// Headers of a delivery. The signature is the hex HMAC-SHA256 of
+// "<id>.<timestamp>.<body>" keyed with the subscription secret, prefixed with
+// "sha256=", as expected by inbound provider webhooks.
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+)
+
+// responseDrainLimit is how much of a response body is read so the
+// connection can be reused.
+const responseDrainLimit = 64 << 10
+
+type sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+var _ interfaces.WebhookSender = (*sender)(nil)
+
+// NewSender returns a sender on the shared HTTP client stack, giving each
+// delivery DeliveryTimeout to be answered. Subscription URLs come from API
+// clients, so unless DeliveryAllowPrivateNetworks is set, deliveries only
+// connect to public addresses.
+func NewSender(cfg config.App) (*sender, error) {
+	client, err := clienthttp.NewClient(clienthttp.Options{
+		Name:             "webhooks",
+		Timeout:          time.Duration(cfg.DeliveryTimeout) * time.Millisecond,
+		HTTPClientConfig: cfg.HTTPClientConfig,
+		PublicOnly:       !cfg.DeliveryAllowPrivateNetworks,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sender{client: client, now: time.Now}, nil
+}
+
+func (s *sender) Deliver(ctx context.Context, req integration.WebhookDeliveryRequestDTO) (int, error) {
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(HeaderID, req.EventID)
+	httpReq.Header.Set(HeaderEvent, req.EventType)
+	httpReq.Header.Set(HeaderTimestamp, ts)
+	httpReq.Header.Set(HeaderSignature, "sha256="+hex.EncodeToString(pkg.SignWebhook(req.Secret, req.EventID, ts, req.Body)))
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, responseDrainLimit))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("subscriber answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
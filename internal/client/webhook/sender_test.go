@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLocalSender returns a sender allowed to reach the loopback test servers.
+func newLocalSender() (*sender, error) {
+	cfg := config.App{}
+	cfg.DeliveryAllowPrivateNetworks = true
+	return NewSender(cfg)
+}
+
+func TestSender_SignsDeliveries(t *testing.T) {
+	body := []byte(`{"id":"evt-1","type":"user.created"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, got)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "evt-1", r.Header.Get(HeaderID))
+		assert.Equal(t, "user.created", r.Header.Get(HeaderEvent))
+		assert.Equal(t, "1700000000", r.Header.Get(HeaderTimestamp))
+		want := "sha256=" + hex.EncodeToString(pkg.SignWebhook("s3cret", "evt-1", "1700000000", body))
+		assert.Equal(t, want, r.Header.Get(HeaderSignature))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s, err := newLocalSender()
+	require.NoError(t, err)
+	s.now = func() time.Time { return time.Unix(1_700_000_000, 0) }
+
+	status, err := s.Deliver(context.Background(), integration.WebhookDeliveryRequestDTO{
+		URL: srv.URL, Secret: "s3cret", EventID: "evt-1", EventType: "user.created", Body: body,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestSender_FailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, strings.Repeat("x", 1<<20), http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s, err := newLocalSender()
+	require.NoError(t, err)
+	status, err := s.Deliver(context.Background(), integration.WebhookDeliveryRequestDTO{URL: srv.URL, Body: []byte(`{}`)})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestSender_RefusesPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer srv.Close()
+
+	s, err := NewSender(config.App{})
+	require.NoError(t, err)
+	for _, url := range []string{srv.URL, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), "http://169.254.169.254/latest/meta-data"} {
+		_, err = s.Deliver(context.Background(), integration.WebhookDeliveryRequestDTO{URL: url, Body: []byte(`{}`)})
+		assert.ErrorContains(t, err, "address is not public", url)
+	}
+	assert.False(t, hit)
+}
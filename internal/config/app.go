@@ -10,6 +10,9 @@ type App struct {
 	UsecaseConfig
 	ProviderConfig
 	WorkerConfig
+	SCIMServerConfig
+	UserEventConsumerConfig
+	AdminConfig
 	AppConfig
 }
 
@@ -20,9 +23,69 @@ type AppConfig struct {
 	SettingsTTL string `envDefault:"30s" env:"SETTINGS_TTL"`
 }
 
+// KafkaConfig configures the message bus. Without brokers the bus is kept in
+// memory and only delivers within the process. Durations in milliseconds.
 type KafkaConfig struct {
-	// add kafka fields here when needed, e.g.
-	// Brokers []string `env:"KAFKA_BROKERS" envSeparator:","`
+	Brokers  []string `env:"KAFKA_BROKERS" envSeparator:","`
+	ClientID string   `env:"KAFKA_CLIENT_ID" envDefault:"user-service"`
+	// PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; empty disables SASL
+	SASLMechanism string `env:"KAFKA_SASL_MECHANISM" envDefault:""`
+	SASLUsername  string `env:"KAFKA_SASL_USERNAME" envDefault:""`
+	SASLPassword  string `env:"KAFKA_SASL_PASSWORD" envDefault:""`
+	TLSEnabled    bool   `env:"KAFKA_TLS_ENABLED" envDefault:"false"`
+	// PEM bundle trusted in addition to the system roots
+	TLSCACertFile string `env:"KAFKA_TLS_CA_CERT_FILE" envDefault:""`
+	// client certificate and key, for brokers requiring mutual TLS
+	TLSCertFile    string `env:"KAFKA_TLS_CERT_FILE" envDefault:""`
+	TLSKeyFile     string `env:"KAFKA_TLS_KEY_FILE" envDefault:""`
+	DialTimeout    int    `env:"KAFKA_DIAL_TIMEOUT" envDefault:"10000"`
+	RequestTimeout int    `env:"KAFKA_REQUEST_TIMEOUT" envDefault:"30000"`
+	// a consumer missing heartbeats for SessionTimeout is removed from its group
+	SessionTimeout    int `env:"KAFKA_SESSION_TIMEOUT" envDefault:"30000"`
+	HeartbeatInterval int `env:"KAFKA_HEARTBEAT_INTERVAL" envDefault:"3000"`
+	// delay before a message that was not acknowledged is delivered again
+	RetryBackoff int `env:"KAFKA_RETRY_BACKOFF" envDefault:"1000"`
+	// where a group without committed offsets starts: earliest or latest
+	StartOffset   string `env:"KAFKA_START_OFFSET" envDefault:"earliest"`
+	FetchMaxWait  int    `env:"KAFKA_FETCH_MAX_WAIT" envDefault:"500"`
+	FetchMaxBytes int    `env:"KAFKA_FETCH_MAX_BYTES" envDefault:"1048576"`
+}
+
+// AdminConfig protects the /admin endpoints, which manage provider instances,
+// syncs and webhook subscriptions. They are disabled while AdminToken is empty.
+type AdminConfig struct {
+	// bearer token administrators authenticate with
+	AdminToken string `env:"ADMIN_TOKEN" envDefault:""`
+}
+
+// SCIMServerConfig configures the SCIM 2.0 endpoints under /scim/v2 through
+// which identity providers push users. They are disabled while Token is empty.
+type SCIMServerConfig struct {
+	// bearer token the identity provider authenticates with
+	Token string `env:"SCIM_SERVER_TOKEN" envDefault:""`
+	// provider recorded on provisioned users; the endpoints only see these users
+	Provider string `env:"SCIM_SERVER_PROVIDER" envDefault:"scim-inbound"`
+	// upper bound and default of the count of a list request
+	MaxResults int `env:"SCIM_SERVER_MAX_RESULTS" envDefault:"200"`
+}
+
+// UserEventConsumerConfig configures the adapter applying the user events
+// upstream systems publish to ConsumerTopic on the message bus. It is disabled
+// while ConsumerTopic is empty. Users are stored under ConsumerProvider keyed
+// by their upstream id. A failing event is retried with exponential backoff,
+// delays in milliseconds; invalid events and those still failing after
+// ConsumerMaxAttempts go to ConsumerDeadLetterTopic.
+type UserEventConsumerConfig struct {
+	ConsumerTopic    string `env:"USER_EVENTS_CONSUMER_TOPIC" envDefault:""`
+	ConsumerGroup    string `env:"USER_EVENTS_CONSUMER_GROUP" envDefault:"user-service"`
+	ConsumerProvider string `env:"USER_EVENTS_CONSUMER_PROVIDER" envDefault:"bus-inbound"`
+	// empty means ConsumerTopic with a .dlq suffix
+	ConsumerDeadLetterTopic string `env:"USER_EVENTS_CONSUMER_DEAD_LETTER_TOPIC" envDefault:""`
+	ConsumerMaxAttempts     int    `env:"USER_EVENTS_CONSUMER_MAX_ATTEMPTS" envDefault:"5"`
+	ConsumerRetryBase       int    `env:"USER_EVENTS_CONSUMER_RETRY_BASE_DELAY" envDefault:"500"`
+	ConsumerRetryMax        int    `env:"USER_EVENTS_CONSUMER_RETRY_MAX_DELAY" envDefault:"30000"`
+	// hours the ids of applied events are remembered to drop redeliveries
+	ConsumerDedupeRetentionHours int `env:"USER_EVENTS_CONSUMER_DEDUPE_RETENTION_HOURS" envDefault:"168"`
 }
 
 type DatabaseConfig struct {
@@ -37,29 +100,174 @@ type DatabaseConfig struct {
 
 type UsecaseConfig struct {
 	// add fields as needed
+	RawArchiveConfig
+	UserUpsertConfig
+	WebhookConfig
+	OutboundWebhookConfig
+	OutboxConfig
+}
+
+// WebhookConfig tunes the asynchronous processing of inbound provider
+// webhooks. Events are applied by WebhookWorkers goroutines; a failing event
+// is retried with exponential backoff, delays in milliseconds.
+type WebhookConfig struct {
+	WebhookWorkers     int `env:"WEBHOOK_WORKERS" envDefault:"4"`
+	WebhookQueueSize   int `env:"WEBHOOK_QUEUE_SIZE" envDefault:"1000"`
+	WebhookMaxAttempts int `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"5"`
+	WebhookRetryBase   int `env:"WEBHOOK_RETRY_BASE_DELAY" envDefault:"500"`
+	WebhookRetryMax    int `env:"WEBHOOK_RETRY_MAX_DELAY" envDefault:"30000"`
+	// bytes of a delivery body read before it is rejected
+	WebhookMaxBodyBytes int64 `env:"WEBHOOK_MAX_BODY_BYTES" envDefault:"1048576"`
+}
+
+// OutboundWebhookConfig tunes the delivery of user events to webhook
+// subscriptions. Due deliveries are polled every DeliveryPollInterval; a
+// failed one is retried with exponential backoff. Durations in milliseconds.
+type OutboundWebhookConfig struct {
+	DeliveryPollInterval int `env:"WEBHOOK_DELIVERY_POLL_INTERVAL" envDefault:"1000"`
+	DeliveryBatchSize    int `env:"WEBHOOK_DELIVERY_BATCH_SIZE" envDefault:"50"`
+	DeliveryTimeout      int `env:"WEBHOOK_DELIVERY_TIMEOUT" envDefault:"10000"`
+	DeliveryMaxAttempts  int `env:"WEBHOOK_DELIVERY_MAX_ATTEMPTS" envDefault:"8"`
+	DeliveryRetryBase    int `env:"WEBHOOK_DELIVERY_RETRY_BASE_DELAY" envDefault:"5000"`
+	DeliveryRetryMax     int `env:"WEBHOOK_DELIVERY_RETRY_MAX_DELAY" envDefault:"3600000"`
+	// lets deliveries reach loopback, private and link-local addresses,
+	// which are refused by default
+	DeliveryAllowPrivateNetworks bool `env:"WEBHOOK_DELIVERY_ALLOW_PRIVATE_NETWORKS" envDefault:"false"`
+}
+
+// OutboxConfig controls the transactional outbox of user events. When enabled,
+// each change to a user stores its event in the same transaction, and a relay
+// publishes the stored events to OutboxTopic every OutboxPollInterval. Sent
+// events are purged every OutboxPurgeInterval once older than
+// OutboxRetentionHours; 0 keeps them forever. Durations in milliseconds.
+type OutboxConfig struct {
+	OutboxEnabled        bool   `env:"OUTBOX_ENABLED" envDefault:"false"`
+	OutboxTopic          string `env:"OUTBOX_TOPIC" envDefault:"user-events"`
+	OutboxPollInterval   int    `env:"OUTBOX_POLL_INTERVAL" envDefault:"1000"`
+	OutboxBatchSize      int    `env:"OUTBOX_BATCH_SIZE" envDefault:"100"`
+	OutboxRetentionHours int    `env:"OUTBOX_RETENTION_HOURS" envDefault:"168"`
+	OutboxPurgeInterval  int    `env:"OUTBOX_PURGE_INTERVAL" envDefault:"3600000"`
+}
+
+// UserUpsertConfig controls how provider users are written to the users table.
+// A column policy is one of overwrite, keep or coalesce (keep the stored value
+// when the provider sends none), e.g. USER_UPSERT_COLUMN_POLICIES=email:keep,attributes:coalesce
+type UserUpsertConfig struct {
+	UpsertBatchSize      int            `env:"USER_UPSERT_BATCH_SIZE" envDefault:"100"`
+	UpsertDefaultPolicy  ColumnPolicy   `env:"USER_UPSERT_DEFAULT_POLICY" envDefault:"overwrite"`
+	UpsertColumnPolicies ColumnPolicies `env:"USER_UPSERT_COLUMN_POLICIES" envDefault:""`
+}
+
+// RawArchiveConfig controls archiving of raw provider responses, kept so users
+// can be re-mapped from history after a mapper fix.
+type RawArchiveConfig struct {
+	RawArchiveEnabled bool `env:"PROVIDER_RAW_ARCHIVE_ENABLED" envDefault:"false"`
+	// days an archived response is kept; 0 keeps them forever
+	RawArchiveRetentionDays int `env:"PROVIDER_RAW_ARCHIVE_RETENTION_DAYS" envDefault:"30"`
 }
 
 type ProviderConfig struct {
 	ReqresConfig
 	JsonplaceholderConfig
+	ProviderInstancesConfig
+	ResilienceConfig
+	HTTPClientConfig
+	PluginHostConfig
+}
+
+// PluginHostConfig restricts the executables plugin provider instances may
+// run. Plugin instances are rejected while PluginDirs is empty, and can only
+// be declared in PROVIDER_INSTANCES, never through the admin API.
+type PluginHostConfig struct {
+	// directories whose executables may be started as plugins
+	PluginDirs []string `env:"PLUGIN_DIRS" envSeparator:","`
+}
+
+// HTTPClientConfig configures the HTTP client stack shared by all provider integrations.
+type HTTPClientConfig struct {
+	MaxIdleConns        int `env:"HTTP_CLIENT_MAX_IDLE_CONNS" envDefault:"100"`
+	MaxIdleConnsPerHost int `env:"HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST" envDefault:"10"`
+	// 0 means unlimited
+	MaxConnsPerHost int `env:"HTTP_CLIENT_MAX_CONNS_PER_HOST" envDefault:"0"`
+	// seconds
+	IdleConnTimeout int `env:"HTTP_CLIENT_IDLE_CONN_TIMEOUT" envDefault:"90"`
+	// empty falls back to HTTP_PROXY / HTTPS_PROXY
+	ProxyURL string `env:"HTTP_CLIENT_PROXY_URL" envDefault:""`
+	// PEM bundle trusted in addition to the system roots
+	CACertFile string `env:"HTTP_CLIENT_CA_CERT_FILE" envDefault:""`
+	LogBodies  bool   `env:"HTTP_CLIENT_LOG_BODIES" envDefault:"false"`
+	// bytes of each body kept in logs
+	LogBodyLimit int `env:"HTTP_CLIENT_LOG_BODY_LIMIT" envDefault:"2048"`
+	// CassetteMode records every exchange to, or replays it from, a file per
+	// client in CassetteDir with secrets redacted: "record", "replay" or empty
+	// for live traffic. Meant for tests.
+	CassetteMode string `env:"HTTP_CLIENT_CASSETTE_MODE" envDefault:""`
+	CassetteDir  string `env:"HTTP_CLIENT_CASSETTE_DIR" envDefault:"testdata/cassettes"`
+}
+
+// ResilienceConfig holds the default circuit breaker and bulkhead settings wrapped
+// around every provider instance. Instances may override them in their config.
+type ResilienceConfig struct {
+	// consecutive failures that open the circuit
+	BreakerFailureThreshold int `env:"PROVIDER_BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	// seconds the circuit stays open before a half-open probe is let through
+	BreakerOpenTimeout int `env:"PROVIDER_BREAKER_OPEN_TIMEOUT" envDefault:"30"`
+	// concurrent probe calls allowed while half-open
+	BreakerHalfOpenMaxCalls int `env:"PROVIDER_BREAKER_HALF_OPEN_MAX_CALLS" envDefault:"1"`
+	// concurrent calls allowed into a single provider instance
+	BulkheadMaxConcurrent int `env:"PROVIDER_BULKHEAD_MAX_CONCURRENT" envDefault:"10"`
+	// milliseconds a call may wait for a free bulkhead slot before it is rejected
+	BulkheadMaxWait int `env:"PROVIDER_BULKHEAD_MAX_WAIT" envDefault:"0"`
+}
+
+// ProviderInstancesConfig declares the provider instances registered at startup.
+// PROVIDER_INSTANCES is a JSON array, e.g.
+// [{"id":"tenant-a","type":"reqres","credentials":{"api_key":"..."}},{"id":"tenant-b","type":"jsonplaceholder"}]
+type ProviderInstancesConfig struct {
+	Instances ProviderInstances `env:"PROVIDER_INSTANCES" envDefault:"[{\"id\":\"jsonplaceholder\",\"type\":\"jsonplaceholder\"}]"`
+	// DefaultProvider is the instance id used when a request does not select one.
+	// Falls back to the first declared instance when empty.
+	DefaultProvider string `env:"PROVIDER_DEFAULT" envDefault:""`
 }
 
+// Timeout is the overall deadline of a provider call and AttemptTimeout the
+// deadline of a single HTTP attempt, both in seconds. Retry delays are in
+// milliseconds and grow exponentially from RetryBaseDelay up to RetryMaxDelay,
+// which also caps a Retry-After sent by the provider.
+// Setting AuthenticationUrl enables OAuth2 (GrantType, default client_credentials);
+// tokens are refreshed at least every RefreshTokenInterval seconds.
 type ReqresConfig struct {
-	BaseUrl              string `env:"REQRES_BASE_URL"  envDefault:"30"`
+	BaseUrl              string `env:"REQRES_BASE_URL"  envDefault:"https://reqres.in"`
 	AuthenticationUrl    string `env:"REQRES_AUTHENTICATION_URL"  envDefault:""`
+	ClientID             string `env:"REQRES_CLIENT_ID" envDefault:""`
+	ClientSecret         string `env:"REQRES_CLIENT_SECRET" envDefault:""`
 	Timeout              int    `env:"REQRES_TIMEOUT" envDefault:"30"`
+	AttemptTimeout       int    `env:"REQRES_ATTEMPT_TIMEOUT" envDefault:"10"`
 	RetryCount           int    `env:"REQRES_RETRY_COUNT" envDefault:"0"`
+	RetryBaseDelay       int    `env:"REQRES_RETRY_BASE_DELAY" envDefault:"200"`
+	RetryMaxDelay        int    `env:"REQRES_RETRY_MAX_DELAY" envDefault:"5000"`
 	RefreshTokenInterval int    `env:"REQRES_REFRESH_TOKEN_INTERVAL" envDefault:"600"`
 	GrantType            string `env:"REQRES_GRANT_TYPE" envDefault:"client_credentials"`
 }
 
 type JsonplaceholderConfig struct {
+	BaseUrl              string `env:"JSONPLACEHOLDER_BASE_URL" envDefault:"https://jsonplaceholder.typicode.com"`
+	AuthenticationUrl    string `env:"JSONPLACEHOLDER_AUTHENTICATION_URL" envDefault:""`
+	ClientID             string `env:"JSONPLACEHOLDER_CLIENT_ID" envDefault:""`
+	ClientSecret         string `env:"JSONPLACEHOLDER_CLIENT_SECRET" envDefault:""`
 	Timeout              int    `env:"JSONPLACEHOLDER_TIMEOUT" envDefault:"30"`
+	AttemptTimeout       int    `env:"JSONPLACEHOLDER_ATTEMPT_TIMEOUT" envDefault:"10"`
 	RetryCount           int    `env:"JSONPLACEHOLDER_RETRY_COUNT" envDefault:"0"`
+	RetryBaseDelay       int    `env:"JSONPLACEHOLDER_RETRY_BASE_DELAY" envDefault:"200"`
+	RetryMaxDelay        int    `env:"JSONPLACEHOLDER_RETRY_MAX_DELAY" envDefault:"5000"`
 	RefreshTokenInterval int    `env:"JSONPLACEHOLDER_REFRESH_TOKEN_INTERVAL" envDefault:"600"`
 	GrantType            string `env:"JSONPLACEHOLDER_GRANT_TYPE" envDefault:"client_credentials"`
 }
 
 type WorkerConfig struct {
 	ExpirePendingEndOfDay string `env:"EXPIRE_PENDING_END_OF_DAY" envDefault:"0 0 * * *"`
+	// ProviderSync is the cron schedule of the full provider synchronisation; empty disables it.
+	ProviderSync string `env:"PROVIDER_SYNC_SCHEDULE" envDefault:"*/30 * * * *"`
+	// RawArchivePurge is the cron schedule of the raw response retention purge; empty disables it.
+	RawArchivePurge string `env:"RAW_ARCHIVE_PURGE_SCHEDULE" envDefault:"0 3 * * *"`
 }
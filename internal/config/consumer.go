@@ -0,0 +1,9 @@
+package config
+
+// DeadLetterTopic returns the topic failed events are routed to.
+func (c UserEventConsumerConfig) DeadLetterTopic() string {
+	if c.ConsumerDeadLetterTopic != "" {
+		return c.ConsumerDeadLetterTopic
+	}
+	return c.ConsumerTopic + ".dlq"
+}
@@ -0,0 +1,354 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProviderInstance is a single provider instance declared in configuration.
+// Zero values fall back to the provider type's own config (e.g. ReqresConfig).
+type ProviderInstance struct {
+	ID          string              `json:"id"`
+	Type        string              `json:"type"`
+	BaseURL     string              `json:"base_url,omitempty"`
+	Credentials ProviderCredentials `json:"credentials,omitempty"`
+	// Timeout is the overall deadline of a provider call and AttemptTimeout the
+	// deadline of a single attempt, both in seconds.
+	Timeout        int `json:"timeout,omitempty"`
+	AttemptTimeout int `json:"attempt_timeout,omitempty"`
+	RetryCount     int `json:"retry_count,omitempty"`
+	// ProxyURL and CACertFile override HTTPClientConfig for this instance.
+	ProxyURL   string `json:"proxy_url,omitempty"`
+	CACertFile string `json:"ca_cert_file,omitempty"`
+	// Auth opts the instance into OAuth2 token authentication.
+	Auth *ProviderAuth `json:"auth,omitempty"`
+	// Resilience overrides the ResilienceConfig defaults for this instance.
+	Resilience *ResilienceOverride `json:"resilience,omitempty"`
+	// Aggregate configures the composite "aggregate" provider type.
+	Aggregate *AggregateConfig `json:"aggregate,omitempty"`
+	// Failover configures the composite "failover" provider type.
+	Failover *FailoverConfig `json:"failover,omitempty"`
+	// HTTPJSON configures the declarative "http-json" provider type.
+	HTTPJSON *HTTPJSONConfig `json:"http_json,omitempty"`
+	// Plugin configures the subprocess "plugin" provider type.
+	Plugin *PluginConfig `json:"plugin,omitempty"`
+	// File configures the local "file" provider type.
+	File *FileConfig `json:"file,omitempty"`
+	// SCIM configures the "scim" provider type; base_url is the SCIM root,
+	// e.g. https://idp.example.com/scim/v2.
+	SCIM *SCIMConfig `json:"scim,omitempty"`
+	// Webhook lets the provider push user changes to POST /webhooks/{id}.
+	Webhook *ProviderWebhookConfig `json:"webhook,omitempty"`
+}
+
+// ProviderWebhookConfig enables inbound webhooks for an instance. Every
+// delivery carries a unix timestamp, a unique id and the hex HMAC-SHA256 of
+// "<id>.<timestamp>.<body>" keyed with Secret, optionally prefixed with
+// "sha256=".
+type ProviderWebhookConfig struct {
+	Secret string `json:"secret"`
+	// PreviousSecret is still accepted while the provider rotates to Secret.
+	PreviousSecret string `json:"previous_secret,omitempty"`
+	// Tolerance is how many seconds the timestamp may differ from our clock,
+	// default 300. Ids are remembered for as long.
+	Tolerance int `json:"tolerance,omitempty"`
+	// Header names, by default X-Webhook-Signature, X-Webhook-Timestamp and
+	// X-Webhook-Id.
+	SignatureHeader string `json:"signature_header,omitempty"`
+	TimestampHeader string `json:"timestamp_header,omitempty"`
+	IDHeader        string `json:"id_header,omitempty"`
+}
+
+// AggregateConfig describes an instance that merges the users of other
+// registered instances. Members are instance ids; their order is the default
+// field precedence, first wins.
+type AggregateConfig struct {
+	Members []string `json:"members"`
+	// MemberTimeout is the deadline of each member call in seconds; 0 leaves
+	// only the caller's deadline.
+	MemberTimeout int `json:"member_timeout,omitempty"`
+	// MinSuccess is how many members must answer for a page to be served;
+	// defaults to 1.
+	MinSuccess int `json:"min_success,omitempty"`
+	// IdentityKeys are the fields users are matched on, any of "email" and
+	// "phone"; defaults to both.
+	IdentityKeys []string `json:"identity_keys,omitempty"`
+	// Precedence overrides the member order per field (name, username, email,
+	// phone, website or an Extra key); "*" applies to every other field.
+	Precedence map[string][]string `json:"precedence,omitempty"`
+}
+
+// FailoverConfig describes an instance that serves from Primary and falls back
+// to Secondaries, in order, when it fails or times out. Shadow names a
+// candidate instance called in the background with the same page; its users
+// are compared with the served ones and the differences logged and counted,
+// but never returned.
+type FailoverConfig struct {
+	Primary     string   `json:"primary"`
+	Secondaries []string `json:"secondaries,omitempty"`
+	// AttemptTimeout is the deadline of each primary or secondary call in
+	// seconds; 0 leaves only the caller's deadline.
+	AttemptTimeout int    `json:"attempt_timeout,omitempty"`
+	Shadow         string `json:"shadow,omitempty"`
+	// ShadowTimeout is the deadline of the shadow call in seconds, default 30.
+	ShadowTimeout int `json:"shadow_timeout,omitempty"`
+}
+
+// HTTPJSONConfig describes a JSON upstream without code: where the users are,
+// how to page through them and how their fields map to a user. Expressions are
+// JSONPath ($.data[*].name, $['first name'], $.items[0]); a field mapping may
+// also be a template such as "{$.first_name} {$.last_name}".
+type HTTPJSONConfig struct {
+	// Path is appended to the instance base_url, e.g. "/api/users".
+	Path string `json:"path"`
+	// Headers are sent with every request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Query parameters are sent with every request.
+	Query map[string]string `json:"query,omitempty"`
+	// APIKeyHeader (default X-Api-Key) or APIKeyQuery carries
+	// credentials.api_key; credentials.token is sent as a bearer token.
+	APIKeyHeader string `json:"api_key_header,omitempty"`
+	APIKeyQuery  string `json:"api_key_query,omitempty"`
+	// Users selects the user array of a response, default "$" (the response
+	// is the array).
+	Users      string             `json:"users,omitempty"`
+	Pagination HTTPJSONPagination `json:"pagination,omitempty"`
+	// Fields maps id, name, username, email, phone and website to expressions
+	// relative to a user; any other key is mapped into Extra. id is required.
+	Fields map[string]string `json:"fields"`
+}
+
+// HTTPJSONPagination is the paging style of an http-json upstream: "none",
+// "page" (PageParam, 1-based unless FirstPage is set), "offset" (OffsetParam
+// and LimitParam) or "cursor" (CursorParam, the next cursor read from
+// NextCursor). TotalPages and Total are optional expressions on the response.
+type HTTPJSONPagination struct {
+	Style       string `json:"style,omitempty"`
+	PageParam   string `json:"page_param,omitempty"`
+	FirstPage   *int   `json:"first_page,omitempty"`
+	SizeParam   string `json:"size_param,omitempty"`
+	Size        int    `json:"size,omitempty"`
+	OffsetParam string `json:"offset_param,omitempty"`
+	LimitParam  string `json:"limit_param,omitempty"`
+	CursorParam string `json:"cursor_param,omitempty"`
+	NextCursor  string `json:"next_cursor,omitempty"`
+	TotalPages  string `json:"total_pages,omitempty"`
+	Total       string `json:"total,omitempty"`
+}
+
+// PluginConfig describes an external executable speaking the protocol of
+// pkg/plugin over stdin and stdout. Durations are in seconds. The plugin only
+// sees PATH, HOME and TMPDIR of the service environment plus Env.
+type PluginConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	// Config is handed to the plugin as is in the handshake.
+	Config json.RawMessage `json:"config,omitempty"`
+	// CallTimeout bounds a get_users call (default 30), StartTimeout the start
+	// and handshake (default 10).
+	CallTimeout  int `json:"call_timeout,omitempty"`
+	StartTimeout int `json:"start_timeout,omitempty"`
+	// HealthInterval is how often the plugin is health checked (default 30);
+	// negative disables the checks.
+	HealthInterval int `json:"health_interval,omitempty"`
+	// MaxRestarts bounds the restarts within RestartWindow (defaults 5 and 60)
+	// before the plugin is given up on.
+	MaxRestarts   int `json:"max_restarts,omitempty"`
+	RestartWindow int `json:"restart_window,omitempty"`
+	// Resource limits of the plugin process; zero leaves them unlimited.
+	MaxMemoryMB     int `json:"max_memory_mb,omitempty"`
+	MaxCPUSeconds   int `json:"max_cpu_seconds,omitempty"`
+	MaxOpenFiles    int `json:"max_open_files,omitempty"`
+	MaxMessageBytes int `json:"max_message_bytes,omitempty"` // default 16 MiB
+}
+
+// FileConfig describes users read from local CSV, JSON or NDJSON files. Path may
+// be a glob; matching files are read in name order and served as one list.
+type FileConfig struct {
+	Path string `json:"path"`
+	// Format is "csv", "json" or "ndjson"; empty picks it from each file's
+	// extension.
+	Format string `json:"format,omitempty"`
+	// Delimiter separates CSV columns; defaults to ",". The first row names
+	// the columns.
+	Delimiter string `json:"delimiter,omitempty"`
+	// Users selects the users in a JSON document; empty expects an array.
+	Users string `json:"users,omitempty"`
+	// Fields maps user fields to columns or expressions as in HTTPJSONConfig,
+	// e.g. {"id":"user_id","name":"{first} {last}"}; empty maps id, name,
+	// username, email, phone and website to the columns of the same name.
+	Fields map[string]string `json:"fields,omitempty"`
+	// PageSize is the number of users per page; defaults to 50.
+	PageSize int `json:"page_size,omitempty"`
+	// WatchInterval is how often, in seconds, the files are checked for
+	// changes (default 5); negative disables watching.
+	WatchInterval int `json:"watch_interval,omitempty"`
+}
+
+// SCIMConfig tunes how a SCIM 2.0 directory is paged. The instance
+// authenticates with credentials.token as a bearer token, with
+// credentials.username and password as basic auth, or with OAuth2 via auth.
+type SCIMConfig struct {
+	// Filter is a SCIM filter expression sent with every request, e.g.
+	// `active eq true and emails.type eq "work"`.
+	Filter string `json:"filter,omitempty"`
+	// Count is the page size requested; defaults to 100.
+	Count int `json:"count,omitempty"`
+	// Attributes limits the attributes returned by the directory.
+	Attributes []string `json:"attributes,omitempty"`
+}
+
+// ResilienceOverride mirrors ResilienceConfig; zero values keep the defaults.
+type ResilienceOverride struct {
+	BreakerFailureThreshold int `json:"breaker_failure_threshold,omitempty"`
+	BreakerOpenTimeout      int `json:"breaker_open_timeout,omitempty"`
+	BreakerHalfOpenMaxCalls int `json:"breaker_half_open_max_calls,omitempty"`
+	BulkheadMaxConcurrent   int `json:"bulkhead_max_concurrent,omitempty"`
+	BulkheadMaxWait         int `json:"bulkhead_max_wait,omitempty"`
+}
+
+// Apply returns c with every non-zero field of o applied.
+func (o *ResilienceOverride) Apply(c ResilienceConfig) ResilienceConfig {
+	if o == nil {
+		return c
+	}
+	if o.BreakerFailureThreshold > 0 {
+		c.BreakerFailureThreshold = o.BreakerFailureThreshold
+	}
+	if o.BreakerOpenTimeout > 0 {
+		c.BreakerOpenTimeout = o.BreakerOpenTimeout
+	}
+	if o.BreakerHalfOpenMaxCalls > 0 {
+		c.BreakerHalfOpenMaxCalls = o.BreakerHalfOpenMaxCalls
+	}
+	if o.BulkheadMaxConcurrent > 0 {
+		c.BulkheadMaxConcurrent = o.BulkheadMaxConcurrent
+	}
+	if o.BulkheadMaxWait > 0 {
+		c.BulkheadMaxWait = o.BulkheadMaxWait
+	}
+	return c
+}
+
+// ProviderAuth configures OAuth2 for an instance; client id and secret come from
+// Credentials. RefreshInterval is in seconds.
+type ProviderAuth struct {
+	TokenURL        string   `json:"token_url,omitempty"`
+	GrantType       string   `json:"grant_type,omitempty"`
+	Scopes          []string `json:"scopes,omitempty"`
+	RefreshInterval int      `json:"refresh_interval,omitempty"`
+}
+
+// ProviderCredentials holds whatever secrets a provider type needs; unused fields stay empty.
+type ProviderCredentials struct {
+	APIKey       string `json:"api_key,omitempty"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password,omitempty"`
+	Token        string `json:"token,omitempty"`
+}
+
+// ProviderInstances is parsed from a JSON array in the environment.
+type ProviderInstances []ProviderInstance
+
+// UnmarshalText implements encoding.TextUnmarshaler so env can parse PROVIDER_INSTANCES.
+func (p *ProviderInstances) UnmarshalText(text []byte) error {
+	var instances []ProviderInstance
+	if err := json.Unmarshal(text, &instances); err != nil {
+		return fmt.Errorf("invalid provider instances: %w", err)
+	}
+	seen := make(map[string]struct{}, len(instances))
+	for i, inst := range instances {
+		if inst.ID == "" || inst.Type == "" {
+			return fmt.Errorf("provider instance %d: id and type are required", i)
+		}
+		if _, ok := seen[inst.ID]; ok {
+			return fmt.Errorf("provider instance %q declared twice", inst.ID)
+		}
+		seen[inst.ID] = struct{}{}
+	}
+	*p = instances
+	return nil
+}
+
+// Default returns the id of the instance used when a request does not pick one.
+func (c ProviderInstancesConfig) Default() string {
+	if c.DefaultProvider != "" {
+		return c.DefaultProvider
+	}
+	if len(c.Instances) > 0 {
+		return c.Instances[0].ID
+	}
+	return ""
+}
+
+// String returns the instance as the JSON config string accepted by provider factories.
+func (p ProviderInstance) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// ParseProviderInstance decodes a provider factory config string. An empty
+// string yields the zero instance so factories fall back to their defaults.
+func ParseProviderInstance(raw string) (ProviderInstance, error) {
+	var inst ProviderInstance
+	if raw == "" {
+		return inst, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &inst); err != nil {
+		return inst, fmt.Errorf("invalid provider config: %w", err)
+	}
+	return inst, nil
+}
+
+// Redacted returns a copy of the instance with every credential masked,
+// including the plugin environment and the static http-json headers and
+// query parameters.
+func (p ProviderInstance) Redacted() ProviderInstance {
+	mask := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "<redacted>"
+	}
+	c := p.Credentials
+	p.Credentials = ProviderCredentials{
+		APIKey:       mask(c.APIKey),
+		ClientID:     c.ClientID,
+		ClientSecret: mask(c.ClientSecret),
+		Username:     c.Username,
+		Password:     mask(c.Password),
+		Token:        mask(c.Token),
+	}
+	if p.Webhook != nil {
+		webhook := *p.Webhook
+		webhook.Secret, webhook.PreviousSecret = mask(webhook.Secret), mask(webhook.PreviousSecret)
+		p.Webhook = &webhook
+	}
+	maskAll := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		out := make(map[string]string, len(m))
+		for k, v := range m {
+			out[k] = mask(v)
+		}
+		return out
+	}
+	if p.Plugin != nil {
+		plugin := *p.Plugin
+		plugin.Env = maskAll(plugin.Env)
+		p.Plugin = &plugin
+	}
+	// static headers and query parameters usually carry the upstream's
+	// Authorization header or API key, so none of them are shown
+	if p.HTTPJSON != nil {
+		httpJSON := *p.HTTPJSON
+		httpJSON.Headers, httpJSON.Query = maskAll(httpJSON.Headers), maskAll(httpJSON.Query)
+		p.HTTPJSON = &httpJSON
+	}
+	return p
+}
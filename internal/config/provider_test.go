@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderInstance_Redacted(t *testing.T) {
+	inst := ProviderInstance{
+		ID:          "crm",
+		Type:        "http-json",
+		Credentials: ProviderCredentials{APIKey: "key", ClientID: "client", Password: "pw"},
+		Webhook:     &ProviderWebhookConfig{Secret: "hook-secret"},
+		HTTPJSON: &HTTPJSONConfig{
+			Path:    "/v1/contacts",
+			Headers: map[string]string{"Authorization": "Bearer abc", "X-Tenant": "acme"},
+			Query:   map[string]string{"api_key": "k", "per_page": "50"},
+		},
+		Plugin: &PluginConfig{Command: "/opt/plugins/partner", Env: map[string]string{"PARTNER_TOKEN": "t"}},
+	}
+
+	out := inst.Redacted()
+	assert.Equal(t, "<redacted>", out.Credentials.APIKey)
+	assert.Equal(t, "client", out.Credentials.ClientID)
+	assert.Equal(t, "<redacted>", out.Credentials.Password)
+	assert.Equal(t, "<redacted>", out.Webhook.Secret)
+	assert.Equal(t, map[string]string{"Authorization": "<redacted>", "X-Tenant": "<redacted>"}, out.HTTPJSON.Headers)
+	assert.Equal(t, map[string]string{"api_key": "<redacted>", "per_page": "<redacted>"}, out.HTTPJSON.Query)
+	assert.Equal(t, "/v1/contacts", out.HTTPJSON.Path)
+	assert.Equal(t, map[string]string{"PARTNER_TOKEN": "<redacted>"}, out.Plugin.Env)
+	assert.NotContains(t, out.String(), "Bearer abc")
+
+	// the original is left alone
+	assert.Equal(t, "Bearer abc", inst.HTTPJSON.Headers["Authorization"])
+	assert.Equal(t, "t", inst.Plugin.Env["PARTNER_TOKEN"])
+	assert.Equal(t, "hook-secret", inst.Webhook.Secret)
+}
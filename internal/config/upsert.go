@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnPolicy is the upsert policy of a users column.
+type ColumnPolicy string
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *ColumnPolicy) UnmarshalText(text []byte) error {
+	switch v := ColumnPolicy(strings.TrimSpace(string(text))); v {
+	case "overwrite", "keep", "coalesce":
+		*p = v
+		return nil
+	default:
+		return fmt.Errorf("unknown column policy %q, want overwrite, keep or coalesce", v)
+	}
+}
+
+// ColumnPolicies maps users columns to their upsert policy, parsed from
+// "column:policy" pairs separated by commas.
+type ColumnPolicies map[string]ColumnPolicy
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *ColumnPolicies) UnmarshalText(text []byte) error {
+	out := ColumnPolicies{}
+	for _, pair := range strings.Split(string(text), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		column, policy, ok := strings.Cut(pair, ":")
+		if !ok {
+			return fmt.Errorf("invalid column policy %q, want column:policy", pair)
+		}
+		var p ColumnPolicy
+		if err := p.UnmarshalText([]byte(policy)); err != nil {
+			return fmt.Errorf("column %s: %w", column, err)
+		}
+		out[strings.TrimSpace(column)] = p
+	}
+	*c = out
+	return nil
+}
@@ -0,0 +1,25 @@
+package consumer
+
+import "time"
+
+// UserEventMessage is the schema of the user events upstream systems publish:
+// the body outbound webhooks and the outbox send, with the user identified by
+// its upstream id. Unknown fields are ignored.
+type UserEventMessage struct {
+	ID         string        `json:"id" validate:"required,max=255"`
+	Type       string        `json:"type" validate:"required,oneof=user.created user.updated user.deleted"`
+	OccurredAt time.Time     `json:"occurred_at" validate:"required"`
+	User       UserEventUser `json:"user"`
+}
+
+// UserEventUser is the state of the user after the change; only the id is
+// required, and deleted events need nothing else.
+type UserEventUser struct {
+	ID       string            `json:"id" validate:"required,max=255"`
+	Name     string            `json:"name,omitempty" validate:"max=255"`
+	Username string            `json:"username,omitempty" validate:"max=255"`
+	Email    string            `json:"email,omitempty" validate:"omitempty,email"`
+	Phone    string            `json:"phone,omitempty" validate:"max=64"`
+	Website  string            `json:"website,omitempty" validate:"max=2048"`
+	Extra    map[string]string `json:"extra,omitempty" validate:"max=100"`
+}
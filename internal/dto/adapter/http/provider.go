@@ -0,0 +1,51 @@
+package api
+
+import "encoding/json"
+
+// RegisterProviderRequestDTO defines model for RegisterProviderRequestDTO.
+type RegisterProviderRequestDTO struct {
+	Id     string          `json:"id" validate:"required"`
+	Type   string          `json:"type" validate:"required"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// ReplaceProviderRequestDTO defines model for ReplaceProviderRequestDTO.
+type ReplaceProviderRequestDTO struct {
+	Type   string          `json:"type" validate:"required"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// ProviderResponse defines model for ProviderResponse.
+type ProviderResponse struct {
+	Id           string                      `json:"id"`
+	Type         string                      `json:"type"`
+	Status       string                      `json:"status"`
+	Config       json.RawMessage             `json:"config,omitempty"`
+	RegisteredAt string                      `json:"registered_at"`
+	Resilience   *ProviderResilienceResponse `json:"resilience,omitempty"`
+}
+
+// ProviderResilienceResponse defines model for ProviderResilienceResponse.
+type ProviderResilienceResponse struct {
+	Breaker             string  `json:"breaker"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	OpenedAt            *string `json:"opened_at,omitempty"`
+	InFlight            int     `json:"in_flight"`
+	MaxConcurrent       int     `json:"max_concurrent"`
+}
+
+// HealthResponse defines model for HealthResponse.
+type HealthResponse struct {
+	Status    string             `json:"status"`
+	Providers []ProviderResponse `json:"providers"`
+}
+
+// GetProvidersResponse defines model for GetProvidersResponse.
+type GetProvidersResponse struct {
+	Providers []ProviderResponse `json:"providers"`
+}
+
+// GetProviderTypesResponse defines model for GetProviderTypesResponse.
+type GetProviderTypesResponse struct {
+	Types []string `json:"types"`
+}
@@ -0,0 +1,114 @@
+package api
+
+// SCIM 2.0 schema and message URNs (RFC 7643, RFC 7644).
+const (
+	SCIMUserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMEnterpriseUserSchema        = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SCIMListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMResourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SCIMSchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+// SCIMUser defines model for a SCIM User resource.
+type SCIMUser struct {
+	Schemas           []string            `json:"schemas"`
+	Id                string              `json:"id,omitempty"`
+	ExternalId        string              `json:"externalId,omitempty"`
+	UserName          string              `json:"userName"`
+	Name              *SCIMName           `json:"name,omitempty"`
+	DisplayName       string              `json:"displayName,omitempty"`
+	NickName          string              `json:"nickName,omitempty"`
+	ProfileUrl        string              `json:"profileUrl,omitempty"`
+	Title             string              `json:"title,omitempty"`
+	UserType          string              `json:"userType,omitempty"`
+	PreferredLanguage string              `json:"preferredLanguage,omitempty"`
+	Locale            string              `json:"locale,omitempty"`
+	Timezone          string              `json:"timezone,omitempty"`
+	Active            *bool               `json:"active,omitempty"`
+	Emails            []SCIMMultiValue    `json:"emails,omitempty"`
+	PhoneNumbers      []SCIMMultiValue    `json:"phoneNumbers,omitempty"`
+	Photos            []SCIMMultiValue    `json:"photos,omitempty"`
+	Addresses         []SCIMAddress       `json:"addresses,omitempty"`
+	Enterprise        *SCIMEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta              *SCIMMeta           `json:"meta,omitempty"`
+}
+
+// SCIMName defines model for the name of a SCIM User.
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// SCIMMultiValue defines model for an entry of emails, phoneNumbers or photos.
+type SCIMMultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMAddress defines model for an entry of addresses.
+type SCIMAddress struct {
+	Locality string `json:"locality,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Primary  bool   `json:"primary,omitempty"`
+}
+
+// SCIMManager defines model for the manager of an enterprise User.
+type SCIMManager struct {
+	Value       string `json:"value,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// SCIMEnterpriseUser defines model for the enterprise User extension.
+type SCIMEnterpriseUser struct {
+	EmployeeNumber string       `json:"employeeNumber,omitempty"`
+	CostCenter     string       `json:"costCenter,omitempty"`
+	Organization   string       `json:"organization,omitempty"`
+	Division       string       `json:"division,omitempty"`
+	Department     string       `json:"department,omitempty"`
+	Manager        *SCIMManager `json:"manager,omitempty"`
+}
+
+// SCIMMeta defines model for the meta attribute of a SCIM resource.
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+	Version      string `json:"version,omitempty"`
+}
+
+// SCIMListResponse defines model for a SCIM ListResponse message.
+type SCIMListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// SCIMPatchRequest defines model for a SCIM PatchOp message.
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation defines model for a single PATCH operation.
+type SCIMPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// SCIMError defines model for a SCIM Error message.
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
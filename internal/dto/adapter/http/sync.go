@@ -0,0 +1,20 @@
+package api
+
+// SyncRunResponse defines model for SyncRunResponse.
+type SyncRunResponse struct {
+	Id          uint   `json:"id"`
+	Provider    string `json:"provider"`
+	Status      string `json:"status"`
+	Created     int    `json:"created"`
+	Updated     int    `json:"updated"`
+	Deactivated int    `json:"deactivated"`
+	Failed      int    `json:"failed"`
+	Error       string `json:"error,omitempty"`
+	StartedAt   string `json:"started_at"`
+	FinishedAt  string `json:"finished_at"`
+}
+
+// GetSyncRunsResponse defines model for GetSyncRunsResponse.
+type GetSyncRunsResponse struct {
+	Runs []SyncRunResponse `json:"runs"`
+}
@@ -0,0 +1,18 @@
+package api
+
+// UserWithIdentityResponse defines the user returned by the user endpoints:
+// the generated UserResponse plus where the user comes from, which the
+// OpenAPI spec does not describe yet. ExternalId is the id of the user at
+// Provider, the instance it was pulled from; both are omitted for users
+// created through the API.
+type UserWithIdentityResponse struct {
+	UserResponse
+	ExternalId *string `json:"external_id,omitempty"`
+	Provider   *string `json:"provider,omitempty"`
+}
+
+// GetUsersWithIdentityResponse is GetUsersResponse with the users carrying
+// their identity.
+type GetUsersWithIdentityResponse struct {
+	Users *[]UserWithIdentityResponse `json:"users,omitempty"`
+}
@@ -0,0 +1,61 @@
+package api
+
+// WebhookAcceptedResponse defines model for WebhookAcceptedResponse.
+type WebhookAcceptedResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+// WebhookSubscriptionRequestDTO defines model for WebhookSubscriptionRequestDTO.
+type WebhookSubscriptionRequestDTO struct {
+	Url         string   `json:"url" validate:"required,url"`
+	Secret      string   `json:"secret,omitempty"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// WebhookSubscriptionResponse defines model for WebhookSubscriptionResponse.
+// Secret is only returned when the subscription is created.
+type WebhookSubscriptionResponse struct {
+	Id          string   `json:"id"`
+	Url         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description,omitempty"`
+	Active      bool     `json:"active"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+// GetWebhookSubscriptionsResponse defines model for GetWebhookSubscriptionsResponse.
+type GetWebhookSubscriptionsResponse struct {
+	Subscriptions []WebhookSubscriptionResponse `json:"subscriptions"`
+}
+
+// WebhookDeliveryResponse defines model for WebhookDeliveryResponse.
+type WebhookDeliveryResponse struct {
+	Id             uint    `json:"id"`
+	SubscriptionId string  `json:"subscription_id"`
+	EventId        string  `json:"event_id"`
+	EventType      string  `json:"event_type"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	NextAttemptAt  *string `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *string `json:"last_attempt_at,omitempty"`
+	ResponseStatus int     `json:"response_status,omitempty"`
+	LastError      string  `json:"last_error,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// GetWebhookDeliveriesResponse defines model for GetWebhookDeliveriesResponse.
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
+
+// UserEventPayload defines the body posted to webhook subscribers.
+type UserEventPayload struct {
+	Id         string                   `json:"id"`
+	Type       string                   `json:"type"`
+	OccurredAt string                   `json:"occurred_at"`
+	User       UserWithIdentityResponse `json:"user"`
+}
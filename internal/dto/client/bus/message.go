@@ -0,0 +1,30 @@
+package bus
+
+import "time"
+
+// Headers carried by the user events published to the bus.
+const (
+	HeaderEventID   = "X-Event-Id"
+	HeaderEventType = "X-Event-Type"
+)
+
+// Headers added to the messages routed to a dead-letter topic.
+const (
+	HeaderDeadLetterTopic    = "X-Dead-Letter-Topic"
+	HeaderDeadLetterConsumer = "X-Dead-Letter-Consumer"
+	HeaderDeadLetterReason   = "X-Dead-Letter-Reason"
+)
+
+// Message is a message of a topic. Messages with the same Key are delivered
+// in the order they were published. Partition, Offset and Time are set on
+// delivered messages.
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+
+	Partition int
+	Offset    int64
+	Time      time.Time
+}
@@ -0,0 +1,40 @@
+package integration
+
+import "time"
+
+// ProviderStatus is the lifecycle state of a registered provider instance.
+type ProviderStatus string
+
+const (
+	ProviderStatusActive  ProviderStatus = "active"
+	ProviderStatusStopped ProviderStatus = "stopped"
+)
+
+// ProviderInstanceDTO describes a provider instance registered at runtime.
+type ProviderInstanceDTO struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Config       string         `json:"config,omitempty"`
+	Status       ProviderStatus `json:"status"`
+	RegisteredAt time.Time      `json:"registered_at"`
+	// Resilience is nil for stopped instances.
+	Resilience *ResilienceStateDTO `json:"resilience,omitempty"`
+}
+
+// BreakerState is the state of the circuit breaker wrapped around a provider instance.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerHalfOpen BreakerState = "half-open"
+	BreakerOpen     BreakerState = "open"
+)
+
+// ResilienceStateDTO is a snapshot of a provider instance's breaker and bulkhead.
+type ResilienceStateDTO struct {
+	Breaker             BreakerState `json:"breaker"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	InFlight            int          `json:"in_flight"`
+	MaxConcurrent       int          `json:"max_concurrent"`
+}
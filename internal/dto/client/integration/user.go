@@ -14,6 +14,9 @@ type UserDTO struct {
 	Phone    user.Phone        `json:"phone,omitempty"`
 	Website  user.Website      `json:"website,omitempty"`
 	Extra    map[string]string `json:"extra,omitempty"` // provider-specific fields
+	// Active is false for users the provider reports as disabled, nil when it
+	// does not report it.
+	Active *bool `json:"active,omitempty"`
 }
 
 type MetaInfoDTO struct {
@@ -25,6 +28,7 @@ type MetaInfoDTO struct {
 
 type UserListResponseDTO struct {
 	Provider string      `json:"provider"`
+	Instance string      `json:"instance,omitempty"` // id of the provider instance that served the page
 	Users    []UserDTO   `json:"users"`
 	Meta     MetaInfoDTO `json:"meta,omitempty"`
 	Raw      []byte      `json:"-"`
@@ -0,0 +1,31 @@
+package integration
+
+import "time"
+
+// WebhookEventType is the kind of change a provider pushed.
+type WebhookEventType string
+
+const (
+	WebhookUserCreated     WebhookEventType = "user.created"
+	WebhookUserUpdated     WebhookEventType = "user.updated"
+	WebhookUserDeactivated WebhookEventType = "user.deactivated"
+)
+
+// WebhookEventDTO is a single user change pushed by a provider. Deactivation
+// events only need User.ID.
+type WebhookEventDTO struct {
+	ID         string           `json:"id"`
+	Type       WebhookEventType `json:"type"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	User       UserDTO          `json:"user"`
+}
+
+// WebhookDeliveryRequestDTO is an event posted to a subscriber, signed with
+// Secret. EventID identifies the event across redeliveries.
+type WebhookDeliveryRequestDTO struct {
+	URL       string
+	Secret    string
+	EventID   string
+	EventType string
+	Body      []byte
+}
@@ -0,0 +1,70 @@
+package mapper
+
+import (
+	"maps"
+
+	consumer "__MODULE__/internal/dto/adapter/consumer"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+)
+
+// UserEventMessageToUsecase converts an upstream event received by the given
+// consumer group for the users of provider. The upstream user id becomes the
+// ExternalID, like the id of provider users.
+func UserEventMessageToUsecase(in consumer.UserEventMessage, group, provider string) usecase.ApplyUserEventRequestDTO {
+	u := UserIntegrationToUsecase(integration.UserDTO{
+		ID:       user.ID(in.User.ID),
+		Name:     user.FullName(in.User.Name),
+		Username: user.Username(in.User.Username),
+		Email:    user.Email(in.User.Email),
+		Phone:    user.Phone(in.User.Phone),
+		Website:  user.Website(in.User.Website),
+		Extra:    in.User.Extra,
+	})
+	return usecase.ApplyUserEventRequestDTO{
+		Consumer:   group,
+		EventID:    in.ID,
+		Type:       usecase.UserEventType(in.Type),
+		OccurredAt: in.OccurredAt,
+		Provider:   provider,
+		User:       u,
+	}
+}
+
+func DeadLetterUsecaseToRepo(in usecase.DeadLetter) repository.DeadLetter {
+	return repository.DeadLetter{
+		ID:              in.ID,
+		Topic:           in.Topic,
+		Consumer:        in.Consumer,
+		DeadLetterTopic: in.DeadLetterTopic,
+		Key:             in.Key,
+		Value:           in.Value,
+		Headers:         repository.StringMap(maps.Clone(in.Headers)),
+		Partition:       in.Partition,
+		Offset:          in.Offset,
+		Reason:          in.Reason,
+		Attempts:        in.Attempts,
+		CreatedAt:       in.CreatedAt,
+		ReplayedAt:      in.ReplayedAt,
+	}
+}
+
+func DeadLetterRepoToUsecase(in repository.DeadLetter) usecase.DeadLetter {
+	return usecase.DeadLetter{
+		ID:              in.ID,
+		Topic:           in.Topic,
+		Consumer:        in.Consumer,
+		DeadLetterTopic: in.DeadLetterTopic,
+		Key:             in.Key,
+		Value:           in.Value,
+		Headers:         maps.Clone(map[string]string(in.Headers)),
+		Partition:       in.Partition,
+		Offset:          in.Offset,
+		Reason:          in.Reason,
+		Attempts:        in.Attempts,
+		CreatedAt:       in.CreatedAt,
+		ReplayedAt:      in.ReplayedAt,
+	}
+}
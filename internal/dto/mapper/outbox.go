@@ -0,0 +1,39 @@
+package mapper
+
+import (
+	"time"
+
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+)
+
+// UserEventToOutbox converts an event and its encoded payload to an outbox row.
+func UserEventToOutbox(in usecase.UserEvent, payload []byte) repository.OutboxEvent {
+	out := repository.OutboxEvent{
+		EventID:   in.ID,
+		EventType: string(in.Type),
+		Payload:   payload,
+		CreatedAt: in.OccurredAt,
+	}
+	if in.User.ID != nil {
+		out.UserID = string(*in.User.ID)
+	}
+	return out
+}
+
+func OutboxEventRepoToUsecase(in repository.OutboxEvent) usecase.OutboxEvent {
+	var sentAt *time.Time
+	if in.SentAt != nil {
+		t := in.SentAt.UTC()
+		sentAt = &t
+	}
+	return usecase.OutboxEvent{
+		ID:        in.ID,
+		EventID:   in.EventID,
+		EventType: usecase.UserEventType(in.EventType),
+		UserID:    in.UserID,
+		Payload:   in.Payload,
+		CreatedAt: in.CreatedAt.UTC(),
+		SentAt:    sentAt,
+	}
+}
@@ -0,0 +1,45 @@
+package mapper
+
+import (
+	"encoding/json"
+	"time"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/client/integration"
+)
+
+func ProviderInstanceToResponse(p integration.ProviderInstanceDTO) adapter.ProviderResponse {
+	var cfg json.RawMessage
+	if json.Valid([]byte(p.Config)) {
+		cfg = json.RawMessage(p.Config)
+	}
+	resp := adapter.ProviderResponse{
+		Id:           p.ID,
+		Type:         p.Type,
+		Status:       string(p.Status),
+		Config:       cfg,
+		RegisteredAt: p.RegisteredAt.UTC().Format(time.RFC3339),
+	}
+	if r := p.Resilience; r != nil {
+		resp.Resilience = &adapter.ProviderResilienceResponse{
+			Breaker:             string(r.Breaker),
+			ConsecutiveFailures: r.ConsecutiveFailures,
+			InFlight:            r.InFlight,
+			MaxConcurrent:       r.MaxConcurrent,
+		}
+		if r.OpenedAt != nil {
+			openedAt := r.OpenedAt.UTC().Format(time.RFC3339)
+			resp.Resilience.OpenedAt = &openedAt
+		}
+	}
+	return resp
+}
+
+// ProviderConfigToString turns the raw JSON config from a request into the
+// string form accepted by provider factories.
+func ProviderConfigToString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
@@ -0,0 +1,186 @@
+package mapper
+
+import (
+	"time"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+)
+
+// Extra keys holding the SCIM attributes without a column of their own; the
+// same keys the SCIM provider fills when pulling users.
+const (
+	extraGivenName         = "given_name"
+	extraFamilyName        = "family_name"
+	extraNickName          = "nick_name"
+	extraTitle             = "title"
+	extraUserType          = "user_type"
+	extraPreferredLanguage = "preferred_language"
+	extraLocale            = "locale"
+	extraTimezone          = "timezone"
+	extraCountry           = "country"
+	extraEmployeeNumber    = "employee_number"
+	extraCostCenter        = "cost_center"
+	extraDivision          = "division"
+	extraDepartment        = "department"
+	extraManagerID         = "manager_id"
+	extraManagerName       = "manager_name"
+)
+
+// UserUsecaseToSCIM maps a stored user onto a SCIM User resource. location is
+// the URL of the resource; meta.version is left to the caller.
+func UserUsecaseToSCIM(b usecase.BaseUser, location string) adapter.SCIMUser {
+	x := b.Extra
+	out := adapter.SCIMUser{
+		Schemas:           []string{adapter.SCIMUserSchema},
+		Id:                getString(b.ID),
+		ExternalId:        getString(b.ExternalID),
+		UserName:          getString(b.Username),
+		DisplayName:       getString(b.FullName),
+		NickName:          x[extraNickName],
+		ProfileUrl:        getString(b.Website),
+		Title:             x[extraTitle],
+		UserType:          x[extraUserType],
+		PreferredLanguage: x[extraPreferredLanguage],
+		Locale:            x[extraLocale],
+		Timezone:          x[extraTimezone],
+		Active:            copyPtr(b.Active),
+		Meta:              &adapter.SCIMMeta{ResourceType: "User", Location: location},
+	}
+	if out.Active == nil {
+		out.Active = ptr(true)
+	}
+	if name := (adapter.SCIMName{Formatted: out.DisplayName, GivenName: x[extraGivenName], FamilyName: x[extraFamilyName]}); name != (adapter.SCIMName{}) {
+		out.Name = &name
+	}
+	if v := getString(b.Email); v != "" {
+		out.Emails = []adapter.SCIMMultiValue{{Value: v, Type: "work", Primary: true}}
+	}
+	if v := getString(b.Phone); v != "" {
+		out.PhoneNumbers = []adapter.SCIMMultiValue{{Value: v, Type: "work", Primary: true}}
+	}
+	if v := getString(b.Avatar); v != "" {
+		out.Photos = []adapter.SCIMMultiValue{{Value: v, Type: "photo", Primary: true}}
+	}
+	if addr := (adapter.SCIMAddress{Locality: getString(b.City), Country: x[extraCountry]}); addr != (adapter.SCIMAddress{}) {
+		addr.Type, addr.Primary = "work", true
+		out.Addresses = []adapter.SCIMAddress{addr}
+	}
+
+	ent := adapter.SCIMEnterpriseUser{
+		EmployeeNumber: x[extraEmployeeNumber],
+		CostCenter:     x[extraCostCenter],
+		Organization:   getString(b.Company),
+		Division:       x[extraDivision],
+		Department:     x[extraDepartment],
+	}
+	if m := (adapter.SCIMManager{Value: x[extraManagerID], DisplayName: x[extraManagerName]}); m != (adapter.SCIMManager{}) {
+		ent.Manager = &m
+	}
+	if ent != (adapter.SCIMEnterpriseUser{}) {
+		out.Schemas = append(out.Schemas, adapter.SCIMEnterpriseUserSchema)
+		out.Enterprise = &ent
+	}
+
+	if b.CreatedAt != nil {
+		out.Meta.Created = b.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if b.UpdatedAt != nil {
+		out.Meta.LastModified = b.UpdatedAt.UTC().Format(time.RFC3339)
+	} else {
+		out.Meta.LastModified = out.Meta.Created
+	}
+	return out
+}
+
+// SCIMToUserUsecase maps a SCIM User resource onto a user. The primary email,
+// phone number, photo and address are kept; id, meta and the provider are
+// left to the caller.
+func SCIMToUserUsecase(in adapter.SCIMUser) usecase.BaseUser {
+	extra := map[string]string{}
+	set := func(k, v string) {
+		if v != "" {
+			extra[k] = v
+		}
+	}
+
+	fullName := in.DisplayName
+	if in.Name != nil {
+		if fullName == "" {
+			fullName = in.Name.Formatted
+		}
+		set(extraGivenName, in.Name.GivenName)
+		set(extraFamilyName, in.Name.FamilyName)
+	}
+	set(extraNickName, in.NickName)
+	set(extraTitle, in.Title)
+	set(extraUserType, in.UserType)
+	set(extraPreferredLanguage, in.PreferredLanguage)
+	set(extraLocale, in.Locale)
+	set(extraTimezone, in.Timezone)
+
+	var city, company string
+	if addr, ok := primarySCIMAddress(in.Addresses); ok {
+		city = addr.Locality
+		set(extraCountry, addr.Country)
+	}
+	if e := in.Enterprise; e != nil {
+		company = e.Organization
+		set(extraEmployeeNumber, e.EmployeeNumber)
+		set(extraCostCenter, e.CostCenter)
+		set(extraDivision, e.Division)
+		set(extraDepartment, e.Department)
+		if e.Manager != nil {
+			set(extraManagerID, e.Manager.Value)
+			set(extraManagerName, e.Manager.DisplayName)
+		}
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+
+	active := true
+	if in.Active != nil {
+		active = *in.Active
+	}
+
+	return usecase.BaseUser{
+		ExternalID: ptrIfNotEmpty(user.ExternalID(in.ExternalId)),
+		Username:   ptrIfNotEmpty(user.Username(in.UserName)),
+		FullName:   ptrIfNotEmpty(user.FullName(fullName)),
+		Email:      ptrIfNotEmpty(user.Email(primarySCIMValue(in.Emails))),
+		Phone:      ptrIfNotEmpty(user.Phone(primarySCIMValue(in.PhoneNumbers))),
+		Avatar:     ptrIfNotEmpty(user.Avatar(primarySCIMValue(in.Photos))),
+		Website:    ptrIfNotEmpty(user.Website(in.ProfileUrl)),
+		Company:    ptrIfNotEmpty(user.Company(company)),
+		City:       ptrIfNotEmpty(user.City(city)),
+		Extra:      extra,
+		Active:     &active,
+	}
+}
+
+// primarySCIMValue returns the value marked primary, else the first one.
+func primarySCIMValue(values []adapter.SCIMMultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func primarySCIMAddress(addrs []adapter.SCIMAddress) (adapter.SCIMAddress, bool) {
+	for _, a := range addrs {
+		if a.Primary {
+			return a, true
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs[0], true
+	}
+	return adapter.SCIMAddress{}, false
+}
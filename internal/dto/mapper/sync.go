@@ -0,0 +1,54 @@
+package mapper
+
+import (
+	"time"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+)
+
+func SyncRunUsecaseToRepo(in usecase.SyncRunSummary) repository.SyncRun {
+	return repository.SyncRun{
+		ID:          in.ID,
+		Provider:    in.Provider,
+		Status:      string(in.Status),
+		Created:     in.Created,
+		Updated:     in.Updated,
+		Deactivated: in.Deactivated,
+		Failed:      in.Failed,
+		Error:       in.Error,
+		StartedAt:   in.StartedAt,
+		FinishedAt:  in.FinishedAt,
+	}
+}
+
+func SyncRunRepoToUsecase(in repository.SyncRun) usecase.SyncRunSummary {
+	return usecase.SyncRunSummary{
+		ID:          in.ID,
+		Provider:    in.Provider,
+		Status:      usecase.SyncStatus(in.Status),
+		Created:     in.Created,
+		Updated:     in.Updated,
+		Deactivated: in.Deactivated,
+		Failed:      in.Failed,
+		Error:       in.Error,
+		StartedAt:   in.StartedAt,
+		FinishedAt:  in.FinishedAt,
+	}
+}
+
+func SyncRunToResponse(in usecase.SyncRunSummary) adapter.SyncRunResponse {
+	return adapter.SyncRunResponse{
+		Id:          in.ID,
+		Provider:    in.Provider,
+		Status:      string(in.Status),
+		Created:     in.Created,
+		Updated:     in.Updated,
+		Deactivated: in.Deactivated,
+		Failed:      in.Failed,
+		Error:       in.Error,
+		StartedAt:   in.StartedAt.UTC().Format(time.RFC3339),
+		FinishedAt:  in.FinishedAt.UTC().Format(time.RFC3339),
+	}
+}
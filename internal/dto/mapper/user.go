@@ -6,9 +6,17 @@ import (
 	"__MODULE__/internal/dto/repository"
 	"__MODULE__/internal/dto/usecase"
 	"__MODULE__/internal/entity/user"
+	"maps"
 	"strings"
 )
 
+// Extra keys that providers report but that have a column of their own.
+const (
+	ExtraAvatar  = "avatar"
+	ExtraCompany = "company"
+	ExtraCity    = "city"
+)
+
 func UserUsecaseToRepo(in usecase.BaseUser) repository.BaseUser {
 	return repository.BaseUser{
 		ID:       copyPtr(in.ID),
@@ -19,9 +27,14 @@ func UserUsecaseToRepo(in usecase.BaseUser) repository.BaseUser {
 		Phone:    copyPtr(in.Phone),
 		Website:  copyPtr(in.Website),
 
-		Company:   nil,
-		City:      nil,
-		IsActive:  nil,
+		Company: copyPtr(in.Company),
+		City:    copyPtr(in.City),
+
+		Provider:   copyPtr(in.Provider),
+		ExternalID: copyPtr(in.ExternalID),
+		Attributes: repository.Attributes(maps.Clone(in.Extra)),
+
+		IsActive:  copyPtr(in.Active),
 		CreatedAt: nil,
 		UpdatedAt: nil,
 	}
@@ -29,24 +42,52 @@ func UserUsecaseToRepo(in usecase.BaseUser) repository.BaseUser {
 
 func UserRepoToUsecase(in repository.BaseUser) usecase.BaseUser {
 	return usecase.BaseUser{
-		ID:       copyPtr(in.ID),
-		FullName: copyPtr(in.FullName),
-		Username: copyPtr(in.Username),
-		Email:    copyPtr(in.Email),
-		Avatar:   copyPtr(in.Avatar),
-		Phone:    copyPtr(in.Phone),
-		Website:  copyPtr(in.Website),
+		ID:         copyPtr(in.ID),
+		FullName:   copyPtr(in.FullName),
+		Username:   copyPtr(in.Username),
+		Email:      copyPtr(in.Email),
+		Avatar:     copyPtr(in.Avatar),
+		Phone:      copyPtr(in.Phone),
+		Website:    copyPtr(in.Website),
+		Company:    copyPtr(in.Company),
+		City:       copyPtr(in.City),
+		Provider:   copyPtr(in.Provider),
+		ExternalID: copyPtr(in.ExternalID),
+		Extra:      maps.Clone(map[string]string(in.Attributes)),
+		Active:     copyPtr(in.IsActive),
+		CreatedAt:  copyPtr(in.CreatedAt),
+		UpdatedAt:  copyPtr(in.UpdatedAt),
 	}
 }
 
+// UserIntegrationToUsecase maps a provider user. The provider id becomes the
+// ExternalID; the internal ID is left nil and assigned when the user is stored.
+// Avatar, company and city are promoted out of Extra; the other keys are kept.
 func UserIntegrationToUsecase(u integration.UserDTO) usecase.BaseUser {
+	var extra map[string]string
+	for k, v := range u.Extra {
+		switch k {
+		case ExtraAvatar, ExtraCompany, ExtraCity:
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]string, len(u.Extra))
+		}
+		extra[k] = v
+	}
+
 	return usecase.BaseUser{
-		ID:       ptrIfNotEmpty(u.ID),
-		FullName: ptrIfNotEmpty(u.Name),
-		Username: ptrIfNotEmpty(u.Username),
-		Email:    ptrIfNotEmpty(u.Email),
-		Phone:    ptrIfNotEmpty(u.Phone),
-		Website:  ptrIfNotEmpty(u.Website),
+		ExternalID: ptrIfNotEmpty(user.ExternalID(u.ID)),
+		FullName:   ptrIfNotEmpty(u.Name),
+		Username:   ptrIfNotEmpty(u.Username),
+		Email:      ptrIfNotEmpty(u.Email),
+		Phone:      ptrIfNotEmpty(u.Phone),
+		Website:    ptrIfNotEmpty(u.Website),
+		Avatar:     ptrIfNotEmpty(user.Avatar(u.Extra[ExtraAvatar])),
+		Company:    ptrIfNotEmpty(user.Company(u.Extra[ExtraCompany])),
+		City:       ptrIfNotEmpty(user.City(u.Extra[ExtraCity])),
+		Extra:      extra,
+		Active:     copyPtr(u.Active),
 	}
 }
 
@@ -61,16 +102,37 @@ func CreateUserRequestDTOToBaseUser(req adapter.CreateUserRequestDTO) usecase.Ba
 }
 
 // Updated UserUsecaseToIntegration to match adapter.UserResponse pointer fields
-func UserUsecaseToIntegration(b usecase.BaseUser) adapter.UserResponse {
-	return adapter.UserResponse{
-		Id:       ptrIfNotEmpty(getString(b.ID)),
-		Name:     ptrIfNotEmpty(getString(b.FullName)),
-		Username: ptrIfNotEmpty(getString(b.Username)),
-		Email:    ptrIfNotEmpty(getString(b.Email)),
-		Phone:    ptrIfNotEmpty(getString(b.Phone)),
-		Website:  ptrIfNotEmpty(getString(b.Website)),
-		Extra:    &map[string]string{}, // extend when needed
+func UserUsecaseToIntegration(b usecase.BaseUser) adapter.UserWithIdentityResponse {
+	return adapter.UserWithIdentityResponse{
+		UserResponse: adapter.UserResponse{
+			Id:       ptrIfNotEmpty(getString(b.ID)),
+			Name:     ptrIfNotEmpty(getString(b.FullName)),
+			Username: ptrIfNotEmpty(getString(b.Username)),
+			Email:    ptrIfNotEmpty(getString(b.Email)),
+			Phone:    ptrIfNotEmpty(getString(b.Phone)),
+			Website:  ptrIfNotEmpty(getString(b.Website)),
+			Extra:    userExtra(b),
+		},
+		Provider:   copyPtr(b.Provider),
+		ExternalId: ptrIfNotEmpty(getString(b.ExternalID)),
+	}
+}
+
+// userExtra returns Extra with the promoted fields folded back in, so API
+// clients see every field the provider reported.
+func userExtra(b usecase.BaseUser) *map[string]string {
+	extra := make(map[string]string, len(b.Extra)+3)
+	maps.Copy(extra, b.Extra)
+	for k, v := range map[string]string{
+		ExtraAvatar:  getString(b.Avatar),
+		ExtraCompany: getString(b.Company),
+		ExtraCity:    getString(b.City),
+	} {
+		if v != "" {
+			extra[k] = v
+		}
 	}
+	return &extra
 }
 
 func copyPtr[T any](p *T) *T {
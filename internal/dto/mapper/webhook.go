@@ -0,0 +1,147 @@
+package mapper
+
+import (
+	"time"
+
+	adapter "__MODULE__/internal/dto/adapter/http"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+)
+
+func WebhookSubscriptionUsecaseToRepo(in usecase.WebhookSubscription) repository.WebhookSubscription {
+	types := make(repository.StringList, 0, len(in.EventTypes))
+	for _, t := range in.EventTypes {
+		types = append(types, string(t))
+	}
+	return repository.WebhookSubscription{
+		ID:          in.ID,
+		URL:         in.URL,
+		Secret:      in.Secret,
+		EventTypes:  types,
+		Description: in.Description,
+		Active:      in.Active,
+		CreatedAt:   in.CreatedAt,
+		UpdatedAt:   in.UpdatedAt,
+	}
+}
+
+func WebhookSubscriptionRepoToUsecase(in repository.WebhookSubscription) usecase.WebhookSubscription {
+	types := make([]usecase.UserEventType, 0, len(in.EventTypes))
+	for _, t := range in.EventTypes {
+		types = append(types, usecase.UserEventType(t))
+	}
+	return usecase.WebhookSubscription{
+		ID:          in.ID,
+		URL:         in.URL,
+		Secret:      in.Secret,
+		EventTypes:  types,
+		Description: in.Description,
+		Active:      in.Active,
+		CreatedAt:   in.CreatedAt,
+		UpdatedAt:   in.UpdatedAt,
+	}
+}
+
+func WebhookDeliveryUsecaseToRepo(in usecase.WebhookDelivery) repository.WebhookDelivery {
+	return repository.WebhookDelivery{
+		ID:             in.ID,
+		SubscriptionID: in.SubscriptionID,
+		EventID:        in.EventID,
+		EventType:      string(in.EventType),
+		Payload:        in.Payload,
+		Status:         string(in.Status),
+		Attempts:       in.Attempts,
+		NextAttemptAt:  in.NextAttemptAt,
+		LastAttemptAt:  in.LastAttemptAt,
+		ResponseStatus: in.ResponseStatus,
+		LastError:      in.LastError,
+		CreatedAt:      in.CreatedAt,
+	}
+}
+
+func WebhookDeliveryRepoToUsecase(in repository.WebhookDelivery) usecase.WebhookDelivery {
+	return usecase.WebhookDelivery{
+		ID:             in.ID,
+		SubscriptionID: in.SubscriptionID,
+		EventID:        in.EventID,
+		EventType:      usecase.UserEventType(in.EventType),
+		Payload:        in.Payload,
+		Status:         usecase.WebhookDeliveryStatus(in.Status),
+		Attempts:       in.Attempts,
+		NextAttemptAt:  in.NextAttemptAt,
+		LastAttemptAt:  in.LastAttemptAt,
+		ResponseStatus: in.ResponseStatus,
+		LastError:      in.LastError,
+		CreatedAt:      in.CreatedAt,
+	}
+}
+
+// WebhookSubscriptionRequestToUsecase converts a request for the subscription
+// with the given id, empty on create. Subscriptions are active by default.
+func WebhookSubscriptionRequestToUsecase(id string, in adapter.WebhookSubscriptionRequestDTO) usecase.WebhookSubscriptionRequestDTO {
+	types := make([]usecase.UserEventType, 0, len(in.EventTypes))
+	for _, t := range in.EventTypes {
+		types = append(types, usecase.UserEventType(t))
+	}
+	return usecase.WebhookSubscriptionRequestDTO{
+		ID:          id,
+		URL:         in.Url,
+		Secret:      in.Secret,
+		EventTypes:  types,
+		Description: in.Description,
+		Active:      in.Active == nil || *in.Active,
+	}
+}
+
+// WebhookSubscriptionToResponse converts a subscription, with its secret only
+// when withSecret is set.
+func WebhookSubscriptionToResponse(in usecase.WebhookSubscription, withSecret bool) adapter.WebhookSubscriptionResponse {
+	types := make([]string, 0, len(in.EventTypes))
+	for _, t := range in.EventTypes {
+		types = append(types, string(t))
+	}
+	out := adapter.WebhookSubscriptionResponse{
+		Id:          in.ID,
+		Url:         in.URL,
+		EventTypes:  types,
+		Description: in.Description,
+		Active:      in.Active,
+		CreatedAt:   in.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:   in.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if withSecret {
+		out.Secret = in.Secret
+	}
+	return out
+}
+
+func WebhookDeliveryToResponse(in usecase.WebhookDelivery) adapter.WebhookDeliveryResponse {
+	out := adapter.WebhookDeliveryResponse{
+		Id:             in.ID,
+		SubscriptionId: in.SubscriptionID,
+		EventId:        in.EventID,
+		EventType:      string(in.EventType),
+		Status:         string(in.Status),
+		Attempts:       in.Attempts,
+		ResponseStatus: in.ResponseStatus,
+		LastError:      in.LastError,
+		CreatedAt:      in.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if in.Status == usecase.WebhookDeliveryPending {
+		out.NextAttemptAt = ptr(in.NextAttemptAt.UTC().Format(time.RFC3339))
+	}
+	if in.LastAttemptAt != nil {
+		out.LastAttemptAt = ptr(in.LastAttemptAt.UTC().Format(time.RFC3339))
+	}
+	return out
+}
+
+// UserEventToPayload converts an event to the body posted to subscribers.
+func UserEventToPayload(in usecase.UserEvent) adapter.UserEventPayload {
+	return adapter.UserEventPayload{
+		Id:         in.ID,
+		Type:       string(in.Type),
+		OccurredAt: in.OccurredAt.UTC().Format(time.RFC3339Nano),
+		User:       UserUsecaseToIntegration(in.User),
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Attributes holds provider-specific user fields in a JSONB column.
+type Attributes map[string]string
+
+// Value implements driver.Valuer. A nil map is stored as NULL.
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (a *Attributes) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into Attributes", src)
+	}
+}
+
+// StringList holds a list of strings in a JSONB column.
+type StringList []string
+
+// Value implements driver.Valuer. A nil list is stored as NULL.
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (l *StringList) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", src)
+	}
+}
+
+// StringMap holds string pairs in a JSONB column.
+type StringMap map[string]string
+
+// Value implements driver.Valuer. A nil map is stored as NULL.
+func (m StringMap) Value() (driver.Value, error) {
+	return Attributes(m).Value()
+}
+
+// Scan implements sql.Scanner.
+func (m *StringMap) Scan(src any) error {
+	return (*Attributes)(m).Scan(src)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import "time"
+
+// ConsumedEvent records an upstream event applied by a consumer group, so a
+// redelivery of it is dropped.
+type ConsumedEvent struct {
+	Consumer   string    `gorm:"primaryKey;column:consumer;type:text"`
+	EventID    string    `gorm:"primaryKey;column:event_id;type:text"`
+	ConsumedAt time.Time `gorm:"column:consumed_at;index"`
+}
+
+func (ConsumedEvent) TableName() string { return "consumed_events" }
+
+// DeadLetter is a message a consumer gave up on, as it was received, kept for
+// inspection and replay. It was also published to DeadLetterTopic.
+type DeadLetter struct {
+	ID              uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	Topic           string     `gorm:"column:topic;type:text;index"`
+	Consumer        string     `gorm:"column:consumer;type:text"`
+	DeadLetterTopic string     `gorm:"column:dead_letter_topic;type:text"`
+	Key             []byte     `gorm:"column:message_key;type:bytea"`
+	Value           []byte     `gorm:"column:value;type:bytea"`
+	Headers         StringMap  `gorm:"column:headers;type:jsonb"`
+	Partition       int        `gorm:"column:message_partition"`
+	Offset          int64      `gorm:"column:message_offset"`
+	Reason          string     `gorm:"column:reason;type:text"`
+	Attempts        int        `gorm:"column:attempts"`
+	CreatedAt       time.Time  `gorm:"column:created_at;index"`
+	ReplayedAt      *time.Time `gorm:"column:replayed_at"`
+}
+
+func (DeadLetter) TableName() string { return "dead_letters" }
+
+// GetDeadLettersRepositoryRequestDTO selects the latest dead letters, newest
+// first. An empty Topic matches all.
+type GetDeadLettersRepositoryRequestDTO struct {
+	Topic string
+	Limit int
+}
@@ -0,0 +1,35 @@
+package repository
+
+import "time"
+
+// OutboxEvent is a user event stored in the transaction of the change it
+// describes. It is relayed to the message bus in ID order; SentAt is set once
+// it was published. RequestID is the id of the request that made the change,
+// carried on the relayed message.
+type OutboxEvent struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	EventID   string     `gorm:"column:event_id;type:text;uniqueIndex"`
+	EventType string     `gorm:"column:event_type;type:text"`
+	UserID    string     `gorm:"column:user_id;type:text"`
+	RequestID string     `gorm:"column:request_id;type:text"`
+	Payload   []byte     `gorm:"column:payload;type:bytea"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+	SentAt    *time.Time `gorm:"column:sent_at;index"`
+}
+
+func (OutboxEvent) TableName() string { return "outbox_events" }
+
+// GetOutboxEventsRepositoryRequestDTO selects the latest outbox events, newest
+// first. Pending selects the unsent events, or the sent ones when false; nil
+// matches all.
+type GetOutboxEventsRepositoryRequestDTO struct {
+	Pending *bool
+	Limit   int
+}
+
+// ReplayOutboxEventsRepositoryRequestDTO selects the sent events to publish
+// again: those in IDs and, when FromID is set, every one from FromID on.
+type ReplayOutboxEventsRepositoryRequestDTO struct {
+	IDs    []uint
+	FromID uint
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "time"
+
+// RawResponse is an archived provider response page.
+type RawResponse struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Provider     string    `gorm:"column:provider;type:text;index"`
+	ProviderType string    `gorm:"column:provider_type;type:text"`
+	Page         int       `gorm:"column:page"`
+	Payload      []byte    `gorm:"column:payload;type:bytea"`
+	FetchedAt    time.Time `gorm:"column:fetched_at;index"`
+}
+
+func (RawResponse) TableName() string { return "provider_raw_responses" }
+
+type CreateRawResponseRepositoryRequestDTO struct {
+	RawResponse
+}
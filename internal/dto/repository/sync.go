@@ -0,0 +1,28 @@
+package repository
+
+import "time"
+
+// SyncRun records the outcome of one provider synchronisation.
+type SyncRun struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement;column:id"`
+	Provider    string    `gorm:"column:provider;type:text;index"`
+	Status      string    `gorm:"column:status;type:text"`
+	Created     int       `gorm:"column:created"`
+	Updated     int       `gorm:"column:updated"`
+	Deactivated int       `gorm:"column:deactivated"`
+	Failed      int       `gorm:"column:failed"`
+	Error       string    `gorm:"column:error;type:text"`
+	StartedAt   time.Time `gorm:"column:started_at;index"`
+	FinishedAt  time.Time `gorm:"column:finished_at"`
+}
+
+func (SyncRun) TableName() string { return "sync_runs" }
+
+type CreateSyncRunRepositoryRequestDTO struct {
+	SyncRun
+}
+
+type GetSyncRunsRepositoryRequestDTO struct {
+	Provider string
+	Limit    int
+}
@@ -0,0 +1,47 @@
+package repository
+
+// ColumnPolicy decides what happens to a column when an upserted user already exists.
+type ColumnPolicy string
+
+const (
+	// ColumnOverwrite replaces the stored value with the incoming one.
+	ColumnOverwrite ColumnPolicy = "overwrite"
+	// ColumnKeep leaves the stored value untouched.
+	ColumnKeep ColumnPolicy = "keep"
+	// ColumnCoalesce replaces the stored value unless the incoming one is NULL.
+	ColumnCoalesce ColumnPolicy = "coalesce"
+)
+
+// UpsertPolicy holds the column policies of an upsert. Columns without an
+// entry use Default, which falls back to ColumnOverwrite. The id and
+// created_at columns are always kept.
+type UpsertPolicy struct {
+	Default ColumnPolicy
+	Columns map[string]ColumnPolicy
+}
+
+// For returns the policy of column.
+func (p UpsertPolicy) For(column string) ColumnPolicy {
+	if c, ok := p.Columns[column]; ok {
+		return c
+	}
+	if p.Default != "" {
+		return p.Default
+	}
+	return ColumnOverwrite
+}
+
+// UpsertUsersRepositoryRequestDTO inserts provider users, or updates them when
+// a user with the same provider and external id exists.
+type UpsertUsersRepositoryRequestDTO struct {
+	Users     []BaseUser
+	Policy    UpsertPolicy
+	BatchSize int
+}
+
+// UpsertedUser is a user stored by an upsert, with the id of the row it ended
+// up in. Changed is false when the row already held the same values.
+type UpsertedUser struct {
+	BaseUser
+	Changed bool
+}
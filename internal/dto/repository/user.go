@@ -15,12 +15,21 @@ type BaseUser struct {
 	Website  *user.Website  `gorm:"column:website;type:text"`
 	Company  *user.Company  `gorm:"column:company;type:text"`
 	City     *user.City     `gorm:"column:city;type:text"`
+	// Provider is the id of the provider instance the user was pulled from and
+	// ExternalID its id there; both are nil for locally created users.
+	Provider   *string          `gorm:"column:provider;type:text;uniqueIndex:idx_users_provider_external_id,priority:1"`
+	ExternalID *user.ExternalID `gorm:"column:external_id;type:text;uniqueIndex:idx_users_provider_external_id,priority:2"`
+
+	// Attributes keeps the provider fields without a column of their own.
+	Attributes Attributes `gorm:"column:attributes;type:jsonb"`
 
 	IsActive  *bool      `gorm:"column:is_active"`
 	CreatedAt *time.Time `gorm:"column:created_at"`
 	UpdatedAt *time.Time `gorm:"column:updated_at"`
 }
 
+func (BaseUser) TableName() string { return "users" }
+
 type CreateUserRepositoryRequestDTO struct {
 	BaseUser
 }
@@ -28,3 +37,11 @@ type CreateUserRepositoryRequestDTO struct {
 type UpdateUserRepositoryRequestDTO struct {
 	BaseUser
 }
+
+// FindUsersRepositoryRequestDTO selects up to Limit users matching Filter,
+// skipping the first Offset. A nil Filter matches every user.
+type FindUsersRepositoryRequestDTO struct {
+	Filter *user.Filter
+	Offset int
+	Limit  int
+}
@@ -0,0 +1,65 @@
+package repository
+
+import "time"
+
+// WebhookNonce records a delivery id seen from a provider until the delivery
+// could no longer pass the timestamp check.
+type WebhookNonce struct {
+	Provider  string    `gorm:"primaryKey;column:provider;type:text"`
+	Nonce     string    `gorm:"primaryKey;column:nonce;type:text"`
+	ExpiresAt time.Time `gorm:"column:expires_at;index"`
+}
+
+func (WebhookNonce) TableName() string { return "webhook_nonces" }
+
+// WebhookSubscription is an endpoint user events are delivered to.
+type WebhookSubscription struct {
+	ID     string `gorm:"primaryKey;column:id;type:text"`
+	URL    string `gorm:"column:url;type:text"`
+	Secret string `gorm:"column:secret;type:text"`
+	// EventTypes lists the event types delivered; empty means all
+	EventTypes  StringList `gorm:"column:event_types;type:jsonb"`
+	Description string     `gorm:"column:description;type:text"`
+	Active      bool       `gorm:"column:active"`
+	CreatedAt   time.Time  `gorm:"column:created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at"`
+}
+
+func (WebhookSubscription) TableName() string { return "webhook_subscriptions" }
+
+// WebhookDelivery is an event queued for, or delivered to, a subscription.
+// Pending deliveries are sent once NextAttemptAt has passed.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement;column:id"`
+	SubscriptionID string     `gorm:"column:subscription_id;type:text;index"`
+	EventID        string     `gorm:"column:event_id;type:text;index"`
+	EventType      string     `gorm:"column:event_type;type:text"`
+	Payload        []byte     `gorm:"column:payload;type:bytea"`
+	Status         string     `gorm:"column:status;type:text;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int        `gorm:"column:attempts"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt  *time.Time `gorm:"column:last_attempt_at"`
+	ResponseStatus int        `gorm:"column:response_status"`
+	LastError      string     `gorm:"column:last_error;type:text"`
+	CreatedAt      time.Time  `gorm:"column:created_at;index"`
+}
+
+func (WebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+// GetWebhookDeliveriesRepositoryRequestDTO selects the latest deliveries,
+// newest first. Empty fields match all.
+type GetWebhookDeliveriesRepositoryRequestDTO struct {
+	SubscriptionID string
+	Status         string
+	Limit          int
+}
+
+// ClaimWebhookDeliveriesRepositoryRequestDTO selects up to Limit deliveries in
+// Status due at Now and leases them until LeaseUntil, so concurrent senders
+// skip them.
+type ClaimWebhookDeliveriesRepositoryRequestDTO struct {
+	Status     string
+	Now        time.Time
+	LeaseUntil time.Time
+	Limit      int
+}
@@ -0,0 +1,39 @@
+package usecase
+
+import "time"
+
+// ApplyUserEventRequestDTO is an upstream user event received by Consumer.
+// User carries the upstream id as ExternalID and is stored under Provider.
+type ApplyUserEventRequestDTO struct {
+	Consumer   string
+	EventID    string
+	Type       UserEventType
+	OccurredAt time.Time
+	Provider   string
+	User       BaseUser
+}
+
+// DeadLetter is a message Consumer gave up on after Attempts, as it was
+// received from Topic, routed to DeadLetterTopic for inspection and replay.
+type DeadLetter struct {
+	ID              uint
+	Topic           string
+	Consumer        string
+	DeadLetterTopic string
+	Key             []byte
+	Value           []byte
+	Headers         map[string]string
+	Partition       int
+	Offset          int64
+	Reason          string
+	Attempts        int
+	CreatedAt       time.Time
+	ReplayedAt      *time.Time
+}
+
+// ListDeadLettersRequestDTO selects the latest dead letters, newest first. An
+// empty Topic matches all.
+type ListDeadLettersRequestDTO struct {
+	Topic string
+	Limit int
+}
@@ -0,0 +1,30 @@
+package usecase
+
+import "time"
+
+// UserEventType names a change to a stored user.
+type UserEventType string
+
+const (
+	UserCreated UserEventType = "user.created"
+	UserUpdated UserEventType = "user.updated"
+	UserDeleted UserEventType = "user.deleted"
+	// UserDeactivated is a user its provider no longer has; it is kept
+	// inactive.
+	UserDeactivated UserEventType = "user.deactivated"
+	// UserSynced is a user a provider page read inserted into or changed in
+	// the cache.
+	UserSynced UserEventType = "user.synced"
+)
+
+// UserEventTypes lists every user event type.
+var UserEventTypes = []UserEventType{UserCreated, UserUpdated, UserDeleted, UserDeactivated, UserSynced}
+
+// UserEvent is a change to a stored user. User holds its state after the
+// change; only the id is set for deleted users.
+type UserEvent struct {
+	ID         string
+	Type       UserEventType
+	OccurredAt time.Time
+	User       BaseUser
+}
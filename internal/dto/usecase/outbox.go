@@ -0,0 +1,30 @@
+package usecase
+
+import "time"
+
+// OutboxEvent is a user event stored with the change it describes, waiting
+// to be, or already, published to the message bus.
+type OutboxEvent struct {
+	ID        uint
+	EventID   string
+	EventType UserEventType
+	UserID    string
+	Payload   []byte
+	CreatedAt time.Time
+	SentAt    *time.Time
+}
+
+// ListOutboxEventsRequestDTO selects the latest outbox events, newest first.
+// Pending selects the unsent events, or the sent ones when false; nil
+// matches all.
+type ListOutboxEventsRequestDTO struct {
+	Pending *bool
+	Limit   int
+}
+
+// ReplayOutboxEventsRequestDTO selects sent events to publish again: those in
+// IDs and, when FromID is set, every one from FromID on.
+type ReplayOutboxEventsRequestDTO struct {
+	IDs    []uint
+	FromID uint
+}
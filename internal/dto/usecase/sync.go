@@ -0,0 +1,29 @@
+package usecase
+
+import "time"
+
+// SyncStatus is the outcome of a provider synchronisation run.
+type SyncStatus string
+
+const (
+	// SyncStatusSucceeded means the provider was walked completely. Individual
+	// users may still have failed, see SyncRunSummary.Failed.
+	SyncStatusSucceeded SyncStatus = "succeeded"
+	// SyncStatusFailed means the provider could not be walked completely, so no
+	// users were deactivated.
+	SyncStatusFailed SyncStatus = "failed"
+)
+
+// SyncRunSummary reports what a provider synchronisation changed.
+type SyncRunSummary struct {
+	ID          uint
+	Provider    string
+	Status      SyncStatus
+	Created     int
+	Updated     int
+	Deactivated int
+	Failed      int
+	Error       string
+	StartedAt   time.Time
+	FinishedAt  time.Time
+}
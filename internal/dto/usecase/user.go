@@ -1,6 +1,8 @@
 package usecase
 
 import (
+	"time"
+
 	"__MODULE__/internal/entity/user"
 )
 
@@ -12,8 +14,38 @@ type BaseUser struct {
 	Avatar   *user.Avatar
 	Phone    *user.Phone
 	Website  *user.Website
+	Company  *user.Company
+	City     *user.City
+	// Extra holds provider-specific fields without a field of their own.
+	Extra map[string]string
+
+	// Provider and ExternalID identify the user at its provider instance.
+	Provider   *string
+	ExternalID *user.ExternalID
+
+	Active    *bool
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
 }
 
 type CreateUserRequestDTO struct {
 	BaseUser
 }
+
+// ListUsersRequestDTO selects up to Limit stored users matching Filter,
+// skipping the first Offset.
+type ListUsersRequestDTO struct {
+	Filter *user.Filter
+	Offset int
+	Limit  int
+}
+
+type ListUsersResponseDTO struct {
+	Users []BaseUser
+	Total int
+}
+
+// ReplaceUserRequestDTO carries the complete new state of an existing user.
+type ReplaceUserRequestDTO struct {
+	BaseUser
+}
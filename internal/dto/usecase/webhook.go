@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"net/http"
+	"time"
+)
+
+// ReceiveWebhookRequestDTO is a delivery posted by a provider instance.
+type ReceiveWebhookRequestDTO struct {
+	Provider string
+	Header   http.Header
+	Body     []byte
+}
+
+// WebhookSubscription is an endpoint user events are delivered to. An empty
+// EventTypes subscribes to every type.
+type WebhookSubscription struct {
+	ID          string
+	URL         string
+	Secret      string
+	EventTypes  []UserEventType
+	Description string
+	Active      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// WebhookSubscriptionRequestDTO creates a subscription, or replaces the one
+// with ID. An empty Secret is generated on create and kept on replace.
+type WebhookSubscriptionRequestDTO struct {
+	ID          string
+	URL         string
+	Secret      string
+	EventTypes  []UserEventType
+	Description string
+	Active      bool
+}
+
+// WebhookDeliveryStatus is the state of a delivery.
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries are sent once NextAttemptAt has passed.
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed deliveries ran out of attempts; they can be redelivered.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event queued for, or delivered to, a subscription.
+type WebhookDelivery struct {
+	ID             uint
+	SubscriptionID string
+	EventID        string
+	EventType      UserEventType
+	Payload        []byte
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	ResponseStatus int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+// ListWebhookDeliveriesRequestDTO selects the latest deliveries of a
+// subscription, optionally in a single status.
+type ListWebhookDeliveriesRequestDTO struct {
+	SubscriptionID string
+	Status         WebhookDeliveryStatus
+	Limit          int
+}
@@ -0,0 +1,50 @@
+package user
+
+// Field is a user attribute a Filter can compare.
+type Field string
+
+const (
+	FieldID         Field = "id"
+	FieldFullName   Field = "full_name"
+	FieldUsername   Field = "username"
+	FieldEmail      Field = "email"
+	FieldPhone      Field = "phone"
+	FieldWebsite    Field = "website"
+	FieldCompany    Field = "company"
+	FieldCity       Field = "city"
+	FieldProvider   Field = "provider"
+	FieldExternalID Field = "external_id"
+	FieldActive     Field = "active"
+	FieldCreatedAt  Field = "created_at"
+	FieldUpdatedAt  Field = "updated_at"
+)
+
+// Op is a Filter operator. The comparisons follow SCIM (RFC 7644 3.4.2.2):
+// string comparisons ignore case, pr tests that a value is present.
+type Op string
+
+const (
+	OpEq  Op = "eq"
+	OpNe  Op = "ne"
+	OpCo  Op = "co"
+	OpSw  Op = "sw"
+	OpEw  Op = "ew"
+	OpPr  Op = "pr"
+	OpGt  Op = "gt"
+	OpGe  Op = "ge"
+	OpLt  Op = "lt"
+	OpLe  Op = "le"
+	OpAnd Op = "and"
+	OpOr  Op = "or"
+	OpNot Op = "not"
+)
+
+// Filter is a boolean expression over user fields. Comparisons set Field and,
+// except for pr, Value (a string, bool or time.Time); and, or and not combine
+// Operands.
+type Filter struct {
+	Op       Op
+	Field    Field
+	Value    any
+	Operands []Filter
+}
@@ -10,4 +10,8 @@ type (
 	Website  string
 	Company  string
 	City     string
+
+	// ExternalID is the id a user has at the provider it was pulled from.
+	// It is only unique together with the provider instance id.
+	ExternalID string
 )
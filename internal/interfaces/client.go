@@ -2,7 +2,10 @@ package interfaces
 
 import (
 	"context"
+	"net/http"
+	"time"
 
+	"__MODULE__/internal/dto/client/bus"
 	"__MODULE__/internal/dto/client/integration"
 )
 
@@ -12,8 +15,59 @@ type UserService interface {
 	GetUsers(ctx context.Context, page int) (integration.UserListResponseDTO, error)
 }
 
-// ProviderService is the interface that defines the methods for managing bank providers.
-// It allows registering new banks, getting a bank service by ID, stopping a bank service,
-// and getting a list of supported providers.
+// ProviderService is the interface that defines the methods for managing user providers.
+// It allows registering new provider instances, getting a user service by ID, stopping
+// or replacing an instance at runtime, and getting a list of supported provider types.
 type ProviderService interface {
+	// SupportedProviders returns the provider types that can be registered.
+	SupportedProviders() []string
+	// ListProviders returns every registered instance with its current status.
+	ListProviders() []integration.ProviderInstanceDTO
+	// RegisterNewProvider creates a new instance of providerName under id.
+	// Plugin and file instances are rejected; they can only be declared in
+	// configuration.
+	RegisterNewProvider(id string, providerName string, providerConfig string) error
+	// ReplaceProvider swaps the instance registered under id for a freshly
+	// built one. Like RegisterNewProvider, it rejects plugin and file instances.
+	ReplaceProvider(id string, providerName string, providerConfig string) error
+	GetUserService(id string) (UserService, error)
+	StopUserService(id string) error
+	// GetWebhookReceiver returns the webhook receiver of an active instance
+	// that has webhooks enabled.
+	GetWebhookReceiver(id string) (WebhookReceiver, error)
 }
+
+// WebhookReceiver authenticates and decodes the webhook deliveries of a
+// provider instance.
+type WebhookReceiver interface {
+	// Verify checks the signature and timestamp of a delivery and returns its
+	// id, which is unique per delivery, and until when that id must be remembered.
+	Verify(header http.Header, body []byte, now time.Time) (id string, expiresAt time.Time, err error)
+	// Parse decodes the user change events of a verified delivery.
+	Parse(body []byte) ([]integration.WebhookEventDTO, error)
+}
+
+// WebhookSender posts signed events to webhook subscribers.
+type WebhookSender interface {
+	// Deliver posts the event and returns the response status. A status other
+	// than 2xx is returned together with an error.
+	Deliver(ctx context.Context, req integration.WebhookDeliveryRequestDTO) (status int, err error)
+}
+
+// MessageBus publishes messages to topics and delivers them to consumer groups.
+type MessageBus interface {
+	// Publish appends msgs to their topics and returns once the bus stored
+	// them. The request id of ctx is added to messages carrying none.
+	Publish(ctx context.Context, msgs ...bus.Message) error
+	// Subscribe hands the messages of topic to handler in the background until
+	// ctx is done. Every group gets every message, which goes to one of the
+	// subscribers of the group. A nil return acknowledges the message; after an
+	// error it is handed again, so messages are delivered at least once.
+	Subscribe(ctx context.Context, topic, group string, handler MessageHandler) error
+	// Close stops the subscriptions and releases the connections of the bus.
+	Close() error
+}
+
+// MessageHandler processes a delivered message. Its ctx carries the request
+// id of the message.
+type MessageHandler func(ctx context.Context, msg bus.Message) error
@@ -3,6 +3,7 @@ package interfaces
 import (
 	"__MODULE__/internal/dto/repository"
 	"context"
+	"time"
 )
 
 // Repository is an interface that defines the methods for interacting with the repository.
@@ -10,6 +11,57 @@ type Repository interface {
 	CreateUser(ctx context.Context, params repository.CreateUserRepositoryRequestDTO) error
 	GetUsersList(ctx context.Context, params repository.ListRepositoryRequestDTO[repository.BaseUser]) (res repository.ListRepositoryResponseDTO[repository.BaseUser], err error)
 	GetUserById(ctx context.Context, id string) (repository.BaseUser, error)
+	GetUserByExternalID(ctx context.Context, provider, externalID string) (repository.BaseUser, error)
 	UpdateUser(ctx context.Context, params repository.UpdateUserRepositoryRequestDTO) error
+	// ReplaceUser overwrites every field of a user, clearing the nil ones.
+	ReplaceUser(ctx context.Context, params repository.UpdateUserRepositoryRequestDTO) error
 	DeleteUser(ctx context.Context, id string) error
+	GetUsersByProvider(ctx context.Context, provider string) ([]repository.BaseUser, error)
+	// FindUsers returns the users matching a filter expression.
+	FindUsers(ctx context.Context, params repository.FindUsersRepositoryRequestDTO) (repository.ListRepositoryResponseDTO[repository.BaseUser], error)
+	UpsertUsers(ctx context.Context, params repository.UpsertUsersRepositoryRequestDTO) ([]repository.UpsertedUser, error)
+
+	// WithTransaction runs fn in a transaction joined by the repository calls made with its ctx.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	CreateSyncRun(ctx context.Context, params repository.CreateSyncRunRepositoryRequestDTO) (uint, error)
+	GetSyncRuns(ctx context.Context, params repository.GetSyncRunsRepositoryRequestDTO) ([]repository.SyncRun, error)
+
+	CreateRawResponse(ctx context.Context, params repository.CreateRawResponseRepositoryRequestDTO) error
+	PurgeRawResponses(ctx context.Context, before time.Time) (int64, error)
+
+	// ClaimWebhookNonce records a webhook delivery id, reporting false when it was already seen.
+	ClaimWebhookNonce(ctx context.Context, provider, nonce string, expiresAt time.Time) (bool, error)
+	ReleaseWebhookNonce(ctx context.Context, provider, nonce string) error
+	PurgeWebhookNonces(ctx context.Context, before time.Time) (int64, error)
+
+	CreateWebhookSubscription(ctx context.Context, sub repository.WebhookSubscription) error
+	GetWebhookSubscriptions(ctx context.Context) ([]repository.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id string) (repository.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, sub repository.WebhookSubscription) error
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+	CreateWebhookDeliveries(ctx context.Context, deliveries []repository.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, id uint) (repository.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, params repository.GetWebhookDeliveriesRepositoryRequestDTO) ([]repository.WebhookDelivery, error)
+	// ClaimWebhookDeliveries leases the due deliveries so concurrent senders skip them.
+	ClaimWebhookDeliveries(ctx context.Context, params repository.ClaimWebhookDeliveriesRepositoryRequestDTO) ([]repository.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, d repository.WebhookDelivery) error
+
+	// CreateOutboxEvents stores events in the transaction of the change they describe.
+	CreateOutboxEvents(ctx context.Context, events []repository.OutboxEvent) error
+	GetOutboxEvent(ctx context.Context, id uint) (repository.OutboxEvent, error)
+	GetOutboxEvents(ctx context.Context, params repository.GetOutboxEventsRepositoryRequestDTO) ([]repository.OutboxEvent, error)
+	// LockOutboxEvents returns the oldest unsent events to the only relay allowed to publish them.
+	LockOutboxEvents(ctx context.Context, limit int) ([]repository.OutboxEvent, error)
+	MarkOutboxEventsSent(ctx context.Context, ids []uint, sentAt time.Time) error
+	ReplayOutboxEvents(ctx context.Context, params repository.ReplayOutboxEventsRepositoryRequestDTO) (int64, error)
+	PurgeOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+
+	// ClaimConsumedEvent records an applied upstream event, reporting false when it was already applied.
+	ClaimConsumedEvent(ctx context.Context, e repository.ConsumedEvent) (bool, error)
+	PurgeConsumedEvents(ctx context.Context, before time.Time) (int64, error)
+	CreateDeadLetter(ctx context.Context, d repository.DeadLetter) (uint, error)
+	GetDeadLetter(ctx context.Context, id uint) (repository.DeadLetter, error)
+	GetDeadLetters(ctx context.Context, params repository.GetDeadLettersRepositoryRequestDTO) ([]repository.DeadLetter, error)
+	MarkDeadLetterReplayed(ctx context.Context, id uint, replayedAt time.Time) error
 }
@@ -11,6 +11,86 @@ type UserUsecase interface {
 	// GetUsers returns users for given page.
 	// First tries repository; if nothing found calls external client, persists results and returns them.
 	GetUsers(ctx context.Context, page int) ([]usecase.BaseUser, error)
+	// GetUser returns a stored user; a missing one is a 404 error.
+	GetUser(ctx context.Context, id string) (usecase.BaseUser, error)
+	// ListUsers returns the stored users matching a filter, without calling the providers.
+	ListUsers(ctx context.Context, req usecase.ListUsersRequestDTO) (usecase.ListUsersResponseDTO, error)
+	// ReplaceUser overwrites every field of a stored user and returns the result.
+	ReplaceUser(ctx context.Context, req usecase.ReplaceUserRequestDTO) (usecase.BaseUser, error)
+	// DeleteUser removes a stored user; a missing one is a 404 error.
+	DeleteUser(ctx context.Context, id string) error
+	// ApplyUserEvent applies an upstream user event once, reporting false when its id was already applied.
+	ApplyUserEvent(ctx context.Context, req usecase.ApplyUserEventRequestDTO) (bool, error)
 }
 
-type BackgroundJobUsecase interface{}
+// BackgroundJobUsecase defines the jobs run by the worker.
+type BackgroundJobUsecase interface {
+	// SyncProviders fully synchronises every active provider instance into the database.
+	SyncProviders(ctx context.Context) error
+	// PurgeRawResponses deletes archived provider responses past their retention.
+	PurgeRawResponses(ctx context.Context) error
+}
+
+// ProviderSyncUsecase triggers provider synchronisation on demand and reports past runs.
+type ProviderSyncUsecase interface {
+	BackgroundJobUsecase
+	// SyncProvider synchronises a single provider instance and returns the run summary.
+	SyncProvider(ctx context.Context, provider string) (usecase.SyncRunSummary, error)
+	// GetSyncRuns returns the latest runs, newest first. An empty provider matches all.
+	GetSyncRuns(ctx context.Context, provider string, limit int) ([]usecase.SyncRunSummary, error)
+}
+
+// WebhookUsecase applies the change events provider instances push to the service.
+type WebhookUsecase interface {
+	// ReceiveWebhook verifies a delivery and queues its events, returning how many were queued.
+	ReceiveWebhook(ctx context.Context, req usecase.ReceiveWebhookRequestDTO) (int, error)
+	// Start runs the workers applying queued events until ctx is done.
+	Start(ctx context.Context)
+}
+
+// UserEventPublisher receives the changes made to stored users. It is called
+// with the transaction of the change, which an error rolls back.
+type UserEventPublisher interface {
+	PublishUserEvents(ctx context.Context, events []usecase.UserEvent) error
+}
+
+// OutboundWebhookUsecase manages webhook subscriptions and delivers the user
+// events it is published to them.
+type OutboundWebhookUsecase interface {
+	UserEventPublisher
+	CreateSubscription(ctx context.Context, req usecase.WebhookSubscriptionRequestDTO) (usecase.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]usecase.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id string) (usecase.WebhookSubscription, error)
+	ReplaceSubscription(ctx context.Context, req usecase.WebhookSubscriptionRequestDTO) (usecase.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	// ListDeliveries returns the latest deliveries of a subscription, newest first.
+	ListDeliveries(ctx context.Context, req usecase.ListWebhookDeliveriesRequestDTO) ([]usecase.WebhookDelivery, error)
+	// Redeliver queues a new delivery of the event of a past one.
+	Redeliver(ctx context.Context, subscriptionID string, deliveryID uint) (usecase.WebhookDelivery, error)
+	// Start sends due deliveries until ctx is done.
+	Start(ctx context.Context)
+}
+
+// OutboxUsecase relays the user events stored in the outbox to the message bus.
+type OutboxUsecase interface {
+	// ListEvents returns the latest outbox events, newest first.
+	ListEvents(ctx context.Context, req usecase.ListOutboxEventsRequestDTO) ([]usecase.OutboxEvent, error)
+	GetEvent(ctx context.Context, id uint) (usecase.OutboxEvent, error)
+	// ReplayEvents queues sent events to be published again, returning how many were queued.
+	ReplayEvents(ctx context.Context, req usecase.ReplayOutboxEventsRequestDTO) (int64, error)
+	// Start publishes stored events and purges the old sent ones until ctx is done.
+	Start(ctx context.Context)
+}
+
+// ConsumerUsecase keeps the books of the message bus consumers.
+type ConsumerUsecase interface {
+	// DeadLetter routes a message a consumer gave up on to its dead-letter topic and stores it.
+	DeadLetter(ctx context.Context, d usecase.DeadLetter) (usecase.DeadLetter, error)
+	// ListDeadLetters returns the latest dead letters, newest first.
+	ListDeadLetters(ctx context.Context, req usecase.ListDeadLettersRequestDTO) ([]usecase.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id uint) (usecase.DeadLetter, error)
+	// ReplayDeadLetter publishes a dead letter to the topic it was received from again.
+	ReplayDeadLetter(ctx context.Context, id uint) (usecase.DeadLetter, error)
+	// Start purges the ids of applied events past their retention until ctx is done.
+	Start(ctx context.Context)
+}
@@ -0,0 +1,87 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+)
+
+// User is a fixture user. It carries the fields of both upstream shapes;
+// missing names are derived from each other.
+type User struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone,omitempty"`
+	Website   string `json:"website,omitempty"`
+	Avatar    string `json:"avatar,omitempty"`
+	Company   string `json:"company,omitempty"`
+	City      string `json:"city,omitempty"`
+}
+
+// LoadFixture reads a JSON array of users.
+func LoadFixture(path string) ([]User, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixture: %w", err)
+	}
+	var users []User
+	if err := json.Unmarshal(b, &users); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	for i := range users {
+		if users[i].ID == 0 {
+			users[i].ID = i + 1
+		}
+		users[i].complete()
+	}
+	return users, nil
+}
+
+var (
+	firstNames = []string{"George", "Janet", "Emma", "Eve", "Charles", "Tracey", "Michael", "Lindsay", "Tobias", "Byron", "George", "Rachel"}
+	lastNames  = []string{"Bluth", "Weaver", "Wong", "Holt", "Morris", "Ramos", "Lawson", "Ferguson", "Funke", "Fields", "Edwards", "Howell"}
+	companies  = []string{"Romaguera-Crona", "Deckow-Crist", "Keebler LLC", "Robel-Corkery", "Hoeger LLC"}
+	cities     = []string{"Gwenborough", "Wisokyburgh", "McKenziehaven", "South Elvis", "Roscoeview"}
+)
+
+// Generate returns n users; the same seed always yields the same users.
+func Generate(n int, seed uint64) []User {
+	r := rand.New(rand.NewPCG(seed, seed))
+	users := make([]User, 0, n)
+	for i := 1; i <= n; i++ {
+		u := User{
+			ID:        i,
+			FirstName: firstNames[r.IntN(len(firstNames))],
+			LastName:  lastNames[r.IntN(len(lastNames))],
+			Phone:     fmt.Sprintf("1-%03d-%03d-%04d", r.IntN(1000), r.IntN(1000), r.IntN(10000)),
+			Company:   companies[r.IntN(len(companies))],
+			City:      cities[r.IntN(len(cities))],
+		}
+		u.Username = fmt.Sprintf("%s.%s%d", strings.ToLower(u.FirstName), strings.ToLower(u.LastName), i)
+		u.Email = u.Username + "@example.com"
+		u.complete()
+		users = append(users, u)
+	}
+	return users
+}
+
+func (u *User) complete() {
+	if u.Name == "" {
+		u.Name = strings.TrimSpace(u.FirstName + " " + u.LastName)
+	}
+	if u.FirstName == "" && u.LastName == "" {
+		u.FirstName, u.LastName, _ = strings.Cut(u.Name, " ")
+	}
+	if u.Website == "" && u.Username != "" {
+		u.Website = strings.ToLower(u.Username) + ".example.com"
+	}
+	if u.Avatar == "" {
+		u.Avatar = fmt.Sprintf("https://reqres.in/img/faces/%d-image.jpg", u.ID)
+	}
+}
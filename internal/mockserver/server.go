@@ -0,0 +1,189 @@
+/*
+Package mockserver serves fake user provider upstreams: reqres-compatible
+(GET /api/users?page=) and jsonplaceholder-compatible (GET /users) endpoints
+backed by fixture or generated users, with injectable latency, errors and
+rate limiting so the service can run and be tested offline.
+*/
+package mockserver
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// Options configures the fault injection and paging of the mock upstreams.
+type Options struct {
+	// PerPage is the reqres page size; jsonplaceholder returns every user
+	// unless the client pages with _page/_limit.
+	PerPage int
+	// Latency delays every answer, plus a random share of up to Jitter.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the share of requests answered with 500, RateLimitRate the
+	// share answered with 429 and a Retry-After of RetryAfter, both in [0, 1].
+	ErrorRate     float64
+	RateLimitRate float64
+	RetryAfter    time.Duration
+	// APIKey, if set, is required in the x-api-key header of reqres calls.
+	APIKey string
+	// Seed makes the injected faults reproducible.
+	Seed uint64
+}
+
+// Server is the mock upstream.
+type Server struct {
+	users []User
+	opts  Options
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New returns a server answering with users.
+func New(users []User, opts Options) *Server {
+	if opts.PerPage <= 0 {
+		opts.PerPage = 6
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = time.Second
+	}
+	return &Server{users: users, opts: opts, rand: rand.New(rand.NewPCG(opts.Seed, opts.Seed))}
+}
+
+// Handler returns the echo instance serving both upstreams.
+func (s *Server) Handler() *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Use(s.faults)
+	e.GET("/api/users", s.reqresUsers)
+	e.GET("/users", s.jsonPlaceholderUsers)
+	return e
+}
+
+// faults delays the request and answers it with an injected failure when one
+// is drawn.
+func (s *Server) faults(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s.mu.Lock()
+		delay := s.opts.Latency
+		if s.opts.Jitter > 0 {
+			delay += time.Duration(s.rand.Int64N(int64(s.opts.Jitter)))
+		}
+		draw := s.rand.Float64()
+		s.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-c.Request().Context().Done():
+				return c.Request().Context().Err()
+			}
+		}
+
+		fields := log.Fields{"method": c.Request().Method, "uri": c.Request().RequestURI}
+		switch {
+		case draw < s.opts.RateLimitRate:
+			log.WithFields(fields).Debug("mock provider: injected 429")
+			c.Response().Header().Set("Retry-After", strconv.Itoa(max(1, int(s.opts.RetryAfter.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "too many requests"})
+		case draw < s.opts.RateLimitRate+s.opts.ErrorRate:
+			log.WithFields(fields).Debug("mock provider: injected 500")
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		}
+		return next(c)
+	}
+}
+
+type reqresUser struct {
+	ID        int    `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Avatar    string `json:"avatar"`
+}
+
+type reqresPage struct {
+	Page       int          `json:"page"`
+	PerPage    int          `json:"per_page"`
+	Total      int          `json:"total"`
+	TotalPages int          `json:"total_pages"`
+	Data       []reqresUser `json:"data"`
+}
+
+func (s *Server) reqresUsers(c echo.Context) error {
+	if s.opts.APIKey != "" && c.Request().Header.Get("x-api-key") != s.opts.APIKey {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing API key"})
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	page = max(page, 1)
+	perPage := s.opts.PerPage
+	if v, err := strconv.Atoi(c.QueryParam("per_page")); err == nil && v > 0 {
+		perPage = v
+	}
+
+	res := reqresPage{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      len(s.users),
+		TotalPages: (len(s.users) + perPage - 1) / perPage,
+		Data:       []reqresUser{},
+	}
+	for _, u := range window(s.users, page, perPage) {
+		res.Data = append(res.Data, reqresUser{ID: u.ID, Email: u.Email, FirstName: u.FirstName, LastName: u.LastName, Avatar: u.Avatar})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type jpUser struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Address  struct {
+		City string `json:"city"`
+	} `json:"address"`
+	Phone   string `json:"phone"`
+	Website string `json:"website"`
+	Company struct {
+		Name string `json:"name"`
+	} `json:"company"`
+}
+
+// jsonPlaceholderUsers returns every user, or a page of them when _page is
+// given; like json-server the total goes into X-Total-Count.
+func (s *Server) jsonPlaceholderUsers(c echo.Context) error {
+	users := s.users
+	if page, err := strconv.Atoi(c.QueryParam("_page")); err == nil && page > 0 {
+		limit, err := strconv.Atoi(c.QueryParam("_limit"))
+		if err != nil || limit <= 0 {
+			limit = 10
+		}
+		users = window(users, page, limit)
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(len(s.users)))
+
+	out := make([]jpUser, 0, len(users))
+	for _, u := range users {
+		j := jpUser{ID: u.ID, Name: u.Name, Username: u.Username, Email: u.Email, Phone: u.Phone, Website: u.Website}
+		j.Address.City = u.City
+		j.Company.Name = u.Company
+		out = append(out, j)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// window returns the users of a 1-based page.
+func window(users []User, page, size int) []User {
+	start := (page - 1) * size
+	if start >= len(users) {
+		return nil
+	}
+	return users[start:min(start+size, len(users))]
+}
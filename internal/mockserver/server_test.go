@@ -0,0 +1,100 @@
+package mockserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"__MODULE__/internal/client/integration"
+	"__MODULE__/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func register(t *testing.T, svc interface {
+	RegisterNewProvider(id, providerName, providerConfig string) error
+}, id, typ, baseURL string, extra ...func(*config.ProviderInstance)) {
+	t.Helper()
+	inst := config.ProviderInstance{ID: id, Type: typ, BaseURL: baseURL}
+	for _, f := range extra {
+		f(&inst)
+	}
+	require.NoError(t, svc.RegisterNewProvider(id, typ, inst.String()))
+}
+
+func TestMockProviders_ServeBothUpstreams(t *testing.T) {
+	srv := httptest.NewServer(New(Generate(8, 7), Options{PerPage: 3, APIKey: "k"}).Handler())
+	defer srv.Close()
+
+	providers := integration.NewUserProviderService(config.App{})
+	register(t, providers, "mock-reqres", integration.ReqresProvider, srv.URL, func(i *config.ProviderInstance) { i.Credentials.APIKey = "k" })
+	register(t, providers, "mock-jp", integration.JsonPlaceholderProvider, srv.URL)
+
+	reqres, err := providers.GetUserService("mock-reqres")
+	require.NoError(t, err)
+	res, err := reqres.GetUsers(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Len(t, res.Users, 2)
+	assert.Equal(t, 3, res.Meta.TotalPages)
+	assert.Equal(t, 8, res.Meta.Total)
+
+	jp, err := providers.GetUserService("mock-jp")
+	require.NoError(t, err)
+	res, err = jp.GetUsers(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, res.Users, 8)
+	assert.NotEmpty(t, res.Users[0].Username)
+	assert.NotEmpty(t, res.Users[0].Extra["city"])
+}
+
+func TestMockProviders_RequiresAPIKey(t *testing.T) {
+	srv := httptest.NewServer(New(Generate(2, 1), Options{APIKey: "k"}).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestMockProviders_InjectsFaults(t *testing.T) {
+	limited := httptest.NewServer(New(Generate(2, 1), Options{RateLimitRate: 1}).Handler())
+	defer limited.Close()
+	resp, err := http.Get(limited.URL + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	failing := httptest.NewServer(New(Generate(2, 1), Options{ErrorRate: 1}).Handler())
+	defer failing.Close()
+	resp, err = http.Get(failing.URL + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestMockProviders_LoadFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name":"Leanne Graham","email":"Sincere@april.biz"},{"id":7,"first_name":"Ervin","last_name":"Howell","email":"Shanna@melissa.tv"}]`), 0o644))
+
+	users, err := LoadFixture(path)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, 1, users[0].ID)
+	assert.Equal(t, "Leanne", users[0].FirstName)
+	assert.Equal(t, "Graham", users[0].LastName)
+	assert.Equal(t, "Ervin Howell", users[1].Name)
+
+	_, err = LoadFixture(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestGenerate_IsDeterministic(t *testing.T) {
+	assert.Equal(t, Generate(5, 42), Generate(5, 42))
+	assert.NotEqual(t, Generate(5, 42), Generate(5, 43))
+}
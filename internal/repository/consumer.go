@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"__MODULE__/internal/dto/repository"
+
+	"gorm.io/gorm/clause"
+)
+
+// ClaimConsumedEvent records an applied event and reports whether it was new.
+// Call it with the transaction of the change the event makes.
+func (r *serviceRepository) ClaimConsumedEvent(ctx context.Context, e repository.ConsumedEvent) (bool, error) {
+	result := conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&e)
+	if err := result.Error; err != nil {
+		return false, r.handleDBErrors(err)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// PurgeConsumedEvents forgets the events applied before the given time and
+// returns how many were removed.
+func (r *serviceRepository) PurgeConsumedEvents(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx).Where("consumed_at < ?", before).Delete(&repository.ConsumedEvent{})
+	if err := result.Error; err != nil {
+		return 0, r.handleDBErrors(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// CreateDeadLetter stores a dead letter and returns its id.
+func (r *serviceRepository) CreateDeadLetter(ctx context.Context, d repository.DeadLetter) (uint, error) {
+	if err := conn(ctx).Create(&d).Error; err != nil {
+		return 0, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return d.ID, nil
+}
+
+// GetDeadLetter returns a dead letter; a missing one is a 404 error.
+func (r *serviceRepository) GetDeadLetter(ctx context.Context, id uint) (repository.DeadLetter, error) {
+	var d repository.DeadLetter
+	if err := conn(ctx).Where("id = ?", id).First(&d).Error; err != nil {
+		return d, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return d, nil
+}
+
+// GetDeadLetters returns the latest dead letters, newest first.
+func (r *serviceRepository) GetDeadLetters(ctx context.Context, params repository.GetDeadLettersRepositoryRequestDTO) ([]repository.DeadLetter, error) {
+	var items []repository.DeadLetter
+	query := conn(ctx).Order("id DESC")
+	if params.Topic != "" {
+		query = query.Where("topic = ?", params.Topic)
+	}
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return items, nil
+}
+
+// MarkDeadLetterReplayed records when a dead letter was published again.
+func (r *serviceRepository) MarkDeadLetterReplayed(ctx context.Context, id uint, replayedAt time.Time) error {
+	err := conn(ctx).Model(&repository.DeadLetter{}).Where("id = ?", id).Update("replayed_at", replayedAt).Error
+	if err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return nil
+}
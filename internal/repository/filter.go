@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"__MODULE__/internal/entity/user"
+)
+
+// filterColumns maps the filterable user fields to columns of the users table.
+var filterColumns = map[user.Field]string{
+	user.FieldID:         "id",
+	user.FieldFullName:   "full_name",
+	user.FieldUsername:   "username",
+	user.FieldEmail:      "email",
+	user.FieldPhone:      "phone",
+	user.FieldWebsite:    "website",
+	user.FieldCompany:    "company",
+	user.FieldCity:       "city",
+	user.FieldProvider:   "provider",
+	user.FieldExternalID: "external_id",
+	user.FieldActive:     "is_active",
+	user.FieldCreatedAt:  "created_at",
+	user.FieldUpdatedAt:  "updated_at",
+}
+
+// likeEscaper escapes the LIKE wildcards of a value matched with co, sw or ew.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filterSQL renders f as a WHERE condition with its arguments. Columns come
+// from filterColumns only, values are always bound.
+func filterSQL(f user.Filter) (string, []any, error) {
+	switch f.Op {
+	case user.OpAnd, user.OpOr:
+		if len(f.Operands) == 0 {
+			return "", nil, fmt.Errorf("filter: %s without operands", f.Op)
+		}
+		parts := make([]string, 0, len(f.Operands))
+		var args []any
+		for _, op := range f.Operands {
+			sql, a, err := filterSQL(op)
+			if err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, sql)
+			args = append(args, a...)
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(string(f.Op))+" ") + ")", args, nil
+	case user.OpNot:
+		if len(f.Operands) != 1 {
+			return "", nil, fmt.Errorf("filter: not takes one operand")
+		}
+		sql, args, err := filterSQL(f.Operands[0])
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + sql, args, nil
+	}
+
+	col, ok := filterColumns[f.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("filter: unknown field %q", f.Field)
+	}
+	if f.Op == user.OpPr {
+		if f.Field == user.FieldActive || f.Field == user.FieldCreatedAt || f.Field == user.FieldUpdatedAt {
+			return col + " IS NOT NULL", nil, nil
+		}
+		return "(" + col + " IS NOT NULL AND " + col + " <> '')", nil, nil
+	}
+
+	switch v := f.Value.(type) {
+	case bool:
+		switch f.Op {
+		case user.OpEq:
+			return col + " = ?", []any{v}, nil
+		case user.OpNe:
+			return "(" + col + " IS NULL OR " + col + " <> ?)", []any{v}, nil
+		}
+		return "", nil, fmt.Errorf("filter: %s does not apply to booleans", f.Op)
+	case time.Time:
+		switch f.Op {
+		case user.OpEq:
+			return col + " = ?", []any{v}, nil
+		case user.OpNe:
+			return "(" + col + " IS NULL OR " + col + " <> ?)", []any{v}, nil
+		case user.OpGt, user.OpGe, user.OpLt, user.OpLe:
+			return col + " " + comparison[f.Op] + " ?", []any{v}, nil
+		}
+		return "", nil, fmt.Errorf("filter: %s does not apply to dates", f.Op)
+	case string:
+		lower := "LOWER(" + col + ")"
+		v = strings.ToLower(v)
+		switch f.Op {
+		case user.OpEq:
+			return lower + " = ?", []any{v}, nil
+		case user.OpNe:
+			return "(" + col + " IS NULL OR " + lower + " <> ?)", []any{v}, nil
+		case user.OpCo:
+			return lower + ` LIKE ? ESCAPE '\'`, []any{"%" + likeEscaper.Replace(v) + "%"}, nil
+		case user.OpSw:
+			return lower + ` LIKE ? ESCAPE '\'`, []any{likeEscaper.Replace(v) + "%"}, nil
+		case user.OpEw:
+			return lower + ` LIKE ? ESCAPE '\'`, []any{"%" + likeEscaper.Replace(v)}, nil
+		case user.OpGt, user.OpGe, user.OpLt, user.OpLe:
+			return lower + " " + comparison[f.Op] + " ?", []any{v}, nil
+		}
+		return "", nil, fmt.Errorf("filter: unknown operator %q", f.Op)
+	default:
+		return "", nil, fmt.Errorf("filter: unsupported value %T for %s", f.Value, f.Field)
+	}
+}
+
+var comparison = map[user.Op]string{user.OpGt: ">", user.OpGe: ">=", user.OpLt: "<", user.OpLe: "<="}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"__MODULE__/internal/entity/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterSQL_RendersNestedExpressions(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	sql, args, err := filterSQL(user.Filter{Op: user.OpAnd, Operands: []user.Filter{
+		{Op: user.OpEq, Field: user.FieldUsername, Value: "BJensen"},
+		{Op: user.OpOr, Operands: []user.Filter{
+			{Op: user.OpCo, Field: user.FieldEmail, Value: "50%_off"},
+			{Op: user.OpNot, Operands: []user.Filter{{Op: user.OpPr, Field: user.FieldPhone}}},
+		}},
+		{Op: user.OpEq, Field: user.FieldActive, Value: true},
+		{Op: user.OpGe, Field: user.FieldCreatedAt, Value: since},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, `(LOWER(username) = ? AND (LOWER(email) LIKE ? ESCAPE '\' OR NOT (phone IS NOT NULL AND phone <> '')) AND is_active = ? AND created_at >= ?)`, sql)
+	assert.Equal(t, []any{"bjensen", `%50\%\_off%`, true, since}, args)
+}
+
+func TestFilterSQL_StringOperators(t *testing.T) {
+	cases := map[user.Op]struct {
+		sql string
+		arg any
+	}{
+		user.OpNe: {"(external_id IS NULL OR LOWER(external_id) <> ?)", "x"},
+		user.OpSw: {`LOWER(external_id) LIKE ? ESCAPE '\'`, "x%"},
+		user.OpEw: {`LOWER(external_id) LIKE ? ESCAPE '\'`, "%x"},
+		user.OpLt: {"LOWER(external_id) < ?", "x"},
+	}
+	for op, want := range cases {
+		sql, args, err := filterSQL(user.Filter{Op: op, Field: user.FieldExternalID, Value: "X"})
+		require.NoError(t, err, op)
+		assert.Equal(t, want.sql, sql, op)
+		assert.Equal(t, []any{want.arg}, args, op)
+	}
+}
+
+func TestFilterSQL_RejectsInvalidFilters(t *testing.T) {
+	for name, f := range map[string]user.Filter{
+		"unknown field":     {Op: user.OpEq, Field: "password", Value: "x"},
+		"bool comparison":   {Op: user.OpCo, Field: user.FieldActive, Value: true},
+		"unsupported value": {Op: user.OpEq, Field: user.FieldEmail, Value: 42},
+		"empty and":         {Op: user.OpAnd},
+		"unknown operator":  {Op: "regex", Field: user.FieldEmail, Value: "x"},
+	} {
+		_, _, err := filterSQL(f)
+		assert.Error(t, err, name)
+	}
+}
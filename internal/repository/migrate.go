@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"fmt"
+
+	"__MODULE__/internal/dto/repository"
+
+	"gorm.io/gorm"
+)
+
+// models lists every table owned by the service, in creation order.
+var models = []any{
+	&repository.BaseUser{},
+	&repository.SyncRun{},
+	&repository.RawResponse{},
+	&repository.WebhookNonce{},
+	&repository.WebhookSubscription{},
+	&repository.WebhookDelivery{},
+	&repository.OutboxEvent{},
+	&repository.ConsumedEvent{},
+	&repository.DeadLetter{},
+}
+
+// uuidPattern matches the internal ids assigned to users.
+const uuidPattern = `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`
+
+// Migrate creates or updates the tables of the service on the current
+// connection and backfills provider-scoped identities.
+//
+// Users stored before identities were scoped used the provider id as primary
+// key. Those rows get the old id as external_id and a fresh internal id. Rows
+// without a provider are only treated as provider users when their id is not a
+// UUID and legacyProvider is set; they are then attributed to it.
+func Migrate(legacyProvider string) error {
+	if db == nil {
+		return fmt.Errorf("database connection is not initialized")
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return backfillExternalIDs(tx, legacyProvider)
+	})
+}
+
+func backfillExternalIDs(tx *gorm.DB, legacyProvider string) error {
+	if legacyProvider != "" {
+		err := tx.Exec(`UPDATE users SET provider = ? WHERE provider IS NULL AND external_id IS NULL AND id !~ ?`,
+			legacyProvider, uuidPattern).Error
+		if err != nil {
+			return fmt.Errorf("attribute legacy users to %s: %w", legacyProvider, err)
+		}
+	}
+
+	err := tx.Exec(`UPDATE users SET external_id = id, id = gen_random_uuid()::text WHERE provider IS NOT NULL AND external_id IS NULL`).Error
+	if err != nil {
+		return fmt.Errorf("backfill external ids: %w", err)
+	}
+	return nil
+}
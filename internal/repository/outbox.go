@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/pkg"
+
+	"gorm.io/gorm"
+)
+
+// outboxRelayLock is the advisory lock held by the relay publishing the
+// outbox, so replicas do not publish the same batch concurrently.
+const outboxRelayLock = 0x6f7574626f78 // "outbox"
+
+// CreateOutboxEvents stores events; call it with the transaction of the change
+// they describe. Their ids are set on return.
+func (r *serviceRepository) CreateOutboxEvents(ctx context.Context, events []repository.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := conn(ctx).Create(&events).Error; err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return nil
+}
+
+// GetOutboxEvent returns an outbox event; a missing one is a 404 error.
+func (r *serviceRepository) GetOutboxEvent(ctx context.Context, id uint) (repository.OutboxEvent, error) {
+	var e repository.OutboxEvent
+	if err := conn(ctx).Where("id = ?", id).First(&e).Error; err != nil {
+		return e, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return e, nil
+}
+
+// GetOutboxEvents returns the latest outbox events, newest first.
+func (r *serviceRepository) GetOutboxEvents(ctx context.Context, params repository.GetOutboxEventsRepositoryRequestDTO) ([]repository.OutboxEvent, error) {
+	var items []repository.OutboxEvent
+	query := conn(ctx).Order("id DESC")
+	if params.Pending != nil {
+		if *params.Pending {
+			query = query.Where("sent_at IS NULL")
+		} else {
+			query = query.Where("sent_at IS NOT NULL")
+		}
+	}
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return items, nil
+}
+
+// LockOutboxEvents returns up to limit unsent events in id order, and keeps
+// the other relays out until the transaction of ctx ends. It returns nothing
+// while another relay holds the lock. Call it within WithTransaction.
+func (r *serviceRepository) LockOutboxEvents(ctx context.Context, limit int) ([]repository.OutboxEvent, error) {
+	var locked bool
+	if err := conn(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLock).Scan(&locked).Error; err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	if !locked {
+		return nil, nil
+	}
+	var items []repository.OutboxEvent
+	if err := conn(ctx).Where("sent_at IS NULL").Order("id").Limit(limit).Find(&items).Error; err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return items, nil
+}
+
+// MarkOutboxEventsSent records that the events with the given ids were published.
+func (r *serviceRepository) MarkOutboxEventsSent(ctx context.Context, ids []uint, sentAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := conn(ctx).Model(&repository.OutboxEvent{}).Where("id IN ?", ids).Update("sent_at", sentAt).Error
+	if err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return nil
+}
+
+// ReplayOutboxEvents marks the selected sent events unsent, so the relay
+// publishes them again, and returns how many were selected.
+func (r *serviceRepository) ReplayOutboxEvents(ctx context.Context, params repository.ReplayOutboxEventsRepositoryRequestDTO) (int64, error) {
+	if len(params.IDs) == 0 && params.FromID == 0 {
+		return 0, pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte("no outbox events selected")).AppendStackLog()
+	}
+	query := conn(ctx).Model(&repository.OutboxEvent{}).Where("sent_at IS NOT NULL")
+	switch {
+	case len(params.IDs) > 0 && params.FromID > 0:
+		query = query.Where("id IN ? OR id >= ?", params.IDs, params.FromID)
+	case len(params.IDs) > 0:
+		query = query.Where("id IN ?", params.IDs)
+	default:
+		query = query.Where("id >= ?", params.FromID)
+	}
+	result := query.Update("sent_at", gorm.Expr("NULL"))
+	if err := result.Error; err != nil {
+		return 0, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return result.RowsAffected, nil
+}
+
+// PurgeOutboxEvents deletes the events sent before the given time and returns
+// how many were removed. Unsent events are kept.
+func (r *serviceRepository) PurgeOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx).Where("sent_at < ?", before).Delete(&repository.OutboxEvent{})
+	if err := result.Error; err != nil {
+		return 0, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return result.RowsAffected, nil
+}
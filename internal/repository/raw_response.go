@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"__MODULE__/internal/dto/repository"
+)
+
+// CreateRawResponse archives a raw provider response.
+func (r *serviceRepository) CreateRawResponse(ctx context.Context, params repository.CreateRawResponseRepositoryRequestDTO) error {
+	if err := conn(ctx).Table("provider_raw_responses").Create(&params.RawResponse).Error; err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return nil
+}
+
+// PurgeRawResponses deletes the responses fetched before the given time and
+// returns how many were removed.
+func (r *serviceRepository) PurgeRawResponses(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx).Table("provider_raw_responses").Where("fetched_at < ?", before).Delete(&repository.RawResponse{})
+	if err := result.Error; err != nil {
+		return 0, r.handleDBErrors(err)
+	}
+	return result.RowsAffected, nil
+}
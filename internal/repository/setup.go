@@ -49,7 +49,7 @@ func NewServiceRepository(config config.App) *serviceRepository {
 	db, err = SetupDB(databaseConfig)
 	if err != nil {
 
-		fmt.Printf("Error initializing DB", "error", err.Error())
+		fmt.Printf("Error initializing DB: %s\n", err.Error())
 	} // Ensure DB instance is initialized
 
 	return &serviceRepository{config: config}
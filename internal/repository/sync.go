@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"__MODULE__/internal/dto/repository"
+)
+
+// CreateSyncRun stores the summary of a provider synchronisation run and returns its id.
+func (r *serviceRepository) CreateSyncRun(ctx context.Context, params repository.CreateSyncRunRepositoryRequestDTO) (uint, error) {
+	if err := conn(ctx).Table("sync_runs").Create(&params.SyncRun).Error; err != nil {
+		return 0, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return params.ID, nil
+}
+
+// GetSyncRuns returns the most recent runs, newest first, optionally for a single provider.
+func (r *serviceRepository) GetSyncRuns(ctx context.Context, params repository.GetSyncRunsRepositoryRequestDTO) ([]repository.SyncRun, error) {
+	var items []repository.SyncRun
+	query := conn(ctx).Table("sync_runs").Order("started_at DESC")
+	if params.Provider != "" {
+		query = query.Where("provider = ?", params.Provider)
+	}
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, r.handleDBErrors(err)
+	}
+	return items, nil
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey carries the transaction opened by WithTransaction in the context.
+type txKey struct{}
+
+// conn returns the transaction carried by ctx, or the shared connection.
+func conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+// WithTransaction runs fn in a database transaction. Repository calls made with
+// the context passed to fn join it; it is committed when fn returns nil and
+// rolled back otherwise. Nested calls use savepoints.
+func (r *serviceRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/pkg"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultUpsertBatchSize is used when the request does not set a batch size.
+const defaultUpsertBatchSize = 100
+
+// upsertColumns are the users columns subject to the column policies. The id
+// and created_at of an existing row are always kept and updated_at is always set.
+var upsertColumns = []string{
+	"full_name", "username", "email", "avatar", "phone", "website", "company", "city",
+	"attributes", "is_active",
+}
+
+// UpsertUsers inserts provider users in batches. A user whose provider and
+// external id already exist is updated according to the column policies
+// instead. The stored users are returned with the id of the row they ended up
+// in, which for existing users is the id already stored, and whether the row
+// was inserted or any of its values changed.
+func (r *serviceRepository) UpsertUsers(ctx context.Context, params repository.UpsertUsersRepositoryRequestDTO) ([]repository.UpsertedUser, error) {
+	users := dedupeByExternalID(params.Users)
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	// the precision of the column, so the returned updated_at compares equal
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for i := range users {
+		if users[i].Provider == nil || users[i].ExternalID == nil || users[i].ID == nil {
+			return nil, pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte(fmt.Sprintf("upsert user %d: id, provider and external id are required", i))).AppendStackLog()
+		}
+		if users[i].CreatedAt == nil {
+			users[i].CreatedAt = &now
+		}
+		updatedAt := now
+		users[i].UpdatedAt = &updatedAt
+	}
+
+	batch := params.BatchSize
+	if batch <= 0 {
+		batch = defaultUpsertBatchSize
+	}
+
+	err := conn(ctx).Table("users").
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "provider"}, {Name: "external_id"}},
+				DoUpdates: upsertAssignments(params.Policy),
+			},
+			clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "updated_at"}}},
+		).
+		CreateInBatches(&users, batch).Error
+	if err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+
+	// updated_at only moves when a value does, see upsertAssignments
+	out := make([]repository.UpsertedUser, 0, len(users))
+	for _, u := range users {
+		out = append(out, repository.UpsertedUser{BaseUser: u, Changed: u.UpdatedAt != nil && u.UpdatedAt.Equal(now)})
+	}
+	return out, nil
+}
+
+// upsertAssignments builds the ON CONFLICT DO UPDATE SET list of policy. The
+// updated_at of a row is only set when one of the assigned columns changes.
+func upsertAssignments(policy repository.UpsertPolicy) clause.Set {
+	var set clause.Set
+	var changed []string
+	for _, column := range upsertColumns {
+		var value string
+		switch policy.For(column) {
+		case repository.ColumnKeep:
+			continue
+		case repository.ColumnCoalesce:
+			value = fmt.Sprintf("COALESCE(excluded.%s, users.%s)", column, column)
+		default:
+			value = "excluded." + column
+		}
+		set = append(set, clause.Assignment{Column: clause.Column{Name: column}, Value: gorm.Expr(value)})
+		changed = append(changed, fmt.Sprintf("users.%s IS DISTINCT FROM %s", column, value))
+	}
+
+	updatedAt := "users.updated_at"
+	if len(changed) > 0 {
+		updatedAt = "CASE WHEN " + strings.Join(changed, " OR ") + " THEN excluded.updated_at ELSE users.updated_at END"
+	}
+	return append(clause.Set{{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr(updatedAt)}}, set...)
+}
+
+// dedupeByExternalID keeps the last user of each provider and external id:
+// Postgres rejects an upsert that touches the same row twice.
+func dedupeByExternalID(users []repository.BaseUser) []repository.BaseUser {
+	type key struct{ provider, externalID string }
+	index := make(map[key]int, len(users))
+	out := make([]repository.BaseUser, 0, len(users))
+	for _, u := range users {
+		if u.Provider == nil || u.ExternalID == nil {
+			out = append(out, u)
+			continue
+		}
+		k := key{*u.Provider, string(*u.ExternalID)}
+		if i, ok := index[k]; ok {
+			out[i] = u
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, u)
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"testing"
+
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/entity/user"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+func TestUpsertAssignments_AppliesPolicies(t *testing.T) {
+	set := upsertAssignments(repository.UpsertPolicy{
+		Default: repository.ColumnCoalesce,
+		Columns: map[string]repository.ColumnPolicy{"email": repository.ColumnKeep, "is_active": repository.ColumnOverwrite},
+	})
+
+	got := map[string]string{}
+	for _, a := range set {
+		got[a.Column.Name] = a.Value.(clause.Expr).SQL
+	}
+	assert.Contains(t, got["updated_at"], "users.phone IS DISTINCT FROM COALESCE(excluded.phone, users.phone)")
+	assert.Contains(t, got["updated_at"], "users.is_active IS DISTINCT FROM excluded.is_active")
+	assert.NotContains(t, got["updated_at"], "email")
+	assert.Equal(t, "excluded.is_active", got["is_active"])
+	assert.Equal(t, "COALESCE(excluded.phone, users.phone)", got["phone"])
+	assert.NotContains(t, got, "email")
+	assert.NotContains(t, got, "id")
+	assert.NotContains(t, got, "created_at")
+}
+
+func TestUpsertAssignments_KeepAllLeavesUpdatedAt(t *testing.T) {
+	set := upsertAssignments(repository.UpsertPolicy{Default: repository.ColumnKeep})
+	if assert.Len(t, set, 1) {
+		assert.Equal(t, "users.updated_at", set[0].Value.(clause.Expr).SQL)
+	}
+}
+
+func TestDedupeByExternalID_LastWins(t *testing.T) {
+	p := "a"
+	first, second, other := user.ExternalID("1"), user.ExternalID("1"), user.ExternalID("2")
+	oldName, newName := user.FullName("old"), user.FullName("new")
+
+	out := dedupeByExternalID([]repository.BaseUser{
+		{Provider: &p, ExternalID: &first, FullName: &oldName},
+		{Provider: &p, ExternalID: &other},
+		{Provider: &p, ExternalID: &second, FullName: &newName},
+	})
+	if assert.Len(t, out, 2) {
+		assert.Equal(t, newName, *out[0].FullName)
+		assert.Equal(t, other, *out[1].ExternalID)
+	}
+}
@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"__MODULE__/internal/dto/repository"
 	"__MODULE__/pkg"
 
 	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
 )
 
 // The repository receiver used in your project. Replace with your actual repo type.
@@ -15,22 +17,32 @@ import (
 
 func (r *serviceRepository) CreateUser(ctx context.Context, params repository.CreateUserRepositoryRequestDTO) error {
 
-	if err := db.WithContext(ctx).Table("users").Create(&params).Error; err != nil {
+	if err := conn(ctx).Table("users").Create(&params).Error; err != nil {
 		return NewAppErrorFromDBErr(err).AppendStackLog()
 	}
 	return nil
 }
 
-// GetUsersList returns a paginated list of users.
+// GetUsersList returns a paginated list of users. A non-nil Filter.Provider
+// restricts the list to the users of that provider instance.
 func (r *serviceRepository) GetUsersList(ctx context.Context, params repository.ListRepositoryRequestDTO[repository.BaseUser]) (res repository.ListRepositoryResponseDTO[repository.BaseUser], err error) {
 	var items []repository.BaseUser
 	offset := (params.Page - 1) * params.Limit
 	var total int64
 
+	scope := func() *gorm.DB {
+		q := conn(ctx).Table("users")
+		if params.Filter.Provider != nil {
+			q = q.Where("provider = ?", *params.Filter.Provider)
+		}
+		return q
+	}
+
 	// total count
-	db.Table("users").Count(&total)
+	scope().Count(&total)
 
-	query := db.Table("users").
+	query := scope().
+		Order("created_at, id").
 		Limit(params.Limit).
 		Offset(offset).
 		Find(&items)
@@ -59,11 +71,56 @@ func (r *serviceRepository) GetUsersList(ctx context.Context, params repository.
 	return res, nil
 }
 
-// GetUserById retrieves a single user by id.
+// GetUserById retrieves a single user by id; a missing user is a 404 error.
 func (r *serviceRepository) GetUserById(ctx context.Context, id string) (repository.BaseUser, error) {
 	var user repository.BaseUser
-	if err := db.Table("users").Where("id = ?", id).First(&user).Error; err != nil {
-		return user, r.handleDBErrors(err)
+	if err := conn(ctx).Table("users").Where("id = ?", id).First(&user).Error; err != nil {
+		return user, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return user, nil
+}
+
+// FindUsers returns the users matching params.Filter in creation order, with
+// the total number of matches.
+func (r *serviceRepository) FindUsers(ctx context.Context, params repository.FindUsersRepositoryRequestDTO) (res repository.ListRepositoryResponseDTO[repository.BaseUser], err error) {
+	scope := func() *gorm.DB {
+		return conn(ctx).Table("users")
+	}
+	if params.Filter != nil {
+		where, args, err := filterSQL(*params.Filter)
+		if err != nil {
+			return res, pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte(err.Error())).AppendStackLog()
+		}
+		scope = func() *gorm.DB {
+			return conn(ctx).Table("users").Where(where, args...)
+		}
+	}
+
+	var total int64
+	if err := scope().Count(&total).Error; err != nil {
+		return res, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	var items []repository.BaseUser
+	if err := scope().Order("created_at, id").Offset(params.Offset).Limit(params.Limit).Find(&items).Error; err != nil {
+		return res, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+
+	res = repository.ListRepositoryResponseDTO[repository.BaseUser]{
+		BasePaginationResponse: repository.BasePaginationResponse{
+			Limit:   params.Limit,
+			Total:   total,
+			HasMore: params.Offset+len(items) < int(total),
+		},
+		List: items,
+	}
+	return res, nil
+}
+
+// GetUserByExternalID retrieves the user pulled from provider with the given external id.
+func (r *serviceRepository) GetUserByExternalID(ctx context.Context, provider, externalID string) (repository.BaseUser, error) {
+	var user repository.BaseUser
+	if err := conn(ctx).Table("users").Where("provider = ? AND external_id = ?", provider, externalID).First(&user).Error; err != nil {
+		return user, NewAppErrorFromDBErr(err).AppendStackLog()
 	}
 	return user, nil
 }
@@ -75,7 +132,7 @@ func (r *serviceRepository) UpdateUser(ctx context.Context, params repository.Up
 		params.IsActive = pkg.PtrBool(false)
 	}
 
-	result := db.Table("users").Where("id = ?", params.ID).Updates(&params)
+	result := conn(ctx).Table("users").Where("id = ?", params.ID).Updates(&params)
 	if err := result.Error; err != nil {
 		return r.handleDBErrors(err)
 	}
@@ -86,9 +143,25 @@ func (r *serviceRepository) UpdateUser(ctx context.Context, params repository.Up
 	return nil
 }
 
+// ReplaceUser overwrites every column of an existing user except its id and
+// creation time, clearing the fields params leaves nil. A missing user is a
+// 404 error.
+func (r *serviceRepository) ReplaceUser(ctx context.Context, params repository.UpdateUserRepositoryRequestDTO) error {
+	now := time.Now()
+	params.UpdatedAt = &now
+	result := conn(ctx).Table("users").Where("id = ?", params.ID).Select("*").Omit("id", "created_at").Updates(&params.BaseUser)
+	if err := result.Error; err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	if result.RowsAffected == 0 {
+		return pkg.NewAppError(pkg.ErrNotFound).AddDescription([]byte(string(*params.ID))).AppendStackLog()
+	}
+	return nil
+}
+
 // DeleteUser deletes a user by id.
 func (r *serviceRepository) DeleteUser(ctx context.Context, id string) error {
-	result := db.Table("users").Where("id = ?", id).Delete(&repository.BaseUser{})
+	result := conn(ctx).Table("users").Where("id = ?", id).Delete(&repository.BaseUser{})
 	if err := result.Error; err != nil {
 		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
 			ErrMsg := fmt.Sprintf("%s %d", mysqlErr.Message, mysqlErr.Number)
@@ -100,8 +173,16 @@ func (r *serviceRepository) DeleteUser(ctx context.Context, id string) error {
 		return r.handleDBErrors(err)
 	}
 	if result.RowsAffected == 0 {
-		// return pkg.ErrRecordNotFound.AddStack().AddDescription("user not found")
-
+		return pkg.NewAppError(pkg.ErrNotFound).AddDescription([]byte(id)).AppendStackLog()
 	}
 	return nil
 }
+
+// GetUsersByProvider returns every user pulled from the given provider instance.
+func (r *serviceRepository) GetUsersByProvider(ctx context.Context, provider string) ([]repository.BaseUser, error) {
+	var items []repository.BaseUser
+	if err := conn(ctx).Table("users").Where("provider = ?", provider).Find(&items).Error; err != nil {
+		return nil, r.handleDBErrors(err)
+	}
+	return items, nil
+}
@@ -45,7 +45,7 @@ func (s *RepositorySuite) SetupSuite() {
 	s.db = gdb
 
 	// Auto-migrate test models (ensures required tables exist)
-	require.NoError(s.T(), db.AutoMigrate(&repository.BaseUser{}), "auto migrate BaseUser")
+	require.NoError(s.T(), Migrate(""), "migrate tables")
 
 	s.ctx = context.Background()
 	s.r = &serviceRepository{}
@@ -65,8 +65,8 @@ func (s *RepositorySuite) TearDownSuite() {
 // BeforeTest ensures a clean table for each test
 func (s *RepositorySuite) BeforeTest(_, _ string) {
 	// Truncate users table for clean state before each test
-	err := s.db.Exec("TRUNCATE TABLE users RESTART IDENTITY CASCADE").Error
-	require.NoError(s.T(), err, "truncate users table")
+	err := s.db.Exec("TRUNCATE TABLE users, sync_runs, provider_raw_responses, outbox_events, consumed_events, dead_letters RESTART IDENTITY CASCADE").Error
+	require.NoError(s.T(), err, "truncate tables")
 }
 
 // TestConstructor_basic shows a minimal constructor-like behavior test.
@@ -160,6 +160,278 @@ func (s *RepositorySuite) TestCRUD_flow() {
 	require.Error(s.T(), err)
 }
 
+// TestProviderUsersAndSyncRuns covers the queries used by provider synchronisation.
+func (s *RepositorySuite) TestProviderUsersAndSyncRuns() {
+	ctx := s.ctx
+	r := s.r
+
+	for i, provider := range []string{"a", "a", "b"} {
+		id := user.ID(fmt.Sprintf("p%d", i))
+		p := provider
+		require.NoError(s.T(), r.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{
+			BaseUser: repository.BaseUser{ID: &id, Provider: &p},
+		}))
+	}
+
+	users, err := r.GetUsersByProvider(ctx, "a")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), users, 2)
+
+	start := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		run := repository.SyncRun{Provider: "a", Status: "succeeded", Created: i, StartedAt: start.Add(time.Duration(i) * time.Minute)}
+		id, err := r.CreateSyncRun(ctx, repository.CreateSyncRunRepositoryRequestDTO{SyncRun: run})
+		require.NoError(s.T(), err)
+		require.NotZero(s.T(), id)
+	}
+
+	runs, err := r.GetSyncRuns(ctx, repository.GetSyncRunsRepositoryRequestDTO{Provider: "a", Limit: 2})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), runs, 2)
+	require.Equal(s.T(), 2, runs[0].Created, "newest run first")
+}
+
+// TestMigrate_BackfillsExternalIDs checks the migration of rows keyed by provider ids.
+func (s *RepositorySuite) TestMigrate_BackfillsExternalIDs() {
+	ctx := s.ctx
+	r := s.r
+
+	scoped, legacy, provider := user.ID("1"), user.ID("2"), "a"
+	require.NoError(s.T(), r.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{BaseUser: repository.BaseUser{ID: &scoped, Provider: &provider}}))
+	require.NoError(s.T(), r.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{BaseUser: repository.BaseUser{ID: &legacy}}))
+
+	require.NoError(s.T(), Migrate("old"))
+
+	got, err := r.GetUserByExternalID(ctx, "a", "1")
+	require.NoError(s.T(), err)
+	require.Regexp(s.T(), uuidPattern, string(*got.ID))
+
+	got, err = r.GetUserByExternalID(ctx, "old", "2")
+	require.NoError(s.T(), err)
+	require.Regexp(s.T(), uuidPattern, string(*got.ID))
+
+	// a second run must not touch migrated rows
+	require.NoError(s.T(), Migrate("old"))
+	again, err := r.GetUserByExternalID(ctx, "old", "2")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), *got.ID, *again.ID)
+
+	filter := "a"
+	list, err := r.GetUsersList(ctx, repository.ListRepositoryRequestDTO[repository.BaseUser]{
+		Filter:                repository.BaseUser{Provider: &filter},
+		BasePaginationRequest: repository.BasePaginationRequest{Limit: 10, Page: 1},
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), list.List, 1)
+	require.EqualValues(s.T(), 1, list.Total)
+}
+
+// TestAttributesAndRawArchive covers the JSONB attributes and the raw response archive.
+func (s *RepositorySuite) TestAttributesAndRawArchive() {
+	ctx := s.ctx
+	r := s.r
+
+	id := user.ID("attr-1")
+	require.NoError(s.T(), r.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{
+		BaseUser: repository.BaseUser{ID: &id, Attributes: repository.Attributes{"catch_phrase": "hi"}},
+	}))
+	got, err := r.GetUserById(ctx, string(id))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), repository.Attributes{"catch_phrase": "hi"}, got.Attributes)
+
+	now := time.Now().UTC()
+	for _, fetched := range []time.Time{now.AddDate(0, 0, -40), now} {
+		require.NoError(s.T(), r.CreateRawResponse(ctx, repository.CreateRawResponseRepositoryRequestDTO{
+			RawResponse: repository.RawResponse{Provider: "a", Page: 1, Payload: []byte("[]"), FetchedAt: fetched},
+		}))
+	}
+	n, err := r.PurgeRawResponses(ctx, now.AddDate(0, 0, -30))
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 1, n)
+}
+
+// TestUpsertUsers_UpdatesExistingRowsInPlace checks a page cached twice does not fail.
+func (s *RepositorySuite) TestUpsertUsers_UpdatesExistingRowsInPlace() {
+	ctx := s.ctx
+	r := s.r
+
+	page := func(name, email string) []repository.BaseUser {
+		id, ext, provider := user.ID("row-"+name), user.ExternalID("1"), "a"
+		full, mail := user.FullName(name), user.Email(email)
+		return []repository.BaseUser{{ID: &id, ExternalID: &ext, Provider: &provider, FullName: &full, Email: &mail}}
+	}
+	policy := repository.UpsertPolicy{Columns: map[string]repository.ColumnPolicy{"email": repository.ColumnKeep}}
+
+	first, err := r.UpsertUsers(ctx, repository.UpsertUsersRepositoryRequestDTO{Users: page("Old", "old@x.com"), Policy: policy})
+	require.NoError(s.T(), err)
+
+	require.True(s.T(), first[0].Changed, "inserted rows are changed")
+
+	var second []repository.UpsertedUser
+	require.NoError(s.T(), r.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		second, err = r.UpsertUsers(ctx, repository.UpsertUsersRepositoryRequestDTO{Users: page("New", "new@x.com"), Policy: policy, BatchSize: 1})
+		return err
+	}))
+	require.Equal(s.T(), *first[0].ID, *second[0].ID, "existing row keeps its id")
+	require.True(s.T(), second[0].Changed)
+
+	got, err := r.GetUserByExternalID(ctx, "a", "1")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), user.FullName("New"), *got.FullName)
+	require.Equal(s.T(), user.Email("old@x.com"), *got.Email, "email is kept by policy")
+
+	// only the kept email differs, so nothing changes
+	third, err := r.UpsertUsers(ctx, repository.UpsertUsersRepositoryRequestDTO{Users: page("New", "other@x.com"), Policy: policy})
+	require.NoError(s.T(), err)
+	require.False(s.T(), third[0].Changed)
+	unchanged, err := r.GetUserByExternalID(ctx, "a", "1")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), got.UpdatedAt.UTC(), unchanged.UpdatedAt.UTC(), "updated_at is kept")
+}
+
+// TestWithTransaction_RollsBack checks calls made with the transaction context are rolled back together.
+func (s *RepositorySuite) TestWithTransaction_RollsBack() {
+	ctx := s.ctx
+	r := s.r
+
+	id := user.ID("tx-1")
+	err := r.WithTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(s.T(), r.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{BaseUser: repository.BaseUser{ID: &id}}))
+		return fmt.Errorf("abort")
+	})
+	require.Error(s.T(), err)
+
+	_, err = r.GetUserById(ctx, string(id))
+	require.Error(s.T(), err)
+}
+
+// TestOutboxEvents covers the relay lock, marking events sent, replay and purge.
+func (s *RepositorySuite) TestOutboxEvents() {
+	ctx := s.ctx
+	r := s.r
+
+	now := time.Now().UTC()
+	require.NoError(s.T(), r.CreateOutboxEvents(ctx, []repository.OutboxEvent{
+		{EventID: "e1", EventType: "user.created", UserID: "u-1", Payload: []byte("{}"), CreatedAt: now},
+		{EventID: "e2", EventType: "user.deleted", UserID: "u-1", Payload: []byte("{}"), CreatedAt: now},
+	}))
+
+	require.NoError(s.T(), r.WithTransaction(ctx, func(ctx context.Context) error {
+		events, err := r.LockOutboxEvents(ctx, 10)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), events, 2)
+		require.Equal(s.T(), "e1", events[0].EventID, "oldest first")
+
+		// a second relay is kept out while the lock is held
+		require.NoError(s.T(), s.db.Transaction(func(tx *gorm.DB) error {
+			other, err := r.LockOutboxEvents(context.WithValue(s.ctx, txKey{}, tx), 10)
+			require.NoError(s.T(), err)
+			require.Empty(s.T(), other)
+			return nil
+		}))
+		return r.MarkOutboxEventsSent(ctx, []uint{events[0].ID, events[1].ID}, now.Add(-48*time.Hour))
+	}))
+
+	pending := true
+	list, err := r.GetOutboxEvents(ctx, repository.GetOutboxEventsRepositoryRequestDTO{Pending: &pending})
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), list)
+
+	n, err := r.ReplayOutboxEvents(ctx, repository.ReplayOutboxEventsRepositoryRequestDTO{FromID: 2})
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 1, n)
+	e, err := r.GetOutboxEvent(ctx, 2)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), e.SentAt)
+
+	n, err = r.PurgeOutboxEvents(ctx, now.Add(-24*time.Hour))
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 1, n, "unsent events are kept")
+}
+
+// TestOutboxEvents_OrderedPerUser shows that the events of one user get ids in
+// commit order: a change waits on the row lock of the previous change to the
+// user, and its event is stored after it.
+func (s *RepositorySuite) TestOutboxEvents_OrderedPerUser() {
+	ctx := s.ctx
+	r := s.r
+
+	id := user.ID("u-order")
+	require.NoError(s.T(), r.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{BaseUser: repository.BaseUser{ID: &id}}))
+	change := func(ctx context.Context, eventID string, stored func()) error {
+		return r.WithTransaction(ctx, func(ctx context.Context) error {
+			name := user.FullName(eventID)
+			if err := r.UpdateUser(ctx, repository.UpdateUserRepositoryRequestDTO{BaseUser: repository.BaseUser{ID: &id, FullName: &name}}); err != nil {
+				return err
+			}
+			stored()
+			return r.CreateOutboxEvents(ctx, []repository.OutboxEvent{{EventID: eventID, UserID: string(id), Payload: []byte("{}"), CreatedAt: time.Now().UTC()}})
+		})
+	}
+
+	// the first change holds the user's row while the second one starts, and
+	// only stores its event afterwards
+	second := make(chan error, 1)
+	require.NoError(s.T(), change(ctx, "first", func() {
+		go func() { second <- change(ctx, "second", func() {}) }()
+		time.Sleep(200 * time.Millisecond)
+	}))
+	require.NoError(s.T(), <-second)
+
+	require.NoError(s.T(), r.WithTransaction(ctx, func(ctx context.Context) error {
+		events, err := r.LockOutboxEvents(ctx, 10)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), events, 2)
+		require.Equal(s.T(), "first", events[0].EventID)
+		require.Equal(s.T(), "second", events[1].EventID)
+		return nil
+	}))
+}
+
+func (s *RepositorySuite) TestConsumedEventsAndDeadLetters() {
+	ctx := s.ctx
+	r := s.r
+
+	now := time.Now().UTC()
+	claimed, err := r.ClaimConsumedEvent(ctx, repository.ConsumedEvent{Consumer: "user-service", EventID: "e1", ConsumedAt: now.Add(-48 * time.Hour)})
+	require.NoError(s.T(), err)
+	require.True(s.T(), claimed)
+	claimed, err = r.ClaimConsumedEvent(ctx, repository.ConsumedEvent{Consumer: "user-service", EventID: "e1", ConsumedAt: now})
+	require.NoError(s.T(), err)
+	require.False(s.T(), claimed, "an event is claimed once per consumer")
+	claimed, err = r.ClaimConsumedEvent(ctx, repository.ConsumedEvent{Consumer: "audit", EventID: "e1", ConsumedAt: now})
+	require.NoError(s.T(), err)
+	require.True(s.T(), claimed)
+
+	n, err := r.PurgeConsumedEvents(ctx, now.Add(-24*time.Hour))
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 1, n)
+
+	id, err := r.CreateDeadLetter(ctx, repository.DeadLetter{
+		Topic: "upstream-users", Consumer: "user-service", DeadLetterTopic: "upstream-users.dlq",
+		Key: []byte("42"), Value: []byte("garbage"), Headers: repository.StringMap{"trace": "t-1"},
+		Offset: 12, Reason: "invalid user event", CreatedAt: now,
+	})
+	require.NoError(s.T(), err)
+	_, err = r.CreateDeadLetter(ctx, repository.DeadLetter{Topic: "other", Value: []byte("x"), CreatedAt: now})
+	require.NoError(s.T(), err)
+
+	list, err := r.GetDeadLetters(ctx, repository.GetDeadLettersRepositoryRequestDTO{Topic: "upstream-users", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), list, 1)
+	require.Equal(s.T(), "t-1", list[0].Headers["trace"])
+	require.EqualValues(s.T(), 12, list[0].Offset)
+
+	require.NoError(s.T(), r.MarkDeadLetterReplayed(ctx, id, now))
+	d, err := r.GetDeadLetter(ctx, id)
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), d.ReplayedAt)
+
+	_, err = r.GetDeadLetter(ctx, 999)
+	require.Error(s.T(), err)
+}
+
 // Run the suite
 func TestRepositorySuite(t *testing.T) {
 	suite.Run(t, new(RepositorySuite))
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/pkg"
+
+	"gorm.io/gorm/clause"
+)
+
+// ClaimWebhookNonce records a delivery id and reports whether it was new.
+func (r *serviceRepository) ClaimWebhookNonce(ctx context.Context, provider, nonce string, expiresAt time.Time) (bool, error) {
+	result := conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&repository.WebhookNonce{Provider: provider, Nonce: nonce, ExpiresAt: expiresAt})
+	if err := result.Error; err != nil {
+		return false, r.handleDBErrors(err)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseWebhookNonce forgets a delivery id so the provider may redeliver it.
+func (r *serviceRepository) ReleaseWebhookNonce(ctx context.Context, provider, nonce string) error {
+	err := conn(ctx).Where("provider = ? AND nonce = ?", provider, nonce).Delete(&repository.WebhookNonce{}).Error
+	if err != nil {
+		return r.handleDBErrors(err)
+	}
+	return nil
+}
+
+// PurgeWebhookNonces deletes the delivery ids that expired before the given
+// time and returns how many were removed.
+func (r *serviceRepository) PurgeWebhookNonces(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx).Where("expires_at < ?", before).Delete(&repository.WebhookNonce{})
+	if err := result.Error; err != nil {
+		return 0, r.handleDBErrors(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// CreateWebhookSubscription stores a new subscription.
+func (r *serviceRepository) CreateWebhookSubscription(ctx context.Context, sub repository.WebhookSubscription) error {
+	if err := conn(ctx).Create(&sub).Error; err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return nil
+}
+
+// GetWebhookSubscriptions returns every subscription, oldest first.
+func (r *serviceRepository) GetWebhookSubscriptions(ctx context.Context) ([]repository.WebhookSubscription, error) {
+	var items []repository.WebhookSubscription
+	if err := conn(ctx).Order("created_at, id").Find(&items).Error; err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return items, nil
+}
+
+// GetWebhookSubscription returns a subscription; a missing one is a 404 error.
+func (r *serviceRepository) GetWebhookSubscription(ctx context.Context, id string) (repository.WebhookSubscription, error) {
+	var sub repository.WebhookSubscription
+	if err := conn(ctx).Where("id = ?", id).First(&sub).Error; err != nil {
+		return sub, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return sub, nil
+}
+
+// UpdateWebhookSubscription overwrites the editable fields of a subscription.
+func (r *serviceRepository) UpdateWebhookSubscription(ctx context.Context, sub repository.WebhookSubscription) error {
+	result := conn(ctx).Model(&repository.WebhookSubscription{}).Where("id = ?", sub.ID).
+		Select("url", "secret", "event_types", "description", "active", "updated_at").Updates(&sub)
+	if err := result.Error; err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	if result.RowsAffected == 0 {
+		return pkg.NewAppError(pkg.ErrNotFound).AddDescription([]byte(sub.ID)).AppendStackLog()
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription removes a subscription together with its deliveries.
+func (r *serviceRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	return r.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := conn(ctx).Where("subscription_id = ?", id).Delete(&repository.WebhookDelivery{}).Error; err != nil {
+			return NewAppErrorFromDBErr(err).AppendStackLog()
+		}
+		result := conn(ctx).Where("id = ?", id).Delete(&repository.WebhookSubscription{})
+		if err := result.Error; err != nil {
+			return NewAppErrorFromDBErr(err).AppendStackLog()
+		}
+		if result.RowsAffected == 0 {
+			return pkg.NewAppError(pkg.ErrNotFound).AddDescription([]byte(id)).AppendStackLog()
+		}
+		return nil
+	})
+}
+
+// CreateWebhookDeliveries queues deliveries; their ids are set on return.
+func (r *serviceRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []repository.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := conn(ctx).Create(&deliveries).Error; err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return nil
+}
+
+// GetWebhookDelivery returns a delivery; a missing one is a 404 error.
+func (r *serviceRepository) GetWebhookDelivery(ctx context.Context, id uint) (repository.WebhookDelivery, error) {
+	var d repository.WebhookDelivery
+	if err := conn(ctx).Where("id = ?", id).First(&d).Error; err != nil {
+		return d, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return d, nil
+}
+
+// GetWebhookDeliveries returns the latest deliveries, newest first.
+func (r *serviceRepository) GetWebhookDeliveries(ctx context.Context, params repository.GetWebhookDeliveriesRepositoryRequestDTO) ([]repository.WebhookDelivery, error) {
+	var items []repository.WebhookDelivery
+	query := conn(ctx).Order("id DESC")
+	if params.SubscriptionID != "" {
+		query = query.Where("subscription_id = ?", params.SubscriptionID)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return items, nil
+}
+
+// ClaimWebhookDeliveries leases the due deliveries, oldest first. Rows locked
+// by another sender are skipped, and a lease that runs out without the
+// delivery being updated makes it due again.
+func (r *serviceRepository) ClaimWebhookDeliveries(ctx context.Context, params repository.ClaimWebhookDeliveriesRepositoryRequestDTO) ([]repository.WebhookDelivery, error) {
+	var items []repository.WebhookDelivery
+	err := conn(ctx).Raw(`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, params.LeaseUntil, params.Status, params.Now, params.Limit).Scan(&items).Error
+	if err != nil {
+		return nil, NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return items, nil
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt.
+func (r *serviceRepository) UpdateWebhookDelivery(ctx context.Context, d repository.WebhookDelivery) error {
+	result := conn(ctx).Model(&repository.WebhookDelivery{}).Where("id = ?", d.ID).
+		Select("status", "attempts", "next_attempt_at", "last_attempt_at", "response_status", "last_error").Updates(&d)
+	if err := result.Error; err != nil {
+		return NewAppErrorFromDBErr(err).AppendStackLog()
+	}
+	return nil
+}
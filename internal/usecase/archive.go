@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/interfaces"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rawArchive keeps raw provider responses so users can be re-mapped from
+// history after a mapper fix. Archiving is best effort: failures are logged and
+// never fail the caller.
+type rawArchive struct {
+	repo interfaces.Repository
+	conf config.RawArchiveConfig
+}
+
+// store archives the raw body of res, fetched for page.
+func (a rawArchive) store(ctx context.Context, page int, res integration.UserListResponseDTO) {
+	if !a.conf.RawArchiveEnabled || len(res.Raw) == 0 {
+		return
+	}
+	raw := repository.CreateRawResponseRepositoryRequestDTO{RawResponse: repository.RawResponse{
+		Provider:     res.Instance,
+		ProviderType: res.Provider,
+		Page:         page,
+		Payload:      res.Raw,
+		FetchedAt:    time.Now().UTC(),
+	}}
+	if err := a.repo.CreateRawResponse(ctx, raw); err != nil {
+		log.WithError(err).WithField("provider", res.Instance).Warn("failed to archive raw provider response")
+	}
+}
+
+// purge deletes the responses older than the retention period.
+func (a rawArchive) purge(ctx context.Context) (int64, error) {
+	if a.conf.RawArchiveRetentionDays <= 0 {
+		return 0, nil
+	}
+	before := time.Now().UTC().AddDate(0, 0, -a.conf.RawArchiveRetentionDays)
+	return a.repo.PurgeRawResponses(ctx, before)
+}
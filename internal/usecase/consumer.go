@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	entity "__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// consumedEventsPurgeInterval is how often the ids of applied events past
+// their retention are forgotten.
+const consumedEventsPurgeInterval = time.Hour
+
+// ApplyUserEvent applies an upstream user event to the users of req.Provider,
+// at most once per event id: the id is claimed in the transaction of the
+// change, and false is returned for an event already applied. Created and
+// updated events create or replace the user with the upstream id; deleted
+// events remove it, and are a no-op for an unknown user.
+func (u *userUsecase) ApplyUserEvent(ctx context.Context, req usecase.ApplyUserEventRequestDTO) (bool, error) {
+	if req.User.ExternalID == nil {
+		return false, pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte("user id is required")).AppendStackLog()
+	}
+
+	applied := false
+	err := u.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		claimed, err := u.repo.ClaimConsumedEvent(ctx, repository.ConsumedEvent{
+			Consumer: req.Consumer, EventID: req.EventID, ConsumedAt: time.Now().UTC(),
+		})
+		if err != nil || !claimed {
+			return err
+		}
+		applied = true
+
+		current, err := u.repo.GetUserByExternalID(ctx, req.Provider, string(*req.User.ExternalID))
+		found := err == nil
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+
+		if req.Type == usecase.UserDeleted {
+			if !found {
+				return nil
+			}
+			if err := u.repo.DeleteUser(ctx, string(*current.ID)); err != nil {
+				return err
+			}
+			events := newUserEvents(usecase.UserDeleted, usecase.BaseUser{ID: current.ID})
+			return u.record(ctx, events)
+		}
+
+		incoming := mapper.UserUsecaseToRepo(req.User)
+		incoming.Provider = &req.Provider
+		incoming.IsActive = pkg.PtrBool(true)
+		if !found {
+			id := entity.ID(uuid.New().String())
+			incoming.ID = &id
+			if err := u.repo.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{BaseUser: incoming}); err != nil {
+				return err
+			}
+			events := newUserEvents(usecase.UserCreated, mapper.UserRepoToUsecase(incoming))
+			return u.record(ctx, events)
+		}
+
+		incoming.ID = current.ID
+		if err := u.repo.ReplaceUser(ctx, repository.UpdateUserRepositoryRequestDTO{BaseUser: incoming}); err != nil {
+			return err
+		}
+		stored, err := u.repo.GetUserById(ctx, string(*current.ID))
+		if err != nil {
+			return err
+		}
+		events := newUserEvents(usecase.UserUpdated, mapper.UserRepoToUsecase(stored))
+		return u.record(ctx, events)
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+// consumerUsecase keeps the books of the bus consumers: the messages they gave
+// up on, and the ids of the events they applied.
+type consumerUsecase struct {
+	repo interfaces.Repository
+	bus  interfaces.MessageBus
+	conf config.UserEventConsumerConfig
+	now  func() time.Time
+}
+
+// NewConsumerUsecase creates a new instance of the consumer usecase. Applied
+// event ids are only purged once Start is called.
+func NewConsumerUsecase(repo interfaces.Repository, bus interfaces.MessageBus, conf config.UserEventConsumerConfig) *consumerUsecase {
+	return &consumerUsecase{repo: repo, bus: bus, conf: conf, now: time.Now}
+}
+
+var _ interfaces.ConsumerUsecase = (*consumerUsecase)(nil)
+
+// DeadLetter publishes a message a consumer gave up on to its dead-letter
+// topic, with the reason in its headers, and stores it for inspection. The
+// message is on the topic once DeadLetter returns nil; failing to store it is
+// only logged.
+func (c *consumerUsecase) DeadLetter(ctx context.Context, d usecase.DeadLetter) (usecase.DeadLetter, error) {
+	headers := maps.Clone(d.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers[bus.HeaderDeadLetterTopic] = d.Topic
+	headers[bus.HeaderDeadLetterConsumer] = d.Consumer
+	headers[bus.HeaderDeadLetterReason] = d.Reason
+	err := c.bus.Publish(ctx, bus.Message{Topic: d.DeadLetterTopic, Key: d.Key, Value: d.Value, Headers: headers})
+	if err != nil {
+		return usecase.DeadLetter{}, err
+	}
+	pkg.CounterAdd("bus_dead_letters_total", 1, "topic", d.Topic, "group", d.Consumer)
+
+	d.ID, d.ReplayedAt = 0, nil
+	d.CreatedAt = c.now().UTC()
+	id, err := c.repo.CreateDeadLetter(context.WithoutCancel(ctx), mapper.DeadLetterUsecaseToRepo(d))
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"topic": d.Topic, "offset": d.Offset}).Error("failed to store dead letter")
+	}
+	d.ID = id
+	return d, nil
+}
+
+// ListDeadLetters returns the latest dead letters, newest first.
+func (c *consumerUsecase) ListDeadLetters(ctx context.Context, req usecase.ListDeadLettersRequestDTO) ([]usecase.DeadLetter, error) {
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	items, err := c.repo.GetDeadLetters(ctx, repository.GetDeadLettersRepositoryRequestDTO{Topic: req.Topic, Limit: req.Limit})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]usecase.DeadLetter, 0, len(items))
+	for _, d := range items {
+		out = append(out, mapper.DeadLetterRepoToUsecase(d))
+	}
+	return out, nil
+}
+
+// GetDeadLetter returns a dead letter; a missing one is a 404 error.
+func (c *consumerUsecase) GetDeadLetter(ctx context.Context, id uint) (usecase.DeadLetter, error) {
+	d, err := c.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		return usecase.DeadLetter{}, err
+	}
+	return mapper.DeadLetterRepoToUsecase(d), nil
+}
+
+// ReplayDeadLetter publishes a dead letter to the topic it was received from
+// again, as it was received, and records when.
+func (c *consumerUsecase) ReplayDeadLetter(ctx context.Context, id uint) (usecase.DeadLetter, error) {
+	d, err := c.GetDeadLetter(ctx, id)
+	if err != nil {
+		return usecase.DeadLetter{}, err
+	}
+	if err := c.bus.Publish(ctx, bus.Message{Topic: d.Topic, Key: d.Key, Value: d.Value, Headers: d.Headers}); err != nil {
+		return usecase.DeadLetter{}, err
+	}
+	now := c.now().UTC()
+	if err := c.repo.MarkDeadLetterReplayed(ctx, id, now); err != nil {
+		return usecase.DeadLetter{}, err
+	}
+	d.ReplayedAt = &now
+	return d, nil
+}
+
+// Start forgets the ids of the events applied longer than
+// ConsumerDedupeRetentionHours ago, every hour until ctx is done.
+func (c *consumerUsecase) Start(ctx context.Context) {
+	if c.conf.ConsumerDedupeRetentionHours <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(consumedEventsPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.purge(ctx); err != nil {
+					log.WithError(err).Error("consumer: failed to purge applied event ids")
+				}
+			}
+		}
+	}()
+}
+
+func (c *consumerUsecase) purge(ctx context.Context) error {
+	before := c.now().UTC().Add(-time.Duration(c.conf.ConsumerDedupeRetentionHours) * time.Hour)
+	n, err := c.repo.PurgeConsumedEvents(ctx, before)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.WithField("deleted", n).Info("purged applied event ids")
+	}
+	return nil
+}
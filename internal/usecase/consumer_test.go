@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newApplyRequest(typ usecase.UserEventType) usecase.ApplyUserEventRequestDTO {
+	externalID, name := user.ExternalID("42"), user.FullName("Ada")
+	return usecase.ApplyUserEventRequestDTO{
+		Consumer: "user-service",
+		EventID:  "evt-1",
+		Type:     typ,
+		Provider: "hr",
+		User:     usecase.BaseUser{ExternalID: &externalID, FullName: &name},
+	}
+}
+
+func TestApplyUserEvent_SkipsAppliedEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	repo.On("ClaimConsumedEvent", ctx, mock.MatchedBy(func(e repository.ConsumedEvent) bool {
+		return e.Consumer == "user-service" && e.EventID == "evt-1"
+	})).Return(false, nil).Once()
+
+	uc := NewUserUsecase(repo, nil, 10)
+	applied, err := uc.ApplyUserEvent(ctx, newApplyRequest(usecase.UserUpdated))
+	require.NoError(t, err)
+	assert.False(t, applied)
+	repo.AssertNotCalled(t, "GetUserByExternalID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApplyUserEvent_CreatesUnknownUser(t *testing.T) {
+	ctx := context.Background()
+	repo, events := &MockRepository{}, &MockUserEventPublisher{}
+	repo.On("ClaimConsumedEvent", ctx, mock.Anything).Return(true, nil).Once()
+	repo.On("GetUserByExternalID", ctx, "hr", "42").Return(nil, pkg.NewAppError(pkg.ErrNotFound)).Once()
+	repo.On("CreateUser", ctx, mock.MatchedBy(func(req repository.CreateUserRepositoryRequestDTO) bool {
+		return req.ID != nil && *req.ID != "" && *req.Provider == "hr" && *req.ExternalID == "42" &&
+			*req.FullName == "Ada" && *req.IsActive
+	})).Return(nil).Once()
+	events.On("PublishUserEvents", mock.Anything, mock.MatchedBy(func(e []usecase.UserEvent) bool {
+		return len(e) == 1 && e[0].Type == usecase.UserCreated
+	})).Return(nil).Once()
+
+	uc := NewUserUsecase(repo, nil, 10, WithUserEvents(events))
+	applied, err := uc.ApplyUserEvent(ctx, newApplyRequest(usecase.UserCreated))
+	require.NoError(t, err)
+	assert.True(t, applied)
+	repo.AssertExpectations(t)
+	events.AssertExpectations(t)
+}
+
+func TestApplyUserEvent_ReplacesKnownUser(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	id, externalID, name := user.ID("u-1"), user.ExternalID("42"), user.FullName("Ada")
+	stored := repository.BaseUser{ID: &id, ExternalID: &externalID, FullName: &name}
+	repo.On("ClaimConsumedEvent", ctx, mock.Anything).Return(true, nil).Once()
+	repo.On("GetUserByExternalID", ctx, "hr", "42").Return(stored, nil).Once()
+	repo.On("ReplaceUser", ctx, mock.MatchedBy(func(req repository.UpdateUserRepositoryRequestDTO) bool {
+		return *req.ID == "u-1" && *req.FullName == "Ada"
+	})).Return(nil).Once()
+	repo.On("GetUserById", ctx, "u-1").Return(stored, nil).Once()
+
+	uc := NewUserUsecase(repo, nil, 10)
+	applied, err := uc.ApplyUserEvent(ctx, newApplyRequest(usecase.UserUpdated))
+	require.NoError(t, err)
+	assert.True(t, applied)
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+func TestApplyUserEvent_Delete(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	id := user.ID("u-1")
+	repo.On("ClaimConsumedEvent", ctx, mock.Anything).Return(true, nil).Twice()
+	repo.On("GetUserByExternalID", ctx, "hr", "42").Return(repository.BaseUser{ID: &id}, nil).Once()
+	repo.On("DeleteUser", ctx, "u-1").Return(nil).Once()
+	uc := NewUserUsecase(repo, nil, 10)
+
+	applied, err := uc.ApplyUserEvent(ctx, newApplyRequest(usecase.UserDeleted))
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	repo.On("GetUserByExternalID", ctx, "hr", "42").Return(nil, pkg.NewAppError(pkg.ErrNotFound)).Once()
+	applied, err = uc.ApplyUserEvent(ctx, newApplyRequest(usecase.UserDeleted))
+	require.NoError(t, err, "deleting an unknown user is a no-op")
+	assert.True(t, applied)
+	repo.AssertNumberOfCalls(t, "DeleteUser", 1)
+}
+
+func TestApplyUserEvent_RequiresUserID(t *testing.T) {
+	req := newApplyRequest(usecase.UserCreated)
+	req.User.ExternalID = nil
+
+	uc := NewUserUsecase(&MockRepository{}, nil, 10)
+	_, err := uc.ApplyUserEvent(context.Background(), req)
+	assertErrorCode(t, pkg.ErrBadRequest, err)
+}
+
+var consumerNow = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestConsumers(repo *MockRepository, b *fakeBus) *consumerUsecase {
+	c := NewConsumerUsecase(repo, b, config.UserEventConsumerConfig{ConsumerDedupeRetentionHours: 24})
+	c.now = func() time.Time { return consumerNow }
+	return c
+}
+
+func TestConsumerUsecase_DeadLetter(t *testing.T) {
+	ctx := context.Background()
+	repo, b := &MockRepository{}, &fakeBus{}
+	repo.On("CreateDeadLetter", mock.Anything, mock.MatchedBy(func(d repository.DeadLetter) bool {
+		return d.Topic == "upstream-users" && d.Reason == "invalid user event" && d.Offset == 12 &&
+			d.CreatedAt.Equal(consumerNow) && d.Headers["trace"] == "t-1"
+	})).Return(3, nil).Once()
+
+	d, err := newTestConsumers(repo, b).DeadLetter(ctx, usecase.DeadLetter{
+		Topic: "upstream-users", Consumer: "user-service", DeadLetterTopic: "upstream-users.dlq",
+		Key: []byte("42"), Value: []byte("garbage"), Headers: map[string]string{"trace": "t-1"},
+		Offset: 12, Reason: "invalid user event",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), d.ID)
+	require.Len(t, b.msgs, 1)
+	assert.Equal(t, bus.Message{
+		Topic: "upstream-users.dlq",
+		Key:   []byte("42"),
+		Value: []byte("garbage"),
+		Headers: map[string]string{
+			"trace":                      "t-1",
+			bus.HeaderDeadLetterTopic:    "upstream-users",
+			bus.HeaderDeadLetterConsumer: "user-service",
+			bus.HeaderDeadLetterReason:   "invalid user event",
+		},
+	}, b.msgs[0])
+	assert.Equal(t, map[string]string{"trace": "t-1"}, d.Headers, "the original headers are kept")
+}
+
+func TestConsumerUsecase_DeadLetterFailsWithoutTheTopic(t *testing.T) {
+	repo := &MockRepository{}
+
+	_, err := newTestConsumers(repo, &fakeBus{err: errors.New("broker unavailable")}).DeadLetter(context.Background(), usecase.DeadLetter{Topic: "t"})
+	require.Error(t, err)
+	repo.AssertNotCalled(t, "CreateDeadLetter", mock.Anything, mock.Anything)
+}
+
+func TestConsumerUsecase_ReplayDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	repo, b := &MockRepository{}, &fakeBus{}
+	repo.On("GetDeadLetter", ctx, uint(3)).Return(repository.DeadLetter{
+		ID: 3, Topic: "upstream-users", Key: []byte("42"), Value: []byte(`{"id":"evt-1"}`),
+		Headers: repository.StringMap{"trace": "t-1"},
+	}, nil).Once()
+	repo.On("MarkDeadLetterReplayed", ctx, uint(3), consumerNow).Return(nil).Once()
+
+	d, err := newTestConsumers(repo, b).ReplayDeadLetter(ctx, 3)
+	require.NoError(t, err)
+	require.NotNil(t, d.ReplayedAt)
+	assert.Equal(t, []bus.Message{{
+		Topic: "upstream-users", Key: []byte("42"), Value: []byte(`{"id":"evt-1"}`), Headers: map[string]string{"trace": "t-1"},
+	}}, b.msgs)
+	repo.AssertExpectations(t)
+}
+
+func TestConsumerUsecase_PurgesAppliedEventIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	repo.On("PurgeConsumedEvents", ctx, consumerNow.Add(-24*time.Hour)).Return(2, nil).Once()
+
+	require.NoError(t, newTestConsumers(repo, nil).purge(ctx))
+	repo.AssertExpectations(t)
+}
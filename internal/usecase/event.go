@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/google/uuid"
+)
+
+// newUserEvents returns an event of type typ for each user.
+func newUserEvents(typ usecase.UserEventType, users ...usecase.BaseUser) []usecase.UserEvent {
+	now := time.Now().UTC()
+	events := make([]usecase.UserEvent, 0, len(users))
+	for _, user := range users {
+		events = append(events, usecase.UserEvent{ID: uuid.New().String(), Type: typ, OccurredAt: now, User: user})
+	}
+	return events
+}
+
+// userWriter stores changes to users together with their events. The usecases
+// writing users share it, so every change reaches the outbox and the
+// publisher the same way, in the transaction of the change.
+type userWriter struct {
+	repo   interfaces.Repository
+	events interfaces.UserEventPublisher // optional receiver of user changes
+	outbox bool                          // store user changes in the outbox
+}
+
+func newUserWriter(repo interfaces.Repository, o options) userWriter {
+	return userWriter{repo: repo, events: o.events, outbox: o.outbox}
+}
+
+// write runs change and records events in one transaction.
+func (w userWriter) write(ctx context.Context, events []usecase.UserEvent, change func(ctx context.Context) error) error {
+	return w.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := change(ctx); err != nil {
+			return err
+		}
+		return w.record(ctx, events)
+	})
+}
+
+// createProviderUser stores a user pulled from a provider, announced as
+// user.created.
+func (w userWriter) createProviderUser(ctx context.Context, ru repository.BaseUser) error {
+	events := newUserEvents(usecase.UserCreated, mapper.UserRepoToUsecase(ru))
+	return w.write(ctx, events, func(ctx context.Context) error {
+		return w.repo.CreateUser(ctx, repository.CreateUserRepositoryRequestDTO{BaseUser: ru})
+	})
+}
+
+// updateProviderUser writes the provider-owned fields of ru over the stored
+// user, announced as user.updated.
+func (w userWriter) updateProviderUser(ctx context.Context, ru repository.BaseUser) error {
+	events := newUserEvents(usecase.UserUpdated, mapper.UserRepoToUsecase(ru))
+	return w.write(ctx, events, func(ctx context.Context) error {
+		return w.repo.UpdateUser(ctx, repository.UpdateUserRepositoryRequestDTO{BaseUser: ru})
+	})
+}
+
+// deactivateProviderUser marks a user its provider no longer has as inactive,
+// announced as user.deactivated.
+func (w userWriter) deactivateProviderUser(ctx context.Context, current repository.BaseUser) error {
+	current.IsActive = pkg.PtrBool(false)
+	events := newUserEvents(usecase.UserDeactivated, mapper.UserRepoToUsecase(current))
+	return w.write(ctx, events, func(ctx context.Context) error {
+		return w.repo.UpdateUser(ctx, repository.UpdateUserRepositoryRequestDTO{
+			BaseUser: repository.BaseUser{ID: current.ID, IsActive: current.IsActive},
+		})
+	})
+}
+
+// record stores events in the outbox, if enabled, with the request id of ctx,
+// and hands them to the publisher, if set. It must be called with the
+// transaction of the change, after the change, so the events and what the
+// publisher stores for them, such as webhook deliveries, are stored exactly
+// when the change is, and the row locks the change took order the events of a
+// user.
+func (w userWriter) record(ctx context.Context, events []usecase.UserEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if w.outbox {
+		if err := w.store(ctx, events); err != nil {
+			return err
+		}
+	}
+	if w.events != nil {
+		return w.events.PublishUserEvents(ctx, events)
+	}
+	return nil
+}
+
+// store adds events to the outbox.
+func (w userWriter) store(ctx context.Context, events []usecase.UserEvent) error {
+	requestID, _ := pkg.RequestIDFromContext(ctx)
+	rows := make([]repository.OutboxEvent, 0, len(events))
+	for _, e := range events {
+		payload, err := json.Marshal(mapper.UserEventToPayload(e))
+		if err != nil {
+			return err
+		}
+		row := mapper.UserEventToOutbox(e, payload)
+		row.RequestID = requestID
+		rows = append(rows, row)
+	}
+	return w.repo.CreateOutboxEvents(ctx, rows)
+}
@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// outboundWebhookUsecase queues a delivery per matching subscription for each
+// published user event and sends them in the background. Deliveries live in
+// the database, so retries survive restarts and are shared between replicas.
+type outboundWebhookUsecase struct {
+	repo   interfaces.Repository
+	sender interfaces.WebhookSender
+	conf   config.OutboundWebhookConfig
+	now    func() time.Time
+}
+
+// NewOutboundWebhookUsecase creates a new instance of the outbound webhook
+// usecase. Deliveries are only sent once Start is called.
+func NewOutboundWebhookUsecase(repo interfaces.Repository, sender interfaces.WebhookSender, conf config.OutboundWebhookConfig) *outboundWebhookUsecase {
+	conf.DeliveryPollInterval = max(conf.DeliveryPollInterval, 1)
+	conf.DeliveryBatchSize = max(conf.DeliveryBatchSize, 1)
+	conf.DeliveryMaxAttempts = max(conf.DeliveryMaxAttempts, 1)
+	return &outboundWebhookUsecase{repo: repo, sender: sender, conf: conf, now: time.Now}
+}
+
+var _ interfaces.OutboundWebhookUsecase = (*outboundWebhookUsecase)(nil)
+
+// CreateSubscription validates and stores a new subscription.
+func (o *outboundWebhookUsecase) CreateSubscription(ctx context.Context, req usecase.WebhookSubscriptionRequestDTO) (usecase.WebhookSubscription, error) {
+	if err := validateSubscription(req); err != nil {
+		return usecase.WebhookSubscription{}, err
+	}
+	if req.Secret == "" {
+		req.Secret = newWebhookSecret()
+	}
+	now := o.now().UTC()
+	sub := usecase.WebhookSubscription{
+		ID:          uuid.New().String(),
+		URL:         req.URL,
+		Secret:      req.Secret,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		Active:      req.Active,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := o.repo.CreateWebhookSubscription(ctx, mapper.WebhookSubscriptionUsecaseToRepo(sub)); err != nil {
+		return usecase.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns every subscription, oldest first.
+func (o *outboundWebhookUsecase) ListSubscriptions(ctx context.Context) ([]usecase.WebhookSubscription, error) {
+	subs, err := o.repo.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]usecase.WebhookSubscription, 0, len(subs))
+	for _, s := range subs {
+		out = append(out, mapper.WebhookSubscriptionRepoToUsecase(s))
+	}
+	return out, nil
+}
+
+// GetSubscription returns a subscription; a missing one is a 404 error.
+func (o *outboundWebhookUsecase) GetSubscription(ctx context.Context, id string) (usecase.WebhookSubscription, error) {
+	sub, err := o.repo.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return usecase.WebhookSubscription{}, err
+	}
+	return mapper.WebhookSubscriptionRepoToUsecase(sub), nil
+}
+
+// ReplaceSubscription overwrites a subscription, keeping its secret when
+// none is given.
+func (o *outboundWebhookUsecase) ReplaceSubscription(ctx context.Context, req usecase.WebhookSubscriptionRequestDTO) (usecase.WebhookSubscription, error) {
+	if err := validateSubscription(req); err != nil {
+		return usecase.WebhookSubscription{}, err
+	}
+	current, err := o.GetSubscription(ctx, req.ID)
+	if err != nil {
+		return usecase.WebhookSubscription{}, err
+	}
+	current.URL = req.URL
+	current.EventTypes = req.EventTypes
+	current.Description = req.Description
+	current.Active = req.Active
+	if req.Secret != "" {
+		current.Secret = req.Secret
+	}
+	current.UpdatedAt = o.now().UTC()
+	if err := o.repo.UpdateWebhookSubscription(ctx, mapper.WebhookSubscriptionUsecaseToRepo(current)); err != nil {
+		return usecase.WebhookSubscription{}, err
+	}
+	return current, nil
+}
+
+// DeleteSubscription removes a subscription and its delivery log.
+func (o *outboundWebhookUsecase) DeleteSubscription(ctx context.Context, id string) error {
+	return o.repo.DeleteWebhookSubscription(ctx, id)
+}
+
+// ListDeliveries returns the latest deliveries of a subscription, newest first.
+func (o *outboundWebhookUsecase) ListDeliveries(ctx context.Context, req usecase.ListWebhookDeliveriesRequestDTO) ([]usecase.WebhookDelivery, error) {
+	if _, err := o.repo.GetWebhookSubscription(ctx, req.SubscriptionID); err != nil {
+		return nil, err
+	}
+	if req.Limit <= 0 {
+		req.Limit = 50
+	}
+	items, err := o.repo.GetWebhookDeliveries(ctx, repository.GetWebhookDeliveriesRepositoryRequestDTO{
+		SubscriptionID: req.SubscriptionID,
+		Status:         string(req.Status),
+		Limit:          req.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]usecase.WebhookDelivery, 0, len(items))
+	for _, d := range items {
+		out = append(out, mapper.WebhookDeliveryRepoToUsecase(d))
+	}
+	return out, nil
+}
+
+// Redeliver queues the event of a past delivery again under the same event
+// id, so subscribers can recognise it. The original stays in the log.
+func (o *outboundWebhookUsecase) Redeliver(ctx context.Context, subscriptionID string, deliveryID uint) (usecase.WebhookDelivery, error) {
+	past, err := o.repo.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return usecase.WebhookDelivery{}, err
+	}
+	if past.SubscriptionID != subscriptionID {
+		return usecase.WebhookDelivery{}, pkg.NewAppError(pkg.ErrNotFound).AppendStackLog()
+	}
+
+	now := o.now().UTC()
+	d := []repository.WebhookDelivery{{
+		SubscriptionID: past.SubscriptionID,
+		EventID:        past.EventID,
+		EventType:      past.EventType,
+		Payload:        past.Payload,
+		Status:         string(usecase.WebhookDeliveryPending),
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}}
+	if err := o.repo.CreateWebhookDeliveries(ctx, d); err != nil {
+		return usecase.WebhookDelivery{}, err
+	}
+	return mapper.WebhookDeliveryRepoToUsecase(d[0]), nil
+}
+
+// PublishUserEvents queues a delivery of each event to every active
+// subscription of its type. Called with the transaction of the change, the
+// deliveries are stored exactly when the change is.
+func (o *outboundWebhookUsecase) PublishUserEvents(ctx context.Context, events []usecase.UserEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	subs, err := o.repo.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := o.now().UTC()
+	var deliveries []repository.WebhookDelivery
+	for _, e := range events {
+		var payload []byte
+		for _, s := range subs {
+			if !s.Active || (len(s.EventTypes) > 0 && !slices.Contains(s.EventTypes, string(e.Type))) {
+				continue
+			}
+			if payload == nil {
+				if payload, err = json.Marshal(mapper.UserEventToPayload(e)); err != nil {
+					return err
+				}
+			}
+			deliveries = append(deliveries, mapper.WebhookDeliveryUsecaseToRepo(usecase.WebhookDelivery{
+				SubscriptionID: s.ID,
+				EventID:        e.ID,
+				EventType:      e.Type,
+				Payload:        payload,
+				Status:         usecase.WebhookDeliveryPending,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+			}))
+		}
+	}
+	return o.repo.CreateWebhookDeliveries(ctx, deliveries)
+}
+
+// Start sends due deliveries every DeliveryPollInterval until ctx is done.
+func (o *outboundWebhookUsecase) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(o.conf.DeliveryPollInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// a full batch suggests more are due
+				for {
+					n, err := o.deliverDue(ctx)
+					if err != nil {
+						log.WithError(err).Error("webhook delivery: failed to claim due deliveries")
+					}
+					if err != nil || n < o.conf.DeliveryBatchSize || ctx.Err() != nil {
+						break
+					}
+				}
+			}
+		}
+	}()
+}
+
+// deliverDue sends a batch of due deliveries concurrently and returns its size.
+func (o *outboundWebhookUsecase) deliverDue(ctx context.Context) (int, error) {
+	now := o.now().UTC()
+	// a lease outlasting the request timeout keeps other replicas away while
+	// the delivery is in flight
+	lease := 2*time.Duration(o.conf.DeliveryTimeout)*time.Millisecond + time.Minute
+	due, err := o.repo.ClaimWebhookDeliveries(ctx, repository.ClaimWebhookDeliveriesRepositoryRequestDTO{
+		Status:     string(usecase.WebhookDeliveryPending),
+		Now:        now,
+		LeaseUntil: now.Add(lease),
+		Limit:      o.conf.DeliveryBatchSize,
+	})
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+	subs, err := o.repo.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[string]repository.WebhookSubscription, len(subs))
+	for _, s := range subs {
+		byID[s.ID] = s
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub, ok := byID[d.SubscriptionID]
+			o.attempt(ctx, mapper.WebhookDeliveryRepoToUsecase(d), mapper.WebhookSubscriptionRepoToUsecase(sub), ok)
+		}()
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+// attempt sends a delivery and records the outcome: succeeded, pending with
+// the next attempt backed off exponentially, or failed once out of attempts.
+// Deliveries of deleted or inactive subscriptions fail without being sent.
+func (o *outboundWebhookUsecase) attempt(ctx context.Context, d usecase.WebhookDelivery, sub usecase.WebhookSubscription, found bool) {
+	var status int
+	var err error
+	switch {
+	case !found:
+		err = pkg.NewAppError(pkg.ErrNotFound).AddDescription([]byte("subscription deleted"))
+	case !sub.Active:
+		err = pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte("subscription inactive"))
+	default:
+		status, err = o.sender.Deliver(ctx, integration.WebhookDeliveryRequestDTO{
+			URL:       sub.URL,
+			Secret:    sub.Secret,
+			EventID:   d.EventID,
+			EventType: string(d.EventType),
+			Body:      d.Payload,
+		})
+	}
+
+	now := o.now().UTC()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = status
+	d.LastError = ""
+	switch {
+	case err == nil:
+		d.Status = usecase.WebhookDeliverySucceeded
+	case !found || !sub.Active || d.Attempts >= o.conf.DeliveryMaxAttempts:
+		d.Status = usecase.WebhookDeliveryFailed
+		d.LastError = errorText(err)
+	default:
+		d.Status = usecase.WebhookDeliveryPending
+		d.LastError = errorText(err)
+		d.NextAttemptAt = now.Add(o.backoff(d.Attempts))
+	}
+	pkg.CounterAdd("webhook_outbound_deliveries_total", 1, "event", string(d.EventType), "status", string(d.Status))
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"subscription": d.SubscriptionID, "delivery": d.ID, "attempt": d.Attempts}).Warn("webhook delivery failed")
+	}
+
+	if err := o.repo.UpdateWebhookDelivery(context.WithoutCancel(ctx), mapper.WebhookDeliveryUsecaseToRepo(d)); err != nil {
+		log.WithError(err).WithField("delivery", d.ID).Error("webhook delivery: failed to store outcome")
+	}
+}
+
+// backoff returns the delay before the attempt following attempt.
+func (o *outboundWebhookUsecase) backoff(attempt int) time.Duration {
+	delay := time.Duration(o.conf.DeliveryRetryBase) * time.Millisecond
+	limit := time.Duration(o.conf.DeliveryRetryMax) * time.Millisecond
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if limit > 0 {
+		delay = min(delay, limit)
+	}
+	return delay
+}
+
+// validateSubscription checks the endpoint is an absolute http(s) URL and
+// every event type is known.
+func validateSubscription(req usecase.WebhookSubscriptionRequestDTO) error {
+	invalid := func(reason string) error {
+		return pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte(reason)).AppendStackLog()
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalid("url must be an absolute http or https URL")
+	}
+	for _, t := range req.EventTypes {
+		if !slices.Contains(usecase.UserEventTypes, t) {
+			return invalid("unknown event type " + string(t))
+		}
+	}
+	return nil
+}
+
+// newWebhookSecret returns 32 random bytes, hex encoded.
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errorText returns the description of an application error, which says more
+// than its message, or the error text otherwise.
+func errorText(err error) string {
+	var appErr *pkg.AppError
+	if errors.As(err, &appErr) && appErr.DescriptionStr() != "" {
+		return appErr.DescriptionStr()
+	}
+	return err.Error()
+}
@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookSender struct {
+	mock.Mock
+}
+
+func (m *MockWebhookSender) Deliver(ctx context.Context, req integration.WebhookDeliveryRequestDTO) (int, error) {
+	args := m.Called(ctx, req)
+	return args.Int(0), args.Error(1)
+}
+
+var webhookNow = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestOutboundWebhooks(repo *MockRepository, sender *MockWebhookSender) *outboundWebhookUsecase {
+	o := NewOutboundWebhookUsecase(repo, sender, config.OutboundWebhookConfig{
+		DeliveryMaxAttempts: 3, DeliveryRetryBase: 1000, DeliveryRetryMax: 60000, DeliveryBatchSize: 10,
+	})
+	o.now = func() time.Time { return webhookNow }
+	return o
+}
+
+func TestPublishUserEvents_FiltersSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	repo.On("GetWebhookSubscriptions", ctx).Return([]repository.WebhookSubscription{
+		{ID: "all", Active: true},
+		{ID: "deletes", Active: true, EventTypes: repository.StringList{"user.deleted"}},
+		{ID: "off", Active: false},
+	}, nil)
+	var queued []repository.WebhookDelivery
+	repo.On("CreateWebhookDeliveries", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		queued = args.Get(1).([]repository.WebhookDelivery)
+	}).Return(nil)
+
+	id := user.ID("u-1")
+	err := newTestOutboundWebhooks(repo, nil).PublishUserEvents(ctx, []usecase.UserEvent{
+		{ID: "e1", Type: usecase.UserCreated, OccurredAt: webhookNow, User: usecase.BaseUser{ID: &id}},
+		{ID: "e2", Type: usecase.UserDeleted, OccurredAt: webhookNow, User: usecase.BaseUser{ID: &id}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, queued, 3)
+	assert.Equal(t, []string{"all", "all", "deletes"}, []string{queued[0].SubscriptionID, queued[1].SubscriptionID, queued[2].SubscriptionID})
+	assert.Equal(t, "pending", queued[0].Status)
+	assert.Equal(t, webhookNow, queued[0].NextAttemptAt)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(queued[0].Payload, &payload))
+	assert.Equal(t, "e1", payload["id"])
+	assert.Equal(t, "user.created", payload["type"])
+	assert.Equal(t, "u-1", payload["user"].(map[string]any)["id"])
+}
+
+func TestOutboundWebhookAttempt(t *testing.T) {
+	ctx := context.Background()
+	sub := usecase.WebhookSubscription{ID: "s1", URL: "https://example.com/hook", Secret: "k", Active: true}
+	pending := usecase.WebhookDelivery{ID: 7, SubscriptionID: "s1", EventID: "e1", EventType: usecase.UserUpdated, Payload: []byte(`{}`), Status: usecase.WebhookDeliveryPending}
+
+	cases := map[string]struct {
+		attempts int
+		status   int
+		err      error
+		check    func(t *testing.T, d repository.WebhookDelivery)
+	}{
+		"success": {status: 200, check: func(t *testing.T, d repository.WebhookDelivery) {
+			assert.Equal(t, "succeeded", d.Status)
+			assert.Equal(t, 200, d.ResponseStatus)
+			assert.Empty(t, d.LastError)
+		}},
+		"retried with backoff": {attempts: 1, status: 503, err: errors.New("subscriber answered 503"), check: func(t *testing.T, d repository.WebhookDelivery) {
+			assert.Equal(t, "pending", d.Status)
+			assert.Equal(t, 2, d.Attempts)
+			assert.Equal(t, webhookNow.Add(2*time.Second), d.NextAttemptAt)
+			assert.Equal(t, "subscriber answered 503", d.LastError)
+		}},
+		"out of attempts": {attempts: 2, err: errors.New("connection refused"), check: func(t *testing.T, d repository.WebhookDelivery) {
+			assert.Equal(t, "failed", d.Status)
+			assert.Equal(t, 3, d.Attempts)
+		}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			repo, sender := &MockRepository{}, &MockWebhookSender{}
+			sender.On("Deliver", ctx, integration.WebhookDeliveryRequestDTO{
+				URL: sub.URL, Secret: "k", EventID: "e1", EventType: "user.updated", Body: []byte(`{}`),
+			}).Return(tc.status, tc.err).Once()
+			var stored repository.WebhookDelivery
+			repo.On("UpdateWebhookDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				stored = args.Get(1).(repository.WebhookDelivery)
+			}).Return(nil).Once()
+
+			d := pending
+			d.Attempts = tc.attempts
+			newTestOutboundWebhooks(repo, sender).attempt(ctx, d, sub, true)
+
+			sender.AssertExpectations(t)
+			assert.Equal(t, uint(7), stored.ID)
+			assert.Equal(t, webhookNow, *stored.LastAttemptAt)
+			tc.check(t, stored)
+		})
+	}
+}
+
+func TestOutboundWebhookAttempt_InactiveSubscriptionFails(t *testing.T) {
+	repo, sender := &MockRepository{}, &MockWebhookSender{}
+	repo.On("UpdateWebhookDelivery", mock.Anything, mock.MatchedBy(func(d repository.WebhookDelivery) bool {
+		return d.Status == "failed" && d.LastError == "subscription inactive"
+	})).Return(nil).Once()
+
+	newTestOutboundWebhooks(repo, sender).attempt(context.Background(),
+		usecase.WebhookDelivery{ID: 1, SubscriptionID: "s1"}, usecase.WebhookSubscription{ID: "s1"}, true)
+	repo.AssertExpectations(t)
+	sender.AssertNotCalled(t, "Deliver", mock.Anything, mock.Anything)
+}
+
+func TestRedeliver_QueuesCopyOfEvent(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	repo.On("GetWebhookDelivery", ctx, uint(7)).Return(repository.WebhookDelivery{
+		ID: 7, SubscriptionID: "s1", EventID: "e1", EventType: "user.created", Payload: []byte(`{"id":"e1"}`), Status: "failed", Attempts: 3,
+	}, nil)
+	repo.On("CreateWebhookDeliveries", ctx, []repository.WebhookDelivery{{
+		SubscriptionID: "s1", EventID: "e1", EventType: "user.created", Payload: []byte(`{"id":"e1"}`),
+		Status: "pending", NextAttemptAt: webhookNow, CreatedAt: webhookNow,
+	}}).Return(nil).Once()
+	o := newTestOutboundWebhooks(repo, nil)
+
+	d, err := o.Redeliver(ctx, "s1", 7)
+	require.NoError(t, err)
+	assert.Equal(t, usecase.WebhookDeliveryPending, d.Status)
+	assert.Zero(t, d.Attempts)
+
+	_, err = o.Redeliver(ctx, "other", 7)
+	assertErrorCode(t, pkg.ErrNotFound, err)
+	repo.AssertExpectations(t)
+}
+
+func TestCreateSubscription_ValidatesAndGeneratesSecret(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	repo.On("CreateWebhookSubscription", ctx, mock.Anything).Return(nil).Once()
+	o := newTestOutboundWebhooks(repo, nil)
+
+	sub, err := o.CreateSubscription(ctx, usecase.WebhookSubscriptionRequestDTO{
+		URL: "https://example.com/hook", EventTypes: []usecase.UserEventType{usecase.UserCreated}, Active: true,
+	})
+	require.NoError(t, err)
+	assert.Len(t, sub.Secret, 64)
+	assert.NotEmpty(t, sub.ID)
+
+	for _, req := range []usecase.WebhookSubscriptionRequestDTO{
+		{URL: "ftp://example.com"},
+		{URL: "/relative"},
+		{URL: "https://example.com", EventTypes: []usecase.UserEventType{"user.exploded"}},
+	} {
+		_, err := o.CreateSubscription(ctx, req)
+		assertErrorCode(t, pkg.ErrBadRequest, err)
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestUserUsecase_EmitsEvents(t *testing.T) {
+	ctx := context.Background()
+	repo, events := &MockRepository{}, &MockUserEventPublisher{}
+	repo.On("CreateUser", ctx, mock.Anything).Return(nil)
+	repo.On("DeleteUser", ctx, "u-9").Return(nil)
+	events.On("PublishUserEvents", mock.Anything, mock.MatchedBy(func(e []usecase.UserEvent) bool {
+		return len(e) == 1 && e[0].Type == usecase.UserCreated && e[0].ID != "" && *e[0].User.FullName == "Ada"
+	})).Return(nil).Once()
+	events.On("PublishUserEvents", mock.Anything, mock.MatchedBy(func(e []usecase.UserEvent) bool {
+		return len(e) == 1 && e[0].Type == usecase.UserDeleted && *e[0].User.ID == "u-9"
+	})).Return(errors.New("database unavailable")).Once()
+	uc := NewUserUsecase(repo, nil, 10, WithUserEvents(events))
+
+	name := user.FullName("Ada")
+	_, err := uc.CreateUser(ctx, usecase.CreateUserRequestDTO{BaseUser: usecase.BaseUser{FullName: &name}})
+	require.NoError(t, err)
+	// deliveries are queued in the transaction of the change, so a failure
+	// to queue them fails the change
+	require.Error(t, uc.DeleteUser(ctx, "u-9"))
+	events.AssertExpectations(t)
+}
+
+type MockUserEventPublisher struct {
+	mock.Mock
+}
+
+func (m *MockUserEventPublisher) PublishUserEvents(ctx context.Context, events []usecase.UserEvent) error {
+	return m.Called(ctx, events).Error(0)
+}
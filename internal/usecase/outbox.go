@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// outboxUsecase relays the user events stored in the outbox to the message
+// bus. A batch is published and marked sent in one transaction holding the
+// relay lock, so only one replica relays at a time. Events are published in id
+// order, keyed by user id. Ordering is guaranteed per user only: an event is
+// stored after the change to its user, whose row lock makes a later change to
+// the same user wait for the commit, so the events of a user get ids in
+// commit order. Events of different users may commit out of id order and are
+// then published in a later batch than events with higher ids. A batch whose
+// transaction fails is published again: delivery is at least once and
+// consumers deduplicate on the event id.
+type outboxUsecase struct {
+	repo interfaces.Repository
+	bus  interfaces.MessageBus
+	conf config.OutboxConfig
+	now  func() time.Time
+}
+
+// NewOutboxUsecase creates a new instance of the outbox usecase. Events are
+// only relayed once Start is called.
+func NewOutboxUsecase(repo interfaces.Repository, bus interfaces.MessageBus, conf config.OutboxConfig) *outboxUsecase {
+	conf.OutboxPollInterval = max(conf.OutboxPollInterval, 1)
+	conf.OutboxBatchSize = max(conf.OutboxBatchSize, 1)
+	conf.OutboxPurgeInterval = max(conf.OutboxPurgeInterval, 1)
+	return &outboxUsecase{repo: repo, bus: bus, conf: conf, now: time.Now}
+}
+
+var _ interfaces.OutboxUsecase = (*outboxUsecase)(nil)
+
+// ListEvents returns the latest outbox events, newest first.
+func (o *outboxUsecase) ListEvents(ctx context.Context, req usecase.ListOutboxEventsRequestDTO) ([]usecase.OutboxEvent, error) {
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	events, err := o.repo.GetOutboxEvents(ctx, repository.GetOutboxEventsRepositoryRequestDTO{Pending: req.Pending, Limit: req.Limit})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]usecase.OutboxEvent, 0, len(events))
+	for _, e := range events {
+		out = append(out, mapper.OutboxEventRepoToUsecase(e))
+	}
+	return out, nil
+}
+
+// GetEvent returns an outbox event; a missing one is a 404 error.
+func (o *outboxUsecase) GetEvent(ctx context.Context, id uint) (usecase.OutboxEvent, error) {
+	e, err := o.repo.GetOutboxEvent(ctx, id)
+	if err != nil {
+		return usecase.OutboxEvent{}, err
+	}
+	return mapper.OutboxEventRepoToUsecase(e), nil
+}
+
+// ReplayEvents marks the selected sent events unsent, so the relay publishes
+// them again, and returns how many were selected.
+func (o *outboxUsecase) ReplayEvents(ctx context.Context, req usecase.ReplayOutboxEventsRequestDTO) (int64, error) {
+	return o.repo.ReplayOutboxEvents(ctx, repository.ReplayOutboxEventsRepositoryRequestDTO{IDs: req.IDs, FromID: req.FromID})
+}
+
+// Start relays stored events every OutboxPollInterval and purges the old sent
+// ones every OutboxPurgeInterval until ctx is done.
+func (o *outboxUsecase) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(o.conf.OutboxPollInterval) * time.Millisecond)
+		defer ticker.Stop()
+		purge := time.NewTicker(time.Duration(o.conf.OutboxPurgeInterval) * time.Millisecond)
+		defer purge.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-purge.C:
+				if err := o.purge(ctx); err != nil {
+					log.WithError(err).Error("outbox: failed to purge sent events")
+				}
+			case <-ticker.C:
+				// a full batch suggests more are waiting
+				for {
+					n, err := o.relay(ctx)
+					if err != nil {
+						log.WithError(err).Error("outbox: failed to relay events")
+					}
+					if err != nil || n < o.conf.OutboxBatchSize || ctx.Err() != nil {
+						break
+					}
+				}
+			}
+		}
+	}()
+}
+
+// relay publishes a batch of unsent events and marks them sent, returning the
+// batch size. Nothing is relayed while another relay holds the lock.
+func (o *outboxUsecase) relay(ctx context.Context) (int, error) {
+	var n int
+	err := o.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		events, err := o.repo.LockOutboxEvents(ctx, o.conf.OutboxBatchSize)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		msgs := make([]bus.Message, 0, len(events))
+		ids := make([]uint, 0, len(events))
+		for _, e := range events {
+			headers := map[string]string{bus.HeaderEventID: e.EventID, bus.HeaderEventType: e.EventType}
+			if e.RequestID != "" {
+				// the request that made the change, not the relay
+				headers[pkg.RequestIDHeader] = e.RequestID
+			}
+			msgs = append(msgs, bus.Message{
+				Topic:   o.conf.OutboxTopic,
+				Key:     []byte(e.UserID),
+				Value:   e.Payload,
+				Headers: headers,
+			})
+			ids = append(ids, e.ID)
+		}
+		if err := o.bus.Publish(ctx, msgs...); err != nil {
+			pkg.CounterAdd("outbox_relay_failures_total", 1)
+			return err
+		}
+		if err := o.repo.MarkOutboxEventsSent(ctx, ids, o.now().UTC()); err != nil {
+			return err
+		}
+		n = len(events)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	pkg.CounterAdd("outbox_events_published_total", float64(n))
+	return n, nil
+}
+
+// purge deletes the events sent longer than OutboxRetentionHours ago.
+func (o *outboxUsecase) purge(ctx context.Context) error {
+	if o.conf.OutboxRetentionHours <= 0 {
+		return nil
+	}
+	before := o.now().UTC().Add(-time.Duration(o.conf.OutboxRetentionHours) * time.Hour)
+	n, err := o.repo.PurgeOutboxEvents(ctx, before)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.WithField("deleted", n).Info("purged sent outbox events")
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/bus"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBus records the messages published to it.
+type fakeBus struct {
+	msgs []bus.Message
+	err  error
+}
+
+func (b *fakeBus) Publish(_ context.Context, msgs ...bus.Message) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.msgs = append(b.msgs, msgs...)
+	return nil
+}
+
+func (b *fakeBus) Subscribe(context.Context, string, string, interfaces.MessageHandler) error {
+	return nil
+}
+
+func (b *fakeBus) Close() error { return nil }
+
+var outboxNow = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestOutbox(repo *MockRepository, b *fakeBus) *outboxUsecase {
+	o := NewOutboxUsecase(repo, b, config.OutboxConfig{OutboxTopic: "user-events", OutboxBatchSize: 10, OutboxRetentionHours: 24})
+	o.now = func() time.Time { return outboxNow }
+	return o
+}
+
+func TestUserUsecase_RecordsEventsInOutbox(t *testing.T) {
+	ctx := context.Background()
+	repo, events := &MockRepository{}, &MockUserEventPublisher{}
+	repo.On("CreateUser", ctx, mock.Anything).Return(nil)
+	var stored []repository.OutboxEvent
+	repo.On("CreateOutboxEvents", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).([]repository.OutboxEvent)
+	}).Return(nil).Once()
+	var published []usecase.UserEvent
+	events.On("PublishUserEvents", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = args.Get(1).([]usecase.UserEvent)
+	}).Return(nil).Once()
+	uc := NewUserUsecase(repo, nil, 10, WithUserEvents(events), WithOutbox())
+
+	name := user.FullName("Ada")
+	res, err := uc.CreateUser(ctx, usecase.CreateUserRequestDTO{BaseUser: usecase.BaseUser{FullName: &name}})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "user.created", stored[0].EventType)
+	assert.Equal(t, string(*res[0].ID), stored[0].UserID)
+	require.Len(t, published, 1)
+	assert.Equal(t, published[0].ID, stored[0].EventID, "webhooks and the bus share the event id")
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(stored[0].Payload, &payload))
+	assert.Equal(t, stored[0].EventID, payload["id"])
+	assert.Equal(t, "user.created", payload["type"])
+}
+
+func TestUserUsecase_OutboxFailureFailsTheChange(t *testing.T) {
+	ctx := context.Background()
+	repo, events := &MockRepository{}, &MockUserEventPublisher{}
+	repo.On("DeleteUser", ctx, "u-9").Return(nil)
+	repo.On("CreateOutboxEvents", ctx, mock.Anything).Return(errors.New("database unavailable"))
+	uc := NewUserUsecase(repo, nil, 10, WithUserEvents(events), WithOutbox())
+
+	require.Error(t, uc.DeleteUser(ctx, "u-9"))
+	events.AssertNotCalled(t, "PublishUserEvents", mock.Anything, mock.Anything)
+}
+
+func TestOutboxRelay_PublishesInOrderAndMarksSent(t *testing.T) {
+	ctx := context.Background()
+	repo, b := &MockRepository{}, &fakeBus{}
+	repo.On("LockOutboxEvents", ctx, 10).Return([]repository.OutboxEvent{
+		{ID: 4, EventID: "e4", EventType: "user.created", UserID: "u-1", Payload: []byte(`{"id":"e4"}`)},
+		{ID: 5, EventID: "e5", EventType: "user.deleted", UserID: "u-1", Payload: []byte(`{"id":"e5"}`)},
+	}, nil).Once()
+	repo.On("MarkOutboxEventsSent", ctx, []uint{4, 5}, outboxNow).Return(nil).Once()
+
+	n, err := newTestOutbox(repo, b).relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, b.msgs, 2)
+	assert.Equal(t, bus.Message{
+		Topic:   "user-events",
+		Key:     []byte("u-1"),
+		Value:   []byte(`{"id":"e4"}`),
+		Headers: map[string]string{bus.HeaderEventID: "e4", bus.HeaderEventType: "user.created"},
+	}, b.msgs[0])
+	assert.Equal(t, "e5", b.msgs[1].Headers[bus.HeaderEventID])
+	repo.AssertExpectations(t)
+}
+
+func TestOutboxRelay_CarriesTheRequestIDOfTheChange(t *testing.T) {
+	repo, b := &MockRepository{}, &fakeBus{}
+	var stored []repository.OutboxEvent
+	repo.On("DeleteUser", mock.Anything, "u-9").Return(nil)
+	repo.On("CreateOutboxEvents", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).([]repository.OutboxEvent)
+	}).Return(nil).Once()
+	uc := NewUserUsecase(repo, nil, 10, WithOutbox())
+	require.NoError(t, uc.DeleteUser(pkg.WithRequestID(context.Background(), "req-1"), "u-9"))
+	require.Len(t, stored, 1)
+	assert.Equal(t, "req-1", stored[0].RequestID)
+
+	// the relay runs outside any request
+	ctx := context.Background()
+	stored[0].ID = 1
+	repo.On("LockOutboxEvents", ctx, 10).Return(stored, nil).Once()
+	repo.On("MarkOutboxEventsSent", ctx, []uint{1}, outboxNow).Return(nil).Once()
+	_, err := newTestOutbox(repo, b).relay(ctx)
+	require.NoError(t, err)
+	require.Len(t, b.msgs, 1)
+	assert.Equal(t, "req-1", b.msgs[0].Headers[pkg.RequestIDHeader])
+}
+
+func TestOutboxRelay_KeepsTheOrderOfEachUser(t *testing.T) {
+	ctx := context.Background()
+	repo, b := &MockRepository{}, &fakeBus{}
+	repo.On("LockOutboxEvents", ctx, 10).Return([]repository.OutboxEvent{
+		{ID: 1, EventID: "a1", UserID: "u-a"},
+		{ID: 2, EventID: "b1", UserID: "u-b"},
+		{ID: 3, EventID: "a2", UserID: "u-a"},
+		{ID: 4, EventID: "b2", UserID: "u-b"},
+		{ID: 5, EventID: "a3", UserID: "u-a"},
+	}, nil).Once()
+	repo.On("MarkOutboxEventsSent", ctx, []uint{1, 2, 3, 4, 5}, outboxNow).Return(nil).Once()
+
+	_, err := newTestOutbox(repo, b).relay(ctx)
+	require.NoError(t, err)
+	byUser := map[string][]string{}
+	for _, m := range b.msgs {
+		byUser[string(m.Key)] = append(byUser[string(m.Key)], m.Headers[bus.HeaderEventID])
+	}
+	assert.Equal(t, map[string][]string{"u-a": {"a1", "a2", "a3"}, "u-b": {"b1", "b2"}}, byUser)
+}
+
+func TestOutboxRelay_KeepsEventsWhenPublishFails(t *testing.T) {
+	ctx := context.Background()
+	repo, b := &MockRepository{}, &fakeBus{err: errors.New("broker unavailable")}
+	repo.On("LockOutboxEvents", ctx, 10).Return([]repository.OutboxEvent{{ID: 4, EventID: "e4"}}, nil).Once()
+
+	n, err := newTestOutbox(repo, b).relay(ctx)
+	require.Error(t, err)
+	assert.Zero(t, n)
+	repo.AssertNotCalled(t, "MarkOutboxEventsSent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOutboxRelay_PurgesPastRetention(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockRepository{}
+	repo.On("PurgeOutboxEvents", ctx, outboxNow.Add(-24*time.Hour)).Return(3, nil).Once()
+
+	require.NoError(t, newTestOutbox(repo, nil).purge(ctx))
+	repo.AssertExpectations(t)
+
+	o := newTestOutbox(repo, nil)
+	o.conf.OutboxRetentionHours = 0
+	require.NoError(t, o.purge(ctx))
+	repo.AssertNumberOfCalls(t, "PurgeOutboxEvents", 1)
+}
@@ -0,0 +1,249 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"__MODULE__/internal/client/pagination"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	entity "__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// syncPrefetch is how many provider pages are fetched ahead during a sync.
+const syncPrefetch = 2
+
+// providerSyncUsecase mirrors provider instances into the users table.
+type providerSyncUsecase struct {
+	repo      interfaces.Repository
+	users     userWriter
+	providers interfaces.ProviderService
+	archive   rawArchive
+
+	// running holds the ids of providers with a sync in progress
+	running sync.Map
+}
+
+// NewProviderSyncUsecase creates a new instance of the provider sync usecase.
+// The user changes a sync makes are published and stored in the outbox as set
+// by opts.
+func NewProviderSyncUsecase(repo interfaces.Repository, providers interfaces.ProviderService, opts ...Option) *providerSyncUsecase {
+	o := applyOptions(opts)
+	return &providerSyncUsecase{
+		repo:      repo,
+		users:     newUserWriter(repo, o),
+		providers: providers,
+		archive:   rawArchive{repo: repo, conf: o.archive},
+	}
+}
+
+var _ interfaces.ProviderSyncUsecase = (*providerSyncUsecase)(nil)
+
+// SyncProviders synchronises every active provider instance one after another.
+// A failing provider does not stop the others; all errors are returned joined.
+func (s *providerSyncUsecase) SyncProviders(ctx context.Context) error {
+	var errs []error
+	for _, p := range s.providers.ListProviders() {
+		if p.Status != integration.ProviderStatusActive {
+			continue
+		}
+		if _, err := s.SyncProvider(ctx, p.ID); err != nil {
+			errs = append(errs, fmt.Errorf("sync provider %s: %w", p.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SyncProvider walks all pages of the provider and diffs them against the
+// users previously pulled from it:
+//   - users missing from the database are created,
+//   - users whose fields changed, or that were inactive, are updated,
+//   - users no longer returned by the provider are deactivated.
+//
+// Each change is written with its user.created, user.updated or
+// user.deactivated event.
+// Deactivation only happens when the provider was walked completely. The run
+// summary is stored even when the sync fails.
+func (s *providerSyncUsecase) SyncProvider(ctx context.Context, provider string) (res usecase.SyncRunSummary, err error) {
+	svc, err := s.providers.GetUserService(provider)
+	if err != nil {
+		return res, err
+	}
+	if _, busy := s.running.LoadOrStore(provider, struct{}{}); busy {
+		return res, pkg.NewAppError(pkg.ErrSyncInProgress).AddDescription([]byte(provider)).AppendStackLog()
+	}
+	defer s.running.Delete(provider)
+
+	res = usecase.SyncRunSummary{Provider: provider, Status: usecase.SyncStatusSucceeded, StartedAt: time.Now().UTC()}
+	defer func() {
+		res.FinishedAt = time.Now().UTC()
+		s.record(ctx, &res)
+	}()
+
+	existing, err := s.repo.GetUsersByProvider(ctx, provider)
+	if err != nil {
+		res.Status, res.Error = usecase.SyncStatusFailed, err.Error()
+		return res, err
+	}
+	// users are matched on their id at the provider, not the internal id
+	known := make(map[string]repository.BaseUser, len(existing))
+	for _, u := range existing {
+		if u.ExternalID != nil {
+			known[string(*u.ExternalID)] = u
+		}
+	}
+
+	// the users of the pages fetched before a failure are still applied
+	var users []integration.UserDTO
+	var fetchErr error
+	page := 0
+	for p, err := range pagination.Pages(ctx, svc, pagination.Options{Prefetch: syncPrefetch}) {
+		if err != nil {
+			fetchErr = err
+			break
+		}
+		page++
+		s.archive.store(ctx, page, p)
+		users = append(users, p.Users...)
+	}
+
+	seen := make(map[string]struct{}, len(users))
+	for _, cu := range users {
+		UserIntegrationValidate(&cu)
+		incoming := mapper.UserUsecaseToRepo(mapper.UserIntegrationToUsecase(cu))
+		if incoming.ExternalID == nil {
+			res.Failed++
+			continue
+		}
+		id := string(*incoming.ExternalID)
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		incoming.Provider = &provider
+		// users the provider reports as disabled are kept, but inactive
+		incoming.IsActive = pkg.PtrBool(incoming.IsActive == nil || *incoming.IsActive)
+
+		current, ok := known[id]
+		switch {
+		case !ok:
+			internalID := entity.ID(uuid.New().String())
+			incoming.ID = &internalID
+			err = s.users.createProviderUser(ctx, incoming)
+			s.count(&res, &res.Created, err, id)
+		case !*incoming.IsActive:
+			if current.IsActive == nil || *current.IsActive {
+				err = s.users.deactivateProviderUser(ctx, current)
+				s.count(&res, &res.Deactivated, err, id)
+			}
+		case userChanged(current, incoming):
+			incoming.ID = current.ID
+			err = s.users.updateProviderUser(ctx, incoming)
+			s.count(&res, &res.Updated, err, id)
+		}
+	}
+
+	if fetchErr != nil {
+		res.Status, res.Error = usecase.SyncStatusFailed, fetchErr.Error()
+		return res, fetchErr
+	}
+
+	for id, current := range known {
+		if _, ok := seen[id]; ok || (current.IsActive != nil && !*current.IsActive) {
+			continue
+		}
+		err = s.users.deactivateProviderUser(ctx, current)
+		s.count(&res, &res.Deactivated, err, id)
+	}
+
+	return res, nil
+}
+
+// PurgeRawResponses deletes archived provider responses past their retention.
+func (s *providerSyncUsecase) PurgeRawResponses(ctx context.Context) error {
+	n, err := s.archive.purge(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.WithField("deleted", n).Info("purged archived provider responses")
+	}
+	return nil
+}
+
+// GetSyncRuns returns the latest stored runs, newest first.
+func (s *providerSyncUsecase) GetSyncRuns(ctx context.Context, provider string, limit int) ([]usecase.SyncRunSummary, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	runs, err := s.repo.GetSyncRuns(ctx, repository.GetSyncRunsRepositoryRequestDTO{Provider: provider, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]usecase.SyncRunSummary, 0, len(runs))
+	for _, r := range runs {
+		out = append(out, mapper.SyncRunRepoToUsecase(r))
+	}
+	return out, nil
+}
+
+// count increments counter on success, or the failed counter otherwise.
+func (s *providerSyncUsecase) count(res *usecase.SyncRunSummary, counter *int, err error, id string) {
+	if err != nil {
+		res.Failed++
+		log.WithError(err).WithFields(log.Fields{"provider": res.Provider, "user_id": id}).Warn("provider sync: failed to write user")
+		return
+	}
+	*counter++
+}
+
+// record stores the run and exports it as metrics. The run is stored even when
+// ctx was cancelled, so aborted runs still show up in the history.
+func (s *providerSyncUsecase) record(ctx context.Context, res *usecase.SyncRunSummary) {
+	for action, n := range map[string]int{"created": res.Created, "updated": res.Updated, "deactivated": res.Deactivated, "failed": res.Failed} {
+		pkg.CounterAdd("provider_sync_users_total", float64(n), "provider", res.Provider, "action", action)
+	}
+	pkg.CounterAdd("provider_sync_runs_total", 1, "provider", res.Provider, "status", string(res.Status))
+	pkg.Observe("provider_sync_duration_seconds", res.FinishedAt.Sub(res.StartedAt).Seconds(), "provider", res.Provider)
+
+	run := repository.CreateSyncRunRepositoryRequestDTO{SyncRun: mapper.SyncRunUsecaseToRepo(*res)}
+	id, err := s.repo.CreateSyncRun(context.WithoutCancel(ctx), run)
+	if err != nil {
+		log.WithError(err).WithField("provider", res.Provider).Error("provider sync: failed to store run")
+		return
+	}
+	res.ID = id
+}
+
+// userChanged reports whether incoming differs from the stored user in any
+// field the provider owns, active state included.
+func userChanged(current, incoming repository.BaseUser) bool {
+	return !equalPtr(current.IsActive, incoming.IsActive) ||
+		!equalPtr(current.FullName, incoming.FullName) ||
+		!equalPtr(current.Username, incoming.Username) ||
+		!equalPtr(current.Email, incoming.Email) ||
+		!equalPtr(current.Avatar, incoming.Avatar) ||
+		!equalPtr(current.Phone, incoming.Phone) ||
+		!equalPtr(current.Website, incoming.Website) ||
+		!equalPtr(current.Company, incoming.Company) ||
+		!equalPtr(current.City, incoming.City) ||
+		!maps.Equal(current.Attributes, incoming.Attributes)
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,290 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// MockProviderService resolves every id to the same user client.
+type MockProviderService struct {
+	mock.Mock
+	client interfaces.UserService
+}
+
+func (m *MockProviderService) SupportedProviders() []string { return nil }
+
+func (m *MockProviderService) ListProviders() []integration.ProviderInstanceDTO {
+	args := m.Called()
+	return args.Get(0).([]integration.ProviderInstanceDTO)
+}
+
+func (m *MockProviderService) RegisterNewProvider(id, providerName, providerConfig string) error {
+	return nil
+}
+
+func (m *MockProviderService) ReplaceProvider(id, providerName, providerConfig string) error {
+	return nil
+}
+
+func (m *MockProviderService) GetUserService(id string) (interfaces.UserService, error) {
+	args := m.Called(id)
+	return m.client, args.Error(0)
+}
+
+func (m *MockProviderService) StopUserService(id string) error { return nil }
+
+func (m *MockProviderService) GetWebhookReceiver(id string) (interfaces.WebhookReceiver, error) {
+	args := m.Called(id)
+	receiver, _ := args.Get(0).(interfaces.WebhookReceiver)
+	return receiver, args.Error(1)
+}
+
+type ProviderSyncSuite struct {
+	suite.Suite
+	repo      *MockRepository
+	client    *MockUserClient
+	providers *MockProviderService
+	uc        *providerSyncUsecase
+}
+
+func (s *ProviderSyncSuite) SetupTest() {
+	s.repo = &MockRepository{}
+	s.client = &MockUserClient{}
+	s.providers = &MockProviderService{client: s.client}
+	s.uc = NewProviderSyncUsecase(s.repo, s.providers)
+}
+
+func TestProviderSyncSuite(t *testing.T) {
+	suite.Run(t, new(ProviderSyncSuite))
+}
+
+// storedUser returns a user of provider p1 whose internal id is "int-"+externalID.
+func storedUser(externalID, name string, active bool) repository.BaseUser {
+	uid, ext, full, provider := user.ID("int-"+externalID), user.ExternalID(externalID), user.FullName(name), "p1"
+	return repository.BaseUser{ID: &uid, ExternalID: &ext, FullName: &full, Provider: &provider, IsActive: &active}
+}
+
+func (s *ProviderSyncSuite) Test_SyncProvider_CreatesUpdatesAndDeactivates() {
+	s.providers.On("GetUserService", "p1").Return(nil)
+	s.repo.On("GetUsersByProvider", mock.Anything, "p1").Return([]repository.BaseUser{
+		storedUser("1", "Same", true),
+		storedUser("2", "Old Name", true),
+		storedUser("3", "Gone", true),
+		storedUser("4", "Already Gone", false),
+	}, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{
+		Users: []integration.UserDTO{
+			{ID: "1", Name: "Same"},
+			{ID: "2", Name: "New Name"},
+			{ID: "5", Name: "Fresh"},
+		},
+		Meta: integration.MetaInfoDTO{Page: 1, TotalPages: 1},
+	}, nil)
+
+	s.repo.On("CreateUser", mock.Anything, mock.MatchedBy(func(p repository.CreateUserRepositoryRequestDTO) bool {
+		return *p.ExternalID == "5" && *p.Provider == "p1" && *p.IsActive && p.ID != nil && *p.ID != "5"
+	})).Return(nil).Once()
+	s.repo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(p repository.UpdateUserRepositoryRequestDTO) bool {
+		return *p.ID == "int-2" && *p.FullName == "New Name" && *p.IsActive
+	})).Return(nil).Once()
+	s.repo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(p repository.UpdateUserRepositoryRequestDTO) bool {
+		return *p.ID == "int-3" && !*p.IsActive
+	})).Return(nil).Once()
+	s.repo.On("CreateSyncRun", mock.Anything, mock.Anything).Return(7, nil).Once()
+
+	res, err := s.uc.SyncProvider(context.Background(), "p1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), usecase.SyncStatusSucceeded, res.Status)
+	assert.Equal(s.T(), uint(7), res.ID)
+	assert.Equal(s.T(), 1, res.Created)
+	assert.Equal(s.T(), 1, res.Updated)
+	assert.Equal(s.T(), 1, res.Deactivated)
+	assert.Equal(s.T(), 0, res.Failed)
+	s.repo.AssertExpectations(s.T())
+}
+
+func (s *ProviderSyncSuite) Test_SyncProvider_EmitsUserEvents() {
+	events := &MockUserEventPublisher{}
+	s.uc = NewProviderSyncUsecase(s.repo, s.providers, WithUserEvents(events), WithOutbox())
+	s.providers.On("GetUserService", "p1").Return(nil)
+	s.repo.On("GetUsersByProvider", mock.Anything, "p1").Return([]repository.BaseUser{
+		storedUser("1", "Same", true),
+		storedUser("2", "Old Name", true),
+		storedUser("3", "Gone", true),
+	}, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{
+		Users: []integration.UserDTO{{ID: "1", Name: "Same"}, {ID: "2", Name: "New Name"}, {ID: "5", Name: "Fresh"}, {ID: "6", Name: "Broken"}},
+		Meta:  integration.MetaInfoDTO{Page: 1, TotalPages: 1},
+	}, nil)
+	s.repo.On("CreateUser", mock.Anything, mock.MatchedBy(func(p repository.CreateUserRepositoryRequestDTO) bool {
+		return *p.ExternalID == "6"
+	})).Return(errors.New("constraint violated")).Once()
+	s.repo.On("CreateUser", mock.Anything, mock.Anything).Return(nil).Once()
+	s.repo.On("UpdateUser", mock.Anything, mock.Anything).Return(nil).Twice()
+	s.repo.On("CreateSyncRun", mock.Anything, mock.Anything).Return(1, nil).Once()
+
+	stored := map[string]usecase.UserEventType{}
+	s.repo.On("CreateOutboxEvents", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		for _, e := range args.Get(1).([]repository.OutboxEvent) {
+			stored[e.UserID] = usecase.UserEventType(e.EventType)
+		}
+	}).Return(nil)
+	published := map[string]usecase.UserEvent{}
+	events.On("PublishUserEvents", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		for _, e := range args.Get(1).([]usecase.UserEvent) {
+			published[string(*e.User.ExternalID)] = e
+		}
+	}).Return(nil)
+
+	res, err := s.uc.SyncProvider(context.Background(), "p1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, res.Failed)
+
+	assert.Equal(s.T(), usecase.UserUpdated, stored["int-2"])
+	assert.Equal(s.T(), usecase.UserDeactivated, stored["int-3"])
+	assert.Len(s.T(), stored, 3, "one outbox row per change, none for unchanged or failed users")
+	require.Len(s.T(), published, 3)
+	assert.Equal(s.T(), usecase.UserCreated, published["5"].Type)
+	assert.Equal(s.T(), usecase.UserUpdated, published["2"].Type)
+	assert.Equal(s.T(), "New Name", string(*published["2"].User.FullName))
+	assert.Equal(s.T(), usecase.UserDeactivated, published["3"].Type)
+	assert.False(s.T(), *published["3"].User.Active)
+}
+
+func (s *ProviderSyncSuite) Test_SyncProvider_FetchFailureSkipsDeactivation() {
+	s.providers.On("GetUserService", "p1").Return(nil)
+	s.repo.On("GetUsersByProvider", mock.Anything, "p1").Return([]repository.BaseUser{storedUser("3", "Gone", true)}, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{}, errors.New("provider down"))
+	s.repo.On("CreateSyncRun", mock.Anything, mock.MatchedBy(func(p repository.CreateSyncRunRepositoryRequestDTO) bool {
+		return p.Status == string(usecase.SyncStatusFailed) && p.Error == "provider down"
+	})).Return(1, nil).Once()
+
+	res, err := s.uc.SyncProvider(context.Background(), "p1")
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), usecase.SyncStatusFailed, res.Status)
+	assert.Zero(s.T(), res.Deactivated)
+	s.repo.AssertNotCalled(s.T(), "UpdateUser", mock.Anything, mock.Anything)
+	s.repo.AssertExpectations(s.T())
+}
+
+func (s *ProviderSyncSuite) Test_SyncProvider_CountsWriteFailures() {
+	s.providers.On("GetUserService", "p1").Return(nil)
+	s.repo.On("GetUsersByProvider", mock.Anything, "p1").Return([]repository.BaseUser{}, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{
+		Users: []integration.UserDTO{{ID: "1"}, {ID: "2"}},
+	}, nil)
+	s.repo.On("CreateUser", mock.Anything, mock.Anything).Return(errors.New("insert failed")).Once()
+	s.repo.On("CreateUser", mock.Anything, mock.Anything).Return(nil).Once()
+	s.repo.On("CreateSyncRun", mock.Anything, mock.Anything).Return(1, nil)
+
+	res, err := s.uc.SyncProvider(context.Background(), "p1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, res.Created)
+	assert.Equal(s.T(), 1, res.Failed)
+}
+
+func (s *ProviderSyncSuite) Test_SyncProvider_RejectsConcurrentRun() {
+	s.providers.On("GetUserService", "p1").Return(nil)
+	s.uc.running.Store("p1", struct{}{})
+
+	_, err := s.uc.SyncProvider(context.Background(), "p1")
+	var appErr *pkg.AppError
+	require.ErrorAs(s.T(), err, &appErr)
+	assert.Equal(s.T(), 409, appErr.ExternalCode())
+}
+
+func (s *ProviderSyncSuite) Test_SyncProviders_SkipsStoppedAndJoinsErrors() {
+	s.providers.On("ListProviders").Return([]integration.ProviderInstanceDTO{
+		{ID: "p1", Status: integration.ProviderStatusActive},
+		{ID: "p2", Status: integration.ProviderStatusStopped},
+		{ID: "p3", Status: integration.ProviderStatusActive},
+	})
+	s.providers.On("GetUserService", "p1").Return(nil)
+	s.providers.On("GetUserService", "p3").Return(errors.New("gone"))
+	s.repo.On("GetUsersByProvider", mock.Anything, "p1").Return([]repository.BaseUser{}, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{}, nil)
+	s.repo.On("CreateSyncRun", mock.Anything, mock.Anything).Return(1, nil)
+
+	err := s.uc.SyncProviders(context.Background())
+	require.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "p3")
+	s.providers.AssertNotCalled(s.T(), "GetUserService", "p2")
+}
+
+func (s *ProviderSyncSuite) Test_PurgeRawResponses_UsesRetention() {
+	s.uc = NewProviderSyncUsecase(s.repo, s.providers, WithRawArchive(config.RawArchiveConfig{RawArchiveRetentionDays: 7}))
+	s.repo.On("PurgeRawResponses", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		age := time.Since(before)
+		return age > 7*24*time.Hour-time.Minute && age < 7*24*time.Hour+time.Minute
+	})).Return(3, nil).Once()
+
+	require.NoError(s.T(), s.uc.PurgeRawResponses(context.Background()))
+	s.repo.AssertExpectations(s.T())
+}
+
+func (s *ProviderSyncSuite) Test_PurgeRawResponses_ZeroRetentionKeepsAll() {
+	require.NoError(s.T(), s.uc.PurgeRawResponses(context.Background()))
+	s.repo.AssertNotCalled(s.T(), "PurgeRawResponses", mock.Anything, mock.Anything)
+}
+
+func (s *ProviderSyncSuite) Test_SyncProvider_UpdatesOnAttributeChange() {
+	s.providers.On("GetUserService", "p1").Return(nil)
+	current := storedUser("1", "Same", true)
+	current.Attributes = repository.Attributes{"catch_phrase": "old"}
+	s.repo.On("GetUsersByProvider", mock.Anything, "p1").Return([]repository.BaseUser{current}, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{
+		Users: []integration.UserDTO{{ID: "1", Name: "Same", Extra: map[string]string{"catch_phrase": "new"}}},
+	}, nil)
+	s.repo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(p repository.UpdateUserRepositoryRequestDTO) bool {
+		return p.Attributes["catch_phrase"] == "new"
+	})).Return(nil).Once()
+	s.repo.On("CreateSyncRun", mock.Anything, mock.Anything).Return(1, nil)
+
+	res, err := s.uc.SyncProvider(context.Background(), "p1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, res.Updated)
+}
+
+func (s *ProviderSyncSuite) Test_SyncProvider_DeactivatesUsersReportedInactive() {
+	s.providers.On("GetUserService", "p1").Return(nil)
+	s.repo.On("GetUsersByProvider", mock.Anything, "p1").Return([]repository.BaseUser{
+		storedUser("1", "Disabled", true),
+		storedUser("2", "Still Disabled", false),
+	}, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{
+		Users: []integration.UserDTO{
+			{ID: "1", Name: "Disabled", Active: pkg.PtrBool(false)},
+			{ID: "2", Name: "Still Disabled", Active: pkg.PtrBool(false)},
+			{ID: "3", Name: "Never Enabled", Active: pkg.PtrBool(false)},
+		},
+	}, nil)
+	s.repo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(p repository.UpdateUserRepositoryRequestDTO) bool {
+		return *p.ID == "int-1" && !*p.IsActive
+	})).Return(nil).Once()
+	s.repo.On("CreateUser", mock.Anything, mock.MatchedBy(func(p repository.CreateUserRepositoryRequestDTO) bool {
+		return *p.ExternalID == "3" && !*p.IsActive
+	})).Return(nil).Once()
+	s.repo.On("CreateSyncRun", mock.Anything, mock.Anything).Return(1, nil)
+
+	res, err := s.uc.SyncProvider(context.Background(), "p1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, res.Created)
+	assert.Equal(s.T(), 0, res.Updated)
+	assert.Equal(s.T(), 1, res.Deactivated)
+	s.repo.AssertExpectations(s.T())
+}
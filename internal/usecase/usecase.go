@@ -1,25 +1,87 @@
 package usecase
 
 import (
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/repository"
 	"__MODULE__/internal/interfaces"
 )
 
+// Option configures optional behaviour of the usecases.
+type Option func(*options)
+
+type options struct {
+	archive config.RawArchiveConfig
+	upsert  config.UserUpsertConfig
+	events  interfaces.UserEventPublisher
+	outbox  bool
+}
+
+// WithRawArchive archives the raw provider responses the usecase fetches.
+func WithRawArchive(conf config.RawArchiveConfig) Option {
+	return func(o *options) { o.archive = conf }
+}
+
+// WithUpsertPolicy sets the batch size and column policies used when provider
+// users are written to the database.
+func WithUpsertPolicy(conf config.UserUpsertConfig) Option {
+	return func(o *options) { o.upsert = conf }
+}
+
+// WithUserEvents publishes the changes the usecase makes to stored users, in
+// the transaction of each change.
+func WithUserEvents(p interfaces.UserEventPublisher) Option {
+	return func(o *options) { o.events = p }
+}
+
+// WithOutbox stores the changes the usecase makes to stored users in the
+// outbox, in the transaction of each change, for the outbox relay to publish.
+func WithOutbox() Option {
+	return func(o *options) { o.outbox = true }
+}
+
+func applyOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 type userUsecase struct {
-	repo   interfaces.Repository  // persistence
-	client interfaces.UserService // external user provider client
-	limit  int                    // default page size
+	userWriter                        // stores user changes with their events
+	repo       interfaces.Repository  // persistence
+	client     interfaces.UserService // external user provider client
+	limit      int                    // default page size
+	archive    rawArchive             // raw provider response archive
+	upsert     config.UserUpsertConfig
 }
 
 // NewUserUsecase creates a new instance of user usecase.
-func NewUserUsecase(repo interfaces.Repository, client interfaces.UserService, defaultLimit int) userUsecase {
+func NewUserUsecase(repo interfaces.Repository, client interfaces.UserService, defaultLimit int, opts ...Option) userUsecase {
 	if defaultLimit <= 0 {
 		defaultLimit = 50
 	}
+	o := applyOptions(opts)
 	return userUsecase{
-		repo:   repo,
-		client: client,
-		limit:  defaultLimit,
+		userWriter: newUserWriter(repo, o),
+		repo:       repo,
+		client:     client,
+		limit:      defaultLimit,
+		archive:    rawArchive{repo: repo, conf: o.archive},
+		upsert:     o.upsert,
 	}
 }
 
 var _ interfaces.UserUsecase = (*userUsecase)(nil)
+
+// upsertPolicy converts the configured column policies for the repository.
+func upsertPolicy(conf config.UserUpsertConfig) repository.UpsertPolicy {
+	policy := repository.UpsertPolicy{
+		Default: repository.ColumnPolicy(conf.UpsertDefaultPolicy),
+		Columns: make(map[string]repository.ColumnPolicy, len(conf.UpsertColumnPolicies)),
+	}
+	for column, p := range conf.UpsertColumnPolicies {
+		policy.Columns[column] = repository.ColumnPolicy(p)
+	}
+	return policy
+}
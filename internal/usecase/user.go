@@ -5,16 +5,19 @@ import (
 	"__MODULE__/internal/dto/repository"
 	"__MODULE__/internal/dto/usecase"
 	entity "__MODULE__/internal/entity/user"
+	"__MODULE__/pkg"
+	"cmp"
 	"context"
 	"fmt"
 
 	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 )
 
 // GetUsers implements the flow:
-// 1) query DB
+// 1) query DB (restricted to the provider selected in ctx, if any)
 // 2) if DB has rows -> map to usecase.BaseUser and return
-// 3) otherwise call client, persist each user, and return mapped usecase.BaseUser list
+// 3) otherwise call client, persist each user under a new internal id, and return mapped usecase.BaseUser list
 func (u *userUsecase) GetUsers(ctx context.Context, page int) (res []usecase.BaseUser, err error) {
 	if page <= 0 {
 		page = 1
@@ -26,6 +29,9 @@ func (u *userUsecase) GetUsers(ctx context.Context, page int) (res []usecase.Bas
 			Page:  page,
 		},
 	}
+	if provider, ok := pkg.ProviderFromContext(ctx); ok {
+		listReq.Filter.Provider = &provider
+	}
 
 	// 1) query DB
 	dbRes, err := u.repo.GetUsersList(ctx, listReq)
@@ -47,23 +53,60 @@ func (u *userUsecase) GetUsers(ctx context.Context, page int) (res []usecase.Bas
 	if err != nil {
 		return
 	}
+	u.archive.store(ctx, page, clientResp)
+
+	// users are keyed by the instance that served them; unwrapped clients
+	// only report their type
+	provider := cmp.Or(clientResp.Instance, clientResp.Provider)
 
+	// 3) upsert in one transaction, so a page fetched twice updates the
+	// cached users instead of failing on the unique index
+	users := make([]repository.BaseUser, 0, len(clientResp.Users))
 	for _, cu := range clientResp.Users {
 		UserIntegrationValidate(&cu)
 		uc := mapper.UserIntegrationToUsecase(cu)
-		ur := mapper.UserUsecaseToRepo(uc)
-		r := repository.CreateUserRepositoryRequestDTO{
-			BaseUser: ur,
-		}
-		if err := u.repo.CreateUser(ctx, r); err != nil {
-			return nil, err
+		if uc.ExternalID == nil {
+			log.WithField("provider", provider).Warn("skipping provider user without id")
+			continue
 		}
+		id := entity.ID(uuid.New().String())
+		uc.ID, uc.Provider = &id, &provider
+
+		ur := mapper.UserUsecaseToRepo(uc)
+		ur.IsActive = pkg.PtrBool(ur.IsActive == nil || *ur.IsActive)
+		users = append(users, ur)
 	}
 
-	// 4) map client response to usecase.BaseUser list and return
-	out := make([]usecase.BaseUser, 0, len(clientResp.Users))
-	for _, cu := range clientResp.Users {
-		out = append(out, mapIntegrationToUsecaseBaseUser(cu))
+	if len(users) == 0 {
+		return []usecase.BaseUser{}, nil
+	}
+
+	var out []usecase.BaseUser
+	err = u.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		stored, err := u.repo.UpsertUsers(ctx, repository.UpsertUsersRepositoryRequestDTO{
+			Users:     users,
+			Policy:    upsertPolicy(u.upsert),
+			BatchSize: u.upsert.UpsertBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 4) map stored users, which carry the ids of already cached rows;
+		// only those the page inserted or changed are announced
+		out = make([]usecase.BaseUser, 0, len(stored))
+		var changed []usecase.BaseUser
+		for _, su := range stored {
+			bu := mapper.UserRepoToUsecase(su.BaseUser)
+			out = append(out, bu)
+			if su.Changed {
+				changed = append(changed, bu)
+			}
+		}
+		return u.record(ctx, newUserEvents(usecase.UserSynced, changed...))
+	})
+	if err != nil {
+		return nil, err
 	}
 	return out, nil
 }
@@ -75,9 +118,87 @@ func (u *userUsecase) CreateUser(ctx context.Context, req usecase.CreateUserRequ
 	r := repository.CreateUserRepositoryRequestDTO{
 		BaseUser: mapper.UserUsecaseToRepo(req.BaseUser),
 	}
-	if err := u.repo.CreateUser(ctx, r); err != nil {
-		return nil, fmt.Errorf("repository.CreateUser: %w", err)
+	created := mapper.UserRepoToUsecase(r.BaseUser)
+	events := newUserEvents(usecase.UserCreated, created)
+	err = u.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.CreateUser(ctx, r); err != nil {
+			return fmt.Errorf("repository.CreateUser: %w", err)
+		}
+		return u.record(ctx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []usecase.BaseUser{created}, nil
+}
+
+// GetUser returns the stored user with the given id.
+func (u *userUsecase) GetUser(ctx context.Context, id string) (usecase.BaseUser, error) {
+	ru, err := u.repo.GetUserById(ctx, id)
+	if err != nil {
+		return usecase.BaseUser{}, err
+	}
+	return mapper.UserRepoToUsecase(ru), nil
+}
+
+// ListUsers returns the stored users matching req.Filter, oldest first.
+func (u *userUsecase) ListUsers(ctx context.Context, req usecase.ListUsersRequestDTO) (usecase.ListUsersResponseDTO, error) {
+	if req.Limit < 0 {
+		req.Limit = u.limit
+	}
+	dbRes, err := u.repo.FindUsers(ctx, repository.FindUsersRepositoryRequestDTO{
+		Filter: req.Filter,
+		Offset: max(req.Offset, 0),
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		return usecase.ListUsersResponseDTO{}, err
+	}
+
+	out := make([]usecase.BaseUser, 0, len(dbRes.List))
+	for _, ru := range dbRes.List {
+		out = append(out, mapper.UserRepoToUsecase(ru))
+	}
+	return usecase.ListUsersResponseDTO{Users: out, Total: int(dbRes.Total)}, nil
+}
+
+// ReplaceUser overwrites a stored user with req and returns its new state.
+func (u *userUsecase) ReplaceUser(ctx context.Context, req usecase.ReplaceUserRequestDTO) (usecase.BaseUser, error) {
+	if req.ID == nil {
+		return usecase.BaseUser{}, pkg.NewAppError(pkg.ErrBadRequest).AddDescription([]byte("user id is required")).AppendStackLog()
+	}
+
+	var replaced usecase.BaseUser
+	err := u.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.ReplaceUser(ctx, repository.UpdateUserRepositoryRequestDTO{BaseUser: mapper.UserUsecaseToRepo(req.BaseUser)}); err != nil {
+			return err
+		}
+		stored, err := u.repo.GetUserById(ctx, string(*req.ID))
+		if err != nil {
+			return err
+		}
+		replaced = mapper.UserRepoToUsecase(stored)
+		events := newUserEvents(usecase.UserUpdated, replaced)
+		return u.record(ctx, events)
+	})
+	if err != nil {
+		return usecase.BaseUser{}, err
 	}
+	return replaced, nil
+}
 
-	return
+// DeleteUser removes a stored user.
+func (u *userUsecase) DeleteUser(ctx context.Context, id string) error {
+	deleted := entity.ID(id)
+	events := newUserEvents(usecase.UserDeleted, usecase.BaseUser{ID: &deleted})
+	err := u.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		return u.record(ctx, events)
+	})
+	if err != nil {
+		return err
+	}
+	return nil
 }
@@ -1,9 +1,12 @@
 package usecase
 
 import (
+	"__MODULE__/internal/config"
 	"__MODULE__/internal/dto/client/integration"
 	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
 	"__MODULE__/internal/entity/user"
+	"__MODULE__/pkg"
 	"context"
 	"errors"
 	"testing"
@@ -41,16 +44,227 @@ func (m *MockRepository) GetUserById(ctx context.Context, id string) (repository
 	return repository.BaseUser{}, args.Error(1)
 }
 
+func (m *MockRepository) GetUserByExternalID(ctx context.Context, provider, externalID string) (repository.BaseUser, error) {
+	args := m.Called(ctx, provider, externalID)
+	if r, ok := args.Get(0).(repository.BaseUser); ok {
+		return r, args.Error(1)
+	}
+	return repository.BaseUser{}, args.Error(1)
+}
+
 func (m *MockRepository) UpdateUser(ctx context.Context, params repository.UpdateUserRepositoryRequestDTO) error {
 	args := m.Called(ctx, params)
 	return args.Error(0)
 }
 
+func (m *MockRepository) ReplaceUser(ctx context.Context, params repository.UpdateUserRepositoryRequestDTO) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindUsers(ctx context.Context, params repository.FindUsersRepositoryRequestDTO) (repository.ListRepositoryResponseDTO[repository.BaseUser], error) {
+	args := m.Called(ctx, params)
+	if res, ok := args.Get(0).(repository.ListRepositoryResponseDTO[repository.BaseUser]); ok {
+		return res, args.Error(1)
+	}
+	return repository.ListRepositoryResponseDTO[repository.BaseUser]{}, args.Error(1)
+}
+
 func (m *MockRepository) DeleteUser(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockRepository) GetUsersByProvider(ctx context.Context, provider string) ([]repository.BaseUser, error) {
+	args := m.Called(ctx, provider)
+	if r, ok := args.Get(0).([]repository.BaseUser); ok {
+		return r, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepository) CreateSyncRun(ctx context.Context, params repository.CreateSyncRunRepositoryRequestDTO) (uint, error) {
+	args := m.Called(ctx, params)
+	return uint(args.Int(0)), args.Error(1)
+}
+
+func (m *MockRepository) GetSyncRuns(ctx context.Context, params repository.GetSyncRunsRepositoryRequestDTO) ([]repository.SyncRun, error) {
+	args := m.Called(ctx, params)
+	if r, ok := args.Get(0).([]repository.SyncRun); ok {
+		return r, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// UpsertUsers returns the users it was given, all changed, unless the
+// expectation returns a list.
+func (m *MockRepository) UpsertUsers(ctx context.Context, params repository.UpsertUsersRepositoryRequestDTO) ([]repository.UpsertedUser, error) {
+	args := m.Called(ctx, params)
+	if r, ok := args.Get(0).([]repository.UpsertedUser); ok {
+		return r, args.Error(1)
+	}
+	out := make([]repository.UpsertedUser, 0, len(params.Users))
+	for _, u := range params.Users {
+		out = append(out, repository.UpsertedUser{BaseUser: u, Changed: true})
+	}
+	return out, args.Error(1)
+}
+
+// WithTransaction runs fn directly; there is no transaction to mock.
+func (m *MockRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (m *MockRepository) CreateRawResponse(ctx context.Context, params repository.CreateRawResponseRepositoryRequestDTO) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
+func (m *MockRepository) PurgeRawResponses(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockRepository) ClaimWebhookNonce(ctx context.Context, provider, nonce string, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, provider, nonce, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) ReleaseWebhookNonce(ctx context.Context, provider, nonce string) error {
+	args := m.Called(ctx, provider, nonce)
+	return args.Error(0)
+}
+
+func (m *MockRepository) PurgeWebhookNonces(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockRepository) CreateWebhookSubscription(ctx context.Context, sub repository.WebhookSubscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetWebhookSubscriptions(ctx context.Context) ([]repository.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	subs, _ := args.Get(0).([]repository.WebhookSubscription)
+	return subs, args.Error(1)
+}
+
+func (m *MockRepository) GetWebhookSubscription(ctx context.Context, id string) (repository.WebhookSubscription, error) {
+	args := m.Called(ctx, id)
+	sub, _ := args.Get(0).(repository.WebhookSubscription)
+	return sub, args.Error(1)
+}
+
+func (m *MockRepository) UpdateWebhookSubscription(ctx context.Context, sub repository.WebhookSubscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []repository.WebhookDelivery) error {
+	args := m.Called(ctx, deliveries)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetWebhookDelivery(ctx context.Context, id uint) (repository.WebhookDelivery, error) {
+	args := m.Called(ctx, id)
+	d, _ := args.Get(0).(repository.WebhookDelivery)
+	return d, args.Error(1)
+}
+
+func (m *MockRepository) GetWebhookDeliveries(ctx context.Context, params repository.GetWebhookDeliveriesRepositoryRequestDTO) ([]repository.WebhookDelivery, error) {
+	args := m.Called(ctx, params)
+	items, _ := args.Get(0).([]repository.WebhookDelivery)
+	return items, args.Error(1)
+}
+
+func (m *MockRepository) ClaimWebhookDeliveries(ctx context.Context, params repository.ClaimWebhookDeliveriesRepositoryRequestDTO) ([]repository.WebhookDelivery, error) {
+	args := m.Called(ctx, params)
+	items, _ := args.Get(0).([]repository.WebhookDelivery)
+	return items, args.Error(1)
+}
+
+func (m *MockRepository) UpdateWebhookDelivery(ctx context.Context, d repository.WebhookDelivery) error {
+	args := m.Called(ctx, d)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateOutboxEvents(ctx context.Context, events []repository.OutboxEvent) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetOutboxEvent(ctx context.Context, id uint) (repository.OutboxEvent, error) {
+	args := m.Called(ctx, id)
+	e, _ := args.Get(0).(repository.OutboxEvent)
+	return e, args.Error(1)
+}
+
+func (m *MockRepository) GetOutboxEvents(ctx context.Context, params repository.GetOutboxEventsRepositoryRequestDTO) ([]repository.OutboxEvent, error) {
+	args := m.Called(ctx, params)
+	items, _ := args.Get(0).([]repository.OutboxEvent)
+	return items, args.Error(1)
+}
+
+func (m *MockRepository) LockOutboxEvents(ctx context.Context, limit int) ([]repository.OutboxEvent, error) {
+	args := m.Called(ctx, limit)
+	items, _ := args.Get(0).([]repository.OutboxEvent)
+	return items, args.Error(1)
+}
+
+func (m *MockRepository) MarkOutboxEventsSent(ctx context.Context, ids []uint, sentAt time.Time) error {
+	args := m.Called(ctx, ids, sentAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ReplayOutboxEvents(ctx context.Context, params repository.ReplayOutboxEventsRepositoryRequestDTO) (int64, error) {
+	args := m.Called(ctx, params)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockRepository) PurgeOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockRepository) ClaimConsumedEvent(ctx context.Context, e repository.ConsumedEvent) (bool, error) {
+	args := m.Called(ctx, e)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) PurgeConsumedEvents(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockRepository) CreateDeadLetter(ctx context.Context, d repository.DeadLetter) (uint, error) {
+	args := m.Called(ctx, d)
+	return uint(args.Int(0)), args.Error(1)
+}
+
+func (m *MockRepository) GetDeadLetter(ctx context.Context, id uint) (repository.DeadLetter, error) {
+	args := m.Called(ctx, id)
+	d, _ := args.Get(0).(repository.DeadLetter)
+	return d, args.Error(1)
+}
+
+func (m *MockRepository) GetDeadLetters(ctx context.Context, params repository.GetDeadLettersRepositoryRequestDTO) ([]repository.DeadLetter, error) {
+	args := m.Called(ctx, params)
+	items, _ := args.Get(0).([]repository.DeadLetter)
+	return items, args.Error(1)
+}
+
+func (m *MockRepository) MarkDeadLetterReplayed(ctx context.Context, id uint, replayedAt time.Time) error {
+	args := m.Called(ctx, id, replayedAt)
+	return args.Error(0)
+}
+
 // Mock external user client
 type MockUserClient struct {
 	mock.Mock
@@ -168,16 +382,17 @@ func (s *UserUsecaseSuite) Test_GetUsers_CallsClientAndPersists_WhenRepoEmpty()
 	}
 	s.client.On("GetUsers", mock.Anything, 1).Return(clientResp, nil)
 
-	// expect CreateUser called twice
-	s.repo.On("CreateUser", mock.Anything, mock.Anything).Return(nil).Times(2)
+	// expect both users upserted in a single call
+	s.repo.On("UpsertUsers", mock.Anything, mock.MatchedBy(func(p repository.UpsertUsersRepositoryRequestDTO) bool {
+		return len(p.Users) == 2
+	})).Return(nil, nil).Once()
 
 	// call
 	resp, err := s.uc.GetUsers(context.Background(), 1)
 	assert.NoError(s.T(), err)
 	assert.Len(s.T(), resp, 2)
 
-	// verify CreateUser called twice
-	s.repo.AssertNumberOfCalls(s.T(), "CreateUser", 2)
+	s.repo.AssertNotCalled(s.T(), "CreateUser", mock.Anything, mock.Anything)
 	s.repo.AssertExpectations(s.T())
 	s.client.AssertExpectations(s.T())
 }
@@ -206,7 +421,7 @@ func (s *UserUsecaseSuite) Test_GetUsers_ReturnsError_WhenClientFails() {
 	s.repo.AssertExpectations(s.T())
 }
 
-func (s *UserUsecaseSuite) Test_GetUsers_ReturnsError_WhenCreateUserFails() {
+func (s *UserUsecaseSuite) Test_GetUsers_ReturnsError_WhenUpsertFails() {
 	// repo empty
 	emptyResp := repository.ListRepositoryResponseDTO[repository.BaseUser]{List: []repository.BaseUser{}}
 	s.repo.On("GetUsersList", mock.Anything, mock.Anything).Return(emptyResp, nil)
@@ -224,8 +439,8 @@ func (s *UserUsecaseSuite) Test_GetUsers_ReturnsError_WhenCreateUserFails() {
 	}
 	s.client.On("GetUsers", mock.Anything, 1).Return(clientResp, nil)
 
-	// CreateUser fails on first call
-	s.repo.On("CreateUser", mock.Anything, mock.Anything).Return(errors.New("insert failed"))
+	// the upsert fails
+	s.repo.On("UpsertUsers", mock.Anything, mock.Anything).Return(nil, errors.New("insert failed"))
 
 	resp, err := s.uc.GetUsers(context.Background(), 1)
 	assert.Error(s.T(), err)
@@ -234,3 +449,184 @@ func (s *UserUsecaseSuite) Test_GetUsers_ReturnsError_WhenCreateUserFails() {
 	s.repo.AssertExpectations(s.T())
 	s.client.AssertExpectations(s.T())
 }
+
+func (s *UserUsecaseSuite) Test_GetUsers_ScopesIdentitiesToProvider() {
+	provider := "jp-1"
+	emptyResp := repository.ListRepositoryResponseDTO[repository.BaseUser]{List: []repository.BaseUser{}}
+	s.repo.On("GetUsersList", mock.Anything, mock.Anything).Return(emptyResp, nil)
+
+	clientResp := integration.UserListResponseDTO{
+		Provider: "jsonplaceholder",
+		Instance: provider,
+		Users:    []integration.UserDTO{{ID: user.ID("1"), Name: user.FullName("Alice")}},
+	}
+	s.client.On("GetUsers", mock.Anything, 1).Return(clientResp, nil)
+
+	var stored repository.BaseUser
+	s.repo.On("UpsertUsers", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(repository.UpsertUsersRepositoryRequestDTO).Users[0]
+	}).Return(nil, nil)
+
+	resp, err := s.uc.GetUsers(pkg.WithProvider(context.Background(), provider), 1)
+	s.Require().NoError(err)
+	s.Require().Len(resp, 1)
+
+	s.Require().NotNil(stored.ID)
+	assert.NotEqual(s.T(), user.ID("1"), *stored.ID, "provider id must not become the internal id")
+	assert.Equal(s.T(), user.ExternalID("1"), *stored.ExternalID)
+	assert.Equal(s.T(), provider, *stored.Provider)
+	assert.Equal(s.T(), *stored.ID, *resp[0].ID)
+
+	s.repo.AssertCalled(s.T(), "GetUsersList", mock.Anything, mock.Anything)
+}
+
+func (s *UserUsecaseSuite) Test_GetUsers_PersistsExtraAndArchivesRaw() {
+	uc := NewUserUsecase(s.repo, s.client, 2, WithRawArchive(config.RawArchiveConfig{RawArchiveEnabled: true}))
+
+	emptyResp := repository.ListRepositoryResponseDTO[repository.BaseUser]{List: []repository.BaseUser{}}
+	s.repo.On("GetUsersList", mock.Anything, mock.Anything).Return(emptyResp, nil)
+
+	clientResp := integration.UserListResponseDTO{
+		Provider: "jsonplaceholder",
+		Instance: "jp-1",
+		Users: []integration.UserDTO{{
+			ID:    user.ID("1"),
+			Extra: map[string]string{"company": "Acme", "city": "Gwenborough", "catch_phrase": "hi"},
+		}},
+		Raw: []byte(`[{"id":1}]`),
+	}
+	s.client.On("GetUsers", mock.Anything, 3).Return(clientResp, nil)
+
+	var stored repository.BaseUser
+	s.repo.On("UpsertUsers", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(repository.UpsertUsersRepositoryRequestDTO).Users[0]
+	}).Return(nil, nil)
+	s.repo.On("CreateRawResponse", mock.Anything, mock.MatchedBy(func(p repository.CreateRawResponseRepositoryRequestDTO) bool {
+		return p.Provider == "jp-1" && p.ProviderType == "jsonplaceholder" && p.Page == 3 && string(p.Payload) == `[{"id":1}]`
+	})).Return(nil).Once()
+
+	resp, err := uc.GetUsers(context.Background(), 3)
+	s.Require().NoError(err)
+	s.Require().Len(resp, 1)
+
+	s.Require().NotNil(stored.Company)
+	assert.Equal(s.T(), user.Company("Acme"), *stored.Company)
+	assert.Equal(s.T(), user.City("Gwenborough"), *stored.City)
+	assert.Equal(s.T(), repository.Attributes{"catch_phrase": "hi"}, stored.Attributes)
+	assert.Equal(s.T(), map[string]string{"catch_phrase": "hi"}, resp[0].Extra)
+	s.repo.AssertExpectations(s.T())
+}
+
+func (s *UserUsecaseSuite) Test_GetUsers_ArchiveFailureIsIgnored() {
+	uc := NewUserUsecase(s.repo, s.client, 2, WithRawArchive(config.RawArchiveConfig{RawArchiveEnabled: true}))
+
+	emptyResp := repository.ListRepositoryResponseDTO[repository.BaseUser]{List: []repository.BaseUser{}}
+	s.repo.On("GetUsersList", mock.Anything, mock.Anything).Return(emptyResp, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{Raw: []byte("[]")}, nil)
+	s.repo.On("CreateRawResponse", mock.Anything, mock.Anything).Return(errors.New("archive down"))
+
+	_, err := uc.GetUsers(context.Background(), 1)
+	s.Require().NoError(err)
+}
+
+func (s *UserUsecaseSuite) Test_GetUsers_ReturnsIDsOfAlreadyCachedUsers() {
+	uc := NewUserUsecase(s.repo, s.client, 2, WithUpsertPolicy(config.UserUpsertConfig{
+		UpsertBatchSize:      50,
+		UpsertDefaultPolicy:  "overwrite",
+		UpsertColumnPolicies: config.ColumnPolicies{"email": "keep"},
+	}))
+
+	emptyResp := repository.ListRepositoryResponseDTO[repository.BaseUser]{List: []repository.BaseUser{}}
+	s.repo.On("GetUsersList", mock.Anything, mock.Anything).Return(emptyResp, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{
+		Provider: "jsonplaceholder",
+		Users:    []integration.UserDTO{{ID: user.ID("1")}, {ID: user.ID("")}},
+	}, nil)
+
+	cachedID, ext, provider := user.ID("cached"), user.ExternalID("1"), "jsonplaceholder"
+	s.repo.On("UpsertUsers", mock.Anything, mock.MatchedBy(func(p repository.UpsertUsersRepositoryRequestDTO) bool {
+		return len(p.Users) == 1 && *p.Users[0].Provider == "jsonplaceholder" && *p.Users[0].IsActive &&
+			p.BatchSize == 50 && p.Policy.For("email") == repository.ColumnKeep && p.Policy.For("phone") == repository.ColumnOverwrite
+	})).Return([]repository.UpsertedUser{{BaseUser: repository.BaseUser{ID: &cachedID, ExternalID: &ext, Provider: &provider}}}, nil).Once()
+
+	resp, err := uc.GetUsers(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Require().Len(resp, 1)
+	assert.Equal(s.T(), cachedID, *resp[0].ID)
+	s.repo.AssertExpectations(s.T())
+}
+
+func (s *UserUsecaseSuite) Test_GetUsers_AnnouncesOnlyChangedUsers() {
+	events := &MockUserEventPublisher{}
+	uc := NewUserUsecase(s.repo, s.client, 2, WithUserEvents(events))
+
+	emptyResp := repository.ListRepositoryResponseDTO[repository.BaseUser]{List: []repository.BaseUser{}}
+	s.repo.On("GetUsersList", mock.Anything, mock.Anything).Return(emptyResp, nil)
+	s.client.On("GetUsers", mock.Anything, 1).Return(integration.UserListResponseDTO{
+		Provider: "jsonplaceholder",
+		Users:    []integration.UserDTO{{ID: user.ID("1")}, {ID: user.ID("2")}},
+	}, nil)
+	same, changed := user.ID("same"), user.ID("changed")
+	s.repo.On("UpsertUsers", mock.Anything, mock.Anything).Return([]repository.UpsertedUser{
+		{BaseUser: repository.BaseUser{ID: &same}},
+		{BaseUser: repository.BaseUser{ID: &changed}, Changed: true},
+	}, nil).Once()
+	events.On("PublishUserEvents", mock.Anything, mock.MatchedBy(func(e []usecase.UserEvent) bool {
+		return len(e) == 1 && e[0].Type == usecase.UserSynced && *e[0].User.ID == changed
+	})).Return(nil).Once()
+
+	resp, err := uc.GetUsers(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Len(resp, 2, "unchanged users are still returned")
+	events.AssertExpectations(s.T())
+}
+
+func (s *UserUsecaseSuite) Test_CreateUser_ReturnsCreatedUser() {
+	username := user.Username("bjensen")
+	s.repo.On("CreateUser", mock.Anything, mock.MatchedBy(func(p repository.CreateUserRepositoryRequestDTO) bool {
+		return p.ID != nil && *p.Username == username
+	})).Return(nil).Once()
+
+	res, err := s.uc.CreateUser(context.Background(), usecase.CreateUserRequestDTO{BaseUser: usecase.BaseUser{Username: &username}})
+	s.Require().NoError(err)
+	s.Require().Len(res, 1)
+	s.Require().NotNil(res[0].ID)
+	assert.Equal(s.T(), username, *res[0].Username)
+}
+
+func (s *UserUsecaseSuite) Test_ListUsers_PassesFilterAndPaging() {
+	id, active := user.ID("u-1"), true
+	filter := &user.Filter{Op: user.OpEq, Field: user.FieldUsername, Value: "bjensen"}
+	s.repo.On("FindUsers", mock.Anything, repository.FindUsersRepositoryRequestDTO{Filter: filter, Offset: 10, Limit: 5}).
+		Return(repository.ListRepositoryResponseDTO[repository.BaseUser]{
+			List:                   []repository.BaseUser{{ID: &id, IsActive: &active}},
+			BasePaginationResponse: repository.BasePaginationResponse{Total: 11},
+		}, nil).Once()
+
+	res, err := s.uc.ListUsers(context.Background(), usecase.ListUsersRequestDTO{Filter: filter, Offset: 10, Limit: 5})
+	s.Require().NoError(err)
+	assert.Equal(s.T(), 11, res.Total)
+	s.Require().Len(res.Users, 1)
+	assert.Equal(s.T(), id, *res.Users[0].ID)
+	assert.True(s.T(), *res.Users[0].Active)
+}
+
+func (s *UserUsecaseSuite) Test_ReplaceUser_ReturnsStoredState() {
+	id, name := user.ID("u-1"), user.Username("bjensen")
+	created := time.Now()
+	s.repo.On("ReplaceUser", mock.Anything, mock.MatchedBy(func(p repository.UpdateUserRepositoryRequestDTO) bool {
+		return *p.ID == id && *p.Username == name && p.Email == nil
+	})).Return(nil).Once()
+	s.repo.On("GetUserById", mock.Anything, "u-1").Return(repository.BaseUser{ID: &id, Username: &name, CreatedAt: &created}, nil).Once()
+
+	res, err := s.uc.ReplaceUser(context.Background(), usecase.ReplaceUserRequestDTO{BaseUser: usecase.BaseUser{ID: &id, Username: &name}})
+	s.Require().NoError(err)
+	assert.Equal(s.T(), created, *res.CreatedAt)
+	s.repo.AssertExpectations(s.T())
+}
+
+func (s *UserUsecaseSuite) Test_ReplaceUser_RequiresID() {
+	_, err := s.uc.ReplaceUser(context.Background(), usecase.ReplaceUserRequestDTO{})
+	s.Require().Error(err)
+	s.repo.AssertNotCalled(s.T(), "ReplaceUser", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/mapper"
+	"__MODULE__/internal/dto/usecase"
+	entity "__MODULE__/internal/entity/user"
+	"__MODULE__/internal/interfaces"
+	"__MODULE__/pkg"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// webhookNoncePurgeInterval is how often expired delivery ids are deleted.
+const webhookNoncePurgeInterval = 5 * time.Minute
+
+// webhookJob is an event waiting to be applied.
+type webhookJob struct {
+	provider string
+	event    integration.WebhookEventDTO
+	attempt  int
+}
+
+// webhookUsecase applies provider webhook events in the background. Events of
+// a user always go to the same worker so they are applied in order, retries
+// aside.
+type webhookUsecase struct {
+	repo      interfaces.Repository
+	users     userWriter
+	providers interfaces.ProviderService
+	conf      config.WebhookConfig
+	now       func() time.Time
+
+	queues []chan webhookJob
+	// queued counts the events accepted and not yet done, retries waiting
+	// included. Reserving room here first keeps sends to queues from blocking.
+	queued atomic.Int64
+}
+
+// NewWebhookUsecase creates a new instance of the webhook usecase. Events are
+// only applied once Start is called. The user changes they make are published
+// and stored in the outbox as set by opts, like any other.
+func NewWebhookUsecase(repo interfaces.Repository, providers interfaces.ProviderService, conf config.WebhookConfig, opts ...Option) *webhookUsecase {
+	conf.WebhookWorkers = max(conf.WebhookWorkers, 1)
+	conf.WebhookQueueSize = max(conf.WebhookQueueSize, 1)
+	conf.WebhookMaxAttempts = max(conf.WebhookMaxAttempts, 1)
+	w := &webhookUsecase{
+		repo:      repo,
+		users:     newUserWriter(repo, applyOptions(opts)),
+		providers: providers,
+		conf:      conf,
+		now:       time.Now,
+		queues:    make([]chan webhookJob, conf.WebhookWorkers),
+	}
+	for i := range w.queues {
+		w.queues[i] = make(chan webhookJob, conf.WebhookQueueSize)
+	}
+	return w
+}
+
+var _ interfaces.WebhookUsecase = (*webhookUsecase)(nil)
+
+// ReceiveWebhook verifies the delivery, records its id against replays and
+// queues its events. A delivery that does not fit in the queue is forgotten so
+// the provider can send it again.
+func (w *webhookUsecase) ReceiveWebhook(ctx context.Context, req usecase.ReceiveWebhookRequestDTO) (n int, err error) {
+	status := "accepted"
+	defer func() {
+		if err != nil {
+			status = "rejected"
+			var appErr *pkg.AppError
+			if errors.As(err, &appErr) && appErr.ExternalCode() == http.StatusConflict {
+				status = "replayed"
+			}
+		}
+		pkg.CounterAdd("webhook_deliveries_total", 1, "provider", req.Provider, "status", status)
+	}()
+
+	receiver, err := w.providers.GetWebhookReceiver(req.Provider)
+	if err != nil {
+		return 0, err
+	}
+	nonce, expiresAt, err := receiver.Verify(req.Header, req.Body, w.now())
+	if err != nil {
+		return 0, err
+	}
+	events, err := receiver.Parse(req.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	fresh, err := w.repo.ClaimWebhookNonce(ctx, req.Provider, nonce, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	if !fresh {
+		return 0, pkg.NewAppError(pkg.ErrWebhookReplay).AddDescription([]byte(nonce)).AppendStackLog()
+	}
+
+	if !w.reserve(len(events)) {
+		if err := w.repo.ReleaseWebhookNonce(context.WithoutCancel(ctx), req.Provider, nonce); err != nil {
+			log.WithError(err).WithField("provider", req.Provider).Error("webhook: failed to release delivery id")
+		}
+		return 0, pkg.NewAppError(pkg.ErrWebhookQueueFull).AddDescription([]byte(req.Provider)).AppendStackLog()
+	}
+	for _, e := range events {
+		w.enqueue(webhookJob{provider: req.Provider, event: e, attempt: 1})
+	}
+	return len(events), nil
+}
+
+// Start runs one goroutine per worker and purges expired delivery ids until
+// ctx is done. Events still queued then are dropped.
+func (w *webhookUsecase) Start(ctx context.Context) {
+	for _, q := range w.queues {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-q:
+					w.process(ctx, job)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(webhookNoncePurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := w.repo.PurgeWebhookNonces(ctx, w.now())
+				if err != nil {
+					log.WithError(err).Error("webhook: failed to purge delivery ids")
+				} else if n > 0 {
+					log.WithField("deleted", n).Debug("purged expired webhook delivery ids")
+				}
+			}
+		}
+	}()
+}
+
+// reserve makes room for n events, reporting false when the queue is full.
+func (w *webhookUsecase) reserve(n int) bool {
+	for {
+		cur := w.queued.Load()
+		next := cur + int64(n)
+		if next > int64(w.conf.WebhookQueueSize) {
+			return false
+		}
+		if w.queued.CompareAndSwap(cur, next) {
+			pkg.GaugeSet("webhook_queue_depth", float64(next))
+			return true
+		}
+	}
+}
+
+// done gives back the room of a finished event.
+func (w *webhookUsecase) done() {
+	pkg.GaugeSet("webhook_queue_depth", float64(w.queued.Add(-1)))
+}
+
+// enqueue sends a job with reserved room to the worker owning its user.
+func (w *webhookUsecase) enqueue(job webhookJob) {
+	h := fnv.New32a()
+	h.Write([]byte(job.provider))
+	h.Write([]byte{0})
+	h.Write([]byte(job.event.User.ID))
+	w.queues[h.Sum32()%uint32(len(w.queues))] <- job
+}
+
+// process applies a job and schedules a retry with exponential backoff when it
+// fails, until WebhookMaxAttempts is reached.
+func (w *webhookUsecase) process(ctx context.Context, job webhookJob) {
+	err := w.apply(ctx, job.provider, job.event)
+	fields := log.Fields{"provider": job.provider, "event_id": job.event.ID, "type": job.event.Type, "attempt": job.attempt}
+	switch {
+	case err == nil:
+		pkg.CounterAdd("webhook_events_total", 1, "provider", job.provider, "type", string(job.event.Type), "status", "applied")
+		w.done()
+	case job.attempt >= w.conf.WebhookMaxAttempts || ctx.Err() != nil:
+		pkg.CounterAdd("webhook_events_total", 1, "provider", job.provider, "type", string(job.event.Type), "status", "failed")
+		log.WithError(err).WithFields(fields).Error("webhook: giving up on event")
+		w.done()
+	default:
+		pkg.CounterAdd("webhook_events_total", 1, "provider", job.provider, "type", string(job.event.Type), "status", "retried")
+		delay := w.backoff(job.attempt)
+		log.WithError(err).WithFields(fields).WithField("retry_in", delay).Warn("webhook: failed to apply event")
+		job.attempt++
+		time.AfterFunc(delay, func() { w.enqueue(job) })
+	}
+}
+
+// backoff returns the delay before the retry following attempt.
+func (w *webhookUsecase) backoff(attempt int) time.Duration {
+	delay := time.Duration(w.conf.WebhookRetryBase) * time.Millisecond
+	limit := time.Duration(w.conf.WebhookRetryMax) * time.Millisecond
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if limit > 0 {
+		delay = min(delay, limit)
+	}
+	return delay
+}
+
+// apply writes an event to the provider's users the way a sync would: created
+// and updated events create or update the user, deactivated ones mark it
+// inactive. Deactivating an unknown user is a no-op. Every change emits its
+// user event.
+func (w *webhookUsecase) apply(ctx context.Context, provider string, e integration.WebhookEventDTO) error {
+	externalID := string(e.User.ID)
+	current, err := w.repo.GetUserByExternalID(ctx, provider, externalID)
+	found := err == nil
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	if e.Type == integration.WebhookUserDeactivated {
+		if !found || (current.IsActive != nil && !*current.IsActive) {
+			return nil
+		}
+		return w.users.deactivateProviderUser(ctx, current)
+	}
+
+	u := e.User
+	UserIntegrationValidate(&u)
+	incoming := mapper.UserUsecaseToRepo(mapper.UserIntegrationToUsecase(u))
+	incoming.Provider = &provider
+	incoming.IsActive = pkg.PtrBool(true)
+	switch {
+	case !found:
+		internalID := entity.ID(uuid.New().String())
+		incoming.ID = &internalID
+		return w.users.createProviderUser(ctx, incoming)
+	case userChanged(current, incoming):
+		incoming.ID = current.ID
+		return w.users.updateProviderUser(ctx, incoming)
+	}
+	return nil
+}
+
+// isNotFound reports whether err is an application error with a 404 status.
+func isNotFound(err error) bool {
+	var appErr *pkg.AppError
+	return errors.As(err, &appErr) && appErr.ExternalCode() == http.StatusNotFound
+}
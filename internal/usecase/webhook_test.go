@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"__MODULE__/internal/config"
+	"__MODULE__/internal/dto/client/integration"
+	"__MODULE__/internal/dto/repository"
+	"__MODULE__/internal/dto/usecase"
+	"__MODULE__/internal/entity/user"
+	"__MODULE__/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReceiver accepts every delivery as nonce "d-1" and returns fixed events.
+type fakeReceiver struct {
+	verifyErr error
+	events    []integration.WebhookEventDTO
+}
+
+func (f fakeReceiver) Verify(http.Header, []byte, time.Time) (string, time.Time, error) {
+	return "d-1", time.Unix(100, 0), f.verifyErr
+}
+
+func (f fakeReceiver) Parse([]byte) ([]integration.WebhookEventDTO, error) {
+	return f.events, nil
+}
+
+func webhookEvent(id string, typ integration.WebhookEventType, userID, name string) integration.WebhookEventDTO {
+	return integration.WebhookEventDTO{ID: id, Type: typ, User: integration.UserDTO{ID: user.ID(userID), Name: user.FullName(name)}}
+}
+
+func newTestWebhookUsecase(repo *MockRepository, receiver fakeReceiver, conf config.WebhookConfig) *webhookUsecase {
+	providers := &MockProviderService{}
+	providers.On("GetWebhookReceiver", "hr").Return(receiver, nil)
+	return NewWebhookUsecase(repo, providers, conf)
+}
+
+func assertErrorCode(t *testing.T, code pkg.ErrorCode, err error) {
+	t.Helper()
+	var appErr *pkg.AppError
+	require.True(t, errors.As(err, &appErr), "expected an AppError, got %v", err)
+	assert.Equal(t, pkg.NewAppError(code).InternalCode(), appErr.InternalCode())
+}
+
+func TestReceiveWebhook_QueuesEvents(t *testing.T) {
+	repo := &MockRepository{}
+	repo.On("ClaimWebhookNonce", mock.Anything, "hr", "d-1", time.Unix(100, 0)).Return(true, nil).Once()
+	events := []integration.WebhookEventDTO{
+		webhookEvent("e1", integration.WebhookUserCreated, "1", "Ada"),
+		webhookEvent("e2", integration.WebhookUserDeactivated, "2", ""),
+	}
+	w := newTestWebhookUsecase(repo, fakeReceiver{events: events}, config.WebhookConfig{WebhookWorkers: 2, WebhookQueueSize: 10})
+
+	n, err := w.ReceiveWebhook(context.Background(), usecase.ReceiveWebhookRequestDTO{Provider: "hr"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, int64(2), w.queued.Load())
+	assert.Equal(t, 2, len(w.queues[0])+len(w.queues[1]))
+	repo.AssertExpectations(t)
+}
+
+func TestReceiveWebhook_RejectsReplays(t *testing.T) {
+	repo := &MockRepository{}
+	repo.On("ClaimWebhookNonce", mock.Anything, "hr", "d-1", mock.Anything).Return(false, nil)
+	w := newTestWebhookUsecase(repo, fakeReceiver{events: []integration.WebhookEventDTO{webhookEvent("e1", integration.WebhookUserCreated, "1", "Ada")}}, config.WebhookConfig{})
+
+	_, err := w.ReceiveWebhook(context.Background(), usecase.ReceiveWebhookRequestDTO{Provider: "hr"})
+	assertErrorCode(t, pkg.ErrWebhookReplay, err)
+	assert.Zero(t, w.queued.Load())
+}
+
+func TestReceiveWebhook_VerificationFailureClaimsNothing(t *testing.T) {
+	repo := &MockRepository{}
+	w := newTestWebhookUsecase(repo, fakeReceiver{verifyErr: pkg.NewAppError(pkg.ErrWebhookUnauthorized)}, config.WebhookConfig{})
+
+	_, err := w.ReceiveWebhook(context.Background(), usecase.ReceiveWebhookRequestDTO{Provider: "hr"})
+	assertErrorCode(t, pkg.ErrWebhookUnauthorized, err)
+	repo.AssertNotCalled(t, "ClaimWebhookNonce", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReceiveWebhook_FullQueueReleasesNonce(t *testing.T) {
+	repo := &MockRepository{}
+	repo.On("ClaimWebhookNonce", mock.Anything, "hr", "d-1", mock.Anything).Return(true, nil)
+	repo.On("ReleaseWebhookNonce", mock.Anything, "hr", "d-1").Return(nil).Once()
+	events := []integration.WebhookEventDTO{
+		webhookEvent("e1", integration.WebhookUserCreated, "1", "Ada"),
+		webhookEvent("e2", integration.WebhookUserCreated, "2", "Bob"),
+	}
+	w := newTestWebhookUsecase(repo, fakeReceiver{events: events}, config.WebhookConfig{WebhookQueueSize: 1})
+
+	_, err := w.ReceiveWebhook(context.Background(), usecase.ReceiveWebhookRequestDTO{Provider: "hr"})
+	assertErrorCode(t, pkg.ErrWebhookQueueFull, err)
+	assert.Zero(t, w.queued.Load())
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookApply(t *testing.T) {
+	ctx := context.Background()
+	notFound := pkg.NewAppError(pkg.ErrNotFound)
+	active := storedUser("2", "Bob", true)
+
+	repo := &MockRepository{}
+	repo.On("GetUserByExternalID", ctx, "hr", "1").Return(nil, notFound)
+	repo.On("GetUserByExternalID", ctx, "hr", "2").Return(active, nil)
+	repo.On("GetUserByExternalID", ctx, "hr", "3").Return(nil, notFound)
+	repo.On("CreateUser", ctx, mock.MatchedBy(func(p repository.CreateUserRepositoryRequestDTO) bool {
+		return *p.Provider == "hr" && string(*p.ExternalID) == "1" && *p.IsActive && p.ID != nil
+	})).Return(nil).Once()
+	repo.On("UpdateUser", ctx, mock.MatchedBy(func(p repository.UpdateUserRepositoryRequestDTO) bool {
+		return *p.ID == "int-2" && !*p.IsActive
+	})).Return(nil).Once()
+	w := newTestWebhookUsecase(repo, fakeReceiver{}, config.WebhookConfig{})
+
+	require.NoError(t, w.apply(ctx, "hr", webhookEvent("e1", integration.WebhookUserCreated, "1", "Ada")))
+	require.NoError(t, w.apply(ctx, "hr", webhookEvent("e2", integration.WebhookUserDeactivated, "2", "")))
+	// deactivating a user that was never stored is a no-op
+	require.NoError(t, w.apply(ctx, "hr", webhookEvent("e3", integration.WebhookUserDeactivated, "3", "")))
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookApply_EmitsUserEvents(t *testing.T) {
+	ctx := context.Background()
+	repo, events := &MockRepository{}, &MockUserEventPublisher{}
+	repo.On("GetUserByExternalID", ctx, "hr", "1").Return(nil, pkg.NewAppError(pkg.ErrNotFound))
+	repo.On("GetUserByExternalID", ctx, "hr", "2").Return(storedUser("2", "Bob", true), nil)
+	repo.On("CreateUser", ctx, mock.Anything).Return(nil).Once()
+	repo.On("UpdateUser", ctx, mock.Anything).Return(nil).Twice()
+
+	var stored []usecase.UserEventType
+	repo.On("CreateOutboxEvents", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		for _, e := range args.Get(1).([]repository.OutboxEvent) {
+			stored = append(stored, usecase.UserEventType(e.EventType))
+		}
+	}).Return(nil)
+	var published []usecase.UserEvent
+	events.On("PublishUserEvents", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).([]usecase.UserEvent)...)
+	}).Return(nil)
+
+	providers := &MockProviderService{}
+	w := NewWebhookUsecase(repo, providers, config.WebhookConfig{}, WithUserEvents(events), WithOutbox())
+	require.NoError(t, w.apply(ctx, "hr", webhookEvent("e1", integration.WebhookUserCreated, "1", "Ada")))
+	require.NoError(t, w.apply(ctx, "hr", webhookEvent("e2", integration.WebhookUserUpdated, "2", "Robert")))
+	require.NoError(t, w.apply(ctx, "hr", webhookEvent("e3", integration.WebhookUserDeactivated, "2", "")))
+
+	want := []usecase.UserEventType{usecase.UserCreated, usecase.UserUpdated, usecase.UserDeactivated}
+	assert.Equal(t, want, stored, "every change is stored in the outbox")
+	require.Len(t, published, len(want))
+	for i, e := range published {
+		assert.Equal(t, want[i], e.Type)
+	}
+	assert.Equal(t, "1", string(*published[0].User.ExternalID))
+	assert.Equal(t, "Robert", string(*published[1].User.FullName))
+	assert.False(t, *published[2].User.Active)
+}
+
+func TestWebhookStart_RetriesFailedEvents(t *testing.T) {
+	repo := &MockRepository{}
+	repo.On("ClaimWebhookNonce", mock.Anything, "hr", "d-1", mock.Anything).Return(true, nil)
+	repo.On("GetUserByExternalID", mock.Anything, "hr", "1").Return(nil, pkg.NewAppError(pkg.ErrNotFound))
+	repo.On("CreateUser", mock.Anything, mock.Anything).Return(errors.New("connection reset")).Once()
+	repo.On("CreateUser", mock.Anything, mock.Anything).Return(nil).Once()
+	w := newTestWebhookUsecase(repo, fakeReceiver{events: []integration.WebhookEventDTO{webhookEvent("e1", integration.WebhookUserCreated, "1", "Ada")}},
+		config.WebhookConfig{WebhookWorkers: 1, WebhookQueueSize: 10, WebhookMaxAttempts: 3, WebhookRetryBase: 1, WebhookRetryMax: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.Start(ctx)
+	_, err := w.ReceiveWebhook(ctx, usecase.ReceiveWebhookRequestDTO{Provider: "hr"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return w.queued.Load() == 0 }, time.Second, 5*time.Millisecond)
+	repo.AssertNumberOfCalls(t, "CreateUser", 2)
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	w := NewWebhookUsecase(nil, nil, config.WebhookConfig{WebhookRetryBase: 500, WebhookRetryMax: 3000})
+	assert.Equal(t, 500*time.Millisecond, w.backoff(1))
+	assert.Equal(t, 2*time.Second, w.backoff(3))
+	assert.Equal(t, 3*time.Second, w.backoff(10))
+}
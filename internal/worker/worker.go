@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"sync/atomic"
 
 	"__MODULE__/internal/config"
 	"__MODULE__/internal/interfaces"
@@ -34,6 +35,12 @@ func (w *worker) Start() {
 
 	// Register jobs with cron schedules
 	// w.registerJobs(ctx, w.conf.ExpirePendingEndOfDay, w.usecase.ExpireEndOfDayPendingTransactions, true)
+	if w.conf.ProviderSync != "" {
+		w.registerJobs(context.Background(), w.conf.ProviderSync, w.usecase.SyncProviders, true)
+	}
+	if w.conf.RawArchivePurge != "" {
+		w.registerJobs(context.Background(), w.conf.RawArchivePurge, w.usecase.PurgeRawResponses, true)
+	}
 
 	// Start the cron scheduler
 	w.cron.Start()
@@ -43,16 +50,15 @@ func (w *worker) registerJobs(ctx context.Context, schedule string, jobFunc jobF
 
 	// Check if the job is dependent on the previous jobs being completed
 	if dependent {
-		var isRunning bool // Flag to track job execution state
+		var isRunning atomic.Bool // Flag to track job execution state
 		_, err := w.cron.AddFunc(schedule, func() {
-			if isRunning {
+			if !isRunning.CompareAndSwap(false, true) {
 				log.Warn("Skipping job execution as the previous job is still running")
 				return
 			}
 
-			isRunning = true
 			defer func() {
-				isRunning = false
+				isRunning.Store(false)
 				if r := recover(); r != nil {
 					log.Error("Recovered from panic in job", "error", r)
 				}
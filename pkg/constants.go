@@ -45,4 +45,13 @@ var (
 
 type ContextKey string
 
-const MetadataKey ContextKey = "metadata"
+const (
+	MetadataKey ContextKey = "metadata"
+	// ProviderKey carries the user provider instance id selected for a request.
+	ProviderKey ContextKey = "provider"
+	// RequestIDKey carries the id used to correlate logs and outgoing calls.
+	RequestIDKey ContextKey = "request_id"
+)
+
+// RequestIDHeader is the header used to propagate request ids.
+const RequestIDHeader = "X-Request-ID"
@@ -2,7 +2,10 @@ package pkg
 
 import (
 	"bytes"
+	_ "embed"
 	"encoding/json"
+	"errors"
+	"io/fs"
 	"os"
 	"runtime"
 	"strconv"
@@ -11,9 +14,19 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// defaultErrorConfig is the copy of errors.json shipped with the package. It is
+// used when no errors.json exists in the working directory (e.g. go test runs
+// inside a package directory).
+//
+//go:embed errors.json
+var defaultErrorConfig []byte
+
 func init() {
 	// Load the error configuration from the JSON file
 	err := LoadErrorConfig("errors.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		err = loadErrorConfigBytes(defaultErrorConfig)
+	}
 	if err != nil {
 		panic("Failed to load error configuration: " + err.Error())
 	}
@@ -61,7 +74,10 @@ func LoadErrorConfig(filePath string) error {
 	if err != nil {
 		return err
 	}
+	return loadErrorConfigBytes(data)
+}
 
+func loadErrorConfigBytes(data []byte) error {
 	// raw structure used only for JSON unmarshalling (exported fields so
 	// encoding/json can populate them). We convert these into AppError values
 	// with unexported fields so callers cannot mutate fields directly.
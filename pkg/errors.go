@@ -5,14 +5,46 @@ type ErrorCode int
 const (
 	ErrBadRequest ErrorCode = iota
 	ErrNotFound
+	ErrUnauthorized
+
+	// user providers
+	ErrUnknownProvider
+	ErrProviderNotFound
+	ErrProviderAlreadyExists
+	ErrProviderCircuitOpen
+	ErrProviderBulkheadFull
+	ErrAggregateUnavailable
+
+	// provider synchronisation
+	ErrSyncInProgress
+
+	// inbound provider webhooks
+	ErrWebhookNotEnabled
+	ErrWebhookUnauthorized
+	ErrWebhookReplay
+	ErrWebhookInvalidPayload
+	ErrWebhookQueueFull
 
 // add more error codes as needed
 )
 
 // ErrorNames maps ErrorCode values to template keys in the JSON file.
 var ErrorNames = map[ErrorCode]string{
-	ErrBadRequest: "ErrBadRequest",
-	ErrNotFound:   "ErrNotFound",
+	ErrBadRequest:            "ErrBadRequest",
+	ErrNotFound:              "ErrNotFound",
+	ErrUnauthorized:          "ErrUnauthorized",
+	ErrUnknownProvider:       "ErrUnknownProvider",
+	ErrProviderNotFound:      "ErrProviderNotFound",
+	ErrProviderAlreadyExists: "ErrProviderAlreadyExists",
+	ErrProviderCircuitOpen:   "ErrProviderCircuitOpen",
+	ErrProviderBulkheadFull:  "ErrProviderBulkheadFull",
+	ErrAggregateUnavailable:  "ErrAggregateUnavailable",
+	ErrSyncInProgress:        "ErrSyncInProgress",
+	ErrWebhookNotEnabled:     "ErrWebhookNotEnabled",
+	ErrWebhookUnauthorized:   "ErrWebhookUnauthorized",
+	ErrWebhookReplay:         "ErrWebhookReplay",
+	ErrWebhookInvalidPayload: "ErrWebhookInvalidPayload",
+	ErrWebhookQueueFull:      "ErrWebhookQueueFull",
 }
 
 // String implements fmt.Stringer.
@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"context"
 	"os"
 	"time"
 )
@@ -32,3 +33,25 @@ func EnvOrDefault(key, def string) string {
 	}
 	return val
 }
+
+// WithProvider returns a copy of ctx that selects the given user provider instance.
+func WithProvider(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ProviderKey, id)
+}
+
+// ProviderFromContext returns the user provider instance selected for ctx, if any.
+func ProviderFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ProviderKey).(string)
+	return id, ok && id != ""
+}
+
+// WithRequestID returns a copy of ctx carrying the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, id)
+}
+
+// RequestIDFromContext returns the request id carried by ctx, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(RequestIDKey).(string)
+	return id, ok && id != ""
+}
@@ -269,6 +269,14 @@ func (h *MaskingHook) Fire(entry *logrus.Entry) error {
 	return nil
 }
 
+var defaultMaskingHook = NewMaskingHook()
+
+// MaskString applies the MaskingHook rules to s. Use it for data that is
+// logged outside logrus fields, e.g. HTTP bodies.
+func MaskString(s string) string {
+	return defaultMaskingHook.maskString(s)
+}
+
 // maskString applies the regex-based masking rules
 func (h *MaskingHook) maskString(s string) string {
 	if s == "" {
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// A small in-process metrics registry rendered in the Prometheus text format.
+// Labels are passed as key/value pairs, e.g.
+//
+//	pkg.CounterAdd("provider_calls_total", 1, "provider", id, "result", "error")
+
+type metricKind int
+
+const (
+	metricCounter metricKind = iota
+	metricGauge
+)
+
+type metricFamily struct {
+	kind   metricKind
+	series map[string]float64 // rendered labels -> value
+}
+
+var (
+	metricsMu sync.Mutex
+	metrics   = map[string]*metricFamily{}
+)
+
+// CounterAdd increases the counter name{labels} by delta.
+func CounterAdd(name string, delta float64, labels ...string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	family(name, metricCounter).series[renderLabels(labels)] += delta
+}
+
+// GaugeSet sets the gauge name{labels} to value.
+func GaugeSet(name string, value float64, labels ...string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	family(name, metricGauge).series[renderLabels(labels)] = value
+}
+
+// Observe records value into the name_sum and name_count counters, which is
+// enough to derive averages such as request latency.
+func Observe(name string, value float64, labels ...string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	key := renderLabels(labels)
+	family(name+"_sum", metricCounter).series[key] += value
+	family(name+"_count", metricCounter).series[key]++
+}
+
+// MetricValue returns the current value of name{labels}; mostly useful in tests.
+func MetricValue(name string, labels ...string) float64 {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if f, ok := metrics[name]; ok {
+		return f.series[renderLabels(labels)]
+	}
+	return 0
+}
+
+// WriteMetrics writes every metric in the Prometheus text exposition format.
+func WriteMetrics(w io.Writer) error {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		f := metrics[name]
+		kind := "counter"
+		if f.kind == metricGauge {
+			kind = "gauge"
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", name, kind); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if _, err := fmt.Fprintf(w, "%s%s %g\n", name, k, f.series[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// family must be called with metricsMu held.
+func family(name string, kind metricKind) *metricFamily {
+	f, ok := metrics[name]
+	if !ok {
+		f = &metricFamily{kind: kind, series: map[string]float64{}}
+		metrics[name] = f
+	}
+	return f
+}
+
+func renderLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
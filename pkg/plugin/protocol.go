@@ -0,0 +1,111 @@
+/*
+Package plugin defines the protocol spoken between the service and user
+provider plugins, external executables registered as instances of the
+"plugin" provider type, and a Serve helper for plugins written in Go.
+
+The service starts the executable and exchanges newline-delimited JSON-RPC 2.0
+messages with it: requests on the plugin's stdin, responses on its stdout, one
+JSON object per line. Anything the plugin writes to stderr is logged. Calls may
+be in flight concurrently; responses are matched by id and may arrive in any
+order. The methods are:
+
+	handshake  HandshakeParams -> HandshakeResult, always the first call
+	get_users  GetUsersParams  -> GetUsersResult
+	health     (no params)     -> HealthResult
+
+A plugin answering a handshake with a protocol version other than the one
+requested is stopped.
+*/
+package plugin
+
+import "encoding/json"
+
+// ProtocolVersion is the version of the protocol described here.
+const ProtocolVersion = 1
+
+const (
+	MethodHandshake = "handshake"
+	MethodGetUsers  = "get_users"
+	MethodHealth    = "health"
+)
+
+// JSON-RPC error codes used by the protocol.
+const (
+	CodeParseError     = -32700
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeVersionMismatch answers a handshake with an unsupported version.
+	CodeVersionMismatch = -32000
+)
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// HandshakeParams hands the plugin its instance id, credentials and the
+// plugin-specific config of the instance.
+type HandshakeParams struct {
+	ProtocolVersion int               `json:"protocol_version"`
+	Instance        string            `json:"instance"`
+	Credentials     map[string]string `json:"credentials,omitempty"`
+	Config          json.RawMessage   `json:"config,omitempty"`
+}
+
+type HandshakeResult struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Name            string `json:"name"`
+	Version         string `json:"version,omitempty"`
+}
+
+type GetUsersParams struct {
+	Page int `json:"page"`
+}
+
+// User mirrors the provider user of the service. Extra carries any other
+// field; avatar, company and city are recognised.
+type User struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	Username string            `json:"username,omitempty"`
+	Email    string            `json:"email,omitempty"`
+	Phone    string            `json:"phone,omitempty"`
+	Website  string            `json:"website,omitempty"`
+	Extra    map[string]string `json:"extra,omitempty"`
+}
+
+// Meta describes paging; a plugin without pages leaves it empty.
+type Meta struct {
+	Page       int `json:"page,omitempty"`
+	PerPage    int `json:"per_page,omitempty"`
+	Total      int `json:"total,omitempty"`
+	TotalPages int `json:"total_pages,omitempty"`
+}
+
+type GetUsersResult struct {
+	Users []User `json:"users"`
+	Meta  Meta   `json:"meta,omitempty"`
+}
+
+type HealthResult struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxMessageBytes bounds a single request line read by Serve.
+const maxMessageBytes = 16 << 20
+
+// Provider is implemented by plugins served with Serve.
+type Provider interface {
+	GetUsers(ctx context.Context, page int) (GetUsersResult, error)
+}
+
+// Configurer is implemented by providers that need the handshake parameters,
+// e.g. their credentials. An error fails the handshake.
+type Configurer interface {
+	Configure(params HandshakeParams) error
+}
+
+// HealthChecker is implemented by providers that can report their own health;
+// providers without it are healthy as long as they answer.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// Serve answers requests read from in on out until in is closed or ctx is
+// done, typically with os.Stdin and os.Stdout. info is returned from the
+// handshake; its ProtocolVersion is filled in. Requests are handled
+// concurrently and Serve waits for the running ones before returning.
+func Serve(ctx context.Context, in io.Reader, out io.Writer, info HandshakeResult, p Provider) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	info.ProtocolVersion = ProtocolVersion
+
+	var (
+		writeMu sync.Mutex
+		running sync.WaitGroup
+		enc     = json.NewEncoder(out)
+	)
+	reply := func(resp Response) {
+		resp.JSONRPC = "2.0"
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = enc.Encode(resp)
+	}
+	defer running.Wait()
+
+	lines := make(chan []byte)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageBytes)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-scanErr:
+			return err
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			var req Request
+			if err := json.Unmarshal(line, &req); err != nil {
+				reply(Response{Error: &Error{Code: CodeParseError, Message: err.Error()}})
+				continue
+			}
+			running.Add(1)
+			go func() {
+				defer running.Done()
+				reply(handle(ctx, req, info, p))
+			}()
+		}
+	}
+}
+
+func handle(ctx context.Context, req Request, info HandshakeResult, p Provider) Response {
+	resp := Response{ID: req.ID}
+	var (
+		result any
+		err    error
+	)
+	switch req.Method {
+	case MethodHandshake:
+		var params HandshakeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: err.Error()}
+			return resp
+		}
+		if params.ProtocolVersion != ProtocolVersion {
+			resp.Error = &Error{Code: CodeVersionMismatch, Message: fmt.Sprintf("protocol version %d is not supported, want %d", params.ProtocolVersion, ProtocolVersion)}
+			return resp
+		}
+		if c, ok := p.(Configurer); ok {
+			err = c.Configure(params)
+		}
+		result = info
+	case MethodGetUsers:
+		var params GetUsersParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: err.Error()}
+			return resp
+		}
+		result, err = p.GetUsers(ctx, params.Page)
+	case MethodHealth:
+		if h, ok := p.(HealthChecker); ok {
+			err = h.Health(ctx)
+		}
+		result = HealthResult{Status: "ok"}
+	default:
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: "unknown method " + req.Method}
+		return resp
+	}
+	if err != nil {
+		resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+		return resp
+	}
+	resp.Result, err = json.Marshal(result)
+	if err != nil {
+		resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+	return resp
+}
@@ -0,0 +1,19 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// SignWebhook returns the HMAC-SHA256 of "<id>.<timestamp>.<body>" keyed
+// with secret, the signature of inbound and outbound webhook deliveries. The
+// delivery id is signed as it is the replay nonce.
+func SignWebhook(secret, id, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return mac.Sum(nil)
+}